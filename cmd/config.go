@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sopial42/cleanic/internal/config"
+)
+
+const configUsage = `usage: cleanic config <command> [flags]
+
+commands:
+  validate  report every configuration problem at once
+  print     print the resolved settings and their source, secrets redacted
+
+flags are the same as the server ones, e.g. --config cleanic.yaml --server-port 8081`
+
+func runConfigCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(configUsage)
+	}
+
+	values, err := config.Describe(args[1:])
+	switch args[0] {
+	case "validate":
+		if err != nil {
+			return fmt.Errorf("invalid configuration:\n%w", err)
+		}
+
+		fmt.Fprintln(out, "configuration is valid")
+		return nil
+	case "print":
+		for _, value := range values {
+			fmt.Fprintln(out, value)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid configuration:\n%w", err)
+		}
+
+		return nil
+	default:
+		return fmt.Errorf("unknown config command %q\n%s", args[0], configUsage)
+	}
+}
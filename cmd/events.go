@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	eventCLI "github.com/sopial42/cleanic/internal/adapters/clients/event"
+	"github.com/sopial42/cleanic/internal/config"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+// newEventSinks returns the sinks enabled in the configuration, in the order they are published to.
+// The webhook subscriptions come first as recording their deliveries again on a retry is a no-op
+func newEventSinks(cfg config.EventsConfig, webhooksCfg config.WebhooksConfig, webhooks webhookSVC.Service) ([]eventSVC.Sink, error) {
+	var sinks []eventSVC.Sink
+	if webhooksCfg.Enabled {
+		sinks = append(sinks, eventCLI.NewSubscriptionsSink(webhooks))
+	}
+
+	if cfg.FilePath != "" {
+		sinks = append(sinks, eventCLI.NewFileSink(cfg.FilePath))
+	}
+
+	if cfg.NATSURL != "" {
+		natsSink, err := eventCLI.NewNATSSink(cfg.NATSURL, cfg.NATSSubjectPrefix)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, natsSink)
+	}
+
+	if cfg.WebhookURL != "" {
+		sinks = append(sinks, eventCLI.NewWebhookSink(cfg.WebhookURL))
+	}
+
+	return sinks, nil
+}
+
+// runEventDispatcher delivers the outbox until ctx is done, the returned channel is closed once it stopped
+func runEventDispatcher(ctx context.Context, events eventSVC.Service, cfg config.EventsConfig, logger *slog.Logger) <-chan struct{} {
+	return runPoller(ctx, events.Dispatch, cfg.BatchSize, cfg.DispatchInterval, func(err error) {
+		logger.Warn("Some events were not delivered, they will be retried", "error", err)
+	})
+}
+
+// runWebhookDeliverer attempts the due webhook deliveries until ctx is done, the returned channel is closed once it stopped
+func runWebhookDeliverer(ctx context.Context, webhooks webhookSVC.Service, cfg config.WebhooksConfig, logger *slog.Logger) <-chan struct{} {
+	return runPoller(ctx, webhooks.Deliver, cfg.BatchSize, cfg.DeliveryInterval, func(err error) {
+		logger.Warn("Some webhook attempts were not recorded, the deliveries will be attempted again", "error", err)
+	})
+}
+
+// runPoller calls poll until ctx is done, the returned channel is closed once it stopped.
+// A full batch is followed by the next one right away, poll is called every interval otherwise
+func runPoller(ctx context.Context, poll func(ctx context.Context) (int, error), batchSize int, interval time.Duration, onError func(err error)) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			// the batch in progress completes, its items would otherwise wait for their lease to end
+			handled, err := poll(context.WithoutCancel(ctx))
+			if err != nil {
+				onError(err)
+			}
+
+			if handled == batchSize && ctx.Err() == nil {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+
+	return done
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/sopial42/cleanic/internal/config"
+	job "github.com/sopial42/cleanic/internal/domains/job"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+)
+
+const (
+	// jobKindIdempotencyPurge deletes the expired idempotency keys
+	jobKindIdempotencyPurge job.Kind = "idempotency.purge"
+	// jobKindSessionPurge deletes the expired refresh tokens and the old session history
+	jobKindSessionPurge job.Kind = "auth.session_purge"
+)
+
+// registerJobs binds the handlers of the job kinds and schedules the periodic ones
+func registerJobs(jobs jobSVC.Service, idempotency idempotencySVC.Service, auth authSVC.Service) error {
+	jobs.Register(jobKindIdempotencyPurge, jobSVC.HandlerFunc[struct{}](func(ctx context.Context, _ struct{}) error {
+		return idempotency.PurgeExpired(ctx)
+	}))
+	jobs.Register(jobKindSessionPurge, jobSVC.HandlerFunc[struct{}](func(ctx context.Context, _ struct{}) error {
+		return auth.PurgeExpiredSessions(ctx)
+	}))
+
+	hourly, err := job.ParseSchedule("@hourly")
+	if err != nil {
+		return err
+	}
+	jobs.Schedule(jobKindIdempotencyPurge, hourly)
+	jobs.Schedule(jobKindSessionPurge, hourly)
+
+	return nil
+}
+
+// runJobWorker runs the due jobs until ctx is done, the returned channel is closed once the jobs in progress ended
+func runJobWorker(ctx context.Context, jobs jobSVC.Service, cfg config.JobsConfig, logger *slog.Logger) <-chan struct{} {
+	return runPoller(ctx, jobs.Work, cfg.Concurrency, cfg.PollInterval, func(err error) {
+		logger.Warn("Some jobs failed, they will be retried unless dead", "error", err)
+	})
+}
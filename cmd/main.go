@@ -2,59 +2,236 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 
+	clinicCLI "github.com/sopial42/cleanic/internal/adapters/clients/clinic"
+	consentCLI "github.com/sopial42/cleanic/internal/adapters/clients/consent"
+	eventCLI "github.com/sopial42/cleanic/internal/adapters/clients/event"
+	oidcCLI "github.com/sopial42/cleanic/internal/adapters/clients/oidc"
+	roleCLI "github.com/sopial42/cleanic/internal/adapters/clients/role"
 	userCLI "github.com/sopial42/cleanic/internal/adapters/clients/user"
-	persistence "github.com/sopial42/cleanic/internal/adapters/persistence"
-	authPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/auth"
-	patientPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/patient"
-	userPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/user"
-	authHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/auth"
+	webhookCLI "github.com/sopial42/cleanic/internal/adapters/clients/webhook"
+	"github.com/sopial42/cleanic/internal/adapters/logging"
+	"github.com/sopial42/cleanic/internal/adapters/metrics"
+	metricsHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/metrics"
 	authMiddleware "github.com/sopial42/cleanic/internal/adapters/rest/middleware"
-	patientHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/patient"
-	userHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/user"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/cookiestore"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	"github.com/sopial42/cleanic/internal/adapters/telemetry"
 	"github.com/sopial42/cleanic/internal/config"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
 	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+	passwordSVC "github.com/sopial42/cleanic/internal/services/password"
 	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+	rateLimitSVC "github.com/sopial42/cleanic/internal/services/ratelimit"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
 	userSVC "github.com/sopial42/cleanic/internal/services/user"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
 )
 
 func main() {
-	config := config.Load()
-	pgClient := persistence.NewPGClient(config.DB)
+	if err := run(os.Args[1:]); err != nil {
+		slog.Error("Unable to run the server", "error", err)
+		os.Exit(1)
+	}
+}
 
-	refreshMiddleware := authMiddleware.NewAuthRefreshMiddleware(config.JWT.RefreshTokenConfig)
-	accessMiddleware := authMiddleware.NewAuthAccessMiddleware(config.JWT.AccessTokenConfig)
-	userPersistence := userPersistence.NewPGClient(pgClient)
-	authPersistence := authPersistence.NewPGClient(pgClient)
-	userService := userSVC.NewUserService(userPersistence)
+// run starts the server, unless args start with a subcommand such as "config validate"
+func run(args []string) error {
+	if len(args) > 0 && args[0] == "config" {
+		return runConfigCommand(args[1:], os.Stdout)
+	}
+
+	config, err := config.Load(args)
+	if err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	slog.SetDefault(logging.NewLogger(config.Log, os.Stdout))
+	logger := logging.Component("main")
+
+	shutdownTracing, err := telemetry.SetupTracing(context.Background(), config.Tracing, os.Stdout)
+	if err != nil {
+		return err
+	}
+
+	metricsRegistry := metrics.NewRegistry()
+
+	storage, err := newStorage(context.Background(), *config, metricsRegistry)
+	if err != nil {
+		return fmt.Errorf("unable to init storage: %w", err)
+	}
+
+	if config.InMemory() {
+		logger.Warn("Running with the memory storage, the data is lost on restart")
+	}
+
+	roleService := roleSVC.NewRoleService(storage.role)
+
+	webhookService := webhookSVC.NewWebhookService(storage.webhook, webhookCLI.NewHTTPSender(config.Webhooks.Timeout, config.Webhooks.AllowedNetworks), config.Webhooks)
+
+	eventSinks, err := newEventSinks(config.Events, config.Webhooks, webhookService)
+	if err != nil {
+		return fmt.Errorf("unable to init event sinks: %w", err)
+	}
+
+	eventService := eventSVC.NewEventService(storage.event, eventSinks, config.Events)
+	eventClient := eventCLI.NewInMemoryEventClient(eventService)
+
+	passwordService, err := passwordSVC.NewPasswordService(config.Password)
+	if err != nil {
+		return fmt.Errorf("unable to init password service: %w", err)
+	}
+
+	roleClient := roleCLI.NewInMemoryRoleClient(roleService)
+	userService := userSVC.NewTracedService(userSVC.NewUserService(storage.user, passwordService, roleClient, storage.auth, eventClient, storage.unitOfWork))
 
 	userClient := userCLI.NewInMemoryUserClient(userService)
-	authService := authSVC.NewAuthService(userClient, config.JWT, authPersistence)
 
-	patientPersistence := patientPersistence.NewPGClient(pgClient)
-	patientService := patientSVC.NewPatientService(patientPersistence)
+	apiKeyService := apiKeySVC.NewAPIKeyService(storage.apiKey, userClient, roleClient)
+
+	rateLimitMiddleware := authMiddleware.NewRateLimitMiddleware(rateLimitSVC.NewRateLimitService(storage.rateLimit), config.RateLimit)
+
+	idempotencyService := idempotencySVC.NewIdempotencyService(storage.idempotency, config.IdempotencyKeyTTL)
+	idempotencyMiddleware := authMiddleware.NewIdempotencyMiddleware(idempotencyService)
+
+	refreshMiddleware := authMiddleware.NewAuthRefreshMiddleware(config.JWT.RefreshTokenConfig)
+	accessMiddleware := authMiddleware.NewAuthAccessMiddleware(config.JWT.AccessTokenConfig, roleService, apiKeyService, rateLimitMiddleware)
+
+	var identityProvider authSVC.IdentityProvider
+	if config.OIDC.Enabled() {
+		identityProvider, err = oidcCLI.NewOIDCClient(context.Background(), config.OIDC)
+		if err != nil {
+			return fmt.Errorf("unable to init OIDC client: %w", err)
+		}
+	}
+
+	clinicService := clinicSVC.NewClinicService(storage.clinic, roleClient)
+	clinicClient := clinicCLI.NewInMemoryClinicClient(clinicService)
+
+	authService, err := authSVC.NewAuthService(userClient, clinicClient, config.JWT, config.Login, config.Sessions, storage.auth, passwordService, identityProvider, config.OIDC, metrics.NewAuthMetrics(metricsRegistry), storage.unitOfWork)
+	if err != nil {
+		return fmt.Errorf("unable to init auth service: %w", err)
+	}
+
+	authService = authSVC.NewTracedService(authService)
+
+	jobService := jobSVC.NewJobService(storage.job, config.Jobs)
+	if err := registerJobs(jobService, idempotencyService, authService); err != nil {
+		return fmt.Errorf("unable to register jobs: %w", err)
+	}
+
+	consentService := consentSVC.NewConsentService(storage.consent, eventClient, storage.unitOfWork)
+	consentClient := consentCLI.NewInMemoryConsentClient(consentService)
+
+	patientService := patientSVC.NewTracedService(patientSVC.NewPatientService(storage.patient, eventClient, consentClient, storage.unitOfWork))
+
+	privacyService := privacySVC.NewPrivacyService(storage.privacy, eventClient, storage.unitOfWork)
+
+	healthService := healthSVC.NewHealthService(storage.health, storage.expectedMigration, logging.Component("health"))
 
 	engine := echo.New()
-	engine.Use(middleware.Logger())
-	engine.Use(session.Middleware(sessions.NewCookieStore(config.JWT.CookieStoreConfig.Secret)))
+	// every output goes through the JSON logger
+	engine.HideBanner = true
+	engine.HidePort = true
+	engine.IPExtractor = authMiddleware.NewIPExtractor(config.TrustedProxies)
+	engine.HTTPErrorHandler = envelope.NewHTTPErrorHandler(apiV2Prefix, engine.DefaultHTTPErrorHandler)
+	engine.Use(authMiddleware.RequestID)
+	engine.Use(authMiddleware.Deprecation(apiV1Prefix, "/api/v2", config.API.V1Deprecation, config.API.V1Sunset))
+	engine.Use(otelecho.Middleware(config.Tracing.ServiceName, otelecho.WithSkipper(func(c echo.Context) bool {
+		// the orchestrator probes would drown the useful traces
+		return c.Path() == "/healthz" || c.Path() == "/readyz"
+	})))
+	engine.Use(authMiddleware.NewRequestLogger(logging.Component("rest")))
+	engine.Use(authMiddleware.NewMetricsMiddleware(metricsRegistry))
+	engine.Use(session.Middleware(cookiestore.NewRotatingCookieStore(config.JWT.CookieStoreConfig.Secrets)))
+
+	spec, err := openapi.NewSpec(openapi.Info{Title: "cleanic", Version: healthService.BuildInfo().Version}, apiOperations(*config))
+	if err != nil {
+		return fmt.Errorf("unable to build the OpenAPI document: %w", err)
+	}
+
+	setRoutes(engine, *config, spec, routeDependencies{
+		healthService:         healthService,
+		patientService:        patientService,
+		consentService:        consentService,
+		privacyService:        privacyService,
+		userService:           userService,
+		roleService:           roleService,
+		apiKeyService:         apiKeyService,
+		authService:           authService,
+		clinicService:         clinicService,
+		webhookService:        webhookService,
+		jobService:            jobService,
+		refreshMiddleware:     refreshMiddleware,
+		accessMiddleware:      accessMiddleware,
+		rateLimitMiddleware:   rateLimitMiddleware,
+		idempotencyMiddleware: idempotencyMiddleware,
+	})
+
+	go func() {
+		logger.Info("Server listening", "address", config.Address())
+		if err := engine.Start(config.Address()); err != nil {
+			logger.Info("Shutting down the server", "error", err)
+		}
+	}()
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	var dispatcherDone, delivererDone, workerDone <-chan struct{}
+	if len(eventSinks) > 0 {
+		dispatcherDone = runEventDispatcher(backgroundCtx, eventService, config.Events, logging.Component("events"))
+	}
+
+	if config.Webhooks.Enabled {
+		delivererDone = runWebhookDeliverer(backgroundCtx, webhookService, config.Webhooks, logging.Component("webhooks"))
+	}
+
+	if config.Jobs.Enabled {
+		workerDone = runJobWorker(backgroundCtx, jobService, config.Jobs, logging.Component("jobs"))
+	}
 
-	patientHTTPHandler.SetHandler(engine, patientService, accessMiddleware)
-	userHTTPHandler.SetHandler(engine, userService, accessMiddleware)
-	authHTTPHandler.SetHandler(engine, config.JWT.CookieStoreConfig, authService, refreshMiddleware)
+	// /metrics is served on its own listener so that it is never exposed with the public API
+	var metricsEngine *echo.Echo
+	if config.MetricsPort != "" {
+		metricsEngine = echo.New()
+		metricsEngine.HideBanner = true
+		metricsEngine.HidePort = true
+		metricsHTTPHandler.SetHandler(metricsEngine, metricsRegistry)
+		go func() {
+			if err := metricsEngine.Start(config.MetricsAddress()); err != nil {
+				logger.Info("Shutting down the metrics server", "error", err)
+			}
+		}()
+	}
 
+	// SIGHUP reloads the secrets, e.g. once the orchestrator updated the mounted secret files
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	go func() {
-		if err := engine.Start(":8080"); err != nil {
-			log.Printf("Shutting down the server: %v", err)
+		for range reload {
+			if err := config.ReloadSecrets(args); err != nil {
+				logger.Error("Unable to reload secrets, keeping the current ones", "error", err)
+				continue
+			}
+			logger.Info("Secrets reloaded")
 		}
 	}()
 
@@ -62,13 +239,50 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	log.Println("Shutting down the server gracefully")
+	// fail the readiness first and keep serving until the orchestrator sees it and stops routing traffic
+	healthService.MarkShuttingDown()
+	logger.Info("Shutting down the server gracefully", "delay", config.ShutdownDelay.String())
+	time.Sleep(config.ShutdownDelay)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
+	// deferred so that the spans of the drained requests are flushed too
+	defer func() {
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Unable to flush the pending spans", "error", err)
+		}
+	}()
+
+	if metricsEngine != nil {
+		if err := metricsEngine.Shutdown(ctx); err != nil {
+			logger.Error("Unable to shutdown metrics server gracefully", "error", err)
+		}
+	}
 
+	// the background loops are stopped even when the requests did not drain in time
 	if err := engine.Shutdown(ctx); err != nil {
-		log.Printf("Unable to shutdown server gracefully: %v\n", err)
+		logger.Error("Unable to shutdown server gracefully", "error", err)
+	}
+
+	// the drained requests may have emitted events, the background loops end their batch in progress.
+	// A job still running once ctx is done is run again by another instance when its lease ends
+	stopBackground()
+	awaitStopped(ctx, dispatcherDone, "event dispatcher", logger)
+	awaitStopped(ctx, delivererDone, "webhook deliverer", logger)
+	awaitStopped(ctx, workerDone, "job worker", logger)
+	logger.Info("Server has shut down gracefully")
+	return nil
+}
+
+// awaitStopped waits for a background loop to close done, a nil done is a loop that never started
+func awaitStopped(ctx context.Context, done <-chan struct{}, name string, logger *slog.Logger) {
+	if done == nil {
 		return
 	}
-	log.Println("Server has shut down gracefully")
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Error("Unable to stop the "+name+" gracefully", "error", ctx.Err())
+	}
 }
@@ -6,6 +6,8 @@ import (
 	"testing"
 )
 
-func TestIntegration(_ *testing.T) {
-	main()
+func TestIntegration(t *testing.T) {
+	if err := run(nil); err != nil {
+		t.Fatal(err)
+	}
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	apiKeyHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/apikey"
+	authHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/auth"
+	clinicHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/clinic"
+	consentHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/consent"
+	healthHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/health"
+	jobHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/job"
+	authMiddleware "github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	patientHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/patient"
+	privacyHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/privacy"
+	roleHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/role"
+	userHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/user"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	webhookHTTPHandler "github.com/sopial42/cleanic/internal/adapters/rest/webhook"
+	"github.com/sopial42/cleanic/internal/config"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+const (
+	apiV1Prefix = "/api/v1/"
+	// apiV2Prefix routes answer with envelopes, their errors included
+	apiV2Prefix = "/api/v2/"
+)
+
+// routeDependencies are only used when serving the requests, zero values are enough to set the routes
+type routeDependencies struct {
+	healthService         healthSVC.Service
+	patientService        patientSVC.Service
+	consentService        consentSVC.Service
+	privacyService        privacySVC.Service
+	userService           userSVC.Service
+	roleService           roleSVC.Service
+	apiKeyService         apiKeySVC.Service
+	authService           authSVC.Service
+	clinicService         clinicSVC.Service
+	webhookService        webhookSVC.Service
+	jobService            jobSVC.Service
+	refreshMiddleware     authMiddleware.AuthRefreshMiddleware
+	accessMiddleware      authMiddleware.AuthAccessMiddleware
+	rateLimitMiddleware   *authMiddleware.RateLimitMiddleware
+	idempotencyMiddleware *authMiddleware.IdempotencyMiddleware
+}
+
+// apiOperations documents the routes set by setRoutes, TestRoutesMatchOpenAPI keeps them in sync
+func apiOperations(config config.Config) []openapi.Operation {
+	operations := slices.Concat(
+		openapi.Operations(),
+		healthHTTPHandler.Operations(),
+		patientHTTPHandler.Operations(),
+		consentHTTPHandler.Operations(),
+		privacyHTTPHandler.Operations(),
+		userHTTPHandler.Operations(),
+		roleHTTPHandler.Operations(),
+		apiKeyHTTPHandler.Operations(),
+		authHTTPHandler.Operations(config.OIDC),
+		clinicHTTPHandler.Operations(),
+		webhookHTTPHandler.Operations(),
+		jobHTTPHandler.Operations(),
+	)
+
+	for i, operation := range operations {
+		switch {
+		case strings.HasPrefix(operation.Path, apiV1Prefix):
+			operations[i].Deprecated = !config.API.V1Deprecation.IsZero()
+		case strings.HasPrefix(operation.Path, apiV2Prefix):
+			operations[i].Error = envelope.Error{}
+		}
+	}
+
+	return operations
+}
+
+func setRoutes(engine *echo.Echo, config config.Config, spec *openapi.Spec, dependencies routeDependencies) {
+	openapi.SetHandler(engine, spec)
+	healthHTTPHandler.SetHandler(engine, dependencies.healthService)
+	patientHTTPHandler.SetHandler(engine, dependencies.patientService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	consentHTTPHandler.SetHandler(engine, dependencies.consentService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	privacyHTTPHandler.SetHandler(engine, dependencies.privacyService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	userHTTPHandler.SetHandler(engine, dependencies.userService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	roleHTTPHandler.SetHandler(engine, dependencies.roleService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	apiKeyHTTPHandler.SetHandler(engine, dependencies.apiKeyService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	authHTTPHandler.SetHandler(engine, config.JWT.CookieStoreConfig, config.OIDC, dependencies.authService, dependencies.refreshMiddleware, dependencies.accessMiddleware, dependencies.rateLimitMiddleware, dependencies.idempotencyMiddleware, spec)
+	clinicHTTPHandler.SetHandler(engine, dependencies.clinicService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	webhookHTTPHandler.SetHandler(engine, dependencies.webhookService, dependencies.accessMiddleware, dependencies.idempotencyMiddleware, spec)
+	jobHTTPHandler.SetHandler(engine, dependencies.jobService, dependencies.accessMiddleware)
+}
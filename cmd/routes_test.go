@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+
+	authMiddleware "github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/config"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+var pathParamRegex = regexp.MustCompile(`:\w+`)
+
+// allowAll authenticates every API key with every permission, so that the requests reach the body validation
+type allowAll struct {
+	permissions user.Permissions
+}
+
+func (a allowAll) PermissionsForRoles(context.Context, user.Roles) (user.Permissions, error) {
+	return a.permissions, nil
+}
+
+func (a allowAll) Authenticate(context.Context, apikey.PlainKey) (apikey.APIKey, user.User, error) {
+	return apikey.APIKey{}, user.User{ID: 1}, nil
+}
+
+// TestRoutesMatchOpenAPI fails when a route is set without being documented, or the other way around,
+// and when a route does not validate its body against the documented request
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	for name, oidcConfig := range map[string]config.OIDCConfig{
+		"without OIDC": {},
+		"with OIDC":    {IssuerURL: "https://idp.example.com"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			config := config.Config{OIDC: oidcConfig}
+			operations := apiOperations(config)
+			spec, err := openapi.NewSpec(openapi.Info{Title: "cleanic", Version: "test"}, operations)
+			if err != nil {
+				t.Fatalf("unable to build the spec: %s", err)
+			}
+
+			access := allowAll{}
+			for _, operation := range operations {
+				access.permissions = append(access.permissions, operation.Permissions...)
+			}
+
+			rateLimits := authMiddleware.NewRateLimitMiddleware(nil, config.RateLimit)
+			engine := echo.New()
+			// the handlers have no service, reaching one is reported as a 500
+			engine.Use(echoMiddleware.Recover())
+			setRoutes(engine, config, spec, routeDependencies{
+				accessMiddleware:    authMiddleware.NewAuthAccessMiddleware(config.JWT.AccessTokenConfig, access, access, rateLimits),
+				rateLimitMiddleware: rateLimits,
+			})
+
+			var routes []string
+			for _, route := range engine.Routes() {
+				if route.Method == echo.RouteNotFound {
+					continue
+				}
+				routes = append(routes, route.Method+" "+route.Path)
+			}
+
+			documented := spec.Operations()
+			for _, route := range routes {
+				if _, found := documented[route]; !found {
+					t.Errorf("route %s is not documented", route)
+				}
+			}
+
+			for operation := range documented {
+				if !slices.Contains(routes, operation) {
+					t.Errorf("operation %s is documented but not routed", operation)
+				}
+			}
+
+			if _, found := documented[http.MethodGet+" /openapi.json"]; !found {
+				t.Error("the document should describe itself")
+			}
+
+			for key, operation := range documented {
+				if operation.Request == nil || operation.Auth == openapi.AuthRefresh {
+					continue
+				}
+
+				req := httptest.NewRequest(operation.Method, pathParamRegex.ReplaceAllString(operation.Path, "1"), strings.NewReader(`{"undocumented": true}`))
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+				req.Header.Set(echo.HeaderAuthorization, authMiddleware.APIKeyScheme+" cln_test_test")
+				rec := httptest.NewRecorder()
+				engine.ServeHTTP(rec, req)
+
+				if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "invalid request body: body.") {
+					t.Errorf("route %s should validate its body against the documented request, got %d: %s", key, rec.Code, rec.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	persistence "github.com/sopial42/cleanic/internal/adapters/persistence"
+	apiKeyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/apikey"
+	authPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/auth"
+	clinicPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/clinic"
+	consentPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/consent"
+	eventPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/event"
+	healthPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/health"
+	idempotencyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/idempotency"
+	jobPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/job"
+	patientPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/patient"
+	privacyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/privacy"
+	rateLimitPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/ratelimit"
+	rolePersistence "github.com/sopial42/cleanic/internal/adapters/persistence/role"
+	userPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/user"
+	webhookPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/webhook"
+	"github.com/sopial42/cleanic/internal/config"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+	rateLimitSVC "github.com/sopial42/cleanic/internal/services/ratelimit"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+	"github.com/sopial42/cleanic/internal/services/transaction"
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+// storage holds the persistence adapters of every service, all of them use the backend chosen by STORAGE
+type storage struct {
+	role        roleSVC.Persistence
+	user        userSVC.Persistence
+	auth        authSVC.Persistence
+	apiKey      apiKeySVC.Persistence
+	rateLimit   rateLimitSVC.Store
+	idempotency idempotencySVC.Persistence
+	clinic      clinicSVC.Persistence
+	patient     patientSVC.Persistence
+	consent     consentSVC.Persistence
+	privacy     privacySVC.Persistence
+	health      healthSVC.Persistence
+	event       eventSVC.Persistence
+	webhook     webhookSVC.Persistence
+	job         jobSVC.Persistence
+	// unitOfWork spans the adapters above
+	unitOfWork transaction.UnitOfWork
+	// expectedMigration is checked by the readiness probe, it is empty when there is no migration to wait for
+	expectedMigration string
+}
+
+func newStorage(ctx context.Context, cfg config.Config, registerer prometheus.Registerer) (storage, error) {
+	switch cfg.Storage {
+	case config.StorageMemory:
+		db := persistence.NewInMemoryDB()
+		return storage{
+			role:        rolePersistence.NewInMemoryClient(db),
+			user:        userPersistence.NewInMemoryClient(db),
+			auth:        authPersistence.NewInMemoryClient(db),
+			apiKey:      apiKeyPersistence.NewInMemoryClient(db),
+			rateLimit:   rateLimitPersistence.NewInMemoryStore(),
+			idempotency: idempotencyPersistence.NewInMemoryClient(db),
+			clinic:      clinicPersistence.NewInMemoryClient(db),
+			patient:     patientPersistence.NewInMemoryClient(db),
+			consent:     consentPersistence.NewInMemoryClient(db),
+			privacy:     privacyPersistence.NewInMemoryClient(db),
+			health:      healthPersistence.NewInMemoryClient(),
+			event:       eventPersistence.NewInMemoryClient(db),
+			webhook:     webhookPersistence.NewInMemoryClient(db),
+			job:         jobPersistence.NewInMemoryClient(db),
+			unitOfWork:  persistence.NewInMemoryUnitOfWork(db),
+		}, nil
+	case config.StorageSQLite:
+		sqliteClient, err := persistence.NewSQLiteClient(ctx, cfg.DB)
+		if err != nil {
+			return storage{}, err
+		}
+
+		expectedMigration, err := healthPersistence.LatestSQLiteMigration()
+		if err != nil {
+			return storage{}, err
+		}
+
+		persistence.InstrumentClient(sqliteClient, registerer)
+		return storage{
+			role:              rolePersistence.NewSQLiteClient(sqliteClient),
+			user:              userPersistence.NewSQLiteClient(sqliteClient),
+			auth:              authPersistence.NewSQLiteClient(sqliteClient),
+			apiKey:            apiKeyPersistence.NewSQLiteClient(sqliteClient),
+			rateLimit:         rateLimitPersistence.NewInMemoryStore(),
+			idempotency:       idempotencyPersistence.NewSQLiteClient(sqliteClient),
+			clinic:            clinicPersistence.NewSQLiteClient(sqliteClient),
+			patient:           patientPersistence.NewSQLiteClient(sqliteClient),
+			consent:           consentPersistence.NewSQLiteClient(sqliteClient),
+			privacy:           privacyPersistence.NewSQLiteClient(sqliteClient),
+			health:            healthPersistence.NewSQLiteClient(sqliteClient, cfg.DB.MigrationsTable),
+			event:             eventPersistence.NewSQLiteClient(sqliteClient),
+			webhook:           webhookPersistence.NewSQLiteClient(sqliteClient),
+			job:               jobPersistence.NewSQLiteClient(sqliteClient),
+			unitOfWork:        persistence.NewUnitOfWork(sqliteClient),
+			expectedMigration: expectedMigration,
+		}, nil
+	}
+
+	pgClient := persistence.NewPGClient(cfg.DB)
+	persistence.InstrumentClient(pgClient, registerer)
+
+	var rateLimitStore rateLimitSVC.Store = rateLimitPersistence.NewInMemoryStore()
+	if cfg.RateLimit.Shared() {
+		rateLimitStore = rateLimitPersistence.NewPGClient(pgClient, cfg.RateLimit.LongestPeriod())
+	}
+
+	var expectedMigration string
+	if cfg.DB.MigrationsTable != "" {
+		var err error
+		expectedMigration, err = healthPersistence.LatestMigration()
+		if err != nil {
+			return storage{}, err
+		}
+	}
+
+	return storage{
+		role:              rolePersistence.NewPGClient(pgClient),
+		user:              userPersistence.NewPGClient(pgClient),
+		auth:              authPersistence.NewPGClient(pgClient),
+		apiKey:            apiKeyPersistence.NewPGClient(pgClient),
+		rateLimit:         rateLimitStore,
+		idempotency:       idempotencyPersistence.NewPGClient(pgClient),
+		clinic:            clinicPersistence.NewPGClient(pgClient),
+		patient:           patientPersistence.NewPGClient(pgClient),
+		consent:           consentPersistence.NewPGClient(pgClient),
+		privacy:           privacyPersistence.NewPGClient(pgClient),
+		health:            healthPersistence.NewPGClient(pgClient, cfg.DB.MigrationsTable),
+		event:             eventPersistence.NewPGClient(pgClient),
+		webhook:           webhookPersistence.NewPGClient(pgClient),
+		job:               jobPersistence.NewPGClient(pgClient),
+		unitOfWork:        persistence.NewUnitOfWork(pgClient),
+		expectedMigration: expectedMigration,
+	}, nil
+}
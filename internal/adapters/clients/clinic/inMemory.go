@@ -0,0 +1,28 @@
+package clinic
+
+import (
+	"context"
+
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+)
+
+type inMemory struct {
+	clinicSVC clinicSVC.Service
+}
+
+func NewInMemoryClinicClient(clinicSVC clinicSVC.Service) authSVC.ClinicClient {
+	return &inMemory{
+		clinicSVC: clinicSVC,
+	}
+}
+
+func (m *inMemory) ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error) {
+	return m.clinicSVC.ListMemberships(ctx, userID)
+}
+
+func (m *inMemory) GetMembership(ctx context.Context, userID user.ID, clinicID clinic.ID) (clinic.Membership, error) {
+	return m.clinicSVC.GetMembership(ctx, userID, clinicID)
+}
@@ -0,0 +1,24 @@
+package consent
+
+import (
+	"context"
+
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+)
+
+type inMemory struct {
+	consentSVC consentSVC.Service
+}
+
+func NewInMemoryConsentClient(consentSVC consentSVC.Service) patientSVC.ConsentClient {
+	return &inMemory{
+		consentSVC: consentSVC,
+	}
+}
+
+func (m *inMemory) AllowedPatients(ctx context.Context, purpose consent.Purpose, patientIDs []patient.ID) ([]patient.ID, error) {
+	return m.consentSVC.AllowedPatients(ctx, purpose, patientIDs)
+}
@@ -0,0 +1,54 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+)
+
+type fileSink struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSink appends every event to path as a JSON line. The file is opened for each event
+// so that it can be rotated by moving it away
+func NewFileSink(path string) eventSVC.Sink {
+	return &fileSink{path: path}
+}
+
+func (f *fileSink) Name() string {
+	return "file"
+}
+
+func (f *fileSink) Publish(ctx context.Context, envelope event.Envelope) error {
+	line, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %w", f.path, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("unable to write to %s: %w", f.path, err)
+	}
+
+	// the event leaves the outbox once this returns, it must survive a crash
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("unable to sync %s: %w", f.path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package event
+
+import (
+	"context"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+)
+
+type inMemory struct {
+	eventSVC eventSVC.Service
+}
+
+// EventClient is the emitter of both the patient and the user services
+type EventClient interface {
+	patientSVC.EventClient
+	userSVC.EventClient
+}
+
+func NewInMemoryEventClient(eventSVC eventSVC.Service) EventClient {
+	return &inMemory{
+		eventSVC: eventSVC,
+	}
+}
+
+func (m *inMemory) Emit(ctx context.Context, eventType event.Type, payload any) error {
+	return m.eventSVC.Emit(ctx, eventType, payload)
+}
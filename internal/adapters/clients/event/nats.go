@@ -0,0 +1,175 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+)
+
+// natsTimeout bounds the connection and the acknowledgement of a publication
+const natsTimeout = 10 * time.Second
+
+// natsSink speaks the text protocol of NATS, which is all a publisher needs, over a single connection.
+// The PING sent after each PUB is answered once the broker processed the PUB, it is the acknowledgement
+type natsSink struct {
+	address       string
+	connectArgs   []byte
+	subjectPrefix string
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewNATSSink publishes every event on subjectPrefix.<event type> of the broker at nats://[user:password@]host:port,
+// the connection is opened on the first event and again after a failure
+func NewNATSSink(rawURL string, subjectPrefix string) (eventSVC.Sink, error) {
+	brokerURL, err := url.Parse(rawURL)
+	if err != nil || brokerURL.Scheme != "nats" || brokerURL.Host == "" {
+		return nil, fmt.Errorf("invalid NATS URL %q", rawURL)
+	}
+
+	address := brokerURL.Host
+	if brokerURL.Port() == "" {
+		address = net.JoinHostPort(brokerURL.Hostname(), "4222")
+	}
+
+	connect := map[string]any{"verbose": false, "pedantic": false, "name": "cleanic", "lang": "go"}
+	if brokerURL.User != nil {
+		connect["user"] = brokerURL.User.Username()
+		connect["pass"], _ = brokerURL.User.Password()
+	}
+
+	connectArgs, err := json.Marshal(connect)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal NATS CONNECT: %w", err)
+	}
+
+	return &natsSink{address: address, connectArgs: connectArgs, subjectPrefix: subjectPrefix}, nil
+}
+
+func (n *natsSink) Name() string {
+	return "nats"
+}
+
+func (n *natsSink) Publish(ctx context.Context, envelope event.Envelope) error {
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event: %w", err)
+	}
+
+	subject := string(envelope.Type)
+	if n.subjectPrefix != "" {
+		subject = n.subjectPrefix + "." + subject
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.publish(ctx, subject, payload); err != nil {
+		// the state of the connection is unknown, the next event opens a new one
+		n.close()
+		return err
+	}
+
+	return nil
+}
+
+func (n *natsSink) publish(ctx context.Context, subject string, payload []byte) error {
+	if n.conn == nil {
+		if err := n.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	if err := n.conn.SetDeadline(deadline); err != nil {
+		return fmt.Errorf("unable to set NATS deadline: %w", err)
+	}
+
+	command := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(payload), payload)
+	if _, err := n.conn.Write([]byte(command)); err != nil {
+		return fmt.Errorf("unable to publish to NATS: %w", err)
+	}
+
+	return n.awaitPong()
+}
+
+// connect reads the INFO greeting and authenticates, the PONG tells that CONNECT was accepted
+func (n *natsSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: natsTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", n.address)
+	if err != nil {
+		return fmt.Errorf("unable to connect to NATS %s: %w", n.address, err)
+	}
+
+	n.conn = conn
+	n.reader = bufio.NewReader(conn)
+	if err := conn.SetDeadline(time.Now().Add(natsTimeout)); err != nil {
+		return fmt.Errorf("unable to set NATS deadline: %w", err)
+	}
+
+	info, err := n.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(info, "INFO ") {
+		return fmt.Errorf("unexpected NATS greeting %q", info)
+	}
+
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", n.connectArgs); err != nil {
+		return fmt.Errorf("unable to send NATS CONNECT: %w", err)
+	}
+
+	return n.awaitPong()
+}
+
+func (n *natsSink) awaitPong() error {
+	for {
+		line, err := n.readLine()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return fmt.Errorf("unable to answer NATS PING: %w", err)
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("NATS refused: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+		// +OK and the INFO updates of a cluster change need no answer
+	}
+}
+
+func (n *natsSink) readLine() (string, error) {
+	line, err := n.reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("unable to read from NATS: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (n *natsSink) close() {
+	if n.conn != nil {
+		_ = n.conn.Close()
+	}
+	n.conn = nil
+	n.reader = nil
+}
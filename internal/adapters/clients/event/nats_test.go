@@ -0,0 +1,105 @@
+package event
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+)
+
+type publication struct {
+	subject string
+	payload []byte
+}
+
+// fakeBroker answers like a NATS server, closing the first connection after its first PUB
+// when dropFirst is set, and sends every publication it gets to the returned channel
+func fakeBroker(t *testing.T, dropFirst bool) (string, <-chan publication) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	publications := make(chan publication, 10)
+	go func() {
+		for connections := 0; ; connections++ {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveNATS(conn, publications, dropFirst && connections == 0)
+		}
+	}()
+
+	return "nats://" + listener.Addr().String(), publications
+}
+
+func serveNATS(conn net.Conn, publications chan<- publication, drop bool) {
+	defer conn.Close()
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+		case fields[0] == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case fields[0] == "PUB" && len(fields) == 3:
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if drop {
+				return
+			}
+			publications <- publication{subject: fields[1], payload: payload[:size]}
+		}
+	}
+}
+
+func TestNATSSinkPublishesAndReconnects(t *testing.T) {
+	brokerURL, publications := fakeBroker(t, true)
+	sink, err := NewNATSSink(brokerURL, "cleanic")
+	if err != nil {
+		t.Fatalf("unable to create the sink: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	envelope := event.Envelope{ID: 1, Type: event.TypePatientCreated, ClinicID: 1, Payload: json.RawMessage(`{"id":10001}`)}
+
+	// the broker drops the first connection before acknowledging
+	if err := sink.Publish(ctx, envelope); err == nil {
+		t.Fatalf("an unacknowledged publication should fail")
+	}
+
+	if err := sink.Publish(ctx, envelope); err != nil {
+		t.Fatalf("the sink should reconnect: %v", err)
+	}
+
+	published := <-publications
+	if published.subject != "cleanic.patient.created" {
+		t.Fatalf("unexpected subject %q", published.subject)
+	}
+
+	var received event.Envelope
+	if err := json.Unmarshal(published.payload, &received); err != nil || received.ID != envelope.ID {
+		t.Fatalf("unexpected payload %s: %v", published.payload, err)
+	}
+}
@@ -0,0 +1,27 @@
+package event
+
+import (
+	"context"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+type subscriptionsSink struct {
+	webhookSVC webhookSVC.Service
+}
+
+// NewSubscriptionsSink hands the events to the webhook subscriptions of their clinic,
+// an event is acknowledged once its deliveries are recorded, the webhook service attempts them afterwards
+func NewSubscriptionsSink(webhookSVC webhookSVC.Service) eventSVC.Sink {
+	return &subscriptionsSink{webhookSVC: webhookSVC}
+}
+
+func (s *subscriptionsSink) Name() string {
+	return "subscriptions"
+}
+
+func (s *subscriptionsSink) Publish(ctx context.Context, envelope event.Envelope) error {
+	return s.webhookSVC.Enqueue(ctx, envelope)
+}
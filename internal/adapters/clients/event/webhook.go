@@ -0,0 +1,61 @@
+package event
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+)
+
+// webhookTimeout bounds a delivery, a slow receiver is retried rather than blocking the outbox
+const webhookTimeout = 10 * time.Second
+
+type webhookSink struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NewWebhookSink POSTs every event to url, any status but 2xx is a failed delivery
+func NewWebhookSink(url string) eventSVC.Sink {
+	return &webhookSink{url: url, httpClient: &http.Client{Timeout: webhookTimeout}}
+}
+
+func (w *webhookSink) Name() string {
+	return "webhook"
+}
+
+func (w *webhookSink) Publish(ctx context.Context, envelope event.Envelope) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event: %w", err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to build webhook request: %w", err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	// a redelivery has the same key, the receiver can ignore it
+	request.Header.Set("Idempotency-Key", strconv.FormatInt(int64(envelope.ID), 10))
+	response, err := w.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("unable to call webhook: %w", err)
+	}
+	defer response.Body.Close()
+
+	// drained so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", response.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const mockKeyID = "mock-key"
+
+// mockIDP is a minimal OpenID Connect provider: discovery, JWKS, authorize and token endpoints
+type mockIDP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+
+	mu sync.Mutex
+	// pending authorization requests by code
+	pending map[string]url.Values
+	// claims added to the issued ID tokens, nonce and audience can be overridden
+	claims jwt.MapClaims
+}
+
+func newMockIDP(t *testing.T, clientID string) *mockIDP {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	idp := &mockIDP{
+		key:      key,
+		clientID: clientID,
+		pending:  map[string]url.Values{},
+		claims:   jwt.MapClaims{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+func (m *mockIDP) issuer() string {
+	return m.server.URL
+}
+
+func (m *mockIDP) discovery(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"issuer":                                m.issuer(),
+		"authorization_endpoint":                m.issuer() + "/authorize",
+		"token_endpoint":                        m.issuer() + "/token",
+		"jwks_uri":                              m.issuer() + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (m *mockIDP) jwks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": mockKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize skips the user interaction and redirects straight back with a code
+func (m *mockIDP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	code := "code-" + query.Get("state")
+
+	m.mu.Lock()
+	m.pending[code] = query
+	m.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockIDP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	m.mu.Lock()
+	authRequest, found := m.pending[r.PostForm.Get("code")]
+	delete(m.pending, r.PostForm.Get("code"))
+	m.mu.Unlock()
+
+	if !found {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	verifierHash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(verifierHash[:]) != authRequest.Get("code_challenge") {
+		writeTokenError(w, "invalid_grant")
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   m.issuer(),
+		"sub":   "mock-subject",
+		"aud":   m.clientID,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"nonce": authRequest.Get("nonce"),
+	}
+
+	m.mu.Lock()
+	for key, value := range m.claims {
+		claims[key] = value
+	}
+	m.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = mockKeyID
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   60,
+		"id_token":     signed,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeTokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,105 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/sopial42/cleanic/internal/config"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+)
+
+type oidcClient struct {
+	oauth2Config oauth2.Config
+	verifier     *oidc.IDTokenVerifier
+	groupsClaim  string
+}
+
+// NewOIDCClient runs the issuer discovery, the identity provider has to be reachable at startup
+func NewOIDCClient(ctx context.Context, cfg config.OIDCConfig) (authSVC.IdentityProvider, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover OIDC issuer: %w", err)
+	}
+
+	return &oidcClient{
+		oauth2Config: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret.Reveal(),
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+		verifier:    provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		groupsClaim: cfg.GroupsClaim,
+	}, nil
+}
+
+func (o *oidcClient) AuthCodeURL(challenge auth.SSOChallenge) string {
+	return o.oauth2Config.AuthCodeURL(
+		challenge.State,
+		oidc.Nonce(challenge.Nonce),
+		oauth2.S256ChallengeOption(challenge.CodeVerifier),
+	)
+}
+
+// Exchange trades the authorization code for tokens and validates the ID token:
+// signature, issuer, audience, expiry and nonce
+func (o *oidcClient) Exchange(ctx context.Context, challenge auth.SSOChallenge, code string) (auth.ExternalIdentity, error) {
+	token, err := o.oauth2Config.Exchange(ctx, code, oauth2.VerifierOption(challenge.CodeVerifier))
+	if err != nil {
+		return auth.ExternalIdentity{}, fmt.Errorf("unable to exchange authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return auth.ExternalIdentity{}, errors.New("no id_token in token response")
+	}
+
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return auth.ExternalIdentity{}, fmt.Errorf("unable to verify id_token: %w", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(challenge.Nonce)) != 1 {
+		return auth.ExternalIdentity{}, errors.New("id_token nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return auth.ExternalIdentity{}, fmt.Errorf("unable to parse id_token claims: %w", err)
+	}
+
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+
+	return auth.ExternalIdentity{
+		Subject:       idToken.Subject,
+		Email:         email,
+		EmailVerified: emailVerified,
+		Groups:        parseGroups(claims[o.groupsClaim]),
+	}, nil
+}
+
+// parseGroups accepts either a list of groups or a single group string
+func parseGroups(raw any) []string {
+	switch groups := raw.(type) {
+	case string:
+		return []string{groups}
+	case []any:
+		parsed := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if groupStr, ok := group.(string); ok {
+				parsed = append(parsed, groupStr)
+			}
+		}
+		return parsed
+	default:
+		return nil
+	}
+}
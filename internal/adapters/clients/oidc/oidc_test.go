@@ -0,0 +1,150 @@
+package oidc
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/sopial42/cleanic/internal/config"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+)
+
+const (
+	testClientID    = "cleanic"
+	testRedirectURL = "http://cleanic.test/api/v1/auth/oidc/callback"
+)
+
+func newTestClient(t *testing.T, idp *mockIDP) authSVC.IdentityProvider {
+	t.Helper()
+
+	client, err := NewOIDCClient(context.Background(), config.OIDCConfig{
+		IssuerURL:    idp.issuer(),
+		ClientID:     testClientID,
+		ClientSecret: "secret",
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+		GroupsClaim:  "groups",
+	})
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+
+	return client
+}
+
+// authorize follows the authorization URL and returns the code sent back to the redirect URL
+func authorize(t *testing.T, authURL string) url.Values {
+	t.Helper()
+
+	httpClient := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := httpClient.Get(authURL)
+	if err != nil {
+		t.Fatalf("unable to call authorize endpoint: %v", err)
+	}
+	defer resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %v", err)
+	}
+
+	return location.Query()
+}
+
+func newChallenge() auth.SSOChallenge {
+	return auth.SSOChallenge{
+		State:        "state-value",
+		Nonce:        "nonce-value",
+		CodeVerifier: "verifier-value-long-enough-to-match-the-pkce-minimal-length",
+	}
+}
+
+func TestExchange(t *testing.T) {
+	idp := newMockIDP(t, testClientID)
+	idp.claims["email"] = "doctor@hospital.test"
+	idp.claims["email_verified"] = true
+	idp.claims["groups"] = []string{"doctors", "admins"}
+	client := newTestClient(t, idp)
+
+	challenge := newChallenge()
+	authURL, err := url.Parse(client.AuthCodeURL(challenge))
+	if err != nil {
+		t.Fatalf("invalid auth URL: %v", err)
+	}
+
+	query := authURL.Query()
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		t.Fatalf("PKCE challenge missing from auth URL: %s", authURL)
+	}
+
+	if query.Get("nonce") != challenge.Nonce || query.Get("state") != challenge.State {
+		t.Fatalf("nonce or state missing from auth URL: %s", authURL)
+	}
+
+	callback := authorize(t, authURL.String())
+	identity, err := client.Exchange(context.Background(), challenge, callback.Get("code"))
+	if err != nil {
+		t.Fatalf("unable to exchange code: %v", err)
+	}
+
+	if identity.Subject != "mock-subject" || identity.Email != "doctor@hospital.test" || !identity.EmailVerified {
+		t.Fatalf("unexpected identity: %+v", identity)
+	}
+
+	if !slices.Equal(identity.Groups, []string{"doctors", "admins"}) {
+		t.Fatalf("unexpected groups: %v", identity.Groups)
+	}
+}
+
+func TestExchangeRejections(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mutate func(idp *mockIDP, challenge *auth.SSOChallenge)
+	}{
+		{
+			name: "nonce mismatch",
+			mutate: func(idp *mockIDP, _ *auth.SSOChallenge) {
+				idp.claims["nonce"] = "another-nonce"
+			},
+		},
+		{
+			name: "wrong audience",
+			mutate: func(idp *mockIDP, _ *auth.SSOChallenge) {
+				idp.claims["aud"] = "another-client"
+			},
+		},
+		{
+			name: "expired id token",
+			mutate: func(idp *mockIDP, _ *auth.SSOChallenge) {
+				idp.claims["exp"] = 1
+			},
+		},
+		{
+			name: "wrong PKCE verifier",
+			mutate: func(_ *mockIDP, challenge *auth.SSOChallenge) {
+				challenge.CodeVerifier = "another-verifier-long-enough-to-match-the-pkce-minimal-length"
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			idp := newMockIDP(t, testClientID)
+			client := newTestClient(t, idp)
+
+			challenge := newChallenge()
+			callback := authorize(t, client.AuthCodeURL(challenge))
+			tc.mutate(idp, &challenge)
+
+			if _, err := client.Exchange(context.Background(), challenge, callback.Get("code")); err == nil {
+				t.Fatal("exchange should have been rejected")
+			}
+		})
+	}
+}
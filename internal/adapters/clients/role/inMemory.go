@@ -0,0 +1,27 @@
+package role
+
+import (
+	"context"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+)
+
+type inMemory struct {
+	roleSVC roleSVC.Service
+}
+
+func NewInMemoryRoleClient(roleSVC roleSVC.Service) userSVC.RoleClient {
+	return &inMemory{
+		roleSVC: roleSVC,
+	}
+}
+
+func (m *inMemory) PermissionsForRoles(ctx context.Context, roles user.Roles) (user.Permissions, error) {
+	return m.roleSVC.PermissionsForRoles(ctx, roles)
+}
+
+func (m *inMemory) EnsureRolesExist(ctx context.Context, roles user.Roles) error {
+	return m.roleSVC.EnsureRolesExist(ctx, roles)
+}
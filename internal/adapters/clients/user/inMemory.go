@@ -29,3 +29,15 @@ func (m *inMemory) GetUserByEmail(ctx context.Context, email user.Email) (user.U
 func (m *inMemory) GetUserByID(ctx context.Context, userID user.ID) (user.User, error) {
 	return m.userSVC.GetUserByID(ctx, userID)
 }
+
+func (m *inMemory) UpdatePassword(ctx context.Context, userID user.ID, newPassword user.Password) error {
+	return m.userSVC.UpdatePassword(ctx, userID, newPassword)
+}
+
+func (m *inMemory) RehashPassword(ctx context.Context, userID user.ID, password user.Password) error {
+	return m.userSVC.RehashPassword(ctx, userID, password)
+}
+
+func (m *inMemory) AssignRoles(ctx context.Context, userID user.ID, roles user.Roles) (user.User, error) {
+	return m.userSVC.AssignRoles(ctx, userID, roles)
+}
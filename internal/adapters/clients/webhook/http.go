@@ -0,0 +1,92 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+// responseBodyLimit keeps enough of the response to debug a receiver without storing whole pages
+const responseBodyLimit = 2 << 10
+
+type httpSender struct {
+	httpClient *http.Client
+}
+
+var errForbiddenReceiver = errors.New("webhook receiver is not a public address")
+
+// NewHTTPSender POSTs the payloads as JSON, the receivers are not followed through redirects.
+// Each connection is checked once resolved, so that a subscription can not reach the internal
+// services unless their address is in allowedNetworks
+func NewHTTPSender(timeout time.Duration, allowedNetworks []*net.IPNet) webhookSVC.Sender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !webhook.ReceiverAllowed(ip, allowedNetworks) {
+				return fmt.Errorf("%w: %s", errForbiddenReceiver, host)
+			}
+
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// dialed directly, a proxy would be the only address checked
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &httpSender{httpClient: &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (h *httpSender) Send(ctx context.Context, url string, headers map[string]string, body []byte) (attempt webhook.Attempt) {
+	attempt.AttemptedAt = time.Now().UTC()
+	defer func() { attempt.DurationMS = time.Since(attempt.AttemptedAt).Milliseconds() }()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "cleanic-webhooks")
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer response.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(response.Body, responseBodyLimit))
+	// drained so that the connection is reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	attempt.StatusCode = response.StatusCode
+	// stored as text, which PostgreSQL wants valid and without NUL
+	attempt.ResponseBody = strings.ReplaceAll(strings.ToValidUTF8(string(responseBody), "\uFFFD"), "\x00", "")
+	return attempt
+}
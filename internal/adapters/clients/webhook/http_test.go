@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	webhookPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/webhook"
+	"github.com/sopial42/cleanic/internal/config"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+const secret = "whsec_test"
+
+// loopback lets the services reach the httptest receivers
+var loopback = []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)}}
+
+func newService(maxAttempts int) webhookSVC.Service {
+	return webhookSVC.NewWebhookService(
+		webhookPersistence.NewInMemoryClient(persistence.NewInMemoryDB()),
+		NewHTTPSender(time.Second, loopback),
+		config.WebhooksConfig{MaxAttempts: maxAttempts, RetryBase: time.Millisecond, RetryMax: time.Millisecond, BatchSize: 10, AllowedNetworks: loopback},
+	)
+}
+
+// deliverAll attempts the due deliveries until none is left, waiting for the backoff in between
+func deliverAll(t *testing.T, service webhookSVC.Service) {
+	t.Helper()
+	for range 10 {
+		handled, err := service.Deliver(context.Background())
+		if err != nil {
+			t.Fatalf("deliver: %v", err)
+		}
+		if handled == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSignedDeliveryIsRetriedUntilAccepted(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if _, err := webhook.Verify(secret, r.Header.Get(webhook.HeaderSignature), body); err != nil {
+			t.Errorf("invalid signature: %v", err)
+		}
+		if r.Header.Get(webhook.HeaderEvent) != string(event.TypePatientCreated) || r.Header.Get("Idempotency-Key") != "10001" {
+			t.Errorf("unexpected headers %v", r.Header)
+		}
+
+		if calls.Add(1) == 1 {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	service := newService(5)
+	ctx := clinic.WithID(context.Background(), clinic.DefaultID)
+	subscription, err := service.CreateSubscription(ctx, webhook.Subscription{
+		URL:        receiver.URL,
+		EventTypes: []event.Type{event.TypePatientCreated},
+		Secret:     secret,
+	})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+
+	envelope := event.Envelope{ID: 10001, Type: event.TypePatientCreated, ClinicID: clinic.DefaultID, Payload: []byte(`{"id":10001}`)}
+	for range 2 {
+		// the redelivery of an event by the outbox is not delivered twice
+		if err := service.Enqueue(context.Background(), envelope); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+	if err := service.Enqueue(context.Background(), event.Envelope{ID: 10002, Type: event.TypeUserRolesChanged, ClinicID: clinic.DefaultID}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	deliverAll(t, service)
+
+	deliveries, err := service.ListDeliveries(ctx, subscription.ID, "")
+	if err != nil {
+		t.Fatalf("list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != webhook.StatusSucceeded || len(deliveries[0].Attempts) != 2 {
+		t.Fatalf("the delivery should succeed on its second attempt, got %+v", deliveries)
+	}
+	if first := deliveries[0].Attempts[0]; first.StatusCode != http.StatusServiceUnavailable || first.ResponseBody != "try again later\n" {
+		t.Fatalf("the failed attempt should keep the response, got %+v", first)
+	}
+}
+
+func TestDeliveryIsDeadLetteredAfterMaxAttempts(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	service := newService(3)
+	ctx := clinic.WithID(context.Background(), clinic.DefaultID)
+	subscription, err := service.CreateSubscription(ctx, webhook.Subscription{URL: receiver.URL, EventTypes: []event.Type{event.TypePatientDeleted}})
+	if err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+	if subscription.Secret == "" {
+		t.Fatalf("a secret should be generated")
+	}
+
+	if err := service.Enqueue(context.Background(), event.Envelope{ID: 10001, Type: event.TypePatientDeleted, ClinicID: clinic.DefaultID}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	deliverAll(t, service)
+
+	dead, err := service.ListDeliveries(ctx, subscription.ID, webhook.StatusDead)
+	if err != nil {
+		t.Fatalf("list deliveries: %v", err)
+	}
+	if len(dead) != 1 || dead[0].AttemptCount != 3 {
+		t.Fatalf("the delivery should be dead after 3 attempts, got %+v", dead)
+	}
+
+	if err := service.RetryDelivery(ctx, subscription.ID, dead[0].ID); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if handled, err := service.Deliver(context.Background()); err != nil || handled != 1 {
+		t.Fatalf("the retried delivery should be attempted again, got %d: %v", handled, err)
+	}
+}
+
+func TestInternalReceiversAreRefused(t *testing.T) {
+	var calls atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	service := webhookSVC.NewWebhookService(
+		webhookPersistence.NewInMemoryClient(persistence.NewInMemoryDB()),
+		NewHTTPSender(time.Second, nil),
+		config.WebhooksConfig{MaxAttempts: 1, RetryBase: time.Millisecond, RetryMax: time.Millisecond, BatchSize: 10},
+	)
+	ctx := clinic.WithID(context.Background(), clinic.DefaultID)
+	for _, url := range []string{receiver.URL, "http://10.0.0.1/hooks", "http://169.254.169.254/latest", "http://[::1]:8080/hooks"} {
+		_, err := service.CreateSubscription(ctx, webhook.Subscription{URL: url, EventTypes: []event.Type{event.TypePatientCreated}})
+		if !errors.Is(err, webhookSVC.ErrInvalidSubscription) {
+			t.Fatalf("a subscription to %s should be refused, got %v", url, err)
+		}
+	}
+
+	// a host name is only checked once resolved
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(receiver.URL, "http://"))
+	attempt := NewHTTPSender(time.Second, nil).Send(context.Background(), "http://localhost:"+port, nil, []byte(`{}`))
+	if !strings.Contains(attempt.Error, errForbiddenReceiver.Error()) || calls.Load() != 0 {
+		t.Fatalf("the loopback receiver should not be reached, got %+v", attempt)
+	}
+}
@@ -0,0 +1,107 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sopial42/cleanic/internal/config"
+)
+
+const (
+	// ComponentKey carries the component of a logger, it selects the level in LOG_LEVELS
+	ComponentKey = "component"
+	RequestIDKey = "request_id"
+	TraceIDKey   = "trace_id"
+)
+
+type requestIDKey struct{}
+
+// NewLogger returns a JSON logger which redacts the personal data and the credentials,
+// and adds the request and trace IDs of the context to each line
+func NewLogger(cfg config.LogConfig, out io.Writer) *slog.Logger {
+	json := slog.NewJSONHandler(out, &slog.HandlerOptions{
+		// the levels are checked by handler, per component
+		Level:       slog.LevelDebug - 4,
+		ReplaceAttr: redactAttr,
+	})
+
+	return slog.New(&handler{
+		next:   json,
+		levels: cfg.Levels,
+		root:   cfg.Level,
+		level:  cfg.Level,
+	})
+}
+
+// Component returns the logger of a component from the default logger,
+// it has to be called once the default logger is set
+func Component(name string) *slog.Logger {
+	return slog.Default().With(ComponentKey, name)
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+type handler struct {
+	next   slog.Handler
+	levels map[string]slog.Level
+	root   slog.Level
+	// level is the one of the component set with WithAttrs, root otherwise
+	level slog.Level
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *handler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String(RequestIDKey, requestID))
+	}
+
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		record.AddAttrs(slog.String(TraceIDKey, span.TraceID().String()))
+	}
+
+	return h.next.Handle(ctx, record)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	level := h.level
+	for _, attr := range attrs {
+		if attr.Key == ComponentKey {
+			level = h.levelFor(attr.Value.String())
+		}
+	}
+
+	return &handler{next: h.next.WithAttrs(attrs), levels: h.levels, root: h.root, level: level}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{next: h.next.WithGroup(name), levels: h.levels, root: h.root, level: h.level}
+}
+
+// levelFor looks for the component, then for its parents: rest/patient, then rest
+func (h *handler) levelFor(component string) slog.Level {
+	for {
+		if level, found := h.levels[component]; found {
+			return level
+		}
+
+		parent := strings.LastIndex(component, "/")
+		if parent < 0 {
+			return h.root
+		}
+		component = component[:parent]
+	}
+}
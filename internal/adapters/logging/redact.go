@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[redacted]"
+
+// sensitiveKeys are masked whatever their value, names can not be recognized inside free text
+var sensitiveKeys = map[string]bool{
+	"email":         true,
+	"firstname":     true,
+	"lastname":      true,
+	"first_name":    true,
+	"last_name":     true,
+	"name":          true,
+	"password":      true,
+	"new_password":  true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"authorization": true,
+	"cookie":        true,
+	"secret":        true,
+	"key":           true,
+}
+
+var patterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`), "[redacted email]"},
+	{regexp.MustCompile(`eyJ[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]*\.[A-Za-z0-9_\-]*`), "[redacted token]"},
+	// the API key prefix is not a secret and helps to find the key
+	{regexp.MustCompile(`(cln_[0-9a-f]{8})_[A-Za-z0-9_\-]+`), "${1}_[redacted]"},
+	{regexp.MustCompile(`(?i)\b(bearer|apikey)\s+[^\s"]+`), "$1 [redacted]"},
+}
+
+// Redact masks the emails, JWTs, API keys and Authorization values found in a text
+func Redact(text string) string {
+	for _, pattern := range patterns {
+		text = pattern.re.ReplaceAllString(text, pattern.replacement)
+	}
+
+	return text
+}
+
+// redactAttr is applied to every attribute, the message included, before it is written
+func redactAttr(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		return slog.Any(attr.Key, redactAny(attr.Value.Any()))
+	default:
+		return attr
+	}
+}
+
+// redactAny goes through the JSON form of a value, so that the fields of a logged struct are masked too
+func redactAny(value any) any {
+	switch v := value.(type) {
+	case error:
+		return Redact(v.Error())
+	case fmt.Stringer:
+		return Redact(v.String())
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return Redact(fmt.Sprintf("%+v", value))
+	}
+
+	var decoded any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return Redact(string(raw))
+	}
+
+	return redactJSON(decoded)
+}
+
+func redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if sensitiveKeys[strings.ToLower(key)] {
+				v[key] = redacted
+				continue
+			}
+			v[key] = redactJSON(field)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+		return v
+	case string:
+		return Redact(v)
+	default:
+		return v
+	}
+}
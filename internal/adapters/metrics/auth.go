@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+)
+
+type authMetrics struct {
+	logins    *prometheus.CounterVec
+	refreshes *prometheus.CounterVec
+}
+
+func NewAuthMetrics(registerer prometheus.Registerer) authSVC.Metrics {
+	m := &authMetrics{
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "auth",
+			Name:      "logins_total",
+			Help:      "Login attempts by method and outcome.",
+		}, []string{"method", "outcome"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "auth",
+			Name:      "refresh_rotations_total",
+			Help:      "Refresh token rotations by outcome.",
+		}, []string{"outcome"}),
+	}
+	registerer.MustRegister(m.logins, m.refreshes)
+
+	return m
+}
+
+func (m *authMetrics) LoginAttempted(method auth.LoginMethod, outcome auth.Outcome) {
+	m.logins.WithLabelValues(string(method), string(outcome)).Inc()
+}
+
+func (m *authMetrics) RefreshAttempted(outcome auth.Outcome) {
+	m.refreshes.WithLabelValues(string(outcome)).Inc()
+}
@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) apiKeySVC.Persistence {
+	return &inMemory{db: db}
+}
+
+// InsertAPIKey binds the key to the context clinic
+func (m *inMemory) InsertAPIKey(ctx context.Context, newKey apikey.APIKey) (apikey.APIKey, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to insert api key: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckUser(newKey.UserID); err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to insert api key: %w", err)
+	}
+
+	for _, existing := range m.db.APIKeys {
+		if existing.Prefix == newKey.Prefix {
+			return apikey.APIKey{}, fmt.Errorf("unable to insert api key: duplicate key value violates unique constraint \"api_key_prefix_key\"")
+		}
+	}
+
+	newKey.ID = apikey.ID(m.db.NextID("api_key"))
+	newKey.ClinicID = clinicID
+	if newKey.CreatedAt.IsZero() {
+		newKey.CreatedAt = time.Now()
+	}
+
+	m.db.APIKeys[newKey.ID] = cloneKey(newKey)
+	return cloneKey(newKey), nil
+}
+
+func (m *inMemory) GetAPIKeyByID(ctx context.Context, keyID apikey.ID) (apikey.APIKey, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to get api key %d: %w", keyID, err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	key, found := m.db.APIKeys[keyID]
+	if !found || key.ClinicID != clinicID {
+		return apikey.APIKey{}, fmt.Errorf("unable to get api key %d: %w", keyID, sql.ErrNoRows)
+	}
+
+	return cloneKey(key), nil
+}
+
+// GetAPIKeyByPrefix is not scoped as the key tells the clinic of the request
+func (m *inMemory) GetAPIKeyByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error) {
+	defer m.db.RLock(ctx)()
+
+	for _, key := range m.db.APIKeys {
+		if key.Prefix == prefix {
+			return cloneKey(key), nil
+		}
+	}
+
+	return apikey.APIKey{}, fmt.Errorf("unable to get api key by prefix: %w", sql.ErrNoRows)
+}
+
+func (m *inMemory) ListAPIKeysByUser(ctx context.Context, userID user.ID) ([]apikey.APIKey, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list api keys: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	keys := []apikey.APIKey{}
+	for _, key := range m.db.APIKeys {
+		if key.UserID == userID && key.ClinicID == clinicID {
+			keys = append(keys, cloneKey(key))
+		}
+	}
+
+	slices.SortFunc(keys, func(a, b apikey.APIKey) int { return cmp.Compare(a.ID, b.ID) })
+	return keys, nil
+}
+
+func (m *inMemory) RevokeAPIKey(ctx context.Context, keyID apikey.ID, revokedAt time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	if key, found := m.db.APIKeys[keyID]; found && key.RevokedAt == nil {
+		key.RevokedAt = &revokedAt
+		m.db.APIKeys[keyID] = key
+	}
+
+	return nil
+}
+
+func (m *inMemory) TouchAPIKey(ctx context.Context, keyID apikey.ID, usedAt time.Time, olderThan time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	if key, found := m.db.APIKeys[keyID]; found && (key.LastUsedAt == nil || key.LastUsedAt.Before(olderThan)) {
+		key.LastUsedAt = &usedAt
+		m.db.APIKeys[keyID] = key
+	}
+
+	return nil
+}
+
+// cloneKey copies the time pointers and the scopes so that the stored key is never shared
+func cloneKey(key apikey.APIKey) apikey.APIKey {
+	key.Scopes = append(user.Permissions{}, key.Scopes...)
+	key.ExpiresAt = clonePtr(key.ExpiresAt)
+	key.LastUsedAt = clonePtr(key.LastUsedAt)
+	key.RevokedAt = clonePtr(key.RevokedAt)
+	return key
+}
+
+func clonePtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	clone := *t
+	return &clone
+}
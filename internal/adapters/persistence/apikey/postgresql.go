@@ -0,0 +1,124 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+}
+
+func NewPGClient(client *bun.DB) apiKeySVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
+
+// InsertAPIKey binds the key to the context clinic
+func (p *pgPersistence) InsertAPIKey(ctx context.Context, newKey apikey.APIKey) (apikey.APIKey, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to insert api key: %w", err)
+	}
+
+	keyDAO := apiKeyFromDomainToDAO(newKey)
+	keyDAO.ClinicID = int64(clinicID)
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&keyDAO).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to insert api key: %w", err)
+	}
+
+	return apiKeyFromDAOToDomain(keyDAO), nil
+}
+
+func (p *pgPersistence) GetAPIKeyByID(ctx context.Context, keyID apikey.ID) (apikey.APIKey, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to get api key %d: %w", keyID, err)
+	}
+
+	var keyDAO apiKeyDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&keyDAO).
+		Where("id = ?", keyID).
+		Where("clinic_id = ?", clinicID).
+		Scan(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to get api key %d: %w", keyID, err)
+	}
+
+	return apiKeyFromDAOToDomain(keyDAO), nil
+}
+
+// GetAPIKeyByPrefix is not scoped as the key tells the clinic of the request
+func (p *pgPersistence) GetAPIKeyByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error) {
+	var keyDAO apiKeyDAO
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&keyDAO).
+		Where("prefix = ?", prefix).
+		Scan(ctx)
+	if err != nil {
+		return apikey.APIKey{}, fmt.Errorf("unable to get api key by prefix: %w", err)
+	}
+
+	return apiKeyFromDAOToDomain(keyDAO), nil
+}
+
+func (p *pgPersistence) ListAPIKeysByUser(ctx context.Context, userID user.ID) ([]apikey.APIKey, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list api keys: %w", err)
+	}
+
+	var keyDAOs []apiKeyDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&keyDAOs).
+		Where("user_id = ?", userID).
+		Where("clinic_id = ?", clinicID).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list api keys: %w", err)
+	}
+
+	return apiKeyFromDAOsToDomains(keyDAOs), nil
+}
+
+func (p *pgPersistence) RevokeAPIKey(ctx context.Context, keyID apikey.ID, revokedAt time.Time) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*apiKeyDAO)(nil)).
+		Set("revoked_at = ?", revokedAt).
+		Where("id = ?", keyID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to revoke api key %d: %w", keyID, err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) TouchAPIKey(ctx context.Context, keyID apikey.ID, usedAt time.Time, olderThan time.Time) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*apiKeyDAO)(nil)).
+		Set("last_used_at = ?", usedAt).
+		Where("id = ?", keyID).
+		Where("last_used_at IS NULL OR last_used_at < ?", olderThan).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to touch api key %d: %w", keyID, err)
+	}
+
+	return nil
+}
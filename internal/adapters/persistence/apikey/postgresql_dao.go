@@ -0,0 +1,78 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type apiKeyDAO struct {
+	bun.BaseModel `bun:"table:api_key"`
+
+	ID         int64      `bun:"id,pk,autoincrement"`
+	UserID     int64      `bun:"user_id,notnull"`
+	ClinicID   int64      `bun:"clinic_id,notnull"`
+	Name       string     `bun:"name,notnull"`
+	Prefix     string     `bun:"prefix,notnull,unique"`
+	Hash       string     `bun:"hash,notnull"`
+	Scopes     []string   `bun:"scopes,type:jsonb,notnull"`
+	ExpiresAt  *time.Time `bun:"expires_at"`
+	LastUsedAt *time.Time `bun:"last_used_at"`
+	RevokedAt  *time.Time `bun:"revoked_at"`
+	CreatedAt  time.Time  `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+func apiKeyFromDomainToDAO(key apikey.APIKey) apiKeyDAO {
+	scopes := make([]string, len(key.Scopes))
+	for i, scope := range key.Scopes {
+		scopes[i] = string(scope)
+	}
+
+	return apiKeyDAO{
+		ID:         int64(key.ID),
+		UserID:     int64(key.UserID),
+		ClinicID:   int64(key.ClinicID),
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Hash:       key.Hash,
+		Scopes:     scopes,
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
+
+func apiKeyFromDAOToDomain(keyDAO apiKeyDAO) apikey.APIKey {
+	scopes := make(user.Permissions, len(keyDAO.Scopes))
+	for i, scope := range keyDAO.Scopes {
+		scopes[i] = user.Permission(scope)
+	}
+
+	return apikey.APIKey{
+		ID:         apikey.ID(keyDAO.ID),
+		UserID:     user.ID(keyDAO.UserID),
+		ClinicID:   clinic.ID(keyDAO.ClinicID),
+		Name:       keyDAO.Name,
+		Prefix:     keyDAO.Prefix,
+		Hash:       keyDAO.Hash,
+		Scopes:     scopes,
+		ExpiresAt:  keyDAO.ExpiresAt,
+		LastUsedAt: keyDAO.LastUsedAt,
+		RevokedAt:  keyDAO.RevokedAt,
+		CreatedAt:  keyDAO.CreatedAt,
+	}
+}
+
+func apiKeyFromDAOsToDomains(keyDAOs []apiKeyDAO) []apikey.APIKey {
+	keys := make([]apikey.APIKey, len(keyDAOs))
+	for i, keyDAO := range keyDAOs {
+		keys[i] = apiKeyFromDAOToDomain(keyDAO)
+	}
+
+	return keys
+}
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect
+func NewSQLiteClient(client *bun.DB) apiKeySVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
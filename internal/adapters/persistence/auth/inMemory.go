@@ -0,0 +1,253 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) authSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) StoreRefreshTokenClaims(ctx context.Context, claims utils.RefreshTokenClaims) error {
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckUser(claims.Subject); err != nil {
+		return fmt.Errorf("unable to update token claims: %w", err)
+	}
+
+	if err := m.db.CheckClinic(claims.ClinicID); err != nil {
+		return fmt.Errorf("unable to update token claims: %w", err)
+	}
+
+	m.db.RefreshTokens[claims.Subject] = claims
+	return nil
+}
+
+func (m *inMemory) GetRefreshTokenClaimsByUserID(ctx context.Context, userID user.ID) (utils.RefreshTokenClaims, error) {
+	defer m.db.RLock(ctx)()
+
+	claims, found := m.db.RefreshTokens[userID]
+	if !found {
+		return utils.RefreshTokenClaims{}, fmt.Errorf("unable to get refresh token: %w", sql.ErrNoRows)
+	}
+
+	return claims, nil
+}
+
+func (m *inMemory) DeleteRefreshTokenClaims(ctx context.Context, userID user.ID) error {
+	defer m.db.Lock(ctx)()
+
+	delete(m.db.RefreshTokens, userID)
+	return nil
+}
+
+func (m *inMemory) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time, limit int) (int, error) {
+	defer m.db.Lock(ctx)()
+
+	var expired []utils.RefreshTokenClaims
+	for _, claims := range m.db.RefreshTokens {
+		if claims.ExpiresAt <= now.Unix() {
+			expired = append(expired, claims)
+		}
+	}
+
+	slices.SortFunc(expired, func(a, b utils.RefreshTokenClaims) int { return cmp.Compare(a.ExpiresAt, b.ExpiresAt) })
+	expired = expired[:min(len(expired), limit)]
+	for _, claims := range expired {
+		m.endSession(claims.Subject, time.Unix(claims.ExpiresAt, 0))
+		delete(m.db.RefreshTokens, claims.Subject)
+	}
+
+	return len(expired), nil
+}
+
+func (m *inMemory) StartSession(ctx context.Context, session auth.Session) error {
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckUser(session.UserID); err != nil {
+		return fmt.Errorf("unable to insert session: %w", err)
+	}
+
+	if err := m.db.CheckClinic(session.ClinicID); err != nil {
+		return fmt.Errorf("unable to insert session: %w", err)
+	}
+
+	m.endSession(session.UserID, session.StartedAt)
+	session.ID = auth.SessionID(m.db.NextID("auth_session"))
+	session.EndedAt = nil
+	m.db.Sessions[session.ID] = session
+	return nil
+}
+
+func (m *inMemory) EndSession(ctx context.Context, userID user.ID, at time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	m.endSession(userID, at)
+	return nil
+}
+
+// endSession replaces the open session of the user by an ended one, the lock must be held
+func (m *inMemory) endSession(userID user.ID, at time.Time) {
+	for id, session := range m.db.Sessions {
+		if session.UserID == userID && session.EndedAt == nil {
+			session.EndedAt = &at
+			m.db.Sessions[id] = session
+		}
+	}
+}
+
+func (m *inMemory) DeleteEndedSessions(ctx context.Context, before time.Time, limit int) (int, error) {
+	defer m.db.Lock(ctx)()
+
+	var ended []auth.SessionID
+	for id, session := range m.db.Sessions {
+		if session.EndedAt != nil && session.EndedAt.Before(before) {
+			ended = append(ended, id)
+		}
+	}
+
+	slices.Sort(ended)
+	ended = ended[:min(len(ended), limit)]
+	for _, id := range ended {
+		delete(m.db.Sessions, id)
+	}
+
+	return len(ended), nil
+}
+
+func (m *inMemory) SessionStats(ctx context.Context, since time.Time, now time.Time) (auth.SessionStats, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return auth.SessionStats{}, fmt.Errorf("unable to count sessions: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	stats := auth.SessionStats{Since: since, LoginsPerDay: []auth.DailyLogins{}, Users: []auth.UserSessions{}}
+	dailyLogins := map[string]*auth.DailyLogins{}
+	dailyUsers := map[string]map[user.ID]bool{}
+	users := map[user.ID]*auth.UserSessions{}
+	var lifetime time.Duration
+	var ended int64
+	for _, session := range m.db.Sessions {
+		if session.ClinicID != clinicID {
+			continue
+		}
+
+		if session.EndedAt != nil && !session.EndedAt.Before(since) {
+			lifetime += session.Lifetime()
+			ended++
+		}
+
+		if session.StartedAt.Before(since) && session.EndedAt != nil && session.EndedAt.Before(since) {
+			continue
+		}
+
+		userSessions, found := users[session.UserID]
+		if !found {
+			userSessions = &auth.UserSessions{UserID: session.UserID}
+			users[session.UserID] = userSessions
+		}
+
+		claims, found := m.db.RefreshTokens[session.UserID]
+		if session.EndedAt == nil && found && claims.ClinicID == session.ClinicID && claims.ExpiresAt > now.Unix() {
+			stats.ActiveSessions++
+			userSessions.ActiveSessions++
+		}
+
+		if session.Method == auth.LoginMethodClinicSwitch || session.StartedAt.Before(since) {
+			continue
+		}
+
+		day := session.StartedAt.UTC().Format(time.DateOnly)
+		if dailyLogins[day] == nil {
+			dailyLogins[day], dailyUsers[day] = &auth.DailyLogins{Day: day}, map[user.ID]bool{}
+		}
+		dailyLogins[day].Logins++
+		dailyUsers[day][session.UserID] = true
+		dailyLogins[day].Users = len(dailyUsers[day])
+
+		userSessions.Logins++
+		if userSessions.LastLoginAt == nil || session.StartedAt.After(*userSessions.LastLoginAt) {
+			startedAt := session.StartedAt
+			userSessions.LastLoginAt = &startedAt
+		}
+	}
+
+	if ended > 0 {
+		stats.AverageLifetimeSeconds = int64(lifetime.Seconds()) / ended
+	}
+
+	for _, logins := range dailyLogins {
+		stats.LoginsPerDay = append(stats.LoginsPerDay, *logins)
+	}
+	slices.SortFunc(stats.LoginsPerDay, func(a, b auth.DailyLogins) int { return cmp.Compare(a.Day, b.Day) })
+
+	for _, userSessions := range users {
+		stats.Users = append(stats.Users, *userSessions)
+	}
+	slices.SortFunc(stats.Users, func(a, b auth.UserSessions) int { return cmp.Compare(a.UserID, b.UserID) })
+
+	return stats, nil
+}
+
+func (m *inMemory) GetLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string) (auth.LoginAttempts, error) {
+	defer m.db.RLock(ctx)()
+
+	attempts, found := m.db.LoginAttempts[persistence.LoginAttemptKey{Scope: scope, Key: key}]
+	if !found {
+		return auth.LoginAttempts{Scope: scope, Key: key}, nil
+	}
+
+	return attempts, nil
+}
+
+func (m *inMemory) IncrementLoginFailures(ctx context.Context, scope auth.AttemptScope, key string, at time.Time, resetWindow time.Duration) (auth.LoginAttempts, error) {
+	defer m.db.Lock(ctx)()
+
+	attemptKey := persistence.LoginAttemptKey{Scope: scope, Key: key}
+	attempts, found := m.db.LoginAttempts[attemptKey]
+	if !found || attempts.LastFailureAt.Before(at.Add(-resetWindow)) {
+		attempts = auth.LoginAttempts{Scope: scope, Key: key, LockedUntil: attempts.LockedUntil}
+	}
+
+	attempts.Failures++
+	attempts.LastFailureAt = at
+	m.db.LoginAttempts[attemptKey] = attempts
+	return attempts, nil
+}
+
+func (m *inMemory) LockLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string, until time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	attemptKey := persistence.LoginAttemptKey{Scope: scope, Key: key}
+	if attempts, found := m.db.LoginAttempts[attemptKey]; found {
+		attempts.LockedUntil = until
+		m.db.LoginAttempts[attemptKey] = attempts
+	}
+
+	return nil
+}
+
+func (m *inMemory) DeleteLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string) error {
+	defer m.db.Lock(ctx)()
+
+	delete(m.db.LoginAttempts, persistence.LoginAttemptKey{Scope: scope, Key: key})
+	return nil
+}
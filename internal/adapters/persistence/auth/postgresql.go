@@ -2,29 +2,37 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"math"
+	"time"
 
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
 
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
 	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
 	user "github.com/sopial42/cleanic/internal/domains/user"
-	"github.com/sopial42/cleanic/internal/services/auth"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
 )
 
 type pgPersistence struct {
 	clientDB *bun.DB
 }
 
-func NewPGClient(client *bun.DB) auth.Persistence {
+func NewPGClient(client *bun.DB) authSVC.Persistence {
 	return &pgPersistence{clientDB: client}
 }
 
 func (p *pgPersistence) StoreRefreshTokenClaims(ctx context.Context, claims utils.RefreshTokenClaims) error {
 	tokenDAO := fromTokenClaimsToTokenDAO(claims)
-	_, err := p.clientDB.NewInsert().
+	_, err := persistence.DB(ctx, p.clientDB).NewInsert().
 		Model(&tokenDAO).
 		On("CONFLICT (user_id) DO UPDATE").
-		Set("id = EXCLUDED.id, issued_at = EXCLUDED.issued_at, expires_at = EXCLUDED.expires_at").
+		Set("id = EXCLUDED.id, issued_at = EXCLUDED.issued_at, expires_at = EXCLUDED.expires_at, clinic_id = EXCLUDED.clinic_id").
 		Returning("*").
 		Exec(ctx)
 	if err != nil {
@@ -41,7 +49,7 @@ func (p *pgPersistence) StoreRefreshTokenClaims(ctx context.Context, claims util
 
 func (p *pgPersistence) GetRefreshTokenClaimsByUserID(ctx context.Context, userID user.ID) (utils.RefreshTokenClaims, error) {
 	tokenDAO := &tokenDAO{}
-	err := p.clientDB.NewSelect().
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
 		Model(tokenDAO).
 		Where("user_id = ?", userID).
 		Limit(1).
@@ -58,7 +66,7 @@ func (p *pgPersistence) GetRefreshTokenClaimsByUserID(ctx context.Context, userI
 }
 
 func (p *pgPersistence) DeleteRefreshTokenClaims(ctx context.Context, userID user.ID) error {
-	_, err := p.clientDB.NewDelete().
+	_, err := persistence.DB(ctx, p.clientDB).NewDelete().
 		Model((*tokenDAO)(nil)).
 		Where("user_id = ?", userID).
 		Exec(ctx)
@@ -68,3 +76,246 @@ func (p *pgPersistence) DeleteRefreshTokenClaims(ctx context.Context, userID use
 
 	return nil
 }
+
+func (p *pgPersistence) DeleteExpiredRefreshTokens(ctx context.Context, now time.Time, limit int) (int, error) {
+	db := persistence.DB(ctx, p.clientDB)
+	var userIDs []int64
+	err := db.NewSelect().
+		Model((*tokenDAO)(nil)).
+		Column("user_id").
+		Where("expires_at <= ?", now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Scan(ctx, &userIDs)
+	if err != nil {
+		return 0, fmt.Errorf("unable to list expired refresh tokens: %w", err)
+	}
+
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	_, err = db.NewUpdate().
+		Model((*sessionDAO)(nil)).
+		Set("ended_at = (SELECT refresh_token.expires_at FROM refresh_token WHERE refresh_token.user_id = auth_session.user_id)").
+		Where("ended_at IS NULL").
+		Where("user_id IN (?)", bun.In(userIDs)).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to end expired sessions: %w", err)
+	}
+
+	result, err := db.NewDelete().
+		Model((*tokenDAO)(nil)).
+		Where("user_id IN (?)", bun.In(userIDs)).
+		Where("expires_at <= ?", now).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete expired refresh tokens: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return int(deleted), nil
+}
+
+func (p *pgPersistence) StartSession(ctx context.Context, session auth.Session) error {
+	if err := p.EndSession(ctx, session.UserID, session.StartedAt); err != nil {
+		return err
+	}
+
+	sessionDAO := fromSessionToSessionDAO(session)
+	_, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&sessionDAO).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to insert session: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) EndSession(ctx context.Context, userID user.ID, at time.Time) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*sessionDAO)(nil)).
+		Set("ended_at = ?", at).
+		Where("user_id = ?", userID).
+		Where("ended_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to end session: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) DeleteEndedSessions(ctx context.Context, before time.Time, limit int) (int, error) {
+	db := persistence.DB(ctx, p.clientDB)
+	endedIDs := db.NewSelect().
+		Model((*sessionDAO)(nil)).
+		Column("id").
+		Where("ended_at < ?", before).
+		Order("id ASC").
+		Limit(limit)
+
+	result, err := db.NewDelete().
+		Model((*sessionDAO)(nil)).
+		Where("id IN (?)", endedIDs).
+		Exec(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to delete ended sessions: %w", err)
+	}
+
+	deleted, _ := result.RowsAffected()
+	return int(deleted), nil
+}
+
+// SessionStats tells a session is active when it is open and the refresh token of its user is still valid in its clinic
+func (p *pgPersistence) SessionStats(ctx context.Context, since time.Time, now time.Time) (auth.SessionStats, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return auth.SessionStats{}, fmt.Errorf("unable to count sessions: %w", err)
+	}
+
+	db := persistence.DB(ctx, p.clientDB)
+	dayExpr, lifetimeExpr := sessionExprs(db)
+	stats := auth.SessionStats{Since: since, LoginsPerDay: []auth.DailyLogins{}, Users: []auth.UserSessions{}}
+
+	var userSessionsDAOs []userSessionsDAO
+	err = db.NewSelect().
+		Model((*sessionDAO)(nil)).
+		Column("auth_session.user_id").
+		ColumnExpr("SUM(CASE WHEN auth_session.ended_at IS NULL AND EXISTS (SELECT 1 FROM refresh_token WHERE refresh_token.user_id = auth_session.user_id AND refresh_token.clinic_id = auth_session.clinic_id AND refresh_token.expires_at > ?) THEN 1 ELSE 0 END) AS active_sessions", now).
+		ColumnExpr("SUM(CASE WHEN auth_session.method <> ? AND auth_session.started_at >= ? THEN 1 ELSE 0 END) AS logins", auth.LoginMethodClinicSwitch, since).
+		ColumnExpr("MAX(CASE WHEN auth_session.method <> ? AND auth_session.started_at >= ? THEN auth_session.started_at END) AS last_login_at", auth.LoginMethodClinicSwitch, since).
+		Where("auth_session.clinic_id = ?", clinicID).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				Where("auth_session.started_at >= ?", since).
+				WhereOr("auth_session.ended_at IS NULL").
+				WhereOr("auth_session.ended_at >= ?", since)
+		}).
+		Group("auth_session.user_id").
+		Order("auth_session.user_id ASC").
+		Scan(ctx, &userSessionsDAOs)
+	if err != nil {
+		return auth.SessionStats{}, fmt.Errorf("unable to count sessions per user: %w", err)
+	}
+
+	for _, userSessionsDAO := range userSessionsDAOs {
+		stats.ActiveSessions += userSessionsDAO.ActiveSessions
+		stats.Users = append(stats.Users, fromUserSessionsDAOToDomain(userSessionsDAO))
+	}
+
+	var dailyLoginsDAOs []dailyLoginsDAO
+	err = db.NewSelect().
+		Model((*sessionDAO)(nil)).
+		ColumnExpr(dayExpr+" AS day").
+		ColumnExpr("COUNT(*) AS logins").
+		ColumnExpr("COUNT(DISTINCT auth_session.user_id) AS users").
+		Where("auth_session.clinic_id = ?", clinicID).
+		Where("auth_session.method <> ?", auth.LoginMethodClinicSwitch).
+		Where("auth_session.started_at >= ?", since).
+		GroupExpr("day").
+		OrderExpr("day ASC").
+		Scan(ctx, &dailyLoginsDAOs)
+	if err != nil {
+		return auth.SessionStats{}, fmt.Errorf("unable to count logins per day: %w", err)
+	}
+
+	for _, dailyLoginsDAO := range dailyLoginsDAOs {
+		stats.LoginsPerDay = append(stats.LoginsPerDay, auth.DailyLogins(dailyLoginsDAO))
+	}
+
+	var averageLifetime float64
+	err = db.NewSelect().
+		Model((*sessionDAO)(nil)).
+		ColumnExpr("COALESCE(AVG("+lifetimeExpr+"), 0.0)").
+		Where("auth_session.clinic_id = ?", clinicID).
+		Where("auth_session.ended_at >= ?", since).
+		Scan(ctx, &averageLifetime)
+	if err != nil {
+		return auth.SessionStats{}, fmt.Errorf("unable to average the session lifetimes: %w", err)
+	}
+
+	// julianday is a float in SQLite, a lifetime of whole seconds may come back a bit short
+	stats.AverageLifetimeSeconds = int64(math.Round(averageLifetime))
+	return stats, nil
+}
+
+// sessionExprs returns the UTC day a session started and its lifetime in seconds, the only expressions
+// of the adapter which PostgreSQL and SQLite spell differently. The timestamps are stored in UTC
+func sessionExprs(db bun.IDB) (dayExpr string, lifetimeExpr string) {
+	if db.Dialect().Name() == dialect.SQLite {
+		return "date(auth_session.started_at)", "(julianday(auth_session.ended_at) - julianday(auth_session.started_at)) * 86400"
+	}
+
+	return "to_char(auth_session.started_at, 'YYYY-MM-DD')", "EXTRACT(EPOCH FROM auth_session.ended_at - auth_session.started_at)"
+}
+
+func (p *pgPersistence) GetLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string) (auth.LoginAttempts, error) {
+	var attemptDAO loginAttemptDAO
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&attemptDAO).
+		Where("scope = ?", scope).
+		Where("key = ?", key).
+		Limit(1).
+		Scan(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return auth.LoginAttempts{Scope: scope, Key: key}, nil
+	}
+
+	if err != nil {
+		return auth.LoginAttempts{}, fmt.Errorf("unable to get login attempts: %w", err)
+	}
+
+	return fromLoginAttemptDAOToDomain(attemptDAO), nil
+}
+
+func (p *pgPersistence) IncrementLoginFailures(ctx context.Context, scope auth.AttemptScope, key string, at time.Time, resetWindow time.Duration) (auth.LoginAttempts, error) {
+	attemptDAO := loginAttemptDAO{
+		Scope:         string(scope),
+		Key:           key,
+		Failures:      1,
+		LastFailureAt: at,
+	}
+
+	_, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&attemptDAO).
+		On("CONFLICT (scope, key) DO UPDATE").
+		Set("failures = CASE WHEN login_attempt.last_failure_at < ? THEN 1 ELSE login_attempt.failures + 1 END", at.Add(-resetWindow)).
+		Set("last_failure_at = EXCLUDED.last_failure_at").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return auth.LoginAttempts{}, fmt.Errorf("unable to increment login failures: %w", err)
+	}
+
+	return fromLoginAttemptDAOToDomain(attemptDAO), nil
+}
+
+func (p *pgPersistence) LockLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string, until time.Time) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*loginAttemptDAO)(nil)).
+		Set("locked_until = ?", until).
+		Where("scope = ?", scope).
+		Where("key = ?", key).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to lock login attempts: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) DeleteLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewDelete().
+		Model((*loginAttemptDAO)(nil)).
+		Where("scope = ?", scope).
+		Where("key = ?", key).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete login attempts: %w", err)
+	}
+
+	return nil
+}
@@ -5,9 +5,11 @@ import (
 
 	"github.com/google/uuid"
 
-	user "github.com/sopial42/cleanic/internal/domains/user"
 	uPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/user"
 	"github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
 	"github.com/uptrace/bun"
 )
 
@@ -19,6 +21,7 @@ type tokenDAO struct {
 	User      *uPersistence.UserDAO `bun:"rel:belongs-to,join:user_id=id"`
 	ExpiresAt time.Time             `bun:"expires_at,notnull"`
 	IssuedAt  time.Time             `bun:"issued_at,notnull"`
+	ClinicID  int64                 `bun:"clinic_id,notnull"`
 }
 
 func fromTokenClaimsToTokenDAO(claims jwt.RefreshTokenClaims) tokenDAO {
@@ -30,6 +33,7 @@ func fromTokenClaimsToTokenDAO(claims jwt.RefreshTokenClaims) tokenDAO {
 		UserID:    int64(claims.Subject),
 		IssuedAt:  issueTime,
 		ExpiresAt: expireTime,
+		ClinicID:  int64(claims.ClinicID),
 	}
 }
 
@@ -39,5 +43,70 @@ func fromTokenDAOToTokenClaims(tokenDAO *tokenDAO) jwt.RefreshTokenClaims {
 		Subject:   user.ID(tokenDAO.UserID),
 		IssuedAt:  tokenDAO.IssuedAt.Unix(),
 		ExpiresAt: tokenDAO.ExpiresAt.Unix(),
+		ClinicID:  clinic.ID(tokenDAO.ClinicID),
+	}
+}
+
+type loginAttemptDAO struct {
+	bun.BaseModel `bun:"table:login_attempt,alias:login_attempt"`
+
+	Scope         string    `bun:"scope,pk"`
+	Key           string    `bun:"key,pk"`
+	Failures      int       `bun:"failures,notnull"`
+	LastFailureAt time.Time `bun:"last_failure_at,notnull"`
+	LockedUntil   time.Time `bun:"locked_until,nullzero"`
+}
+
+func fromLoginAttemptDAOToDomain(attemptDAO loginAttemptDAO) auth.LoginAttempts {
+	return auth.LoginAttempts{
+		Scope:         auth.AttemptScope(attemptDAO.Scope),
+		Key:           attemptDAO.Key,
+		Failures:      attemptDAO.Failures,
+		LastFailureAt: attemptDAO.LastFailureAt,
+		LockedUntil:   attemptDAO.LockedUntil,
+	}
+}
+
+type sessionDAO struct {
+	bun.BaseModel `bun:"table:auth_session,alias:auth_session"`
+
+	ID        int64      `bun:"id,pk,autoincrement"`
+	UserID    int64      `bun:"user_id,notnull"`
+	ClinicID  int64      `bun:"clinic_id,notnull"`
+	Method    string     `bun:"method,notnull"`
+	StartedAt time.Time  `bun:"started_at,notnull"`
+	EndedAt   *time.Time `bun:"ended_at"`
+}
+
+func fromSessionToSessionDAO(session auth.Session) sessionDAO {
+	return sessionDAO{
+		UserID:    int64(session.UserID),
+		ClinicID:  int64(session.ClinicID),
+		Method:    string(session.Method),
+		StartedAt: session.StartedAt,
+		EndedAt:   session.EndedAt,
+	}
+}
+
+// dailyLoginsDAO and userSessionsDAO are the rows of the session stats, grouped by the database
+type dailyLoginsDAO struct {
+	Day    string `bun:"day"`
+	Logins int    `bun:"logins"`
+	Users  int    `bun:"users"`
+}
+
+type userSessionsDAO struct {
+	UserID         int64      `bun:"user_id"`
+	ActiveSessions int        `bun:"active_sessions"`
+	Logins         int        `bun:"logins"`
+	LastLoginAt    *time.Time `bun:"last_login_at"`
+}
+
+func fromUserSessionsDAOToDomain(userSessionsDAO userSessionsDAO) auth.UserSessions {
+	return auth.UserSessions{
+		UserID:         user.ID(userSessionsDAO.UserID),
+		ActiveSessions: userSessionsDAO.ActiveSessions,
+		Logins:         userSessionsDAO.Logins,
+		LastLoginAt:    userSessionsDAO.LastLoginAt,
 	}
 }
@@ -0,0 +1,13 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect and
+// sessionExprs spells the few expressions which differ
+func NewSQLiteClient(client *bun.DB) authSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
@@ -0,0 +1,105 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) clinicSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) InsertClinic(ctx context.Context, newClinic clinic.Clinic, founderID user.ID, founderRoles user.Roles) (clinic.Clinic, error) {
+	defer m.db.Lock(ctx)()
+
+	for _, existing := range m.db.Clinics {
+		if existing.Name == newClinic.Name {
+			return clinic.Clinic{}, fmt.Errorf("unable to insert clinic: duplicate key value violates unique constraint \"clinic_name_key\"")
+		}
+	}
+
+	if err := m.db.CheckUser(founderID); err != nil {
+		return clinic.Clinic{}, fmt.Errorf("unable to insert clinic: %w", err)
+	}
+
+	newClinic.ID = clinic.ID(m.db.NextID("clinic"))
+	m.db.Clinics[newClinic.ID] = newClinic
+	m.db.ClinicMembers[persistence.ClinicMemberKey{ClinicID: newClinic.ID, UserID: founderID}] = append(user.Roles{}, founderRoles...)
+
+	// the builtin roles are the same in every clinic, the other ones are managed by each clinic
+	for key, role := range m.db.Roles {
+		if key.ClinicID == clinic.DefaultID && role.Builtin {
+			role.Permissions = append(user.Permissions{}, role.Permissions...)
+			m.db.Roles[persistence.RoleKey{ClinicID: newClinic.ID, Name: key.Name}] = role
+		}
+	}
+
+	return newClinic, nil
+}
+
+// ListMemberships is scoped to the user and not to the context clinic, it is used to choose the clinic
+func (m *inMemory) ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error) {
+	defer m.db.RLock(ctx)()
+
+	memberships := []clinic.Membership{}
+	for key, roles := range m.db.ClinicMembers {
+		if key.UserID == userID {
+			memberships = append(memberships, m.membership(key, roles))
+		}
+	}
+
+	slices.SortFunc(memberships, func(a, b clinic.Membership) int { return cmp.Compare(a.Clinic.ID, b.Clinic.ID) })
+	return memberships, nil
+}
+
+func (m *inMemory) InsertMember(ctx context.Context, email user.Email, roles user.Roles) (clinic.Membership, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckClinic(clinicID); err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: %w", err)
+	}
+
+	var userID user.ID
+	for _, existing := range m.db.Users {
+		if existing.Email == email {
+			userID = existing.ID
+		}
+	}
+
+	if userID == 0 {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: no user with email %s", email)
+	}
+
+	key := persistence.ClinicMemberKey{ClinicID: clinicID, UserID: userID}
+	if _, found := m.db.ClinicMembers[key]; found {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: duplicate key value violates unique constraint \"clinic_member_pkey\"")
+	}
+
+	m.db.ClinicMembers[key] = append(user.Roles{}, roles...)
+	return m.membership(key, roles), nil
+}
+
+// membership joins the clinic of the membership, the lock must be held
+func (m *inMemory) membership(key persistence.ClinicMemberKey, roles user.Roles) clinic.Membership {
+	return clinic.Membership{
+		Clinic: m.db.Clinics[key.ClinicID],
+		UserID: key.UserID,
+		Roles:  append(user.Roles{}, roles...),
+	}
+}
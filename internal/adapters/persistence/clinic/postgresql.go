@@ -0,0 +1,115 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+}
+
+func NewPGClient(client *bun.DB) clinicSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
+
+func (p *pgPersistence) InsertClinic(ctx context.Context, newClinic clinic.Clinic, founderID user.ID, founderRoles user.Roles) (clinic.Clinic, error) {
+	clinicDAO := clinicDAO{Name: newClinic.Name}
+	err := persistence.DB(ctx, p.clientDB).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewInsert().
+			Model(&clinicDAO).
+			Returning("*").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		founderDAO := membershipFromDomainToDAO(clinic.Membership{
+			Clinic: clinic.Clinic{ID: clinic.ID(clinicDAO.ID)},
+			UserID: founderID,
+			Roles:  founderRoles,
+		})
+		_, err = tx.NewInsert().
+			Model(&founderDAO).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		// the builtin roles are the same in every clinic, the other ones are managed by each clinic
+		_, err = tx.NewRaw(
+			"INSERT INTO role (clinic_id, name, description, permissions, builtin) SELECT ?, name, description, permissions, builtin FROM role WHERE clinic_id = ? AND builtin",
+			clinicDAO.ID, clinic.DefaultID,
+		).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return clinic.Clinic{}, fmt.Errorf("unable to insert clinic: %w", err)
+	}
+
+	return clinicFromDAOToDomain(clinicDAO), nil
+}
+
+// ListMemberships is scoped to the user and not to the context clinic, it is used to choose the clinic
+func (p *pgPersistence) ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error) {
+	var membershipDAOs []membershipDAO
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&membershipDAOs).
+		Relation("Clinic").
+		Where("m.user_id = ?", userID).
+		Order("m.clinic_id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list memberships: %w", err)
+	}
+
+	return membershipFromDAOsToDomains(membershipDAOs), nil
+}
+
+func (p *pgPersistence) InsertMember(ctx context.Context, email user.Email, roles user.Roles) (clinic.Membership, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: %w", err)
+	}
+
+	memberDAO := membershipFromDomainToDAO(clinic.Membership{Clinic: clinic.Clinic{ID: clinicID}, Roles: roles})
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Table("users").
+		Column("id").
+		Where("email = ?", email).
+		Scan(ctx, &memberDAO.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: no user with email %s", email)
+	}
+
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: %w", err)
+	}
+
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&memberDAO).
+		Exec(ctx)
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to insert member: %w", err)
+	}
+
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&memberDAO).
+		Relation("Clinic").
+		WherePK().
+		Scan(ctx)
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to get inserted member: %w", err)
+	}
+
+	return membershipFromDAOToDomain(memberDAO), nil
+}
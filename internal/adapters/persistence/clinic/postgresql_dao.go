@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type clinicDAO struct {
+	bun.BaseModel `bun:"table:clinic,alias:c"`
+
+	ID        int64     `bun:"id,pk,autoincrement"`
+	Name      string    `bun:"name,notnull,unique"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type membershipDAO struct {
+	bun.BaseModel `bun:"table:clinic_member,alias:m"`
+
+	ClinicID int64      `bun:"clinic_id,pk"`
+	UserID   int64      `bun:"user_id,pk"`
+	Roles    []string   `bun:"roles,type:jsonb,notnull"`
+	Clinic   *clinicDAO `bun:"rel:belongs-to,join:clinic_id=id"`
+}
+
+func clinicFromDAOToDomain(clinicDAO clinicDAO) clinic.Clinic {
+	return clinic.Clinic{
+		ID:   clinic.ID(clinicDAO.ID),
+		Name: clinicDAO.Name,
+	}
+}
+
+func membershipFromDomainToDAO(membership clinic.Membership) membershipDAO {
+	roles := make([]string, len(membership.Roles))
+	for i, role := range membership.Roles {
+		roles[i] = string(role)
+	}
+
+	return membershipDAO{
+		ClinicID: int64(membership.Clinic.ID),
+		UserID:   int64(membership.UserID),
+		Roles:    roles,
+	}
+}
+
+func membershipFromDAOToDomain(membershipDAO membershipDAO) clinic.Membership {
+	roles := make(user.Roles, len(membershipDAO.Roles))
+	for i, role := range membershipDAO.Roles {
+		roles[i] = user.Role(role)
+	}
+
+	membership := clinic.Membership{
+		Clinic: clinic.Clinic{ID: clinic.ID(membershipDAO.ClinicID)},
+		UserID: user.ID(membershipDAO.UserID),
+		Roles:  roles,
+	}
+
+	if membershipDAO.Clinic != nil {
+		membership.Clinic = clinicFromDAOToDomain(*membershipDAO.Clinic)
+	}
+
+	return membership
+}
+
+func membershipFromDAOsToDomains(membershipDAOs []membershipDAO) []clinic.Membership {
+	memberships := make([]clinic.Membership, len(membershipDAOs))
+	for i, membershipDAO := range membershipDAOs {
+		memberships[i] = membershipFromDAOToDomain(membershipDAO)
+	}
+
+	return memberships
+}
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect
+func NewSQLiteClient(client *bun.DB) clinicSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
@@ -0,0 +1,108 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) consentSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) InsertConsent(ctx context.Context, newConsent consent.Consent) (consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return consent.Consent{}, fmt.Errorf("unable to insert consent: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if _, found := m.db.Patients[newConsent.PatientID]; !found {
+		return consent.Consent{}, fmt.Errorf("unable to insert consent: violates foreign key constraint: patient %d does not exist", newConsent.PatientID)
+	}
+
+	if newConsent.CollectedBy != 0 {
+		if err := m.db.CheckUser(newConsent.CollectedBy); err != nil {
+			return consent.Consent{}, fmt.Errorf("unable to insert consent: %w", err)
+		}
+	}
+
+	newConsent.ID = consent.ID(m.db.NextID("patient_consent"))
+	m.db.Consents[newConsent.ID] = persistence.ConsentRow{Consent: newConsent, ClinicID: clinicID}
+	return newConsent, nil
+}
+
+func (m *inMemory) ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	consents := []consent.Consent{}
+	for _, row := range m.db.Consents {
+		if row.ClinicID == clinicID && row.PatientID == patientID {
+			consents = append(consents, row.Consent)
+		}
+	}
+
+	slices.SortFunc(consents, func(a, b consent.Consent) int { return cmp.Compare(b.ID, a.ID) })
+	return consents, nil
+}
+
+func (m *inMemory) ListLatestConsents(ctx context.Context, purpose consent.Purpose) ([]consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list latest consents: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	latest := map[patient.ID]consent.Consent{}
+	for _, row := range m.db.Consents {
+		if row.ClinicID != clinicID || row.Purpose != purpose {
+			continue
+		}
+
+		if current, found := latest[row.PatientID]; !found || row.ID > current.ID {
+			latest[row.PatientID] = row.Consent
+		}
+	}
+
+	consents := make([]consent.Consent, 0, len(latest))
+	for _, latestConsent := range latest {
+		consents = append(consents, latestConsent)
+	}
+
+	slices.SortFunc(consents, func(a, b consent.Consent) int { return cmp.Compare(a.PatientID, b.PatientID) })
+	return consents, nil
+}
+
+func (m *inMemory) CheckPatient(ctx context.Context, patientID patient.ID) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get patient: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	if row, found := m.db.Patients[patientID]; !found || row.ClinicID != clinicID {
+		return fmt.Errorf("unable to get patient: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
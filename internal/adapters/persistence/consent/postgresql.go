@@ -0,0 +1,113 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+}
+
+// NewPGClient scopes every query to the clinic of the context, a query without clinic fails
+func NewPGClient(client *bun.DB) consentSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
+
+func (p *pgPersistence) InsertConsent(ctx context.Context, newConsent consent.Consent) (consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return consent.Consent{}, fmt.Errorf("unable to insert consent: %w", err)
+	}
+
+	consentDAO := consentFromDomainToDAO(newConsent)
+	consentDAO.ClinicID = int64(clinicID)
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&consentDAO).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return consent.Consent{}, fmt.Errorf("unable to insert consent: %w", err)
+	}
+
+	return consentFromDAOToDomain(consentDAO), nil
+}
+
+func (p *pgPersistence) ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	var consentDAOs []consentDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&consentDAOs).
+		Where("clinic_id = ?", clinicID).
+		Where("patient_id = ?", patientID).
+		Order("id DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	return consentsFromDAOsToDomains(consentDAOs), nil
+}
+
+// ListLatestConsents relies on the ids growing with the time of recording, the consents are never updated
+func (p *pgPersistence) ListLatestConsents(ctx context.Context, purpose consent.Purpose) ([]consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list latest consents: %w", err)
+	}
+
+	db := persistence.DB(ctx, p.clientDB)
+	latestIDs := db.NewSelect().
+		Model((*consentDAO)(nil)).
+		ColumnExpr("MAX(id)").
+		Where("clinic_id = ?", clinicID).
+		Where("purpose = ?", purpose).
+		Group("patient_id")
+
+	var consentDAOs []consentDAO
+	err = db.NewSelect().
+		Model(&consentDAOs).
+		Where("id IN (?)", latestIDs).
+		Order("patient_id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list latest consents: %w", err)
+	}
+
+	return consentsFromDAOsToDomains(consentDAOs), nil
+}
+
+func (p *pgPersistence) CheckPatient(ctx context.Context, patientID patient.ID) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get patient: %w", err)
+	}
+
+	exists, err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Table("patient").
+		Where("id = ?", patientID).
+		Where("clinic_id = ?", clinicID).
+		Exists(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get patient: %w", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("unable to get patient: %w", sql.ErrNoRows)
+	}
+
+	return nil
+}
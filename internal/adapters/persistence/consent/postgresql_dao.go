@@ -0,0 +1,57 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type consentDAO struct {
+	bun.BaseModel `bun:"table:patient_consent,alias:patient_consent"`
+
+	ID        int64  `bun:"id,pk,autoincrement"`
+	ClinicID  int64  `bun:"clinic_id,notnull"`
+	PatientID int64  `bun:"patient_id,notnull"`
+	Purpose   string `bun:"purpose,notnull"`
+	Status    string `bun:"status,notnull"`
+	// CollectedBy is NULL once the user is deleted
+	CollectedBy int64     `bun:"collected_by,nullzero"`
+	RecordedAt  time.Time `bun:"recorded_at,notnull"`
+	DocumentRef string    `bun:"document_ref,nullzero"`
+}
+
+func consentFromDomainToDAO(c consent.Consent) consentDAO {
+	return consentDAO{
+		PatientID:   int64(c.PatientID),
+		Purpose:     string(c.Purpose),
+		Status:      string(c.Status),
+		CollectedBy: int64(c.CollectedBy),
+		RecordedAt:  c.RecordedAt,
+		DocumentRef: c.DocumentRef,
+	}
+}
+
+func consentFromDAOToDomain(c consentDAO) consent.Consent {
+	return consent.Consent{
+		ID:          consent.ID(c.ID),
+		PatientID:   patient.ID(c.PatientID),
+		Purpose:     consent.Purpose(c.Purpose),
+		Status:      consent.Status(c.Status),
+		CollectedBy: user.ID(c.CollectedBy),
+		RecordedAt:  c.RecordedAt,
+		DocumentRef: c.DocumentRef,
+	}
+}
+
+func consentsFromDAOsToDomains(consentDAOs []consentDAO) []consent.Consent {
+	consents := make([]consent.Consent, 0, len(consentDAOs))
+	for _, consentDAO := range consentDAOs {
+		consents = append(consents, consentFromDAOToDomain(consentDAO))
+	}
+
+	return consents
+}
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect
+func NewSQLiteClient(client *bun.DB) consentSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
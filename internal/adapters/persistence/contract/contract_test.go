@@ -0,0 +1,887 @@
+package contract
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	"github.com/sopial42/cleanic/internal/domains/auth"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+	"github.com/sopial42/cleanic/internal/domains/job"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/privacy"
+	"github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+	"github.com/sopial42/cleanic/internal/services/transaction"
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+// missingClinicID is never created, neither by the schema nor by the fixtures
+const missingClinicID clinic.ID = 999999
+
+// firstID is where the sequences of the schema start
+const firstID = 10001
+
+// Ports are the adapters of one backend, newPorts must return them without any user, patient,
+// login attempt, event, webhook subscription, job, consent or erasure, and with the default clinic and the builtin roles
+type Ports struct {
+	Patient     patientSVC.Persistence
+	User        userSVC.Persistence
+	Auth        authSVC.Persistence
+	Clinics     clinicSVC.Persistence
+	Roles       roleSVC.Persistence
+	Outbox      eventSVC.Persistence
+	Webhooks    webhookSVC.Persistence
+	Jobs        jobSVC.Persistence
+	Consents    consentSVC.Persistence
+	Privacy     privacySVC.Persistence
+	Idempotency idempotencySVC.Persistence
+	UnitOfWork  transaction.UnitOfWork
+}
+
+func run(t *testing.T, newPorts func(t *testing.T) Ports) {
+	ctx := clinic.WithID(context.Background(), clinic.DefaultID)
+
+	t.Run("ids start at 10001 and increase", func(t *testing.T) {
+		ports := newPorts(t)
+		first := insertPatient(t, ctx, ports, "first@gmail.com")
+		second := insertPatient(t, ctx, ports, "second@gmail.com")
+		if first.ID < firstID || second.ID <= first.ID {
+			t.Fatalf("unexpected ids %d then %d", first.ID, second.ID)
+		}
+
+		newUser := insertUser(t, ctx, ports, "user@gmail.com")
+		if newUser.ID < firstID {
+			t.Fatalf("unexpected user id %d", newUser.ID)
+		}
+	})
+
+	t.Run("emails are unique", func(t *testing.T) {
+		ports := newPorts(t)
+		insertPatient(t, ctx, ports, "patient@gmail.com")
+		if _, err := ports.Patient.InsertPatient(ctx, patient.Patient{Firstname: "a", Lastname: "b", Email: "patient@gmail.com"}); err == nil {
+			t.Fatalf("duplicate patient email should fail")
+		}
+
+		existing := insertUser(t, ctx, ports, "user@gmail.com")
+		if _, err := ports.User.Insert(ctx, user.User{Email: "user@gmail.com", Password: "hash", Roles: user.Roles{user.RoleDoctor}}); err == nil {
+			t.Fatalf("duplicate user email should fail")
+		}
+
+		other := insertUser(t, ctx, ports, "other@gmail.com")
+		other.Email = existing.Email
+		if _, err := ports.User.UpdateUser(ctx, other); err == nil {
+			t.Fatalf("update to an existing email should fail")
+		}
+	})
+
+	t.Run("missing rows are sql.ErrNoRows", func(t *testing.T) {
+		ports := newPorts(t)
+		if _, err := ports.Patient.GetPatientByID(ctx, 1); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get missing patient: %v", err)
+		}
+
+		if _, err := ports.User.GetUserByID(ctx, 1); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get missing user: %v", err)
+		}
+
+		if _, err := ports.User.GetUserByEmail(ctx, "missing@gmail.com"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get missing user by email: %v", err)
+		}
+
+		if _, err := ports.Auth.GetRefreshTokenClaimsByUserID(ctx, 1); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get missing refresh token: %v", err)
+		}
+
+		if err := ports.Patient.DeletePatient(ctx, 1); err != nil {
+			t.Fatalf("delete missing patient should be silent: %v", err)
+		}
+	})
+
+	t.Run("queries are scoped to the context clinic", func(t *testing.T) {
+		ports := newPorts(t)
+		created := insertPatient(t, ctx, ports, "patient@gmail.com")
+		if _, err := ports.Patient.ListPatients(context.Background()); !errors.Is(err, clinic.ErrNoClinic) {
+			t.Fatalf("list without clinic: %v", err)
+		}
+
+		missingClinic := clinic.WithID(context.Background(), missingClinicID)
+		if _, err := ports.Patient.GetPatientByID(missingClinic, int64(created.ID)); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get from another clinic: %v", err)
+		}
+
+		if _, err := ports.Patient.InsertPatient(missingClinic, patient.Patient{Firstname: "a", Lastname: "b", Email: "x@gmail.com"}); err == nil {
+			t.Fatalf("insert in a missing clinic should fail")
+		}
+
+		patients, err := ports.Patient.ListPatients(ctx)
+		if err != nil || len(patients) != 1 || patients[0] != created {
+			t.Fatalf("list patients: %v %+v", err, patients)
+		}
+
+		member := insertUser(t, ctx, ports, "member@gmail.com")
+		if _, err := ports.User.UpdateUser(missingClinic, user.User{ID: member.ID, Email: "moved@gmail.com"}); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("update of a user of another clinic should fail with sql.ErrNoRows: %v", err)
+		}
+
+		// the password rotation has no clinic yet
+		rotated, err := ports.User.UpdateUser(context.Background(), user.User{ID: member.ID, Password: "rotated"})
+		if err != nil || rotated.Email != member.Email {
+			t.Fatalf("update without clinic: %v %+v", err, rotated)
+		}
+	})
+
+	t.Run("roles are scoped to their clinic", func(t *testing.T) {
+		ports := newPorts(t)
+		founder := insertUser(t, ctx, ports, "founder@gmail.com")
+		north, err := ports.Clinics.InsertClinic(ctx, clinic.Clinic{Name: "north"}, founder.ID, user.Roles{user.RoleAdmin})
+		if err != nil {
+			t.Fatalf("insert clinic: %v", err)
+		}
+		northCtx := clinic.WithID(context.Background(), north.ID)
+
+		// a new clinic only receives the builtin roles
+		roles, err := ports.Roles.ListRoles(northCtx)
+		if err != nil || len(roles) != 2 || roles[0].Name != user.RoleAdmin || roles[1].Name != user.RoleDoctor || !roles[1].Builtin {
+			t.Fatalf("roles of a new clinic: %v %+v", err, roles)
+		}
+		if _, err := ports.Roles.GetRole(northCtx, "nurse"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get a role of another clinic: %v", err)
+		}
+		if _, err := ports.Roles.ListRoles(context.Background()); !errors.Is(err, clinic.ErrNoClinic) {
+			t.Fatalf("list without clinic: %v", err)
+		}
+
+		if _, err := ports.Roles.InsertRole(northCtx, user.RoleDefinition{Name: "nurse", Permissions: user.Permissions{user.PermissionUserRead}}); err != nil {
+			t.Fatalf("insert a role named like one of another clinic: %v", err)
+		}
+		if _, err := ports.Roles.UpdateRole(northCtx, user.RoleDefinition{Name: user.RoleDoctor, Permissions: user.Permissions{user.PermissionPatientRead}}); err != nil {
+			t.Fatalf("update role: %v", err)
+		}
+		if err := ports.Roles.DeleteRole(northCtx, "nurse"); err != nil {
+			t.Fatalf("delete role: %v", err)
+		}
+
+		nurse, err := ports.Roles.GetRole(ctx, "nurse")
+		if err != nil || slices.Contains(nurse.Permissions, user.PermissionUserRead) {
+			t.Fatalf("nurse of the default clinic: %v %+v", err, nurse)
+		}
+		doctor, err := ports.Roles.GetRole(ctx, user.RoleDoctor)
+		if err != nil || !slices.Contains(doctor.Permissions, user.PermissionPatientWrite) {
+			t.Fatalf("doctor of the default clinic: %v %+v", err, doctor)
+		}
+
+		if count, err := ports.Roles.CountUsersWithRole(northCtx, user.RoleAdmin); err != nil || count != 1 {
+			t.Fatalf("admins of the new clinic: %v %d", err, count)
+		}
+		if count, err := ports.Roles.CountUsersWithRole(ctx, user.RoleAdmin); err != nil || count != 0 {
+			t.Fatalf("admins of the default clinic: %v %d", err, count)
+		}
+	})
+
+	t.Run("updates only change the non zero fields", func(t *testing.T) {
+		ports := newPorts(t)
+		created := insertPatient(t, ctx, ports, "patient@gmail.com")
+		updated, err := ports.Patient.UpdatePatient(ctx, patient.Patient{ID: created.ID, Firstname: "new"})
+		if err != nil || updated.Firstname != "new" || updated.Lastname != created.Lastname || updated.Email != created.Email {
+			t.Fatalf("update patient: %v %+v", err, updated)
+		}
+
+		if _, err := ports.Patient.UpdatePatient(ctx, patient.Patient{ID: created.ID + 1, Firstname: "new"}); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("update of a missing patient should fail with sql.ErrNoRows: %v", err)
+		}
+
+		missingClinic := clinic.WithID(context.Background(), missingClinicID)
+		if _, err := ports.Patient.UpdatePatient(missingClinic, patient.Patient{ID: created.ID, Firstname: "new"}); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("update from another clinic should fail with sql.ErrNoRows: %v", err)
+		}
+	})
+
+	t.Run("deleting a user cascades", func(t *testing.T) {
+		ports := newPorts(t)
+		created := insertUser(t, ctx, ports, "user@gmail.com")
+		if err := ports.User.InsertPasswordHistory(ctx, created.ID, "old"); err != nil {
+			t.Fatalf("insert password history: %v", err)
+		}
+
+		if err := ports.Auth.StoreRefreshTokenClaims(ctx, refreshClaims(created.ID)); err != nil {
+			t.Fatalf("store refresh token: %v", err)
+		}
+
+		if err := ports.User.DeleteUser(ctx, created.ID); err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+
+		if _, err := ports.Auth.GetRefreshTokenClaimsByUserID(ctx, created.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("refresh token should be deleted with its user: %v", err)
+		}
+
+		history, err := ports.User.ListPasswordHistory(ctx, created.ID, 0)
+		if err != nil || len(history) != 0 {
+			t.Fatalf("password history should be deleted with its user: %v %v", err, history)
+		}
+	})
+
+	t.Run("foreign keys are enforced", func(t *testing.T) {
+		ports := newPorts(t)
+		if err := ports.Auth.StoreRefreshTokenClaims(ctx, refreshClaims(1)); err == nil {
+			t.Fatalf("refresh token of a missing user should fail")
+		}
+
+		if err := ports.User.InsertPasswordHistory(ctx, 1, "old"); err == nil {
+			t.Fatalf("password history of a missing user should fail")
+		}
+	})
+
+	t.Run("password history is newest first", func(t *testing.T) {
+		ports := newPorts(t)
+		created := insertUser(t, ctx, ports, "user@gmail.com")
+		for _, hash := range []user.Password{"first", "second", "third"} {
+			if err := ports.User.InsertPasswordHistory(ctx, created.ID, hash); err != nil {
+				t.Fatalf("insert password history: %v", err)
+			}
+		}
+
+		history, err := ports.User.ListPasswordHistory(ctx, created.ID, 2)
+		if err != nil || len(history) != 2 || history[0] != "third" || history[1] != "second" {
+			t.Fatalf("list password history: %v %v", err, history)
+		}
+	})
+
+	t.Run("refresh tokens are replaced", func(t *testing.T) {
+		ports := newPorts(t)
+		created := insertUser(t, ctx, ports, "user@gmail.com")
+		first, second := refreshClaims(created.ID), refreshClaims(created.ID)
+		for _, claims := range []jwt.RefreshTokenClaims{first, second} {
+			if err := ports.Auth.StoreRefreshTokenClaims(ctx, claims); err != nil {
+				t.Fatalf("store refresh token: %v", err)
+			}
+		}
+
+		stored, err := ports.Auth.GetRefreshTokenClaimsByUserID(ctx, created.ID)
+		if err != nil || stored.ID != second.ID {
+			t.Fatalf("the last refresh token should be kept: %v %+v", err, stored)
+		}
+	})
+
+	t.Run("expired refresh tokens are purged in batches and end their sessions", func(t *testing.T) {
+		ports := newPorts(t)
+		now := time.Now().UTC().Truncate(time.Second)
+		expired := insertUser(t, ctx, ports, "expired@gmail.com")
+		other := insertUser(t, ctx, ports, "other@gmail.com")
+		active := insertUser(t, ctx, ports, "active@gmail.com")
+		for _, session := range []auth.Session{
+			{UserID: expired.ID, StartedAt: now.Add(-2 * time.Hour)},
+			{UserID: expired.ID, StartedAt: now.Add(-90 * time.Minute)},
+			{UserID: other.ID, StartedAt: now.Add(-2 * time.Hour)},
+			{UserID: active.ID, StartedAt: now.Add(-2 * time.Hour)},
+		} {
+			session.ClinicID, session.Method = clinic.DefaultID, auth.LoginMethodPassword
+			if err := ports.Auth.StartSession(ctx, session); err != nil {
+				t.Fatalf("start session: %v", err)
+			}
+		}
+
+		for userID, expiresAt := range map[user.ID]time.Time{expired.ID: now.Add(-time.Hour), other.ID: now.Add(-30 * time.Minute), active.ID: now.Add(time.Hour)} {
+			claims := refreshClaims(userID)
+			claims.ExpiresAt = expiresAt.Unix()
+			if err := ports.Auth.StoreRefreshTokenClaims(ctx, claims); err != nil {
+				t.Fatalf("store refresh token: %v", err)
+			}
+		}
+
+		for _, want := range []int{1, 1, 0} {
+			if deleted, err := ports.Auth.DeleteExpiredRefreshTokens(ctx, now, 1); err != nil || deleted != want {
+				t.Fatalf("delete expired refresh tokens: %v, %d deleted instead of %d", err, deleted, want)
+			}
+		}
+
+		if _, err := ports.Auth.GetRefreshTokenClaimsByUserID(ctx, active.ID); err != nil {
+			t.Fatalf("the valid refresh token should be kept: %v", err)
+		}
+
+		stats, err := ports.Auth.SessionStats(ctx, now.Add(-3*time.Hour), now)
+		if err != nil || len(stats.Users) != 3 || stats.ActiveSessions != 1 || stats.AverageLifetimeSeconds != 50*60 {
+			t.Fatalf("the sessions should end with their refresh token or the next login: %v %+v", err, stats)
+		}
+
+		for i, want := range []auth.UserSessions{{UserID: expired.ID, Logins: 2}, {UserID: other.ID, Logins: 1}, {UserID: active.ID, ActiveSessions: 1, Logins: 1}} {
+			if got := stats.Users[i]; got.UserID != want.UserID || got.ActiveSessions != want.ActiveSessions || got.Logins != want.Logins {
+				t.Fatalf("user %d should have %+v, got %+v", i, want, got)
+			}
+		}
+
+		if others, err := ports.Auth.SessionStats(clinic.WithID(context.Background(), missingClinicID), now.Add(-3*time.Hour), now); err != nil || len(others.Users) != 0 {
+			t.Fatalf("another clinic should not see the sessions, got %+v: %v", others, err)
+		}
+
+		if err := ports.Auth.EndSession(ctx, active.ID, now); err != nil {
+			t.Fatalf("end session: %v", err)
+		}
+
+		if deleted, err := ports.Auth.DeleteEndedSessions(ctx, now.Add(-45*time.Minute), 10); err != nil || deleted != 2 {
+			t.Fatalf("delete ended sessions: %v, %d deleted", err, deleted)
+		}
+
+		stats, err = ports.Auth.SessionStats(ctx, now.Add(-3*time.Hour), now)
+		if err != nil || len(stats.Users) != 2 || stats.Users[0].UserID != other.ID || stats.ActiveSessions != 0 {
+			t.Fatalf("the recent sessions should be kept and ended: %v %+v", err, stats)
+		}
+	})
+
+	t.Run("session stats are grouped per user and per UTC day", func(t *testing.T) {
+		ports := newPorts(t)
+		now := time.Now()
+		since := now.UTC().Truncate(24*time.Hour).AddDate(0, 0, -3)
+		at := func(day, hour int) time.Time {
+			return since.AddDate(0, 0, day-1).Add(time.Duration(hour) * time.Hour)
+		}
+
+		first := insertUser(t, ctx, ports, "first@gmail.com")
+		second := insertUser(t, ctx, ports, "second@gmail.com")
+		third := insertUser(t, ctx, ports, "third@gmail.com")
+		for _, step := range []struct {
+			session auth.Session
+			endedAt time.Time
+		}{
+			// ended before the window, it is not counted at all
+			{session: auth.Session{UserID: third.ID, Method: auth.LoginMethodPassword, StartedAt: since.Add(-5 * time.Hour)}, endedAt: since.Add(-4 * time.Hour)},
+			// started before the window, only its end counts
+			{session: auth.Session{UserID: first.ID, Method: auth.LoginMethodPassword, StartedAt: since.Add(-2 * time.Hour)}, endedAt: at(1, 2)},
+			{session: auth.Session{UserID: first.ID, Method: auth.LoginMethodSSO, StartedAt: at(1, 10)}},
+			{session: auth.Session{UserID: first.ID, Method: auth.LoginMethodClinicSwitch, StartedAt: at(1, 12)}},
+			{session: auth.Session{UserID: second.ID, Method: auth.LoginMethodPassword, StartedAt: at(3, 8)}},
+			{session: auth.Session{UserID: third.ID, Method: auth.LoginMethodPassword, StartedAt: at(3, 9)}, endedAt: at(3, 10)},
+			{session: auth.Session{UserID: second.ID, Method: auth.LoginMethodPassword, StartedAt: at(3, 11)}},
+		} {
+			step.session.ClinicID = clinic.DefaultID
+			if err := ports.Auth.StartSession(ctx, step.session); err != nil {
+				t.Fatalf("start session: %v", err)
+			}
+			if !step.endedAt.IsZero() {
+				if err := ports.Auth.EndSession(ctx, step.session.UserID, step.endedAt); err != nil {
+					t.Fatalf("end session: %v", err)
+				}
+			}
+		}
+
+		for _, userID := range []user.ID{first.ID, second.ID} {
+			if err := ports.Auth.StoreRefreshTokenClaims(ctx, refreshClaims(userID)); err != nil {
+				t.Fatalf("store refresh token: %v", err)
+			}
+		}
+
+		stats, err := ports.Auth.SessionStats(ctx, since, now)
+		if err != nil {
+			t.Fatalf("session stats: %v", err)
+		}
+
+		// 4h, 2h, 3h and 1h
+		if stats.ActiveSessions != 2 || stats.AverageLifetimeSeconds != 9000 {
+			t.Fatalf("unexpected totals %+v", stats)
+		}
+
+		wantDays := []auth.DailyLogins{{Day: at(1, 0).Format(time.DateOnly), Logins: 1, Users: 1}, {Day: at(3, 0).Format(time.DateOnly), Logins: 3, Users: 2}}
+		if !slices.Equal(stats.LoginsPerDay, wantDays) {
+			t.Fatalf("the clinic switches and the sessions started before the window are not logins, got %+v", stats.LoginsPerDay)
+		}
+
+		wantUsers := []auth.UserSessions{
+			{UserID: first.ID, ActiveSessions: 1, Logins: 1},
+			{UserID: second.ID, ActiveSessions: 1, Logins: 2},
+			{UserID: third.ID, Logins: 1},
+		}
+		wantLastLogins := []time.Time{at(1, 10), at(3, 11), at(3, 9)}
+		if len(stats.Users) != len(wantUsers) {
+			t.Fatalf("unexpected users %+v", stats.Users)
+		}
+		for i, want := range wantUsers {
+			got := stats.Users[i]
+			if got.UserID != want.UserID || got.ActiveSessions != want.ActiveSessions || got.Logins != want.Logins || got.LastLoginAt == nil || !got.LastLoginAt.Equal(wantLastLogins[i]) {
+				t.Fatalf("user %d should have %+v last logged in at %s, got %+v", i, want, wantLastLogins[i], got)
+			}
+		}
+	})
+
+	t.Run("units of work commit or roll back as a whole", func(t *testing.T) {
+		ports := newPorts(t)
+		errAbort := errors.New("abort")
+		err := ports.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+			created := insertUser(t, ctx, ports, "rolledback@gmail.com")
+			if err := ports.Auth.StoreRefreshTokenClaims(ctx, refreshClaims(created.ID)); err != nil {
+				t.Fatalf("store refresh token: %v", err)
+			}
+
+			return errAbort
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("the error of the unit of work should be returned, got %v", err)
+		}
+
+		if _, err := ports.User.GetUserByEmail(ctx, "rolledback@gmail.com"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("the user should be rolled back: %v", err)
+		}
+
+		var created user.User
+		err = ports.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+			created = insertUser(t, ctx, ports, "committed@gmail.com")
+			// a nested unit of work joins the outer one
+			return ports.UnitOfWork.Do(ctx, func(ctx context.Context) error {
+				return ports.Auth.StoreRefreshTokenClaims(ctx, refreshClaims(created.ID))
+			})
+		})
+		if err != nil {
+			t.Fatalf("unit of work: %v", err)
+		}
+
+		if _, err := ports.Auth.GetRefreshTokenClaimsByUserID(ctx, created.ID); err != nil {
+			t.Fatalf("the refresh token should be committed: %v", err)
+		}
+	})
+
+	t.Run("due events are claimed once until their lease ends", func(t *testing.T) {
+		ports := newPorts(t)
+		now := time.Now().UTC().Truncate(time.Second)
+		var inserted []event.Event
+		for _, nextAttemptAt := range []time.Time{now, now.Add(-time.Minute), now.Add(time.Hour)} {
+			newEvent, err := ports.Outbox.Insert(ctx, event.Event{
+				Type:          event.TypePatientCreated,
+				ClinicID:      clinic.DefaultID,
+				Payload:       []byte(`{"id":10001}`),
+				OccurredAt:    now,
+				NextAttemptAt: nextAttemptAt,
+			})
+			if err != nil {
+				t.Fatalf("insert event: %v", err)
+			}
+			inserted = append(inserted, newEvent)
+		}
+
+		claimed, err := ports.Outbox.ClaimDue(ctx, now, time.Minute, 10)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if len(claimed) != 2 || claimed[0].ID != inserted[0].ID || claimed[1].ID != inserted[1].ID {
+			t.Fatalf("the two due events should be claimed oldest first, got %+v", claimed)
+		}
+		if string(claimed[0].Payload) != `{"id":10001}` || claimed[0].ClinicID != clinic.DefaultID {
+			t.Fatalf("unexpected event %+v", claimed[0])
+		}
+
+		if again, err := ports.Outbox.ClaimDue(ctx, now, time.Minute, 10); err != nil || len(again) != 0 {
+			t.Fatalf("leased events should not be claimed again, got %d: %v", len(again), err)
+		}
+
+		if err := ports.Outbox.Delete(ctx, claimed[0].ID); err != nil {
+			t.Fatalf("delete: %v", err)
+		}
+		if err := ports.Outbox.Reschedule(ctx, claimed[1].ID, 1, now.Add(time.Minute), "refused"); err != nil {
+			t.Fatalf("reschedule: %v", err)
+		}
+
+		retried, err := ports.Outbox.ClaimDue(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("claim after the lease: %v", err)
+		}
+		if len(retried) != 1 || retried[0].ID != claimed[1].ID || retried[0].Attempts != 1 || retried[0].LastError != "refused" {
+			t.Fatalf("only the rescheduled event should be due again, got %+v", retried)
+		}
+	})
+
+	t.Run("webhook deliveries are recorded once and dead-lettered", func(t *testing.T) {
+		ports := newPorts(t)
+		now := time.Now().UTC().Truncate(time.Second)
+		subscription, err := ports.Webhooks.InsertSubscription(ctx, webhook.Subscription{
+			URL:        "http://127.0.0.1/hooks",
+			EventTypes: []event.Type{event.TypePatientCreated},
+			Secret:     "whsec_test",
+		})
+		if err != nil {
+			t.Fatalf("insert subscription: %v", err)
+		}
+		if _, err := ports.Webhooks.GetSubscription(clinic.WithID(context.Background(), missingClinicID), subscription.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("the subscription of another clinic should be sql.ErrNoRows, got %v", err)
+		}
+
+		delivery := webhook.Delivery{
+			SubscriptionID: subscription.ID,
+			ClinicID:       clinic.DefaultID,
+			EventID:        10001,
+			EventType:      event.TypePatientCreated,
+			Payload:        []byte(`{"id":10001}`),
+			Status:         webhook.StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		}
+		for range 2 {
+			if err := ports.Webhooks.InsertDeliveries(ctx, []webhook.Delivery{delivery}); err != nil {
+				t.Fatalf("insert deliveries: %v", err)
+			}
+		}
+
+		claimed, err := ports.Webhooks.ClaimDueDeliveries(ctx, now, time.Minute, 10)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if len(claimed) != 1 || string(claimed[0].Payload) != `{"id":10001}` {
+			t.Fatalf("the event should be delivered once to the subscription, got %+v", claimed)
+		}
+		if again, err := ports.Webhooks.ClaimDueDeliveries(ctx, now, time.Minute, 10); err != nil || len(again) != 0 {
+			t.Fatalf("leased deliveries should not be claimed again, got %d: %v", len(again), err)
+		}
+
+		attempt := webhook.Attempt{StatusCode: 503, ResponseBody: "unavailable", DurationMS: 12, AttemptedAt: now}
+		if err := ports.Webhooks.RecordAttempt(ctx, claimed[0].ID, attempt, webhook.StatusDead, now); err != nil {
+			t.Fatalf("record attempt: %v", err)
+		}
+		if due, err := ports.Webhooks.ClaimDueDeliveries(ctx, now.Add(time.Hour), time.Minute, 10); err != nil || len(due) != 0 {
+			t.Fatalf("dead deliveries should not be claimed, got %d: %v", len(due), err)
+		}
+
+		dead, err := ports.Webhooks.ListDeliveries(ctx, subscription.ID, webhook.StatusDead, 10)
+		if err != nil {
+			t.Fatalf("list deliveries: %v", err)
+		}
+		if len(dead) != 1 || dead[0].AttemptCount != 1 || len(dead[0].Attempts) != 1 || dead[0].Attempts[0].ResponseBody != "unavailable" {
+			t.Fatalf("the dead delivery should be listed with its attempt, got %+v", dead)
+		}
+
+		if err := ports.Webhooks.ResetDelivery(ctx, subscription.ID, dead[0].ID, now); err != nil {
+			t.Fatalf("reset: %v", err)
+		}
+		if err := ports.Webhooks.ResetDelivery(ctx, subscription.ID, dead[0].ID, now); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("only dead deliveries should be reset, got %v", err)
+		}
+		if due, err := ports.Webhooks.ClaimDueDeliveries(ctx, now, time.Minute, 10); err != nil || len(due) != 1 || due[0].AttemptCount != 0 {
+			t.Fatalf("the reset delivery should be due again, got %+v: %v", due, err)
+		}
+
+		if err := ports.Webhooks.DeleteSubscription(ctx, subscription.ID); err != nil {
+			t.Fatalf("delete subscription: %v", err)
+		}
+		if deliveries, err := ports.Webhooks.ListDeliveries(ctx, subscription.ID, "", 10); err != nil || len(deliveries) != 0 {
+			t.Fatalf("deleting the subscription should delete its deliveries, got %d: %v", len(deliveries), err)
+		}
+	})
+
+	t.Run("due jobs are claimed once and unique keys are honored", func(t *testing.T) {
+		ports := newPorts(t)
+		now := time.Now().UTC().Truncate(time.Second)
+		newJob := func(uniqueKey string, clinicID clinic.ID, runAt time.Time) job.Job {
+			return job.Job{
+				Kind:        "test.run",
+				ClinicID:    clinicID,
+				Payload:     []byte(`{"id":10001}`),
+				UniqueKey:   uniqueKey,
+				State:       job.StateQueued,
+				MaxAttempts: 3,
+				RunAt:       runAt,
+				CreatedAt:   now,
+			}
+		}
+
+		scheduled, inserted, err := ports.Jobs.InsertJob(ctx, newJob("test.run@tick", 0, now.Add(-time.Minute)))
+		if err != nil || !inserted {
+			t.Fatalf("insert scheduled job: %v", err)
+		}
+		if _, inserted, err := ports.Jobs.InsertJob(ctx, newJob("test.run@tick", 0, now)); err != nil || inserted {
+			t.Fatalf("a unique key should be enqueued once, got %v: %v", inserted, err)
+		}
+		clinicJob, _, err := ports.Jobs.InsertJob(ctx, newJob("", clinic.DefaultID, now))
+		if err != nil {
+			t.Fatalf("insert clinic job: %v", err)
+		}
+		if _, _, err := ports.Jobs.InsertJob(ctx, newJob("", clinic.DefaultID, now.Add(time.Hour))); err != nil {
+			t.Fatalf("insert later job: %v", err)
+		}
+
+		claimed, err := ports.Jobs.ClaimDueJobs(ctx, now, time.Minute, 10)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		if len(claimed) != 2 || claimed[0].ID != scheduled.ID || claimed[1].ID != clinicJob.ID {
+			t.Fatalf("the two due jobs should be claimed oldest first, got %+v", claimed)
+		}
+		if claimed[0].State != job.StateRunning || claimed[0].Attempts != 1 || string(claimed[1].Payload) != `{"id":10001}` || claimed[1].ClinicID != clinic.DefaultID {
+			t.Fatalf("unexpected claimed jobs %+v", claimed)
+		}
+		if again, err := ports.Jobs.ClaimDueJobs(ctx, now, time.Minute, 10); err != nil || len(again) != 0 {
+			t.Fatalf("running jobs should not be claimed again, got %d: %v", len(again), err)
+		}
+
+		if err := ports.Jobs.FinishRun(ctx, scheduled.ID, job.StateSucceeded, now, "", &now); err != nil {
+			t.Fatalf("finish run: %v", err)
+		}
+		if err := ports.Jobs.FinishRun(ctx, clinicJob.ID, job.StateQueued, now.Add(time.Minute), "refused", nil); err != nil {
+			t.Fatalf("finish run: %v", err)
+		}
+
+		retried, err := ports.Jobs.ClaimDueJobs(ctx, now.Add(2*time.Minute), time.Minute, 10)
+		if err != nil {
+			t.Fatalf("claim after the backoff: %v", err)
+		}
+		if len(retried) != 1 || retried[0].ID != clinicJob.ID || retried[0].Attempts != 2 || retried[0].LastError != "refused" {
+			t.Fatalf("only the failed job should be due again, got %+v", retried)
+		}
+		if stalled, err := ports.Jobs.ClaimDueJobs(ctx, now.Add(4*time.Minute), time.Minute, 1); err != nil || len(stalled) != 1 || stalled[0].ID != clinicJob.ID {
+			t.Fatalf("a running job should be due again once its lease ended, got %+v: %v", stalled, err)
+		}
+
+		succeeded, err := ports.Jobs.ListJobs(ctx, job.StateSucceeded, "", 10)
+		if err != nil {
+			t.Fatalf("list jobs: %v", err)
+		}
+		if len(succeeded) != 1 || succeeded[0].ID != scheduled.ID || succeeded[0].FinishedAt == nil || succeeded[0].ClinicID != 0 {
+			t.Fatalf("the succeeded system job should be listed, got %+v", succeeded)
+		}
+		if others, err := ports.Jobs.ListJobs(clinic.WithID(context.Background(), missingClinicID), "", "", 10); err != nil || len(others) != 1 || others[0].ID != scheduled.ID {
+			t.Fatalf("another clinic should only see the system jobs, got %+v: %v", others, err)
+		}
+	})
+
+	t.Run("consents keep their history and follow their patient", func(t *testing.T) {
+		ports := newPorts(t)
+		first := insertPatient(t, ctx, ports, "first@gmail.com")
+		second := insertPatient(t, ctx, ports, "second@gmail.com")
+		collector := insertUser(t, ctx, ports, "user@gmail.com")
+		recordedAt := time.Now().UTC().Truncate(time.Second)
+		record := func(patientID patient.ID, purpose consent.Purpose, status consent.Status) consent.Consent {
+			t.Helper()
+			recorded, err := ports.Consents.InsertConsent(ctx, consent.Consent{PatientID: patientID, Purpose: purpose, Status: status, RecordedAt: recordedAt, CollectedBy: collector.ID})
+			if err != nil {
+				t.Fatalf("insert consent: %v", err)
+			}
+			return recorded
+		}
+
+		record(first.ID, consent.PurposeResearch, consent.StatusGranted)
+		revoked := record(first.ID, consent.PurposeResearch, consent.StatusRevoked)
+		care := record(first.ID, consent.PurposeCare, consent.StatusGranted)
+		granted := record(second.ID, consent.PurposeResearch, consent.StatusGranted)
+		if revoked.ID < firstID || !revoked.RecordedAt.Equal(recordedAt) || revoked.CollectedBy != collector.ID {
+			t.Fatalf("unexpected consent %+v", revoked)
+		}
+
+		history, err := ports.Consents.ListConsents(ctx, first.ID)
+		if err != nil || len(history) != 3 || history[0].ID != care.ID || history[1].ID != revoked.ID {
+			t.Fatalf("the history should be newest first: %v %+v", err, history)
+		}
+
+		latest, err := ports.Consents.ListLatestConsents(ctx, consent.PurposeResearch)
+		if err != nil || len(latest) != 2 {
+			t.Fatalf("list latest consents: %v %+v", err, latest)
+		}
+		for _, found := range latest {
+			if found.ID != revoked.ID && found.ID != granted.ID {
+				t.Fatalf("only the latest consent of each patient should be listed, got %+v", latest)
+			}
+		}
+
+		missingClinic := clinic.WithID(context.Background(), missingClinicID)
+		if err := ports.Consents.CheckPatient(missingClinic, first.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("check a patient of another clinic: %v", err)
+		}
+		if others, err := ports.Consents.ListConsents(missingClinic, first.ID); err != nil || len(others) != 0 {
+			t.Fatalf("another clinic should not see the consents, got %+v: %v", others, err)
+		}
+
+		if err := ports.User.DeleteUser(ctx, collector.ID); err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+		if history, err := ports.Consents.ListConsents(ctx, first.ID); err != nil || len(history) != 3 || history[0].CollectedBy != 0 {
+			t.Fatalf("the consents should outlive the user who collected them: %v %+v", err, history)
+		}
+
+		if err := ports.Patient.DeletePatient(ctx, int64(first.ID)); err != nil {
+			t.Fatalf("delete patient: %v", err)
+		}
+		if history, err := ports.Consents.ListConsents(ctx, first.ID); err != nil || len(history) != 0 {
+			t.Fatalf("the consents should be deleted with their patient: %v %+v", err, history)
+		}
+	})
+
+	t.Run("erasures are reviewed once and pseudonymize the patient records", func(t *testing.T) {
+		ports := newPorts(t)
+		erased := insertPatient(t, ctx, ports, "erased@gmail.com")
+		kept := insertPatient(t, ctx, ports, "kept@gmail.com")
+		requester := insertUser(t, ctx, ports, "requester@gmail.com")
+		reviewer := insertUser(t, ctx, ports, "reviewer@gmail.com")
+		now := time.Now().UTC().Truncate(time.Second)
+		for _, newEvent := range []event.Event{
+			{Type: event.TypePatientCreated, Payload: []byte(`{"id":` + strconv.FormatInt(int64(erased.ID), 10) + `,"email":"erased@gmail.com"}`)},
+			{Type: event.TypePatientConsentRecorded, Payload: []byte(`{"id":1,"patient_id":` + strconv.FormatInt(int64(erased.ID), 10) + `}`)},
+			{Type: event.TypePatientCreated, Payload: []byte(`{"id":` + strconv.FormatInt(int64(kept.ID), 10) + `,"email":"kept@gmail.com"}`)},
+		} {
+			newEvent.ClinicID = clinic.DefaultID
+			newEvent.OccurredAt = now
+			newEvent.NextAttemptAt = now
+			if _, err := ports.Outbox.Insert(ctx, newEvent); err != nil {
+				t.Fatalf("insert event: %v", err)
+			}
+		}
+
+		events, err := ports.Privacy.ListEvents(ctx, erased.ID)
+		if err != nil || len(events) != 2 {
+			t.Fatalf("only the events referencing the patient should be listed: %v %+v", err, events)
+		}
+
+		for key, body := range map[string]string{
+			"create-erased": `{"data":{"id":` + strconv.FormatInt(int64(erased.ID), 10) + `,"email":"erased@gmail.com"}}`,
+			"create-kept":   `{"data":{"id":` + strconv.FormatInt(int64(kept.ID), 10) + `,"email":"kept@gmail.com"}}`,
+		} {
+			record := idempotency.Record{Subject: "user:10001", Key: key, Fingerprint: key, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+			if inserted, err := ports.Idempotency.InsertRecord(ctx, record); err != nil || !inserted {
+				t.Fatalf("insert idempotency record: %v", err)
+			}
+			record.Completed, record.StatusCode, record.ContentType, record.Body = true, 201, "application/json", []byte(body)
+			if err := ports.Idempotency.CompleteRecord(ctx, record); err != nil {
+				t.Fatalf("complete idempotency record: %v", err)
+			}
+		}
+
+		requested, err := ports.Privacy.InsertErasure(ctx, privacy.Erasure{PatientID: erased.ID, Status: privacy.ErasureStatusPending, Reason: "asked by mail", RequestedBy: requester.ID, RequestedAt: now})
+		if err != nil || requested.ID < firstID {
+			t.Fatalf("insert erasure: %v %+v", err, requested)
+		}
+		if _, err := ports.Privacy.InsertErasure(ctx, privacy.Erasure{PatientID: erased.ID, Status: privacy.ErasureStatusPending, RequestedBy: requester.ID, RequestedAt: now}); err == nil {
+			t.Fatalf("a second pending erasure of the patient should fail")
+		}
+
+		missingClinic := clinic.WithID(context.Background(), missingClinicID)
+		if _, err := ports.Privacy.GetErasure(missingClinic, requested.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get an erasure of another clinic: %v", err)
+		}
+		if _, err := ports.Privacy.GetPatient(missingClinic, erased.ID); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("get a patient of another clinic: %v", err)
+		}
+		if err := ports.Privacy.ErasePatient(missingClinic, privacy.Pseudonym(erased.ID)); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("erase a patient of another clinic: %v", err)
+		}
+
+		if err := ports.Privacy.ErasePatient(ctx, privacy.Pseudonym(erased.ID)); err != nil {
+			t.Fatalf("erase patient: %v", err)
+		}
+		reviewedAt := now.Add(time.Minute)
+		completed, err := ports.Privacy.ReviewErasure(ctx, privacy.Erasure{ID: requested.ID, Status: privacy.ErasureStatusCompleted, ReviewedBy: reviewer.ID, ReviewedAt: &reviewedAt})
+		if err != nil || completed.Status != privacy.ErasureStatusCompleted || completed.ReviewedBy != reviewer.ID || completed.RequestedBy != requester.ID || completed.Reason != "asked by mail" {
+			t.Fatalf("review erasure: %v %+v", err, completed)
+		}
+		if _, err := ports.Privacy.ReviewErasure(ctx, privacy.Erasure{ID: requested.ID, Status: privacy.ErasureStatusRejected, ReviewedBy: reviewer.ID, ReviewedAt: &reviewedAt}); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("an erasure should only be reviewed once: %v", err)
+		}
+
+		if pseudonymized, err := ports.Privacy.GetPatient(ctx, erased.ID); err != nil || pseudonymized != privacy.Pseudonym(erased.ID) {
+			t.Fatalf("the patient should be pseudonymized: %v %+v", err, pseudonymized)
+		}
+		if untouched, err := ports.Privacy.GetPatient(ctx, kept.ID); err != nil || untouched.Email != "kept@gmail.com" {
+			t.Fatalf("the other patients should be untouched: %v %+v", err, untouched)
+		}
+		events, err = ports.Privacy.ListEvents(ctx, erased.ID)
+		if err != nil || len(events) != 2 {
+			t.Fatalf("list events: %v %+v", err, events)
+		}
+		for _, found := range events {
+			if strings.Contains(string(found.Payload), "erased@gmail.com") {
+				t.Fatalf("the identity should be erased from the outbox, got %s", found.Payload)
+			}
+		}
+
+		if _, err := ports.Idempotency.GetRecord(ctx, "user:10001", "create-erased"); !errors.Is(err, sql.ErrNoRows) {
+			t.Fatalf("the replayed responses holding the patient should be erased: %v", err)
+		}
+		if record, err := ports.Idempotency.GetRecord(ctx, "user:10001", "create-kept"); err != nil || !strings.Contains(string(record.Body), "kept@gmail.com") {
+			t.Fatalf("the other replayed responses should be kept: %v %+v", err, record)
+		}
+
+		if pending, err := ports.Privacy.ListErasures(ctx, privacy.ErasureStatusPending); err != nil || len(pending) != 0 {
+			t.Fatalf("no erasure should be pending, got %+v: %v", pending, err)
+		}
+		if all, err := ports.Privacy.ListPatientErasures(ctx, erased.ID); err != nil || len(all) != 1 || all[0].ID != requested.ID {
+			t.Fatalf("list patient erasures: %v %+v", err, all)
+		}
+
+		if err := ports.User.DeleteUser(ctx, requester.ID); err != nil {
+			t.Fatalf("delete user: %v", err)
+		}
+		if proof, err := ports.Privacy.GetErasure(ctx, requested.ID); err != nil || proof.RequestedBy != 0 || proof.ReviewedBy != reviewer.ID {
+			t.Fatalf("the erasure should outlive the user who requested it: %v %+v", err, proof)
+		}
+	})
+
+	t.Run("login failures reset after the window", func(t *testing.T) {
+		ports := newPorts(t)
+		now := time.Now().UTC().Truncate(time.Second)
+		for i := range 2 {
+			attempts, err := ports.Auth.IncrementLoginFailures(ctx, auth.AttemptScopeIP, "1.2.3.4", now, time.Minute)
+			if err != nil || attempts.Failures != i+1 {
+				t.Fatalf("increment %d: %v %+v", i, err, attempts)
+			}
+		}
+
+		attempts, err := ports.Auth.IncrementLoginFailures(ctx, auth.AttemptScopeIP, "1.2.3.4", now.Add(2*time.Minute), time.Minute)
+		if err != nil || attempts.Failures != 1 {
+			t.Fatalf("increment after the window: %v %+v", err, attempts)
+		}
+
+		if err := ports.Auth.DeleteLoginAttempts(ctx, auth.AttemptScopeIP, "1.2.3.4"); err != nil {
+			t.Fatalf("delete login attempts: %v", err)
+		}
+
+		attempts, err = ports.Auth.GetLoginAttempts(ctx, auth.AttemptScopeIP, "1.2.3.4")
+		if err != nil || attempts.Failures != 0 {
+			t.Fatalf("login attempts should be deleted: %v %+v", err, attempts)
+		}
+	})
+}
+
+func insertPatient(t *testing.T, ctx context.Context, ports Ports, email patient.Email) patient.Patient {
+	t.Helper()
+	created, err := ports.Patient.InsertPatient(ctx, patient.Patient{Firstname: "John", Lastname: "Doe", Email: email})
+	if err != nil {
+		t.Fatalf("insert patient: %v", err)
+	}
+
+	return created
+}
+
+func insertUser(t *testing.T, ctx context.Context, ports Ports, email user.Email) user.User {
+	t.Helper()
+	created, err := ports.User.Insert(ctx, user.User{Email: email, Password: "hash", Roles: user.Roles{user.RoleDoctor}})
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+
+	return created
+}
+
+func refreshClaims(userID user.ID) jwt.RefreshTokenClaims {
+	now := time.Now()
+	return jwt.RefreshTokenClaims{
+		ID:        uuid.New(),
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(time.Hour).Unix(),
+		ClinicID:  clinic.DefaultID,
+	}
+}
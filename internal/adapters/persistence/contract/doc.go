@@ -0,0 +1,3 @@
+// Package contract holds the tests every persistence backend must pass, so that the in-memory
+// adapters keep the semantics of the PostgreSQL ones
+package contract
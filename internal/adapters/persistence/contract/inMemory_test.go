@@ -0,0 +1,38 @@
+package contract
+
+import (
+	"testing"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	authPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/auth"
+	clinicPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/clinic"
+	consentPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/consent"
+	eventPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/event"
+	idempotencyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/idempotency"
+	jobPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/job"
+	patientPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/patient"
+	privacyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/privacy"
+	rolePersistence "github.com/sopial42/cleanic/internal/adapters/persistence/role"
+	userPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/user"
+	webhookPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/webhook"
+)
+
+func TestInMemory(t *testing.T) {
+	run(t, func(t *testing.T) Ports {
+		db := persistence.NewInMemoryDB()
+		return Ports{
+			Patient:     patientPersistence.NewInMemoryClient(db),
+			User:        userPersistence.NewInMemoryClient(db),
+			Auth:        authPersistence.NewInMemoryClient(db),
+			Clinics:     clinicPersistence.NewInMemoryClient(db),
+			Roles:       rolePersistence.NewInMemoryClient(db),
+			Outbox:      eventPersistence.NewInMemoryClient(db),
+			Webhooks:    webhookPersistence.NewInMemoryClient(db),
+			Jobs:        jobPersistence.NewInMemoryClient(db),
+			Consents:    consentPersistence.NewInMemoryClient(db),
+			Privacy:     privacyPersistence.NewInMemoryClient(db),
+			Idempotency: idempotencyPersistence.NewInMemoryClient(db),
+			UnitOfWork:  persistence.NewInMemoryUnitOfWork(db),
+		}
+	})
+}
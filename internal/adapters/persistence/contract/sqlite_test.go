@@ -0,0 +1,49 @@
+package contract
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	authPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/auth"
+	clinicPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/clinic"
+	consentPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/consent"
+	eventPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/event"
+	idempotencyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/idempotency"
+	jobPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/job"
+	patientPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/patient"
+	privacyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/privacy"
+	rolePersistence "github.com/sopial42/cleanic/internal/adapters/persistence/role"
+	userPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/user"
+	webhookPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/webhook"
+	"github.com/sopial42/cleanic/internal/config"
+)
+
+func TestSQLite(t *testing.T) {
+	run(t, func(t *testing.T) Ports {
+		client, err := persistence.NewSQLiteClient(context.Background(), config.DBConfig{
+			SQLitePath:      filepath.Join(t.TempDir(), "cleanic.db"),
+			MigrationsTable: "gorp_migrations",
+		})
+		if err != nil {
+			t.Fatalf("unable to open the database: %v", err)
+		}
+		t.Cleanup(func() { client.Close() })
+
+		return Ports{
+			Patient:     patientPersistence.NewSQLiteClient(client),
+			User:        userPersistence.NewSQLiteClient(client),
+			Auth:        authPersistence.NewSQLiteClient(client),
+			Clinics:     clinicPersistence.NewSQLiteClient(client),
+			Roles:       rolePersistence.NewSQLiteClient(client),
+			Outbox:      eventPersistence.NewSQLiteClient(client),
+			Webhooks:    webhookPersistence.NewSQLiteClient(client),
+			Jobs:        jobPersistence.NewSQLiteClient(client),
+			Consents:    consentPersistence.NewSQLiteClient(client),
+			Privacy:     privacyPersistence.NewSQLiteClient(client),
+			Idempotency: idempotencyPersistence.NewSQLiteClient(client),
+			UnitOfWork:  persistence.NewUnitOfWork(client),
+		}
+	})
+}
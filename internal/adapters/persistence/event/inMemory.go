@@ -0,0 +1,73 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) eventSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) Insert(ctx context.Context, newEvent event.Event) (event.Event, error) {
+	defer m.db.Lock(ctx)()
+
+	newEvent.ID = event.ID(m.db.NextID("outbox_event"))
+	newEvent.Payload = slices.Clone(newEvent.Payload)
+	m.db.Outbox[newEvent.ID] = persistence.OutboxRow{Event: newEvent}
+	return newEvent, nil
+}
+
+func (m *inMemory) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]event.Event, error) {
+	defer m.db.Lock(ctx)()
+
+	var due []event.Event
+	for _, row := range m.db.Outbox {
+		if row.NextAttemptAt.After(now) || row.LockedUntil.After(now) {
+			continue
+		}
+		due = append(due, row.Event)
+	}
+
+	slices.SortFunc(due, func(a, b event.Event) int { return cmp.Compare(a.ID, b.ID) })
+	due = due[:min(len(due), limit)]
+	for i, claimed := range due {
+		m.db.Outbox[claimed.ID] = persistence.OutboxRow{Event: claimed, LockedUntil: now.Add(lease)}
+		due[i].Payload = slices.Clone(claimed.Payload)
+	}
+
+	return due, nil
+}
+
+func (m *inMemory) Delete(ctx context.Context, id event.ID) error {
+	defer m.db.Lock(ctx)()
+
+	delete(m.db.Outbox, id)
+	return nil
+}
+
+func (m *inMemory) Reschedule(ctx context.Context, id event.ID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.Outbox[id]
+	if !found {
+		return nil
+	}
+
+	row.Attempts = attempts
+	row.NextAttemptAt = nextAttemptAt
+	row.LastError = lastError
+	row.LockedUntil = time.Time{}
+	m.db.Outbox[id] = row
+	return nil
+}
@@ -0,0 +1,100 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+	// skipLocked lets concurrent dispatchers claim distinct events instead of waiting on each other
+	skipLocked bool
+}
+
+func NewPGClient(client *bun.DB) eventSVC.Persistence {
+	return &pgPersistence{clientDB: client, skipLocked: true}
+}
+
+func (p *pgPersistence) Insert(ctx context.Context, newEvent event.Event) (event.Event, error) {
+	eventDAO := outboxEventFromDomainToDAO(newEvent)
+	_, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&eventDAO).
+		Returning("id").
+		Exec(ctx)
+	if err != nil {
+		return event.Event{}, fmt.Errorf("unable to insert event: %w", err)
+	}
+
+	return outboxEventFromDAOToDomain(eventDAO), nil
+}
+
+func (p *pgPersistence) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]event.Event, error) {
+	db := persistence.DB(ctx, p.clientDB)
+	dueIDs := db.NewSelect().
+		Model((*outboxEventDAO)(nil)).
+		Column("id").
+		Where("next_attempt_at <= ?", now).
+		Where("locked_until IS NULL OR locked_until <= ?", now).
+		Order("id ASC").
+		Limit(limit)
+	if p.skipLocked {
+		dueIDs = dueIDs.For("UPDATE SKIP LOCKED")
+	}
+
+	var eventDAOs []outboxEventDAO
+	_, err := db.NewUpdate().
+		Model((*outboxEventDAO)(nil)).
+		Set("locked_until = ?", now.Add(lease)).
+		Where("id IN (?)", dueIDs).
+		Returning("*").
+		Exec(ctx, &eventDAOs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to claim due events: %w", err)
+	}
+
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(eventDAOs, func(a, b outboxEventDAO) int { return cmp.Compare(a.ID, b.ID) })
+	events := make([]event.Event, 0, len(eventDAOs))
+	for _, eventDAO := range eventDAOs {
+		events = append(events, outboxEventFromDAOToDomain(eventDAO))
+	}
+
+	return events, nil
+}
+
+func (p *pgPersistence) Delete(ctx context.Context, id event.ID) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewDelete().
+		Model((*outboxEventDAO)(nil)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete event %d: %w", id, err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) Reschedule(ctx context.Context, id event.ID, attempts int, nextAttemptAt time.Time, lastError string) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*outboxEventDAO)(nil)).
+		Set("attempts = ?", attempts).
+		Set("next_attempt_at = ?", nextAttemptAt).
+		Set("last_error = ?", lastError).
+		Set("locked_until = NULL").
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to reschedule event %d: %w", id, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/event"
+)
+
+type outboxEventDAO struct {
+	bun.BaseModel `bun:"table:outbox_event,alias:outbox_event"`
+
+	ID       int64  `bun:"id,pk,autoincrement"`
+	Type     string `bun:"type,notnull"`
+	ClinicID int64  `bun:"clinic_id,notnull"`
+	// Payload is sent as text, PostgreSQL casts it to jsonb
+	Payload       string    `bun:"payload,notnull"`
+	OccurredAt    time.Time `bun:"occurred_at,notnull"`
+	Attempts      int       `bun:"attempts,notnull"`
+	NextAttemptAt time.Time `bun:"next_attempt_at,notnull"`
+	LockedUntil   time.Time `bun:"locked_until,nullzero"`
+	LastError     string    `bun:"last_error,nullzero"`
+}
+
+func outboxEventFromDomainToDAO(e event.Event) outboxEventDAO {
+	return outboxEventDAO{
+		ID:            int64(e.ID),
+		Type:          string(e.Type),
+		ClinicID:      int64(e.ClinicID),
+		Payload:       string(e.Payload),
+		OccurredAt:    e.OccurredAt,
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt,
+		LastError:     e.LastError,
+	}
+}
+
+func outboxEventFromDAOToDomain(eventDAO outboxEventDAO) event.Event {
+	return event.Event{
+		ID:            event.ID(eventDAO.ID),
+		Type:          event.Type(eventDAO.Type),
+		ClinicID:      clinic.ID(eventDAO.ClinicID),
+		Payload:       json.RawMessage(eventDAO.Payload),
+		OccurredAt:    eventDAO.OccurredAt,
+		Attempts:      eventDAO.Attempts,
+		NextAttemptAt: eventDAO.NextAttemptAt,
+		LastError:     eventDAO.LastError,
+	}
+}
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	eventSVC "github.com/sopial42/cleanic/internal/services/event"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient without the row locks, SQLite has a single writer anyway
+func NewSQLiteClient(client *bun.DB) eventSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
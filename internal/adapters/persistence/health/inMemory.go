@@ -0,0 +1,22 @@
+package persistence
+
+import (
+	"context"
+
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+)
+
+type inMemory struct{}
+
+// NewInMemoryClient is always ready, its schema is the code
+func NewInMemoryClient() healthSVC.Persistence {
+	return inMemory{}
+}
+
+func (inMemory) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (inMemory) MigrationApplied(ctx context.Context, migrationID string) (bool, error) {
+	return true, nil
+}
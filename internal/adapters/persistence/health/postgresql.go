@@ -0,0 +1,46 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+)
+
+// LatestMigration is the last schema of internal/adapters/persistence/postgresql, the one the code relies on
+func LatestMigration() (string, error) {
+	return persistence.LatestMigration(persistence.PGMigrations())
+}
+
+type pgPersistence struct {
+	clientDB        *bun.DB
+	migrationsTable string
+}
+
+// NewPGClient reads the migrations recorded in migrationsTable by sql-migrate
+func NewPGClient(client *bun.DB, migrationsTable string) healthSVC.Persistence {
+	return &pgPersistence{clientDB: client, migrationsTable: migrationsTable}
+}
+
+func (p *pgPersistence) Ping(ctx context.Context) error {
+	if err := p.clientDB.PingContext(ctx); err != nil {
+		return fmt.Errorf("unable to ping database: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) MigrationApplied(ctx context.Context, migrationID string) (bool, error) {
+	exists, err := p.clientDB.NewSelect().
+		Table(p.migrationsTable).
+		Where("id = ?", migrationID).
+		Exists(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to read applied migrations: %w", err)
+	}
+
+	return exists, nil
+}
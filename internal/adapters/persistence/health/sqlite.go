@@ -0,0 +1,18 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+)
+
+// LatestSQLiteMigration is the last schema of internal/adapters/persistence/sqlite
+func LatestSQLiteMigration() (string, error) {
+	return persistence.LatestMigration(persistence.SQLiteMigrations())
+}
+
+// NewSQLiteClient reads the migrations recorded in migrationsTable by persistence.NewSQLiteClient
+func NewSQLiteClient(client *bun.DB, migrationsTable string) healthSVC.Persistence {
+	return &pgPersistence{clientDB: client, migrationsTable: migrationsTable}
+}
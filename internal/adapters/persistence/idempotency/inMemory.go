@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) idempotencySVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) InsertRecord(ctx context.Context, record idempotency.Record) (bool, error) {
+	defer m.db.Lock(ctx)()
+
+	key := persistence.IdempotencyKey{Subject: record.Subject, Key: record.Key}
+	if _, found := m.db.IdempotencyRecords[key]; found {
+		return false, nil
+	}
+
+	record.Body = slices.Clone(record.Body)
+	m.db.IdempotencyRecords[key] = record
+	return true, nil
+}
+
+func (m *inMemory) GetRecord(ctx context.Context, subject string, key string) (idempotency.Record, error) {
+	defer m.db.RLock(ctx)()
+
+	record, found := m.db.IdempotencyRecords[persistence.IdempotencyKey{Subject: subject, Key: key}]
+	if !found {
+		return idempotency.Record{}, fmt.Errorf("unable to get idempotency key: %w", sql.ErrNoRows)
+	}
+
+	record.Body = slices.Clone(record.Body)
+	return record, nil
+}
+
+func (m *inMemory) CompleteRecord(ctx context.Context, record idempotency.Record) error {
+	defer m.db.Lock(ctx)()
+
+	key := persistence.IdempotencyKey{Subject: record.Subject, Key: record.Key}
+	existing, found := m.db.IdempotencyRecords[key]
+	if !found {
+		return nil
+	}
+
+	existing.Completed = record.Completed
+	existing.StatusCode = record.StatusCode
+	existing.ContentType = record.ContentType
+	existing.Location = record.Location
+	existing.Body = slices.Clone(record.Body)
+	m.db.IdempotencyRecords[key] = existing
+	return nil
+}
+
+func (m *inMemory) DeleteRecord(ctx context.Context, subject string, key string) error {
+	defer m.db.Lock(ctx)()
+
+	delete(m.db.IdempotencyRecords, persistence.IdempotencyKey{Subject: subject, Key: key})
+	return nil
+}
+
+func (m *inMemory) DeleteExpiredRecords(ctx context.Context, now time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	for key, record := range m.db.IdempotencyRecords {
+		if !record.ExpiresAt.After(now) {
+			delete(m.db.IdempotencyRecords, key)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+}
+
+func NewPGClient(client *bun.DB) idempotencySVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
+
+func (p *pgPersistence) InsertRecord(ctx context.Context, record idempotency.Record) (bool, error) {
+	recordDAO := idempotencyKeyFromDomainToDAO(record)
+	result, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&recordDAO).
+		On("CONFLICT (subject, key) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("unable to insert idempotency key: %w", err)
+	}
+
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("unable to count inserted idempotency keys: %w", err)
+	}
+
+	return inserted == 1, nil
+}
+
+func (p *pgPersistence) GetRecord(ctx context.Context, subject string, key string) (idempotency.Record, error) {
+	var recordDAO idempotencyKeyDAO
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&recordDAO).
+		Where("subject = ?", subject).
+		Where("key = ?", key).
+		Scan(ctx)
+	if err != nil {
+		return idempotency.Record{}, fmt.Errorf("unable to get idempotency key: %w", err)
+	}
+
+	return idempotencyKeyFromDAOToDomain(recordDAO), nil
+}
+
+func (p *pgPersistence) CompleteRecord(ctx context.Context, record idempotency.Record) error {
+	recordDAO := idempotencyKeyFromDomainToDAO(record)
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model(&recordDAO).
+		Column("completed", "status_code", "content_type", "location", "body").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) DeleteRecord(ctx context.Context, subject string, key string) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewDelete().
+		Model((*idempotencyKeyDAO)(nil)).
+		Where("subject = ?", subject).
+		Where("key = ?", key).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) DeleteExpiredRecords(ctx context.Context, now time.Time) error {
+	_, err := persistence.DB(ctx, p.clientDB).NewDelete().
+		Model((*idempotencyKeyDAO)(nil)).
+		Where("expires_at <= ?", now).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete expired idempotency keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+)
+
+type idempotencyKeyDAO struct {
+	bun.BaseModel `bun:"table:idempotency_key"`
+
+	Subject     string    `bun:"subject,pk"`
+	Key         string    `bun:"key,pk"`
+	Fingerprint string    `bun:"fingerprint,notnull"`
+	Completed   bool      `bun:"completed,notnull"`
+	StatusCode  int       `bun:"status_code,nullzero"`
+	ContentType string    `bun:"content_type,nullzero"`
+	Location    string    `bun:"location,nullzero"`
+	Body        []byte    `bun:"body"`
+	CreatedAt   time.Time `bun:"created_at,notnull"`
+	ExpiresAt   time.Time `bun:"expires_at,notnull"`
+}
+
+func idempotencyKeyFromDomainToDAO(record idempotency.Record) idempotencyKeyDAO {
+	return idempotencyKeyDAO{
+		Subject:     record.Subject,
+		Key:         record.Key,
+		Fingerprint: record.Fingerprint,
+		Completed:   record.Completed,
+		StatusCode:  record.StatusCode,
+		ContentType: record.ContentType,
+		Location:    record.Location,
+		Body:        record.Body,
+		CreatedAt:   record.CreatedAt,
+		ExpiresAt:   record.ExpiresAt,
+	}
+}
+
+func idempotencyKeyFromDAOToDomain(recordDAO idempotencyKeyDAO) idempotency.Record {
+	return idempotency.Record{
+		Subject:     recordDAO.Subject,
+		Key:         recordDAO.Key,
+		Fingerprint: recordDAO.Fingerprint,
+		Completed:   recordDAO.Completed,
+		StatusCode:  recordDAO.StatusCode,
+		ContentType: recordDAO.ContentType,
+		Location:    recordDAO.Location,
+		Body:        recordDAO.Body,
+		CreatedAt:   recordDAO.CreatedAt,
+		ExpiresAt:   recordDAO.ExpiresAt,
+	}
+}
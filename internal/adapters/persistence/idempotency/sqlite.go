@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect
+func NewSQLiteClient(client *bun.DB) idempotencySVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
@@ -0,0 +1,347 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/auth"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+	"github.com/sopial42/cleanic/internal/domains/job"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/privacy"
+	"github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+	"github.com/sopial42/cleanic/internal/services/transaction"
+)
+
+// firstID is where the sequences start, like the ones of the schema
+const firstID = 10001
+
+// InMemoryDB holds the tables of the in-memory adapters, they lock it like a transaction would,
+// a unit of work holds the lock until it ends. It mirrors the schema: the default clinic and the builtin roles are seeded, and deleting a user
+// deletes its rows in the other tables like the foreign keys do. A missing row is reported
+// with sql.ErrNoRows, as bun does
+type InMemoryDB struct {
+	mu sync.RWMutex
+
+	Clinics       map[clinic.ID]clinic.Clinic
+	ClinicMembers map[ClinicMemberKey]user.Roles
+	// Users roles are stored in ClinicMembers
+	Users           map[user.ID]user.User
+	PasswordHistory map[user.ID][]user.Password
+	// Roles are defined per clinic, a new clinic receives a copy of the builtin ones
+	Roles    map[RoleKey]user.RoleDefinition
+	Patients map[patient.ID]PatientRow
+	// Consents are deleted along with their patient
+	Consents           map[consent.ID]ConsentRow
+	Erasures           map[privacy.ErasureID]ErasureRow
+	RefreshTokens      map[user.ID]jwt.RefreshTokenClaims
+	Sessions           map[auth.SessionID]auth.Session
+	LoginAttempts      map[LoginAttemptKey]auth.LoginAttempts
+	APIKeys            map[apikey.ID]apikey.APIKey
+	IdempotencyRecords map[IdempotencyKey]idempotency.Record
+	Outbox             map[event.ID]OutboxRow
+	// WebhookDeliveries hold their attempts, deleting a subscription deletes its deliveries
+	WebhookSubscriptions map[webhook.ID]webhook.Subscription
+	WebhookDeliveries    map[webhook.DeliveryID]WebhookDeliveryRow
+	Jobs                 map[job.ID]JobRow
+
+	sequences map[string]int64
+}
+
+type ClinicMemberKey struct {
+	ClinicID clinic.ID
+	UserID   user.ID
+}
+
+type RoleKey struct {
+	ClinicID clinic.ID
+	Name     user.Role
+}
+
+type LoginAttemptKey struct {
+	Scope auth.AttemptScope
+	Key   string
+}
+
+type IdempotencyKey struct {
+	Subject string
+	Key     string
+}
+
+// OutboxRow is an event waiting for its delivery, along with the lease of the dispatcher delivering it
+type OutboxRow struct {
+	event.Event
+	LockedUntil time.Time
+}
+
+// WebhookDeliveryRow is a delivery along with the lease of the deliverer attempting it
+type WebhookDeliveryRow struct {
+	webhook.Delivery
+	LockedUntil time.Time
+}
+
+// JobRow is a job along with the lease of the worker running it
+type JobRow struct {
+	job.Job
+	LockedUntil time.Time
+}
+
+// PatientRow is a patient along with the clinic it belongs to
+type PatientRow struct {
+	patient.Patient
+	ClinicID clinic.ID
+}
+
+// ConsentRow is a consent along with the clinic of its patient
+type ConsentRow struct {
+	consent.Consent
+	ClinicID clinic.ID
+}
+
+// ErasureRow is an erasure along with the clinic of its patient
+type ErasureRow struct {
+	privacy.Erasure
+	ClinicID clinic.ID
+}
+
+func NewInMemoryDB() *InMemoryDB {
+	db := &InMemoryDB{
+		Clinics:              map[clinic.ID]clinic.Clinic{},
+		ClinicMembers:        map[ClinicMemberKey]user.Roles{},
+		Users:                map[user.ID]user.User{},
+		PasswordHistory:      map[user.ID][]user.Password{},
+		Roles:                map[RoleKey]user.RoleDefinition{},
+		Patients:             map[patient.ID]PatientRow{},
+		Consents:             map[consent.ID]ConsentRow{},
+		Erasures:             map[privacy.ErasureID]ErasureRow{},
+		RefreshTokens:        map[user.ID]jwt.RefreshTokenClaims{},
+		Sessions:             map[auth.SessionID]auth.Session{},
+		LoginAttempts:        map[LoginAttemptKey]auth.LoginAttempts{},
+		APIKeys:              map[apikey.ID]apikey.APIKey{},
+		IdempotencyRecords:   map[IdempotencyKey]idempotency.Record{},
+		Outbox:               map[event.ID]OutboxRow{},
+		WebhookSubscriptions: map[webhook.ID]webhook.Subscription{},
+		WebhookDeliveries:    map[webhook.DeliveryID]WebhookDeliveryRow{},
+		Jobs:                 map[job.ID]JobRow{},
+		sequences:            map[string]int64{},
+	}
+
+	db.Clinics[clinic.DefaultID] = clinic.Clinic{ID: clinic.DefaultID, Name: "default"}
+	for _, role := range []user.RoleDefinition{
+		{Name: user.RoleAdmin, Description: "Full access", Builtin: true, Permissions: user.Permissions{
+			user.PermissionPatientRead, user.PermissionPatientWrite, user.PermissionUserRead, user.PermissionUserManage,
+			user.PermissionRoleManage, user.PermissionProfileWrite, user.PermissionClinicManage,
+			user.PermissionWebhookManage, user.PermissionJobRead, user.PermissionPrivacyManage,
+		}},
+		{Name: user.RoleDoctor, Description: "Reads and writes patient records", Builtin: true, Permissions: user.Permissions{
+			user.PermissionPatientRead, user.PermissionPatientWrite, user.PermissionProfileWrite,
+		}},
+		{Name: "nurse", Description: "Reads patient records", Permissions: user.Permissions{
+			user.PermissionPatientRead, user.PermissionProfileWrite,
+		}},
+		{Name: "receptionist", Description: "Registers and updates patients", Permissions: user.Permissions{
+			user.PermissionPatientRead, user.PermissionPatientWrite, user.PermissionProfileWrite,
+		}},
+		{Name: "billing", Description: "Reads patient records for invoicing", Permissions: user.Permissions{
+			user.PermissionPatientRead, user.PermissionProfileWrite,
+		}},
+	} {
+		db.Roles[RoleKey{ClinicID: clinic.DefaultID, Name: role.Name}] = role
+	}
+
+	return db
+}
+
+// Lock takes the write lock and returns its release. A unit of work of db already holds it
+func (db *InMemoryDB) Lock(ctx context.Context) (unlock func()) {
+	if db.inUnitOfWork(ctx) {
+		return func() {}
+	}
+
+	db.mu.Lock()
+	return db.mu.Unlock
+}
+
+// RLock takes the read lock and returns its release. A unit of work of db already holds the write lock
+func (db *InMemoryDB) RLock(ctx context.Context) (unlock func()) {
+	if db.inUnitOfWork(ctx) {
+		return func() {}
+	}
+
+	db.mu.RLock()
+	return db.mu.RUnlock
+}
+
+func (db *InMemoryDB) inUnitOfWork(ctx context.Context) bool {
+	owner, _ := ctx.Value(inMemoryTxKey{}).(*InMemoryDB)
+	return owner == db
+}
+
+// NextID returns the next value of the table sequence, the lock must be held
+func (db *InMemoryDB) NextID(table string) int64 {
+	if db.sequences[table] == 0 {
+		db.sequences[table] = firstID - 1
+	}
+
+	db.sequences[table]++
+	return db.sequences[table]
+}
+
+// snapshot copies the tables, not the sequences which a rollback does not rewind either.
+// The rows are values, the adapters replace them rather than update them in place. The lock must be held
+func (db *InMemoryDB) snapshot() *InMemoryDB {
+	return &InMemoryDB{
+		Clinics:              maps.Clone(db.Clinics),
+		ClinicMembers:        maps.Clone(db.ClinicMembers),
+		Users:                maps.Clone(db.Users),
+		PasswordHistory:      maps.Clone(db.PasswordHistory),
+		Roles:                maps.Clone(db.Roles),
+		Patients:             maps.Clone(db.Patients),
+		Consents:             maps.Clone(db.Consents),
+		Erasures:             maps.Clone(db.Erasures),
+		RefreshTokens:        maps.Clone(db.RefreshTokens),
+		Sessions:             maps.Clone(db.Sessions),
+		LoginAttempts:        maps.Clone(db.LoginAttempts),
+		APIKeys:              maps.Clone(db.APIKeys),
+		IdempotencyRecords:   maps.Clone(db.IdempotencyRecords),
+		Outbox:               maps.Clone(db.Outbox),
+		WebhookSubscriptions: maps.Clone(db.WebhookSubscriptions),
+		WebhookDeliveries:    maps.Clone(db.WebhookDeliveries),
+		Jobs:                 maps.Clone(db.Jobs),
+	}
+}
+
+// restore puts back the tables of a snapshot, the lock must be held
+func (db *InMemoryDB) restore(snapshot *InMemoryDB) {
+	db.Clinics = snapshot.Clinics
+	db.ClinicMembers = snapshot.ClinicMembers
+	db.Users = snapshot.Users
+	db.PasswordHistory = snapshot.PasswordHistory
+	db.Roles = snapshot.Roles
+	db.Patients = snapshot.Patients
+	db.Consents = snapshot.Consents
+	db.Erasures = snapshot.Erasures
+	db.RefreshTokens = snapshot.RefreshTokens
+	db.Sessions = snapshot.Sessions
+	db.LoginAttempts = snapshot.LoginAttempts
+	db.APIKeys = snapshot.APIKeys
+	db.IdempotencyRecords = snapshot.IdempotencyRecords
+	db.Outbox = snapshot.Outbox
+	db.WebhookSubscriptions = snapshot.WebhookSubscriptions
+	db.WebhookDeliveries = snapshot.WebhookDeliveries
+	db.Jobs = snapshot.Jobs
+}
+
+type inMemoryTxKey struct{}
+
+type inMemoryUnitOfWork struct {
+	db *InMemoryDB
+}
+
+// NewInMemoryUnitOfWork holds the write lock of db while the unit of work runs, so that nobody sees
+// its writes before it ends nor writes in between, and rolls back by restoring the tables as they were.
+// The adapters called with its ctx do not lock db again
+func NewInMemoryUnitOfWork(db *InMemoryDB) transaction.UnitOfWork {
+	return &inMemoryUnitOfWork{db: db}
+}
+
+func (u *inMemoryUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.db.inUnitOfWork(ctx) {
+		return fn(ctx)
+	}
+
+	defer u.db.Lock(ctx)()
+
+	snapshot := u.db.snapshot()
+	if err := fn(context.WithValue(ctx, inMemoryTxKey{}, u.db)); err != nil {
+		u.db.restore(snapshot)
+		return err
+	}
+
+	return nil
+}
+
+// DeleteUser deletes the user along with its memberships, password history, sessions and API keys, the lock must be held
+func (db *InMemoryDB) DeleteUser(userID user.ID) {
+	delete(db.Users, userID)
+	delete(db.PasswordHistory, userID)
+	delete(db.RefreshTokens, userID)
+	for id, session := range db.Sessions {
+		if session.UserID == userID {
+			delete(db.Sessions, id)
+		}
+	}
+
+	for key := range db.ClinicMembers {
+		if key.UserID == userID {
+			delete(db.ClinicMembers, key)
+		}
+	}
+
+	for id, key := range db.APIKeys {
+		if key.UserID == userID {
+			delete(db.APIKeys, id)
+		}
+	}
+
+	// the consents collected and the erasures reviewed by the user are kept, like the ON DELETE SET NULL does
+	for id, row := range db.Consents {
+		if row.CollectedBy == userID {
+			row.CollectedBy = 0
+			db.Consents[id] = row
+		}
+	}
+
+	for id, row := range db.Erasures {
+		if row.RequestedBy == userID {
+			row.RequestedBy = 0
+		}
+		if row.ReviewedBy == userID {
+			row.ReviewedBy = 0
+		}
+		db.Erasures[id] = row
+	}
+}
+
+// DeletePatient deletes the patient along with its consents and erasures, the lock must be held
+func (db *InMemoryDB) DeletePatient(patientID patient.ID) {
+	delete(db.Patients, patientID)
+	for id, row := range db.Consents {
+		if row.PatientID == patientID {
+			delete(db.Consents, id)
+		}
+	}
+
+	for id, row := range db.Erasures {
+		if row.PatientID == patientID {
+			delete(db.Erasures, id)
+		}
+	}
+}
+
+// CheckUser is the users foreign key, the lock must be held
+func (db *InMemoryDB) CheckUser(userID user.ID) error {
+	if _, found := db.Users[userID]; !found {
+		return fmt.Errorf("violates foreign key constraint: user %d does not exist", userID)
+	}
+
+	return nil
+}
+
+// CheckClinic is the clinic foreign key, the lock must be held
+func (db *InMemoryDB) CheckClinic(clinicID clinic.ID) error {
+	if _, found := db.Clinics[clinicID]; !found {
+		return fmt.Errorf("violates foreign key constraint: clinic %d does not exist", clinicID)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+)
+
+func TestInMemoryUnitOfWorkIsolation(t *testing.T) {
+	db := NewInMemoryDB()
+	uow := NewInMemoryUnitOfWork(db)
+	insertClinic := func(ctx context.Context, id clinic.ID) {
+		defer db.Lock(ctx)()
+		db.Clinics[id] = clinic.Clinic{ID: id}
+	}
+	hasClinic := func(id clinic.ID) bool {
+		defer db.RLock(context.Background())()
+		_, found := db.Clinics[id]
+		return found
+	}
+
+	started, outsideDone := make(chan struct{}), make(chan struct{})
+	go func() {
+		<-started
+		insertClinic(context.Background(), 3)
+		close(outsideDone)
+	}()
+
+	rollback := errors.New("rollback")
+	err := uow.Do(context.Background(), func(ctx context.Context) error {
+		insertClinic(ctx, 2)
+		close(started)
+
+		// the write outside of the unit of work waits for it to end
+		select {
+		case <-outsideDone:
+			t.Errorf("a write went through during the unit of work")
+		default:
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("unit of work: %v", err)
+	}
+
+	<-outsideDone
+	if hasClinic(2) || !hasClinic(3) {
+		t.Fatalf("the rollback should only undo the unit of work writes: %v", db.Clinics)
+	}
+}
@@ -0,0 +1,127 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/job"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) jobSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) InsertJob(ctx context.Context, newJob job.Job) (job.Job, bool, error) {
+	defer m.db.Lock(ctx)()
+
+	if newJob.ClinicID != 0 {
+		if err := m.db.CheckClinic(newJob.ClinicID); err != nil {
+			return job.Job{}, false, fmt.Errorf("unable to insert job: %w", err)
+		}
+	}
+
+	// the unique_key constraint
+	if newJob.UniqueKey != "" {
+		for _, row := range m.db.Jobs {
+			if row.UniqueKey == newJob.UniqueKey {
+				return job.Job{}, false, nil
+			}
+		}
+	}
+
+	newJob.ID = job.ID(m.db.NextID("job"))
+	newJob.Payload = slices.Clone(newJob.Payload)
+	m.db.Jobs[newJob.ID] = persistence.JobRow{Job: newJob}
+	return cloneJob(newJob), true, nil
+}
+
+func (m *inMemory) ClaimDueJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]job.Job, error) {
+	defer m.db.Lock(ctx)()
+
+	var due []persistence.JobRow
+	for _, row := range m.db.Jobs {
+		queued := row.State == job.StateQueued && !row.RunAt.After(now)
+		leaseEnded := row.State == job.StateRunning && !row.LockedUntil.After(now)
+		if queued || leaseEnded {
+			due = append(due, row)
+		}
+	}
+
+	slices.SortFunc(due, func(a, b persistence.JobRow) int {
+		return cmp.Or(a.RunAt.Compare(b.RunAt), cmp.Compare(a.ID, b.ID))
+	})
+	due = due[:min(len(due), limit)]
+	jobs := make([]job.Job, 0, len(due))
+	for _, row := range due {
+		row.State = job.StateRunning
+		row.Attempts++
+		row.LockedUntil = now.Add(lease)
+		m.db.Jobs[row.ID] = row
+		jobs = append(jobs, cloneJob(row.Job))
+	}
+
+	return jobs, nil
+}
+
+func (m *inMemory) FinishRun(ctx context.Context, id job.ID, state job.State, runAt time.Time, lastError string, finishedAt *time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.Jobs[id]
+	if !found {
+		return nil
+	}
+
+	row.State = state
+	row.RunAt = runAt
+	row.LockedUntil = time.Time{}
+	row.LastError = lastError
+	row.FinishedAt = nil
+	if finishedAt != nil {
+		finished := *finishedAt
+		row.FinishedAt = &finished
+	}
+
+	m.db.Jobs[id] = row
+	return nil
+}
+
+// ListJobs lists the system jobs along with the ones of the context clinic
+func (m *inMemory) ListJobs(ctx context.Context, state job.State, kind job.Kind, limit int) ([]job.Job, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list jobs: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	var jobs []job.Job
+	for _, row := range m.db.Jobs {
+		if (row.ClinicID != 0 && row.ClinicID != clinicID) || (state != "" && row.State != state) || (kind != "" && row.Kind != kind) {
+			continue
+		}
+		jobs = append(jobs, cloneJob(row.Job))
+	}
+
+	slices.SortFunc(jobs, func(a, b job.Job) int { return cmp.Compare(b.ID, a.ID) })
+	return jobs[:min(len(jobs), limit)], nil
+}
+
+func cloneJob(j job.Job) job.Job {
+	j.Payload = slices.Clone(j.Payload)
+	if j.FinishedAt != nil {
+		finishedAt := *j.FinishedAt
+		j.FinishedAt = &finishedAt
+	}
+
+	return j
+}
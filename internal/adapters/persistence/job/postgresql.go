@@ -0,0 +1,140 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/job"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+	// skipLocked lets concurrent workers claim distinct jobs instead of waiting on each other
+	skipLocked bool
+}
+
+func NewPGClient(client *bun.DB) jobSVC.Persistence {
+	return &pgPersistence{clientDB: client, skipLocked: true}
+}
+
+func (p *pgPersistence) InsertJob(ctx context.Context, newJob job.Job) (job.Job, bool, error) {
+	jobDAO := jobFromDomainToDAO(newJob)
+	result, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&jobDAO).
+		On("CONFLICT (unique_key) DO NOTHING").
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return job.Job{}, false, fmt.Errorf("unable to insert job: %w", err)
+	}
+
+	if inserted, _ := result.RowsAffected(); inserted == 0 {
+		return job.Job{}, false, nil
+	}
+
+	return jobFromDAOToDomain(jobDAO), true, nil
+}
+
+func (p *pgPersistence) ClaimDueJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]job.Job, error) {
+	db := persistence.DB(ctx, p.clientDB)
+	dueIDs := db.NewSelect().
+		Model((*jobDAO)(nil)).
+		Column("id").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.
+				Where("state = ? AND run_at <= ?", job.StateQueued, now).
+				WhereOr("state = ? AND locked_until <= ?", job.StateRunning, now)
+		}).
+		Order("run_at ASC", "id ASC").
+		Limit(limit)
+	if p.skipLocked {
+		dueIDs = dueIDs.For("UPDATE SKIP LOCKED")
+	}
+
+	var jobDAOs []jobDAO
+	_, err := db.NewUpdate().
+		Model((*jobDAO)(nil)).
+		Set("state = ?", job.StateRunning).
+		Set("attempts = attempts + 1").
+		Set("locked_until = ?", now.Add(lease)).
+		Where("id IN (?)", dueIDs).
+		Returning("*").
+		Exec(ctx, &jobDAOs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to claim due jobs: %w", err)
+	}
+
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(jobDAOs, func(a, b jobDAO) int {
+		return cmp.Or(a.RunAt.Compare(b.RunAt), cmp.Compare(a.ID, b.ID))
+	})
+	jobs := make([]job.Job, 0, len(jobDAOs))
+	for _, jobDAO := range jobDAOs {
+		jobs = append(jobs, jobFromDAOToDomain(jobDAO))
+	}
+
+	return jobs, nil
+}
+
+func (p *pgPersistence) FinishRun(ctx context.Context, id job.ID, state job.State, runAt time.Time, lastError string, finishedAt *time.Time) error {
+	var finished time.Time
+	if finishedAt != nil {
+		finished = *finishedAt
+	}
+
+	_, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*jobDAO)(nil)).
+		Set("state = ?", state).
+		Set("run_at = ?", runAt).
+		Set("locked_until = NULL").
+		Set("last_error = ?", bun.NullZero(lastError)).
+		Set("finished_at = ?", bun.NullZero(finished)).
+		Where("id = ?", id).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to finish the run of job %d: %w", id, err)
+	}
+
+	return nil
+}
+
+// ListJobs lists the system jobs along with the ones of the context clinic
+func (p *pgPersistence) ListJobs(ctx context.Context, state job.State, kind job.Kind, limit int) ([]job.Job, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list jobs: %w", err)
+	}
+
+	var jobDAOs []jobDAO
+	query := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&jobDAOs).
+		Where("clinic_id = ? OR clinic_id IS NULL", clinicID).
+		Order("id DESC").
+		Limit(limit)
+	if state != "" {
+		query = query.Where("state = ?", state)
+	}
+
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("unable to list jobs: %w", err)
+	}
+
+	jobs := make([]job.Job, 0, len(jobDAOs))
+	for _, jobDAO := range jobDAOs {
+		jobs = append(jobs, jobFromDAOToDomain(jobDAO))
+	}
+
+	return jobs, nil
+}
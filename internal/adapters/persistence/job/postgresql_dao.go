@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/job"
+)
+
+type jobDAO struct {
+	bun.BaseModel `bun:"table:job,alias:job"`
+
+	ID       int64  `bun:"id,pk,autoincrement"`
+	Kind     string `bun:"kind,notnull"`
+	ClinicID int64  `bun:"clinic_id,nullzero"`
+	// Payload is sent as text, PostgreSQL casts it to jsonb
+	Payload     string    `bun:"payload,notnull"`
+	UniqueKey   string    `bun:"unique_key,nullzero"`
+	State       string    `bun:"state,notnull"`
+	Attempts    int       `bun:"attempts,notnull"`
+	MaxAttempts int       `bun:"max_attempts,notnull"`
+	RunAt       time.Time `bun:"run_at,notnull"`
+	LockedUntil time.Time `bun:"locked_until,nullzero"`
+	LastError   string    `bun:"last_error,nullzero"`
+	CreatedAt   time.Time `bun:"created_at,notnull"`
+	FinishedAt  time.Time `bun:"finished_at,nullzero"`
+}
+
+func jobFromDomainToDAO(j job.Job) jobDAO {
+	jobDAO := jobDAO{
+		ID:          int64(j.ID),
+		Kind:        string(j.Kind),
+		ClinicID:    int64(j.ClinicID),
+		Payload:     string(j.Payload),
+		UniqueKey:   j.UniqueKey,
+		State:       string(j.State),
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		LastError:   j.LastError,
+		CreatedAt:   j.CreatedAt,
+	}
+
+	if j.FinishedAt != nil {
+		jobDAO.FinishedAt = *j.FinishedAt
+	}
+
+	return jobDAO
+}
+
+func jobFromDAOToDomain(jobDAO jobDAO) job.Job {
+	j := job.Job{
+		ID:          job.ID(jobDAO.ID),
+		Kind:        job.Kind(jobDAO.Kind),
+		ClinicID:    clinic.ID(jobDAO.ClinicID),
+		Payload:     json.RawMessage(jobDAO.Payload),
+		UniqueKey:   jobDAO.UniqueKey,
+		State:       job.State(jobDAO.State),
+		Attempts:    jobDAO.Attempts,
+		MaxAttempts: jobDAO.MaxAttempts,
+		RunAt:       jobDAO.RunAt,
+		LastError:   jobDAO.LastError,
+		CreatedAt:   jobDAO.CreatedAt,
+	}
+
+	if !jobDAO.FinishedAt.IsZero() {
+		finishedAt := jobDAO.FinishedAt
+		j.FinishedAt = &finishedAt
+	}
+
+	return j
+}
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient without the row locks, SQLite has a single writer anyway
+func NewSQLiteClient(client *bun.DB) jobSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
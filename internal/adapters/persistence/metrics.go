@@ -0,0 +1,49 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/metrics"
+)
+
+// InstrumentClient times the bun queries and exposes the sql.DB pool stats of a PostgreSQL or SQLite client
+func InstrumentClient(client *bun.DB, registerer prometheus.Registerer) {
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "SQL storage (PostgreSQL or SQLite) query latency by operation and result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "result"})
+	registerer.MustRegister(
+		durations,
+		collectors.NewDBStatsCollector(client.DB, "cleanic"),
+	)
+
+	client.AddQueryHook(&queryMetricsHook{durations: durations})
+}
+
+type queryMetricsHook struct {
+	durations *prometheus.HistogramVec
+}
+
+func (h *queryMetricsHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *queryMetricsHook) AfterQuery(_ context.Context, event *bun.QueryEvent) {
+	// a missing row is an answer, not a database failure
+	result := "success"
+	if event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) {
+		result = "error"
+	}
+
+	h.durations.WithLabelValues(event.Operation(), result).Observe(time.Since(event.StartTime).Seconds())
+}
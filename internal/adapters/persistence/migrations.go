@@ -0,0 +1,37 @@
+package persistence
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// LatestMigration is the name of the migration with the highest number at the root of migrations,
+// the one /readyz expects to be applied
+func LatestMigration(migrations fs.FS) (string, error) {
+	entries, err := fs.Glob(migrations, "*.sql")
+	if err != nil {
+		return "", fmt.Errorf("unable to list the migrations: %w", err)
+	}
+
+	latest := ""
+	for _, name := range entries {
+		if latest == "" || migrationNumber(name) > migrationNumber(latest) {
+			latest = name
+		}
+	}
+
+	if latest == "" {
+		return "", fmt.Errorf("no migration found")
+	}
+
+	return latest, nil
+}
+
+// migrationNumber is the prefix of 1_init.sql, sql-migrate sorts the migrations by it
+func migrationNumber(name string) int {
+	prefix, _, _ := strings.Cut(name, "_")
+	number, _ := strconv.Atoi(prefix)
+	return number
+}
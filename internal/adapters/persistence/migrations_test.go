@@ -0,0 +1,30 @@
+package persistence
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLatestMigration(t *testing.T) {
+	latest, err := LatestMigration(fstest.MapFS{
+		"1_init.sql":         {},
+		"9_init_other.sql":   {},
+		"10_init_clinic.sql": {},
+		"schemas.go":         {},
+	})
+	if err != nil || latest != "10_init_clinic.sql" {
+		t.Fatalf("the migrations should be sorted by number, got %q: %v", latest, err)
+	}
+
+	if _, err := LatestMigration(fstest.MapFS{}); err == nil {
+		t.Fatalf("an empty directory should fail")
+	}
+
+	if latest, err := LatestMigration(SQLiteMigrations()); err != nil || latest == "" {
+		t.Fatalf("the embedded sqlite migrations should have a latest one, got %q: %v", latest, err)
+	}
+
+	if latest, err := LatestMigration(PGMigrations()); err != nil || latest != "18_role_clinic.sql" {
+		t.Fatalf("the embedded postgresql migrations should have a latest one, got %q: %v", latest, err)
+	}
+}
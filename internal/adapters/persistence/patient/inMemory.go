@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+// NewInMemoryClient scopes every query to the clinic of the context, like NewPGClient
+func NewInMemoryClient(db *persistence.InMemoryDB) patientSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) InsertPatient(ctx context.Context, newPatient patient.Patient) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to create a new patient: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckClinic(clinicID); err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to create a new patient: %w", err)
+	}
+
+	if err := m.checkUniqueEmail(clinicID, newPatient); err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to create a new patient: %w", err)
+	}
+
+	if newPatient.ID == 0 {
+		newPatient.ID = patient.ID(m.db.NextID("patient"))
+	}
+
+	if _, found := m.db.Patients[newPatient.ID]; found {
+		return patient.Patient{}, fmt.Errorf("unable to create a new patient: duplicate id %d", newPatient.ID)
+	}
+
+	m.db.Patients[newPatient.ID] = persistence.PatientRow{Patient: newPatient, ClinicID: clinicID}
+	return newPatient, nil
+}
+
+func (m *inMemory) ListPatients(ctx context.Context) ([]patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list patients: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	patients := []patient.Patient{}
+	for _, row := range m.db.Patients {
+		if row.ClinicID == clinicID {
+			patients = append(patients, row.Patient)
+		}
+	}
+
+	slices.SortFunc(patients, func(a, b patient.Patient) int { return cmp.Compare(a.ID, b.ID) })
+	return patients, nil
+}
+
+func (m *inMemory) GetPatientByID(ctx context.Context, id int64) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to get patient: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	row, found := m.db.Patients[patient.ID(id)]
+	if !found || row.ClinicID != clinicID {
+		return patient.Patient{}, fmt.Errorf("err: %w", sql.ErrNoRows)
+	}
+
+	return row.Patient, nil
+}
+
+// UpdatePatient only updates the non zero fields, like NewPGClient
+func (m *inMemory) UpdatePatient(ctx context.Context, updatedPatient patient.Patient) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to request patient update: %w", err)
+	}
+
+	if updatedPatient.ID == 0 {
+		return patient.Patient{}, fmt.Errorf("unable to update any patient as ID is 0: %+v", updatedPatient)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.Patients[updatedPatient.ID]
+	if !found || row.ClinicID != clinicID {
+		return patient.Patient{}, fmt.Errorf("patient not found with id: %d: %w", updatedPatient.ID, sql.ErrNoRows)
+	}
+
+	row.Firstname = cmp.Or(updatedPatient.Firstname, row.Firstname)
+	row.Lastname = cmp.Or(updatedPatient.Lastname, row.Lastname)
+	row.Email = cmp.Or(updatedPatient.Email, row.Email)
+	if err := m.checkUniqueEmail(clinicID, row.Patient); err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to request patient update: %w", err)
+	}
+
+	m.db.Patients[row.ID] = row
+	return row.Patient, nil
+}
+
+func (m *inMemory) DeletePatient(ctx context.Context, id int64) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete patient id: %d, err: %w", id, err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if row, found := m.db.Patients[patient.ID(id)]; found && row.ClinicID == clinicID {
+		m.db.DeletePatient(row.ID)
+	}
+
+	return nil
+}
+
+// checkUniqueEmail is the (clinic_id, email) unique constraint, the lock must be held
+func (m *inMemory) checkUniqueEmail(clinicID clinic.ID, p patient.Patient) error {
+	for _, row := range m.db.Patients {
+		if row.ClinicID == clinicID && row.Email == p.Email && row.ID != p.ID {
+			return fmt.Errorf("duplicate key value violates unique constraint \"patient_clinic_id_email_key\"")
+		}
+	}
+
+	return nil
+}
@@ -2,10 +2,13 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/uptrace/bun"
 
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	patient "github.com/sopial42/cleanic/internal/domains/patient"
 	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
 )
@@ -14,14 +17,21 @@ type pgPersistence struct {
 	clientDB *bun.DB
 }
 
+// NewPGClient scopes every query to the clinic of the context, a query without clinic fails
 func NewPGClient(client *bun.DB) patientSVC.Persistence {
 	return &pgPersistence{clientDB: client}
 }
 
 func (p *pgPersistence) InsertPatient(ctx context.Context, newPatient patient.Patient) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to create a new patient: %w", err)
+	}
+
 	patientDAO := patientFromDomainToDAO(newPatient)
+	patientDAO.ClinicID = int64(clinicID)
 
-	_, err := p.clientDB.NewInsert().
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
 		Model(&patientDAO).
 		Returning("*").
 		Exec(ctx)
@@ -38,10 +48,15 @@ func (p *pgPersistence) InsertPatient(ctx context.Context, newPatient patient.Pa
 }
 
 func (p *pgPersistence) ListPatients(ctx context.Context) ([]patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list patients: %w", err)
+	}
+
 	var patientDAOs []patientDAO
 
-	request := p.clientDB.NewSelect().Model(&patientDAOs)
-	err := request.Scan(ctx)
+	request := persistence.DB(ctx, p.clientDB).NewSelect().Model(&patientDAOs).Where("clinic_id = ?", clinicID)
+	err = request.Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("err: %w", err)
 	}
@@ -50,11 +65,17 @@ func (p *pgPersistence) ListPatients(ctx context.Context) ([]patient.Patient, er
 }
 
 func (p *pgPersistence) GetPatientByID(ctx context.Context, id int64) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to get patient: %w", err)
+	}
+
 	var patientDAO patientDAO
 
-	err := p.clientDB.NewSelect().
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
 		Model(&patientDAO).
 		Where("id = ?", id).
+		Where("clinic_id = ?", clinicID).
 		Scan(ctx)
 	if err != nil {
 		return patient.Patient{}, fmt.Errorf("err: %w", err)
@@ -68,28 +89,45 @@ func (p *pgPersistence) GetPatientByID(ctx context.Context, id int64) (patient.P
 }
 
 func (p *pgPersistence) UpdatePatient(ctx context.Context, updatedPatient patient.Patient) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to request patient update: %w", err)
+	}
+
 	patientDAO := patientFromDomainToDAO(updatedPatient)
 	if patientDAO.ID == 0 {
 		return patient.Patient{}, fmt.Errorf("unable to update any patient as ID is 0: %+v", patientDAO)
 	}
 
-	_, err := p.clientDB.NewUpdate().
+	result, err := persistence.DB(ctx, p.clientDB).NewUpdate().
 		Model(&patientDAO).
 		Where("id = ?", updatedPatient.ID).
+		Where("clinic_id = ?", clinicID).
 		OmitZero().
+		ExcludeColumn("clinic_id").
 		Returning("*").
 		Exec(ctx)
 	if err != nil {
 		return patient.Patient{}, fmt.Errorf("unable to request patient update: %w", err)
 	}
 
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return patient.Patient{}, fmt.Errorf("patient not found with id: %d: %w", updatedPatient.ID, sql.ErrNoRows)
+	}
+
 	return patientFromDAOToDomain(patientDAO), nil
 }
 
 func (p *pgPersistence) DeletePatient(ctx context.Context, id int64) error {
-	_, err := p.clientDB.NewDelete().
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete patient id: %d, err: %w", id, err)
+	}
+
+	_, err = persistence.DB(ctx, p.clientDB).NewDelete().
 		Model((*patientDAO)(nil)).
 		Where("id = ?", id).
+		Where("clinic_id = ?", clinicID).
 		Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("unable to delete patient id: %d, err: %w", id, err)
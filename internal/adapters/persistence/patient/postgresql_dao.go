@@ -12,6 +12,7 @@ type patientDAO struct {
 	Firstname     string    `bun:"firstname"`
 	Lastname      string    `bun:"lastname"`
 	Email         string    `bun:"email"`
+	ClinicID      int64     `bun:"clinic_id,notnull"`
 }
 
 func patientFromDAOToDomain(p patientDAO) patient.Patient {
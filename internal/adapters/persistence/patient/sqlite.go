@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect
+func NewSQLiteClient(client *bun.DB) patientSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
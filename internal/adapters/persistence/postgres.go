@@ -1,41 +1,79 @@
 package persistence
 
 import (
+	"context"
 	"database/sql"
-	"fmt"
+	"database/sql/driver"
+	"embed"
+	"io/fs"
+	"net"
+	"net/url"
 	"runtime"
 	"time"
 
+	"github.com/sopial42/cleanic/internal/adapters/logging"
 	"github.com/sopial42/cleanic/internal/config"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/dialect/pgdialect"
 	"github.com/uptrace/bun/driver/pgdriver"
-	"github.com/uptrace/bun/extra/bundebug"
+	"github.com/uptrace/bun/extra/bunotel"
 )
 
+//go:embed postgresql/*.sql
+var pgMigrations embed.FS
+
+// PGMigrations are the migrations applied by sql-migrate, the integration tests apply them too
+func PGMigrations() fs.FS {
+	migrations, _ := fs.Sub(pgMigrations, "postgresql")
+	return migrations
+}
+
 func NewPGClient(cfg config.DBConfig) *bun.DB {
-	dsn := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable",
-		cfg.User, cfg.Password, cfg.Host, cfg.DBName)
-	sqldb := sql.OpenDB(pgdriver.NewConnector(
-		pgdriver.WithDSN(dsn),
-		pgdriver.WithTimeout(5*time.Second)))
+	logger := logging.Component("persistence")
+	sqldb := sql.OpenDB(credentialsConnector{cfg: cfg})
 
 	maxOpenConns := 4 * runtime.GOMAXPROCS(0)
 	sqldb.SetMaxOpenConns(maxOpenConns)
 	sqldb.SetMaxIdleConns(maxOpenConns)
 	sqldb.SetConnMaxLifetime(30 * time.Minute)
 
-	err := sqldb.Ping()
-	if err != nil {
-		panic(err)
+	// the DB may come up after the server, /readyz reports it until then
+	if err := sqldb.Ping(); err != nil {
+		logger.Warn("Unable to reach the database, readiness will fail until it is", "error", err)
 	}
 
 	client := bun.NewDB(sqldb, pgdialect.New())
-	client.AddQueryHook(bundebug.NewQueryHook(
-		// Ensure false by default
-		bundebug.WithEnabled(false),
-		bundebug.FromEnv("DB_LOG_LEVEL"),
-	))
+	client.AddQueryHook(newQueryLogHook(logger, cfg.LogLevel))
+	// spans are only recorded once a tracer provider is installed
+	client.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName(cfg.DBName)))
 
 	return client
 }
+
+// credentialsConnector builds the DSN for each new connection, so that a reloaded
+// password applies to new connections while the open ones keep serving their requests
+type credentialsConnector struct {
+	cfg config.DBConfig
+}
+
+func (c credentialsConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.connector().Connect(ctx)
+}
+
+func (c credentialsConnector) Driver() driver.Driver {
+	return c.connector().Driver()
+}
+
+func (c credentialsConnector) connector() *pgdriver.Connector {
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.cfg.User, c.cfg.Password.Current().Reveal()),
+		Host:     net.JoinHostPort(c.cfg.Host, c.cfg.Port),
+		Path:     c.cfg.DBName,
+		RawQuery: "sslmode=disable",
+	}
+
+	return pgdriver.NewConnector(
+		pgdriver.WithDSN(dsn.String()),
+		pgdriver.WithTimeout(5*time.Second))
+}
@@ -0,0 +1,59 @@
+-- +migrate Up
+CREATE TABLE clinic (
+  id          BIGSERIAL PRIMARY KEY,
+  name        TEXT      NOT NULL UNIQUE CHECK (name <> ''),
+  created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+-- The default clinic receives the existing data, the self sign-ups and the SSO provisioned users
+INSERT INTO clinic (id, name) VALUES (1, 'default');
+ALTER SEQUENCE clinic_id_seq RESTART WITH 10001;
+
+-- The roles of a user are now given per clinic
+CREATE TABLE clinic_member (
+  clinic_id  BIGINT NOT NULL,
+  user_id    BIGINT NOT NULL,
+  roles      JSONB  NOT NULL,
+  PRIMARY KEY (clinic_id, user_id),
+  CONSTRAINT fk_clinic_id
+    FOREIGN KEY (clinic_id)
+    REFERENCES clinic(id)
+    ON DELETE CASCADE,
+  CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX clinic_member_user_id_idx ON clinic_member (user_id);
+
+INSERT INTO clinic_member (clinic_id, user_id, roles) SELECT 1, id, roles FROM users;
+ALTER TABLE users DROP COLUMN roles;
+
+-- Two clinics may register the same patient email
+ALTER TABLE patient ADD COLUMN clinic_id BIGINT NOT NULL DEFAULT 1 REFERENCES clinic(id) ON DELETE CASCADE;
+ALTER TABLE patient ALTER COLUMN clinic_id DROP DEFAULT;
+ALTER TABLE patient DROP CONSTRAINT patient_email_key;
+ALTER TABLE patient ADD CONSTRAINT patient_clinic_id_email_key UNIQUE (clinic_id, email);
+
+-- A session and an API key act in a single clinic
+ALTER TABLE refresh_token ADD COLUMN clinic_id BIGINT NOT NULL DEFAULT 1 REFERENCES clinic(id) ON DELETE CASCADE;
+ALTER TABLE api_key ADD COLUMN clinic_id BIGINT NOT NULL DEFAULT 1 REFERENCES clinic(id) ON DELETE CASCADE;
+ALTER TABLE refresh_token ALTER COLUMN clinic_id DROP DEFAULT;
+ALTER TABLE api_key ALTER COLUMN clinic_id DROP DEFAULT;
+
+UPDATE role SET permissions = permissions || '["clinic:manage"]' WHERE name = 'admin';
+
+-- +migrate Down
+UPDATE role SET permissions = permissions - 'clinic:manage' WHERE name = 'admin';
+ALTER TABLE api_key DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE refresh_token DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE patient DROP CONSTRAINT IF EXISTS patient_clinic_id_email_key;
+DELETE FROM patient WHERE clinic_id <> 1;
+ALTER TABLE patient ADD CONSTRAINT patient_email_key UNIQUE (email);
+ALTER TABLE patient DROP COLUMN IF EXISTS clinic_id;
+ALTER TABLE users ADD COLUMN roles JSONB NOT NULL DEFAULT '[]';
+UPDATE users SET roles = clinic_member.roles FROM clinic_member WHERE clinic_member.user_id = users.id AND clinic_member.clinic_id = 1;
+ALTER TABLE users ALTER COLUMN roles DROP DEFAULT;
+DROP TABLE IF EXISTS clinic_member;
+DROP TABLE IF EXISTS clinic;
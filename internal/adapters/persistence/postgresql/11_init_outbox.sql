@@ -0,0 +1,18 @@
+-- +migrate Up
+-- The domain events wait here for their delivery, they are inserted in the transaction of the change they describe
+CREATE TABLE outbox_event (
+  id               BIGSERIAL PRIMARY KEY,
+  type             TEXT      NOT NULL,
+  clinic_id        BIGINT    NOT NULL,
+  payload          JSONB     NOT NULL,
+  occurred_at      TIMESTAMP NOT NULL,
+  attempts         INTEGER   NOT NULL DEFAULT 0,
+  next_attempt_at  TIMESTAMP NOT NULL,
+  locked_until     TIMESTAMP,
+  last_error       TEXT
+);
+
+CREATE INDEX outbox_event_next_attempt_at_idx ON outbox_event (next_attempt_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_event;
@@ -0,0 +1,52 @@
+-- +migrate Up
+-- The partners of a clinic subscribe to its domain events, the payloads are signed with the secret
+CREATE TABLE webhook_subscription (
+  id           BIGSERIAL PRIMARY KEY,
+  clinic_id    BIGINT    NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  url          TEXT      NOT NULL,
+  event_types  JSONB     NOT NULL,
+  secret       TEXT      NOT NULL,
+  created_at   TIMESTAMP NOT NULL DEFAULT now()
+);
+
+ALTER SEQUENCE webhook_subscription_id_seq RESTART WITH 10001;
+
+CREATE INDEX webhook_subscription_clinic_id_idx ON webhook_subscription (clinic_id);
+
+-- An event is delivered once per subscription, until it succeeds or is dead-lettered
+CREATE TABLE webhook_delivery (
+  id               BIGSERIAL PRIMARY KEY,
+  subscription_id  BIGINT    NOT NULL REFERENCES webhook_subscription(id) ON DELETE CASCADE,
+  clinic_id        BIGINT    NOT NULL,
+  event_id         BIGINT    NOT NULL,
+  event_type       TEXT      NOT NULL,
+  payload          JSONB     NOT NULL,
+  status           TEXT      NOT NULL,
+  attempt_count    INTEGER   NOT NULL DEFAULT 0,
+  next_attempt_at  TIMESTAMP NOT NULL,
+  locked_until     TIMESTAMP,
+  created_at       TIMESTAMP NOT NULL,
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_delivery_pending_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE webhook_attempt (
+  id             BIGSERIAL PRIMARY KEY,
+  delivery_id    BIGINT    NOT NULL REFERENCES webhook_delivery(id) ON DELETE CASCADE,
+  status_code    INTEGER,
+  response_body  TEXT,
+  error          TEXT,
+  duration_ms    BIGINT    NOT NULL,
+  attempted_at   TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhook_attempt_delivery_id_idx ON webhook_attempt (delivery_id);
+
+UPDATE role SET permissions = permissions || '["webhook:manage"]' WHERE name = 'admin';
+
+-- +migrate Down
+UPDATE role SET permissions = permissions - 'webhook:manage' WHERE name = 'admin';
+DROP TABLE IF EXISTS webhook_attempt;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook_subscription;
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- The background jobs, claimed by the workers with FOR UPDATE SKIP LOCKED.
+-- The system jobs, such as the scheduled ones, have no clinic
+CREATE TABLE job (
+  id            BIGSERIAL PRIMARY KEY,
+  kind          TEXT      NOT NULL,
+  clinic_id     BIGINT    REFERENCES clinic(id) ON DELETE CASCADE,
+  payload       JSONB     NOT NULL,
+  -- a tick of a schedule is enqueued once whatever the number of instances
+  unique_key    TEXT      UNIQUE,
+  state         TEXT      NOT NULL,
+  attempts      INTEGER   NOT NULL DEFAULT 0,
+  max_attempts  INTEGER   NOT NULL,
+  run_at        TIMESTAMP NOT NULL,
+  locked_until  TIMESTAMP,
+  last_error    TEXT,
+  created_at    TIMESTAMP NOT NULL,
+  finished_at   TIMESTAMP
+);
+
+CREATE INDEX job_due_idx ON job (run_at) WHERE state IN ('queued', 'running');
+CREATE INDEX job_clinic_id_idx ON job (clinic_id);
+
+UPDATE role SET permissions = permissions || '["job:read"]' WHERE name = 'admin';
+
+-- +migrate Down
+UPDATE role SET permissions = permissions - 'job:read' WHERE name = 'admin';
+DROP TABLE IF EXISTS job;
@@ -0,0 +1,21 @@
+-- +migrate Up
+-- The consents of the patients, a revocation is a new row so that the history is kept as a proof
+CREATE TABLE patient_consent (
+  id            BIGSERIAL PRIMARY KEY,
+  clinic_id     BIGINT    NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  patient_id    BIGINT    NOT NULL REFERENCES patient(id) ON DELETE CASCADE,
+  purpose       TEXT      NOT NULL,
+  status        TEXT      NOT NULL,
+  collected_by  BIGINT    REFERENCES users(id) ON DELETE SET NULL,
+  recorded_at   TIMESTAMP NOT NULL,
+  document_ref  TEXT
+);
+
+ALTER SEQUENCE patient_consent_id_seq RESTART WITH 10001;
+
+CREATE INDEX patient_consent_patient_id_idx ON patient_consent (patient_id);
+CREATE INDEX patient_consent_clinic_id_purpose_idx ON patient_consent (clinic_id, purpose, patient_id);
+CREATE INDEX patient_consent_collected_by_idx ON patient_consent (collected_by);
+
+-- +migrate Down
+DROP TABLE IF EXISTS patient_consent;
@@ -0,0 +1,28 @@
+-- +migrate Up
+-- The erasure requests of the patients, approved by a second user. They are kept as the proof of the erasure
+CREATE TABLE patient_erasure (
+  id            BIGSERIAL PRIMARY KEY,
+  clinic_id     BIGINT    NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  patient_id    BIGINT    NOT NULL REFERENCES patient(id) ON DELETE CASCADE,
+  status        TEXT      NOT NULL,
+  reason        TEXT,
+  requested_by  BIGINT    REFERENCES users(id) ON DELETE SET NULL,
+  requested_at  TIMESTAMP NOT NULL,
+  reviewed_by   BIGINT    REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at   TIMESTAMP
+);
+
+ALTER SEQUENCE patient_erasure_id_seq RESTART WITH 10001;
+
+CREATE INDEX patient_erasure_patient_id_idx ON patient_erasure (patient_id);
+CREATE INDEX patient_erasure_clinic_id_status_idx ON patient_erasure (clinic_id, status);
+CREATE INDEX patient_erasure_requested_by_idx ON patient_erasure (requested_by);
+CREATE INDEX patient_erasure_reviewed_by_idx ON patient_erasure (reviewed_by);
+-- a patient has a single erasure waiting for its review
+CREATE UNIQUE INDEX patient_erasure_pending_idx ON patient_erasure (patient_id) WHERE status = 'pending';
+
+UPDATE role SET permissions = permissions || '["privacy:manage"]' WHERE name = 'admin';
+
+-- +migrate Down
+UPDATE role SET permissions = permissions - 'privacy:manage' WHERE name = 'admin';
+DROP TABLE IF EXISTS patient_erasure;
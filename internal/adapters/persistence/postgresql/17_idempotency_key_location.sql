@@ -0,0 +1,6 @@
+-- +migrate Up
+-- the Location header of the created resources is sent again on replay
+ALTER TABLE idempotency_key ADD COLUMN location TEXT;
+
+-- +migrate Down
+ALTER TABLE idempotency_key DROP COLUMN IF EXISTS location;
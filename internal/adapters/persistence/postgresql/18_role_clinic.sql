@@ -0,0 +1,17 @@
+-- +migrate Up
+-- Each clinic manages its own roles, the existing ones are copied to every clinic
+ALTER TABLE role ADD COLUMN clinic_id BIGINT NOT NULL DEFAULT 1 REFERENCES clinic(id) ON DELETE CASCADE;
+ALTER TABLE role ALTER COLUMN clinic_id DROP DEFAULT;
+ALTER TABLE role DROP CONSTRAINT role_pkey;
+ALTER TABLE role ADD PRIMARY KEY (clinic_id, name);
+
+INSERT INTO role (clinic_id, name, description, permissions, builtin)
+  SELECT clinic.id, role.name, role.description, role.permissions, role.builtin
+  FROM clinic JOIN role ON role.clinic_id = 1
+  WHERE clinic.id <> 1;
+
+-- +migrate Down
+DELETE FROM role WHERE clinic_id <> 1;
+ALTER TABLE role DROP CONSTRAINT role_pkey;
+ALTER TABLE role ADD PRIMARY KEY (name);
+ALTER TABLE role DROP COLUMN IF EXISTS clinic_id;
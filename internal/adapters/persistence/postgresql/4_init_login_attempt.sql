@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE login_attempt (
+  scope            TEXT      NOT NULL,
+  key              TEXT      NOT NULL,
+  failures         INTEGER   NOT NULL DEFAULT 0,
+  last_failure_at  TIMESTAMP NOT NULL,
+  locked_until     TIMESTAMP,
+  PRIMARY KEY (scope, key)
+);
+
+-- +migrate Down
+DROP TABLE IF EXISTS login_attempt;
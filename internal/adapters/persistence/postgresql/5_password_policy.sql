@@ -0,0 +1,19 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP NOT NULL DEFAULT now();
+
+CREATE TABLE password_history (
+  id          BIGSERIAL PRIMARY KEY,
+  user_id     BIGINT    NOT NULL,
+  password    TEXT      NOT NULL,
+  created_at  TIMESTAMP NOT NULL DEFAULT now(),
+  CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX password_history_user_id_idx ON password_history (user_id, created_at DESC);
+
+-- +migrate Down
+DROP TABLE IF EXISTS password_history;
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
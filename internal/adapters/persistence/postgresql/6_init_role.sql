@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE role (
+  name         TEXT    PRIMARY KEY,
+  description  TEXT    NOT NULL DEFAULT '',
+  permissions  JSONB   NOT NULL,
+  builtin      BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO role (name, description, permissions, builtin) VALUES
+  ('admin', 'Full access', '["patient:read", "patient:write", "user:read", "user:manage", "role:manage", "profile:write"]', TRUE),
+  ('doctor', 'Reads and writes patient records', '["patient:read", "patient:write", "profile:write"]', TRUE),
+  ('nurse', 'Reads patient records', '["patient:read", "profile:write"]', FALSE),
+  ('receptionist', 'Registers and updates patients', '["patient:read", "patient:write", "profile:write"]', FALSE),
+  ('billing', 'Reads patient records for invoicing', '["patient:read", "profile:write"]', FALSE);
+
+-- +migrate Down
+DROP TABLE IF EXISTS role;
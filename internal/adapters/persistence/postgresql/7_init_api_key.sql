@@ -0,0 +1,25 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN service_account BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE api_key (
+  id            BIGSERIAL PRIMARY KEY,
+  user_id       BIGINT    NOT NULL,
+  name          TEXT      NOT NULL,
+  prefix        TEXT      NOT NULL UNIQUE,
+  hash          TEXT      NOT NULL,
+  scopes        JSONB     NOT NULL,
+  expires_at    TIMESTAMP,
+  last_used_at  TIMESTAMP,
+  revoked_at    TIMESTAMP,
+  created_at    TIMESTAMP NOT NULL DEFAULT now(),
+  CONSTRAINT fk_user_id
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE
+);
+
+CREATE INDEX api_key_user_id_idx ON api_key (user_id);
+
+-- +migrate Down
+DROP TABLE IF EXISTS api_key;
+ALTER TABLE users DROP COLUMN IF EXISTS service_account;
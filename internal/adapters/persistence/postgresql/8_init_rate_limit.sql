@@ -0,0 +1,11 @@
+-- +migrate Up
+CREATE TABLE rate_limit_bucket (
+  key         TEXT             PRIMARY KEY,
+  tokens      DOUBLE PRECISION NOT NULL,
+  updated_at  TIMESTAMP        NOT NULL
+);
+
+CREATE INDEX rate_limit_bucket_updated_at_idx ON rate_limit_bucket (updated_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS rate_limit_bucket;
@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE idempotency_key (
+  subject       TEXT      NOT NULL,
+  key           TEXT      NOT NULL,
+  fingerprint   TEXT      NOT NULL,
+  completed     BOOLEAN   NOT NULL DEFAULT FALSE,
+  status_code   INTEGER,
+  content_type  TEXT,
+  body          BYTEA,
+  created_at    TIMESTAMP NOT NULL,
+  expires_at    TIMESTAMP NOT NULL,
+  PRIMARY KEY (subject, key)
+);
+
+CREATE INDEX idempotency_key_expires_at_idx ON idempotency_key (expires_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS idempotency_key;
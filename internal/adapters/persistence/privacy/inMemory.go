@@ -0,0 +1,297 @@
+package persistence
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/privacy"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) privacySVC.Persistence {
+	return &inMemory{db: db}
+}
+
+func (m *inMemory) GetPatient(ctx context.Context, patientID patient.ID) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to get patient %d: %w", patientID, err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	row, found := m.db.Patients[patientID]
+	if !found || row.ClinicID != clinicID {
+		return patient.Patient{}, fmt.Errorf("unable to get patient %d: %w", patientID, sql.ErrNoRows)
+	}
+
+	return row.Patient, nil
+}
+
+func (m *inMemory) ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	consents := []consent.Consent{}
+	for _, row := range m.db.Consents {
+		if row.ClinicID == clinicID && row.PatientID == patientID {
+			consents = append(consents, row.Consent)
+		}
+	}
+
+	slices.SortFunc(consents, func(a, b consent.Consent) int { return cmp.Compare(b.ID, a.ID) })
+	return consents, nil
+}
+
+func (m *inMemory) ListEvents(ctx context.Context, patientID patient.ID) ([]event.Event, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list events: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	return m.events(clinicID, patientID), nil
+}
+
+// events are the ones referencing the patient, the lock must be held
+func (m *inMemory) events(clinicID clinic.ID, patientID patient.ID) []event.Event {
+	events := []event.Event{}
+	for _, row := range m.db.Outbox {
+		if row.ClinicID == clinicID && privacy.ReferencesPatient(row.Type, row.Payload, patientID) {
+			events = append(events, row.Event)
+		}
+	}
+
+	slices.SortFunc(events, func(a, b event.Event) int { return cmp.Compare(a.ID, b.ID) })
+	return events
+}
+
+func (m *inMemory) ListWebhookDeliveries(ctx context.Context, patientID patient.ID) ([]webhook.Delivery, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook deliveries: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	deliveries := m.deliveries(clinicID, patientID)
+	for i, delivery := range deliveries {
+		deliveries[i].Attempts = slices.Clone(delivery.Attempts)
+	}
+
+	return deliveries, nil
+}
+
+// deliveries are the ones of the events referencing the patient, the lock must be held
+func (m *inMemory) deliveries(clinicID clinic.ID, patientID patient.ID) []webhook.Delivery {
+	deliveries := []webhook.Delivery{}
+	for _, row := range m.db.WebhookDeliveries {
+		var envelope event.Envelope
+		if row.ClinicID != clinicID || json.Unmarshal(row.Payload, &envelope) != nil {
+			continue
+		}
+
+		if privacy.ReferencesPatient(row.EventType, envelope.Payload, patientID) {
+			deliveries = append(deliveries, row.Delivery)
+		}
+	}
+
+	slices.SortFunc(deliveries, func(a, b webhook.Delivery) int { return cmp.Compare(a.ID, b.ID) })
+	return deliveries
+}
+
+func (m *inMemory) ErasePatient(ctx context.Context, pseudonym patient.Patient) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to erase patient %d: %w", pseudonym.ID, err)
+	}
+
+	payload, err := json.Marshal(pseudonym)
+	if err != nil {
+		return fmt.Errorf("unable to encode pseudonym: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.Patients[pseudonym.ID]
+	if !found || row.ClinicID != clinicID {
+		return fmt.Errorf("unable to erase patient %d: %w", pseudonym.ID, sql.ErrNoRows)
+	}
+
+	erased := row.Patient
+	row.Patient = pseudonym
+	m.db.Patients[pseudonym.ID] = row
+
+	for _, pending := range m.events(clinicID, pseudonym.ID) {
+		if slices.Contains(privacy.IdentityEventTypes, pending.Type) {
+			outboxRow := m.db.Outbox[pending.ID]
+			outboxRow.Payload = payload
+			m.db.Outbox[pending.ID] = outboxRow
+		}
+	}
+
+	for _, delivery := range m.deliveries(clinicID, pseudonym.ID) {
+		if !slices.Contains(privacy.IdentityEventTypes, delivery.EventType) {
+			continue
+		}
+
+		var envelope event.Envelope
+		if err := json.Unmarshal(delivery.Payload, &envelope); err != nil {
+			return fmt.Errorf("unable to decode webhook delivery %d: %w", delivery.ID, err)
+		}
+
+		envelope.Payload = payload
+		erased, err := json.Marshal(envelope)
+		if err != nil {
+			return fmt.Errorf("unable to encode webhook delivery %d: %w", delivery.ID, err)
+		}
+
+		deliveryRow := m.db.WebhookDeliveries[delivery.ID]
+		deliveryRow.Payload = erased
+		deliveryRow.Attempts = slices.Clone(deliveryRow.Attempts)
+		for i := range deliveryRow.Attempts {
+			deliveryRow.Attempts[i].ResponseBody = ""
+		}
+		m.db.WebhookDeliveries[delivery.ID] = deliveryRow
+	}
+
+	email, err := json.Marshal(erased.Email)
+	if err != nil {
+		return fmt.Errorf("unable to encode patient %d email: %w", pseudonym.ID, err)
+	}
+
+	for key, record := range m.db.IdempotencyRecords {
+		if bytes.Contains(record.Body, email) {
+			delete(m.db.IdempotencyRecords, key)
+		}
+	}
+
+	return nil
+}
+
+func (m *inMemory) InsertErasure(ctx context.Context, newErasure privacy.Erasure) (privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to insert erasure: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if _, found := m.db.Patients[newErasure.PatientID]; !found {
+		return privacy.Erasure{}, fmt.Errorf("unable to insert erasure: violates foreign key constraint: patient %d does not exist", newErasure.PatientID)
+	}
+
+	if newErasure.RequestedBy != 0 {
+		if err := m.db.CheckUser(newErasure.RequestedBy); err != nil {
+			return privacy.Erasure{}, fmt.Errorf("unable to insert erasure: %w", err)
+		}
+	}
+
+	for _, row := range m.db.Erasures {
+		if newErasure.Status == privacy.ErasureStatusPending && row.PatientID == newErasure.PatientID && row.Status == privacy.ErasureStatusPending {
+			return privacy.Erasure{}, fmt.Errorf("unable to insert erasure: duplicate key value violates unique constraint: patient %d has a pending erasure", newErasure.PatientID)
+		}
+	}
+
+	newErasure.ID = privacy.ErasureID(m.db.NextID("patient_erasure"))
+	m.db.Erasures[newErasure.ID] = persistence.ErasureRow{Erasure: newErasure, ClinicID: clinicID}
+	return newErasure, nil
+}
+
+func (m *inMemory) GetErasure(ctx context.Context, erasureID privacy.ErasureID) (privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to get erasure %d: %w", erasureID, err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	row, found := m.db.Erasures[erasureID]
+	if !found || row.ClinicID != clinicID {
+		return privacy.Erasure{}, fmt.Errorf("unable to get erasure %d: %w", erasureID, sql.ErrNoRows)
+	}
+
+	return row.Erasure, nil
+}
+
+func (m *inMemory) ListErasures(ctx context.Context, status privacy.ErasureStatus) ([]privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list erasures: %w", err)
+	}
+
+	return m.listErasures(ctx, func(row persistence.ErasureRow) bool {
+		return row.ClinicID == clinicID && (status == "" || row.Status == status)
+	}), nil
+}
+
+func (m *inMemory) ListPatientErasures(ctx context.Context, patientID patient.ID) ([]privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list erasures: %w", err)
+	}
+
+	return m.listErasures(ctx, func(row persistence.ErasureRow) bool {
+		return row.ClinicID == clinicID && row.PatientID == patientID
+	}), nil
+}
+
+func (m *inMemory) listErasures(ctx context.Context, keep func(row persistence.ErasureRow) bool) []privacy.Erasure {
+	defer m.db.RLock(ctx)()
+
+	erasures := []privacy.Erasure{}
+	for _, row := range m.db.Erasures {
+		if keep(row) {
+			erasures = append(erasures, row.Erasure)
+		}
+	}
+
+	slices.SortFunc(erasures, func(a, b privacy.Erasure) int { return cmp.Compare(b.ID, a.ID) })
+	return erasures
+}
+
+func (m *inMemory) ReviewErasure(ctx context.Context, reviewed privacy.Erasure) (privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", reviewed.ID, err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.Erasures[reviewed.ID]
+	if !found || row.ClinicID != clinicID || row.Status != privacy.ErasureStatusPending {
+		return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", reviewed.ID, sql.ErrNoRows)
+	}
+
+	if reviewed.ReviewedBy != 0 {
+		if err := m.db.CheckUser(reviewed.ReviewedBy); err != nil {
+			return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", reviewed.ID, err)
+		}
+	}
+
+	row.Status = reviewed.Status
+	row.ReviewedBy = reviewed.ReviewedBy
+	row.ReviewedAt = reviewed.ReviewedAt
+	m.db.Erasures[reviewed.ID] = row
+	return row.Erasure, nil
+}
@@ -0,0 +1,401 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/privacy"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+}
+
+// NewPGClient scopes every query to the clinic of the context, a query without clinic fails
+func NewPGClient(client *bun.DB) privacySVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
+
+func (p *pgPersistence) GetPatient(ctx context.Context, patientID patient.ID) (patient.Patient, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to get patient %d: %w", patientID, err)
+	}
+
+	var patientFound patientDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&patientFound).
+		Where("id = ?", patientID).
+		Where("clinic_id = ?", clinicID).
+		Scan(ctx)
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to get patient %d: %w", patientID, err)
+	}
+
+	return patientFromDAOToDomain(patientFound), nil
+}
+
+func (p *pgPersistence) ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	var consentDAOs []consentDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&consentDAOs).
+		Where("clinic_id = ?", clinicID).
+		Where("patient_id = ?", patientID).
+		Order("id DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	consents := make([]consent.Consent, len(consentDAOs))
+	for i, consentDAO := range consentDAOs {
+		consents[i] = consentFromDAOToDomain(consentDAO)
+	}
+
+	return consents, nil
+}
+
+func (p *pgPersistence) ListEvents(ctx context.Context, patientID patient.ID) ([]event.Event, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list events: %w", err)
+	}
+
+	var eventDAOs []outboxEventDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&eventDAOs).
+		Where("clinic_id = ?", clinicID).
+		WhereGroup(" AND ", referencesPatient("outbox_event.type", "outbox_event.payload", patientID)).
+		Order("id").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list events: %w", err)
+	}
+
+	events := make([]event.Event, len(eventDAOs))
+	for i, eventDAO := range eventDAOs {
+		events[i] = outboxEventFromDAOToDomain(eventDAO)
+	}
+
+	return events, nil
+}
+
+func (p *pgPersistence) ListWebhookDeliveries(ctx context.Context, patientID patient.ID) ([]webhook.Delivery, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook deliveries: %w", err)
+	}
+
+	db := persistence.DB(ctx, p.clientDB)
+	var deliveryDAOs []deliveryDAO
+	err = db.NewSelect().
+		Model(&deliveryDAOs).
+		Where("clinic_id = ?", clinicID).
+		WhereGroup(" AND ", referencesPatient("webhook_delivery.event_type", "(webhook_delivery.payload -> 'payload')", patientID)).
+		Order("id").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook deliveries: %w", err)
+	}
+
+	if len(deliveryDAOs) == 0 {
+		return []webhook.Delivery{}, nil
+	}
+
+	deliveryIDs := make([]int64, len(deliveryDAOs))
+	for i, deliveryDAO := range deliveryDAOs {
+		deliveryIDs[i] = deliveryDAO.ID
+	}
+
+	var attemptDAOs []attemptDAO
+	err = db.NewSelect().
+		Model(&attemptDAOs).
+		Where("delivery_id IN (?)", bun.In(deliveryIDs)).
+		Order("id").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook attempts: %w", err)
+	}
+
+	attempts := map[int64][]webhook.Attempt{}
+	for _, attemptDAO := range attemptDAOs {
+		attempts[attemptDAO.DeliveryID] = append(attempts[attemptDAO.DeliveryID], attemptFromDAOToDomain(attemptDAO))
+	}
+
+	deliveries := make([]webhook.Delivery, len(deliveryDAOs))
+	for i, deliveryDAO := range deliveryDAOs {
+		deliveries[i] = deliveryFromDAOToDomain(deliveryDAO)
+		deliveries[i].Attempts = attempts[deliveryDAO.ID]
+	}
+
+	return deliveries, nil
+}
+
+func (p *pgPersistence) ErasePatient(ctx context.Context, pseudonym patient.Patient) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to erase patient %d: %w", pseudonym.ID, err)
+	}
+
+	erased, err := p.GetPatient(ctx, pseudonym.ID)
+	if err != nil {
+		return fmt.Errorf("unable to erase patient %d: %w", pseudonym.ID, err)
+	}
+
+	db := persistence.DB(ctx, p.clientDB)
+	result, err := db.NewUpdate().
+		Model((*patientDAO)(nil)).
+		Set("firstname = ?", pseudonym.Firstname).
+		Set("lastname = ?", pseudonym.Lastname).
+		Set("email = ?", pseudonym.Email).
+		Where("id = ?", pseudonym.ID).
+		Where("clinic_id = ?", clinicID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to erase patient %d: %w", pseudonym.ID, err)
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return fmt.Errorf("unable to erase patient %d: %w", pseudonym.ID, sql.ErrNoRows)
+	}
+
+	payload, err := json.Marshal(pseudonym)
+	if err != nil {
+		return fmt.Errorf("unable to encode pseudonym: %w", err)
+	}
+
+	events, err := p.ListEvents(ctx, pseudonym.ID)
+	if err != nil {
+		return err
+	}
+
+	var eventIDs []event.ID
+	for _, pending := range events {
+		if slices.Contains(privacy.IdentityEventTypes, pending.Type) {
+			eventIDs = append(eventIDs, pending.ID)
+		}
+	}
+
+	if len(eventIDs) > 0 {
+		_, err = db.NewUpdate().
+			Model((*outboxEventDAO)(nil)).
+			Set("payload = ?", string(payload)).
+			Where("id IN (?)", bun.In(eventIDs)).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to erase patient %d from the outbox: %w", pseudonym.ID, err)
+		}
+	}
+
+	deliveries, err := p.ListWebhookDeliveries(ctx, pseudonym.ID)
+	if err != nil {
+		return err
+	}
+
+	var deliveryIDs []webhook.DeliveryID
+	for _, delivery := range deliveries {
+		if !slices.Contains(privacy.IdentityEventTypes, delivery.EventType) {
+			continue
+		}
+
+		var envelope event.Envelope
+		if err := json.Unmarshal(delivery.Payload, &envelope); err != nil {
+			return fmt.Errorf("unable to decode webhook delivery %d: %w", delivery.ID, err)
+		}
+
+		envelope.Payload = payload
+		erased, err := json.Marshal(envelope)
+		if err != nil {
+			return fmt.Errorf("unable to encode webhook delivery %d: %w", delivery.ID, err)
+		}
+
+		_, err = db.NewUpdate().
+			Model((*deliveryDAO)(nil)).
+			Set("payload = ?", string(erased)).
+			Where("id = ?", delivery.ID).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to erase patient %d from webhook delivery %d: %w", pseudonym.ID, delivery.ID, err)
+		}
+
+		deliveryIDs = append(deliveryIDs, delivery.ID)
+	}
+
+	if len(deliveryIDs) > 0 {
+		// the partners may have echoed the patient in their responses
+		_, err = db.NewUpdate().
+			Model((*attemptDAO)(nil)).
+			Set("response_body = NULL").
+			Where("delivery_id IN (?)", bun.In(deliveryIDs)).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to erase patient %d from webhook attempts: %w", pseudonym.ID, err)
+		}
+	}
+
+	// the responses replayed to the retried requests hold the patient as it was, the records go
+	// with it and a retry of these requests is processed again
+	email, err := json.Marshal(erased.Email)
+	if err != nil {
+		return fmt.Errorf("unable to encode patient %d email: %w", pseudonym.ID, err)
+	}
+
+	_, err = db.NewDelete().
+		Model((*idempotencyKeyDAO)(nil)).
+		Where(bodyContains(db), email).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to erase patient %d from the idempotency keys: %w", pseudonym.ID, err)
+	}
+
+	return nil
+}
+
+// bodyContains matches the idempotency records whose response holds the given bytes, the only
+// expression of the adapter which PostgreSQL and SQLite spell differently
+func bodyContains(db bun.IDB) string {
+	if db.Dialect().Name() == dialect.SQLite {
+		return "instr(idempotency_key.body, ?) > 0"
+	}
+
+	return "position(? IN idempotency_key.body) > 0"
+}
+
+func (p *pgPersistence) InsertErasure(ctx context.Context, newErasure privacy.Erasure) (privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to insert erasure: %w", err)
+	}
+
+	erasureDAO := erasureFromDomainToDAO(newErasure)
+	erasureDAO.ID = 0
+	erasureDAO.ClinicID = int64(clinicID)
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&erasureDAO).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to insert erasure: %w", err)
+	}
+
+	return erasureFromDAOToDomain(erasureDAO), nil
+}
+
+func (p *pgPersistence) GetErasure(ctx context.Context, erasureID privacy.ErasureID) (privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to get erasure %d: %w", erasureID, err)
+	}
+
+	var erasureFound erasureDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&erasureFound).
+		Where("id = ?", erasureID).
+		Where("clinic_id = ?", clinicID).
+		Scan(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to get erasure %d: %w", erasureID, err)
+	}
+
+	return erasureFromDAOToDomain(erasureFound), nil
+}
+
+func (p *pgPersistence) ListErasures(ctx context.Context, status privacy.ErasureStatus) ([]privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list erasures: %w", err)
+	}
+
+	query := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model((*erasureDAO)(nil)).
+		Where("clinic_id = ?", clinicID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	return p.listErasures(ctx, query)
+}
+
+func (p *pgPersistence) ListPatientErasures(ctx context.Context, patientID patient.ID) ([]privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list erasures: %w", err)
+	}
+
+	return p.listErasures(ctx, persistence.DB(ctx, p.clientDB).NewSelect().
+		Model((*erasureDAO)(nil)).
+		Where("clinic_id = ?", clinicID).
+		Where("patient_id = ?", patientID))
+}
+
+func (p *pgPersistence) listErasures(ctx context.Context, query *bun.SelectQuery) ([]privacy.Erasure, error) {
+	var erasureDAOs []erasureDAO
+	if err := query.Order("id DESC").Scan(ctx, &erasureDAOs); err != nil {
+		return nil, fmt.Errorf("unable to list erasures: %w", err)
+	}
+
+	erasures := make([]privacy.Erasure, len(erasureDAOs))
+	for i, erasureDAO := range erasureDAOs {
+		erasures[i] = erasureFromDAOToDomain(erasureDAO)
+	}
+
+	return erasures, nil
+}
+
+func (p *pgPersistence) ReviewErasure(ctx context.Context, reviewed privacy.Erasure) (privacy.Erasure, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", reviewed.ID, err)
+	}
+
+	erasureDAO := erasureFromDomainToDAO(reviewed)
+	result, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model(&erasureDAO).
+		Column("status", "reviewed_by", "reviewed_at").
+		Where("id = ?", reviewed.ID).
+		Where("clinic_id = ?", clinicID).
+		Where("status = ?", privacy.ErasureStatusPending).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", reviewed.ID, err)
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", reviewed.ID, sql.ErrNoRows)
+	}
+
+	return erasureFromDAOToDomain(erasureDAO), nil
+}
+
+// referencesPatient keeps the rows of the events referencing the patient, the payload field
+// is read as text so that PostgreSQL and SQLite compare it the same way
+func referencesPatient(typeColumn string, payload string, patientID patient.ID) func(q *bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		for _, reference := range privacy.PatientReferences {
+			q = q.WhereOr("? = ? AND CAST(? ->> ? AS TEXT) = ?",
+				bun.Safe(typeColumn), reference.Type, bun.Safe(payload), reference.Field, strconv.FormatInt(int64(patientID), 10))
+		}
+
+		return q
+	}
+}
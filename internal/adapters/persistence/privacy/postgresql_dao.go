@@ -0,0 +1,190 @@
+package persistence
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/privacy"
+	"github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+)
+
+// The DAOs of the other tables only hold what the export shows and the erasure rewrites
+
+type patientDAO struct {
+	bun.BaseModel `bun:"table:patient,alias:patient"`
+
+	ID        int64  `bun:"id,pk,autoincrement"`
+	ClinicID  int64  `bun:"clinic_id,notnull"`
+	Firstname string `bun:"firstname"`
+	Lastname  string `bun:"lastname"`
+	Email     string `bun:"email"`
+}
+
+type idempotencyKeyDAO struct {
+	bun.BaseModel `bun:"table:idempotency_key,alias:idempotency_key"`
+
+	Subject string `bun:"subject,pk"`
+	Key     string `bun:"key,pk"`
+	Body    []byte `bun:"body"`
+}
+
+type consentDAO struct {
+	bun.BaseModel `bun:"table:patient_consent,alias:patient_consent"`
+
+	ID          int64     `bun:"id,pk,autoincrement"`
+	PatientID   int64     `bun:"patient_id,notnull"`
+	Purpose     string    `bun:"purpose,notnull"`
+	Status      string    `bun:"status,notnull"`
+	CollectedBy int64     `bun:"collected_by,nullzero"`
+	RecordedAt  time.Time `bun:"recorded_at,notnull"`
+	DocumentRef string    `bun:"document_ref,nullzero"`
+}
+
+type erasureDAO struct {
+	bun.BaseModel `bun:"table:patient_erasure,alias:patient_erasure"`
+
+	ID        int64  `bun:"id,pk,autoincrement"`
+	ClinicID  int64  `bun:"clinic_id,notnull"`
+	PatientID int64  `bun:"patient_id,notnull"`
+	Status    string `bun:"status,notnull"`
+	Reason    string `bun:"reason,nullzero"`
+	// RequestedBy and ReviewedBy are NULL once their user is deleted
+	RequestedBy int64     `bun:"requested_by,nullzero"`
+	RequestedAt time.Time `bun:"requested_at,notnull"`
+	ReviewedBy  int64     `bun:"reviewed_by,nullzero"`
+	ReviewedAt  time.Time `bun:"reviewed_at,nullzero"`
+}
+
+type outboxEventDAO struct {
+	bun.BaseModel `bun:"table:outbox_event,alias:outbox_event"`
+
+	ID         int64     `bun:"id,pk,autoincrement"`
+	Type       string    `bun:"type,notnull"`
+	ClinicID   int64     `bun:"clinic_id,notnull"`
+	Payload    string    `bun:"payload,notnull"`
+	OccurredAt time.Time `bun:"occurred_at,notnull"`
+}
+
+type deliveryDAO struct {
+	bun.BaseModel `bun:"table:webhook_delivery,alias:webhook_delivery"`
+
+	ID             int64     `bun:"id,pk,autoincrement"`
+	SubscriptionID int64     `bun:"subscription_id,notnull"`
+	ClinicID       int64     `bun:"clinic_id,notnull"`
+	EventID        int64     `bun:"event_id,notnull"`
+	EventType      string    `bun:"event_type,notnull"`
+	Payload        string    `bun:"payload,notnull"`
+	Status         string    `bun:"status,notnull"`
+	AttemptCount   int       `bun:"attempt_count,notnull"`
+	NextAttemptAt  time.Time `bun:"next_attempt_at,notnull"`
+	CreatedAt      time.Time `bun:"created_at,notnull"`
+}
+
+type attemptDAO struct {
+	bun.BaseModel `bun:"table:webhook_attempt,alias:webhook_attempt"`
+
+	ID           int64     `bun:"id,pk,autoincrement"`
+	DeliveryID   int64     `bun:"delivery_id,notnull"`
+	StatusCode   int       `bun:"status_code,nullzero"`
+	ResponseBody string    `bun:"response_body,nullzero"`
+	Error        string    `bun:"error,nullzero"`
+	DurationMS   int64     `bun:"duration_ms,notnull"`
+	AttemptedAt  time.Time `bun:"attempted_at,notnull"`
+}
+
+func patientFromDAOToDomain(p patientDAO) patient.Patient {
+	return patient.Patient{
+		ID:        patient.ID(p.ID),
+		Firstname: p.Firstname,
+		Lastname:  p.Lastname,
+		Email:     patient.Email(p.Email),
+	}
+}
+
+func consentFromDAOToDomain(c consentDAO) consent.Consent {
+	return consent.Consent{
+		ID:          consent.ID(c.ID),
+		PatientID:   patient.ID(c.PatientID),
+		Purpose:     consent.Purpose(c.Purpose),
+		Status:      consent.Status(c.Status),
+		RecordedAt:  c.RecordedAt,
+		CollectedBy: user.ID(c.CollectedBy),
+		DocumentRef: c.DocumentRef,
+	}
+}
+
+func erasureFromDomainToDAO(e privacy.Erasure) erasureDAO {
+	erasure := erasureDAO{
+		ID:          int64(e.ID),
+		PatientID:   int64(e.PatientID),
+		Status:      string(e.Status),
+		Reason:      e.Reason,
+		RequestedBy: int64(e.RequestedBy),
+		RequestedAt: e.RequestedAt,
+		ReviewedBy:  int64(e.ReviewedBy),
+	}
+	if e.ReviewedAt != nil {
+		erasure.ReviewedAt = *e.ReviewedAt
+	}
+
+	return erasure
+}
+
+func erasureFromDAOToDomain(e erasureDAO) privacy.Erasure {
+	erasure := privacy.Erasure{
+		ID:          privacy.ErasureID(e.ID),
+		PatientID:   patient.ID(e.PatientID),
+		Status:      privacy.ErasureStatus(e.Status),
+		Reason:      e.Reason,
+		RequestedBy: user.ID(e.RequestedBy),
+		RequestedAt: e.RequestedAt,
+		ReviewedBy:  user.ID(e.ReviewedBy),
+	}
+	if !e.ReviewedAt.IsZero() {
+		erasure.ReviewedAt = &e.ReviewedAt
+	}
+
+	return erasure
+}
+
+func outboxEventFromDAOToDomain(e outboxEventDAO) event.Event {
+	return event.Event{
+		ID:         event.ID(e.ID),
+		Type:       event.Type(e.Type),
+		ClinicID:   clinic.ID(e.ClinicID),
+		Payload:    json.RawMessage(e.Payload),
+		OccurredAt: e.OccurredAt,
+	}
+}
+
+func deliveryFromDAOToDomain(d deliveryDAO) webhook.Delivery {
+	return webhook.Delivery{
+		ID:             webhook.DeliveryID(d.ID),
+		SubscriptionID: webhook.ID(d.SubscriptionID),
+		ClinicID:       clinic.ID(d.ClinicID),
+		EventID:        event.ID(d.EventID),
+		EventType:      event.Type(d.EventType),
+		Payload:        json.RawMessage(d.Payload),
+		Status:         webhook.Status(d.Status),
+		AttemptCount:   d.AttemptCount,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+	}
+}
+
+func attemptFromDAOToDomain(a attemptDAO) webhook.Attempt {
+	return webhook.Attempt{
+		StatusCode:   a.StatusCode,
+		ResponseBody: a.ResponseBody,
+		Error:        a.Error,
+		DurationMS:   a.DurationMS,
+		AttemptedAt:  a.AttemptedAt,
+	}
+}
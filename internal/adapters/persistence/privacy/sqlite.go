@@ -0,0 +1,13 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect and
+// bodyContains spells the expression which differs
+func NewSQLiteClient(client *bun.DB) privacySVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
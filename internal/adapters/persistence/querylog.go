@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/schema"
+)
+
+// queryLogHook logs the queries with their placeholders rather than their arguments,
+// which hold the names of the patients and the other fields the redaction does not know
+type queryLogHook struct {
+	logger *slog.Logger
+	// logLevel 1 logs the failed queries, 2 every query
+	logLevel int
+}
+
+func newQueryLogHook(logger *slog.Logger, logLevel int) bun.QueryHook {
+	return &queryLogHook{logger: logger, logLevel: logLevel}
+}
+
+func (h *queryLogHook) BeforeQuery(ctx context.Context, _ *bun.QueryEvent) context.Context {
+	return ctx
+}
+
+func (h *queryLogHook) AfterQuery(ctx context.Context, event *bun.QueryEvent) {
+	failed := event.Err != nil && !errors.Is(event.Err, sql.ErrNoRows) && !errors.Is(event.Err, sql.ErrTxDone)
+	if h.logLevel < 1 || (h.logLevel < 2 && !failed) {
+		return
+	}
+
+	attrs := []any{"operation", event.Operation(), "query", queryTemplate(event), "duration", time.Since(event.StartTime).String()}
+	if failed {
+		h.logger.WarnContext(ctx, "Query failed", append(attrs, "error", event.Err)...)
+		return
+	}
+
+	h.logger.InfoContext(ctx, "Query", attrs...)
+}
+
+// queryTemplate formats the query without its arguments, the raw queries are logged as written
+func queryTemplate(event *bun.QueryEvent) string {
+	if event.IQuery != nil {
+		if query, err := event.IQuery.AppendQuery(schema.NewNopFormatter(), nil); err == nil {
+			return string(query)
+		}
+	}
+
+	return event.QueryTemplate
+}
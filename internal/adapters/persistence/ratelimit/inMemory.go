@@ -0,0 +1,58 @@
+package persistence
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/ratelimit"
+	rateLimitSVC "github.com/sopial42/cleanic/internal/services/ratelimit"
+)
+
+// pruneEvery is the number of takes between two sweeps of the idle buckets
+const pruneEvery = 1000
+
+type inMemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]bucketEntry
+	takes   int
+}
+
+type bucketEntry struct {
+	bucket ratelimit.Bucket
+	quota  ratelimit.Quota
+}
+
+// NewInMemoryStore keeps the buckets of this replica only
+func NewInMemoryStore() rateLimitSVC.Store {
+	return &inMemoryStore{buckets: map[string]bucketEntry{}}
+}
+
+func (s *inMemoryStore) Take(_ context.Context, key string, quota ratelimit.Quota, now time.Time) (ratelimit.Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, found := s.buckets[key]
+	if !found {
+		entry.bucket = ratelimit.NewBucket(quota, now)
+	}
+
+	bucket, decision := entry.bucket.Take(quota, now)
+	s.buckets[key] = bucketEntry{bucket: bucket, quota: quota}
+
+	s.takes++
+	if s.takes%pruneEvery == 0 {
+		s.prune(now)
+	}
+
+	return decision, nil
+}
+
+// prune forgets the full buckets, a new one is created full anyway
+func (s *inMemoryStore) prune(now time.Time) {
+	for key, entry := range s.buckets {
+		if entry.bucket.Idle(entry.quota, now) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/ratelimit"
+	rateLimitSVC "github.com/sopial42/cleanic/internal/services/ratelimit"
+)
+
+type pgPersistence struct {
+	clientDB  *bun.DB
+	idleAfter time.Duration
+	takes     atomic.Int64
+}
+
+// NewPGClient shares the buckets between the replicas.
+// idleAfter is the longest quota period, a bucket unused for longer is full and can be deleted
+func NewPGClient(client *bun.DB, idleAfter time.Duration) rateLimitSVC.Store {
+	return &pgPersistence{clientDB: client, idleAfter: idleAfter}
+}
+
+// Take locks the bucket row so that concurrent replicas do not both take the last token
+func (p *pgPersistence) Take(ctx context.Context, key string, quota ratelimit.Quota, now time.Time) (ratelimit.Decision, error) {
+	var decision ratelimit.Decision
+	err := p.clientDB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		newBucketDAO := bucketFromDomainToDAO(key, ratelimit.NewBucket(quota, now))
+		_, err := tx.NewInsert().
+			Model(&newBucketDAO).
+			On("CONFLICT (key) DO NOTHING").
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to create bucket: %w", err)
+		}
+
+		var currentDAO bucketDAO
+		err = tx.NewSelect().
+			Model(&currentDAO).
+			Where("key = ?", key).
+			For("UPDATE").
+			Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to lock bucket: %w", err)
+		}
+
+		var bucket ratelimit.Bucket
+		bucket, decision = bucketFromDAOToDomain(currentDAO).Take(quota, now)
+
+		updatedDAO := bucketFromDomainToDAO(key, bucket)
+		_, err = tx.NewUpdate().
+			Model(&updatedDAO).
+			WherePK().
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to update bucket: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return ratelimit.Decision{}, fmt.Errorf("unable to take token for %s: %w", key, err)
+	}
+
+	if p.takes.Add(1)%pruneEvery == 0 {
+		if err := p.deleteIdleBuckets(ctx, now.Add(-p.idleAfter)); err != nil {
+			return ratelimit.Decision{}, err
+		}
+	}
+
+	return decision, nil
+}
+
+func (p *pgPersistence) deleteIdleBuckets(ctx context.Context, olderThan time.Time) error {
+	_, err := p.clientDB.NewDelete().
+		Model((*bucketDAO)(nil)).
+		Where("updated_at < ?", olderThan).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete idle buckets: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package persistence
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/ratelimit"
+)
+
+type bucketDAO struct {
+	bun.BaseModel `bun:"table:rate_limit_bucket"`
+
+	Key       string    `bun:"key,pk"`
+	Tokens    float64   `bun:"tokens,notnull"`
+	UpdatedAt time.Time `bun:"updated_at,notnull"`
+}
+
+func bucketFromDomainToDAO(key string, bucket ratelimit.Bucket) bucketDAO {
+	return bucketDAO{
+		Key:       key,
+		Tokens:    bucket.Tokens,
+		UpdatedAt: bucket.UpdatedAt,
+	}
+}
+
+func bucketFromDAOToDomain(bucketDAO bucketDAO) ratelimit.Bucket {
+	return ratelimit.Bucket{
+		Tokens:    bucketDAO.Tokens,
+		UpdatedAt: bucketDAO.UpdatedAt,
+	}
+}
@@ -0,0 +1,141 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) roleSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+// ListRoles lists the roles of the context clinic
+func (m *inMemory) ListRoles(ctx context.Context) ([]user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	roles := []user.RoleDefinition{}
+	for key, role := range m.db.Roles {
+		if key.ClinicID == clinicID {
+			roles = append(roles, cloneRole(role))
+		}
+	}
+
+	slices.SortFunc(roles, func(a, b user.RoleDefinition) int { return cmp.Compare(a.Name, b.Name) })
+	return roles, nil
+}
+
+func (m *inMemory) GetRole(ctx context.Context, name user.Role) (user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to get role %s: %w", name, err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	role, found := m.db.Roles[persistence.RoleKey{ClinicID: clinicID, Name: name}]
+	if !found {
+		return user.RoleDefinition{}, fmt.Errorf("unable to get role %s: %w", name, sql.ErrNoRows)
+	}
+
+	return cloneRole(role), nil
+}
+
+// InsertRole binds the role to the context clinic
+func (m *inMemory) InsertRole(ctx context.Context, newRole user.RoleDefinition) (user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to insert role: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckClinic(clinicID); err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to insert role: %w", err)
+	}
+
+	key := persistence.RoleKey{ClinicID: clinicID, Name: newRole.Name}
+	if _, found := m.db.Roles[key]; found {
+		return user.RoleDefinition{}, fmt.Errorf("unable to insert role: duplicate key value violates unique constraint \"role_pkey\"")
+	}
+
+	m.db.Roles[key] = cloneRole(newRole)
+	return cloneRole(newRole), nil
+}
+
+// UpdateRole never updates the builtin flag
+func (m *inMemory) UpdateRole(ctx context.Context, updatedRole user.RoleDefinition) (user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to update role: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	key := persistence.RoleKey{ClinicID: clinicID, Name: updatedRole.Name}
+	role, found := m.db.Roles[key]
+	if !found {
+		return user.RoleDefinition{}, fmt.Errorf("no role found with name: %s", updatedRole.Name)
+	}
+
+	role.Description = updatedRole.Description
+	role.Permissions = updatedRole.Permissions
+	m.db.Roles[key] = cloneRole(role)
+	return cloneRole(role), nil
+}
+
+func (m *inMemory) DeleteRole(ctx context.Context, name user.Role) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete role %s: %w", name, err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	key := persistence.RoleKey{ClinicID: clinicID, Name: name}
+	if role, found := m.db.Roles[key]; found && !role.Builtin {
+		delete(m.db.Roles, key)
+	}
+
+	return nil
+}
+
+// CountUsersWithRole counts the memberships of the context clinic
+func (m *inMemory) CountUsersWithRole(ctx context.Context, name user.Role) (int, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count users with role %s: %w", name, err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	count := 0
+	for key, roles := range m.db.ClinicMembers {
+		if key.ClinicID == clinicID && roles.Has(name) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func cloneRole(role user.RoleDefinition) user.RoleDefinition {
+	role.Permissions = append(user.Permissions{}, role.Permissions...)
+	return role
+}
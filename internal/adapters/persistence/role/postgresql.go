@@ -0,0 +1,144 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+}
+
+func NewPGClient(client *bun.DB) roleSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
+
+// ListRoles lists the roles of the context clinic
+func (p *pgPersistence) ListRoles(ctx context.Context) ([]user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	var roleDAOs []roleDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&roleDAOs).
+		Where("clinic_id = ?", clinicID).
+		Order("name ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list roles: %w", err)
+	}
+
+	return roleFromDAOsToDomains(roleDAOs), nil
+}
+
+func (p *pgPersistence) GetRole(ctx context.Context, name user.Role) (user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to get role %s: %w", name, err)
+	}
+
+	var roleDAO roleDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&roleDAO).
+		Where("clinic_id = ?", clinicID).
+		Where("name = ?", name).
+		Scan(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to get role %s: %w", name, err)
+	}
+
+	return roleFromDAOToDomain(roleDAO), nil
+}
+
+// InsertRole binds the role to the context clinic
+func (p *pgPersistence) InsertRole(ctx context.Context, newRole user.RoleDefinition) (user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to insert role: %w", err)
+	}
+
+	roleDAO := roleFromDomainToDAO(newRole)
+	roleDAO.ClinicID = int64(clinicID)
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&roleDAO).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to insert role: %w", err)
+	}
+
+	return roleFromDAOToDomain(roleDAO), nil
+}
+
+// UpdateRole never updates the builtin flag
+func (p *pgPersistence) UpdateRole(ctx context.Context, updatedRole user.RoleDefinition) (user.RoleDefinition, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to update role: %w", err)
+	}
+
+	roleDAO := roleFromDomainToDAO(updatedRole)
+	res, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model(&roleDAO).
+		Column("description", "permissions").
+		Where("clinic_id = ?", clinicID).
+		Where("name = ?", updatedRole.Name).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to update role: %w", err)
+	}
+
+	if rows, err := res.RowsAffected(); err == nil && rows == 0 {
+		return user.RoleDefinition{}, fmt.Errorf("no role found with name: %s", updatedRole.Name)
+	}
+
+	return roleFromDAOToDomain(roleDAO), nil
+}
+
+func (p *pgPersistence) DeleteRole(ctx context.Context, name user.Role) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete role %s: %w", name, err)
+	}
+
+	_, err = persistence.DB(ctx, p.clientDB).NewDelete().
+		Model((*roleDAO)(nil)).
+		Where("clinic_id = ?", clinicID).
+		Where("name = ?", name).
+		Where("builtin = FALSE").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete role %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// CountUsersWithRole counts the memberships of the context clinic
+func (p *pgPersistence) CountUsersWithRole(ctx context.Context, name user.Role) (int, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count users with role %s: %w", name, err)
+	}
+
+	count, err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Table("clinic_member").
+		Where("clinic_id = ?", clinicID).
+		Where("roles @> ?::jsonb", fmt.Sprintf("[%q]", name)).
+		Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count users with role %s: %w", name, err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type roleDAO struct {
+	bun.BaseModel `bun:"table:role"`
+
+	ClinicID    int64    `bun:"clinic_id,pk"`
+	Name        string   `bun:"name,pk"`
+	Description string   `bun:"description,notnull"`
+	Permissions []string `bun:"permissions,type:jsonb,notnull"`
+	Builtin     bool     `bun:"builtin,notnull"`
+}
+
+func roleFromDomainToDAO(role user.RoleDefinition) roleDAO {
+	permissions := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		permissions[i] = string(permission)
+	}
+
+	return roleDAO{
+		Name:        string(role.Name),
+		Description: role.Description,
+		Permissions: permissions,
+		Builtin:     role.Builtin,
+	}
+}
+
+func roleFromDAOToDomain(roleDAO roleDAO) user.RoleDefinition {
+	permissions := make(user.Permissions, len(roleDAO.Permissions))
+	for i, permission := range roleDAO.Permissions {
+		permissions[i] = user.Permission(permission)
+	}
+
+	return user.RoleDefinition{
+		Name:        user.Role(roleDAO.Name),
+		Description: roleDAO.Description,
+		Permissions: permissions,
+		Builtin:     roleDAO.Builtin,
+	}
+}
+
+func roleFromDAOsToDomains(roleDAOs []roleDAO) []user.RoleDefinition {
+	roles := make([]user.RoleDefinition, len(roleDAOs))
+	for i, roleDAO := range roleDAOs {
+		roles[i] = roleFromDAOToDomain(roleDAO)
+	}
+
+	return roles
+}
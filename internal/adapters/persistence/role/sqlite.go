@@ -0,0 +1,41 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+)
+
+// sqlitePersistence runs the queries of pgPersistence but the ones reading inside the JSON columns
+type sqlitePersistence struct {
+	pgPersistence
+}
+
+func NewSQLiteClient(client *bun.DB) roleSVC.Persistence {
+	return &sqlitePersistence{pgPersistence{clientDB: client}}
+}
+
+// CountUsersWithRole looks for the role in the JSON arrays with json_each, SQLite has no containment operator
+func (p *sqlitePersistence) CountUsersWithRole(ctx context.Context, name user.Role) (int, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count users with role %s: %w", name, err)
+	}
+
+	count, err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Table("clinic_member").
+		Where("clinic_id = ?", clinicID).
+		Where("EXISTS (SELECT 1 FROM json_each(clinic_member.roles) WHERE json_each.value = ?)", name).
+		Count(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("unable to count users with role %s: %w", name, err)
+	}
+
+	return count, nil
+}
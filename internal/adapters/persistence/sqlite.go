@@ -0,0 +1,112 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+	"github.com/uptrace/bun/extra/bunotel"
+	// registers the "sqlite" driver, it needs no cgo
+	_ "modernc.org/sqlite"
+
+	"github.com/sopial42/cleanic/internal/adapters/logging"
+	"github.com/sopial42/cleanic/internal/config"
+)
+
+//go:embed sqlite/*.sql
+var sqliteMigrations embed.FS
+
+// SQLiteMigrations are the migrations applied by NewSQLiteClient
+func SQLiteMigrations() fs.FS {
+	migrations, _ := fs.Sub(sqliteMigrations, "sqlite")
+	return migrations
+}
+
+// NewSQLiteClient opens the database file, creating it if needed, and applies the migrations
+// that are not recorded in cfg.MigrationsTable yet, like sql-migrate does for PostgreSQL
+func NewSQLiteClient(ctx context.Context, cfg config.DBConfig) (*bun.DB, error) {
+	logger := logging.Component("persistence")
+	pragmas := url.Values{"_pragma": {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"}}
+	sqldb, err := sql.Open("sqlite", "file:"+cfg.SQLitePath+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database %s: %w", cfg.SQLitePath, err)
+	}
+
+	// SQLite has a single writer, one connection avoids the busy errors of concurrent transactions
+	sqldb.SetMaxOpenConns(1)
+
+	client := bun.NewDB(sqldb, sqlitedialect.New())
+	client.AddQueryHook(newQueryLogHook(logger, cfg.LogLevel))
+	client.AddQueryHook(bunotel.NewQueryHook(bunotel.WithDBName(path.Base(cfg.SQLitePath))))
+
+	if err := migrateSQLite(ctx, client, cfg.MigrationsTable); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// migrateSQLite runs the Up section of each migration in its own transaction, in the order of their number
+func migrateSQLite(ctx context.Context, client *bun.DB, migrationsTable string) error {
+	_, err := client.NewRaw("CREATE TABLE IF NOT EXISTS ? (id TEXT NOT NULL PRIMARY KEY, applied_at TIMESTAMP)", bun.Ident(migrationsTable)).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to create the migrations table: %w", err)
+	}
+
+	migrations, err := sqliteMigrations.ReadDir("sqlite")
+	if err != nil {
+		return fmt.Errorf("unable to list the sqlite migrations: %w", err)
+	}
+
+	slices.SortFunc(migrations, func(a, b fs.DirEntry) int { return migrationNumber(a.Name()) - migrationNumber(b.Name()) })
+	for _, migration := range migrations {
+		applied, err := client.NewSelect().
+			Table(migrationsTable).
+			Where("id = ?", migration.Name()).
+			Exists(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to read applied migrations: %w", err)
+		}
+
+		if applied {
+			continue
+		}
+
+		content, err := sqliteMigrations.ReadFile("sqlite/" + migration.Name())
+		if err != nil {
+			return fmt.Errorf("unable to read migration %s: %w", migration.Name(), err)
+		}
+
+		err = client.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+			if _, err := tx.ExecContext(ctx, upSection(string(content))); err != nil {
+				return err
+			}
+
+			_, err := tx.NewRaw("INSERT INTO ? (id, applied_at) VALUES (?, ?)", bun.Ident(migrationsTable), migration.Name(), time.Now()).
+				Exec(ctx)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to apply migration %s: %w", migration.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func upSection(migration string) string {
+	_, up, _ := strings.Cut(migration, "-- +migrate Up")
+	up, _, _ = strings.Cut(up, "-- +migrate Down")
+	return up
+}
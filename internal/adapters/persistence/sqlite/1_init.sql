@@ -0,0 +1,144 @@
+-- The SQLite schema matches the PostgreSQL one of internal/adapters/persistence/postgresql, the JSONB columns
+-- are JSON text and the UUID ones their text form. The clinic, patient and users sequences start at 10001
+-- like the PostgreSQL ones
+
+-- +migrate Up
+CREATE TABLE clinic (
+  id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+  name        TEXT      NOT NULL UNIQUE CHECK (name <> ''),
+  created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO clinic (id, name) VALUES (1, 'default');
+
+CREATE TABLE patient (
+  id         INTEGER PRIMARY KEY AUTOINCREMENT,
+  firstname  TEXT    NOT NULL,
+  lastname   TEXT    NOT NULL,
+  email      TEXT    NOT NULL,
+  clinic_id  INTEGER NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  CONSTRAINT patient_clinic_id_email_key UNIQUE (clinic_id, email)
+);
+
+CREATE TABLE users (
+  id                   INTEGER   PRIMARY KEY AUTOINCREMENT,
+  password             TEXT      NOT NULL CHECK (password <> ''),
+  email                TEXT      NOT NULL UNIQUE,
+  password_changed_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  service_account      BOOLEAN   NOT NULL DEFAULT FALSE
+);
+
+CREATE TABLE clinic_member (
+  clinic_id  INTEGER NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  user_id    INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  roles      TEXT    NOT NULL CHECK (json_valid(roles)),
+  PRIMARY KEY (clinic_id, user_id)
+);
+
+CREATE INDEX clinic_member_user_id_idx ON clinic_member (user_id);
+
+CREATE TABLE refresh_token (
+  id          TEXT      PRIMARY KEY,
+  user_id     INTEGER   NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+  issued_at   TIMESTAMP NOT NULL,
+  expires_at  TIMESTAMP NOT NULL,
+  clinic_id   INTEGER   NOT NULL REFERENCES clinic(id) ON DELETE CASCADE
+);
+
+CREATE TABLE login_attempt (
+  scope            TEXT      NOT NULL,
+  key              TEXT      NOT NULL,
+  failures         INTEGER   NOT NULL DEFAULT 0,
+  last_failure_at  TIMESTAMP NOT NULL,
+  locked_until     TIMESTAMP,
+  PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE password_history (
+  id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+  user_id     INTEGER   NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  password    TEXT      NOT NULL,
+  created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX password_history_user_id_idx ON password_history (user_id, created_at DESC);
+
+CREATE TABLE role (
+  name         TEXT    PRIMARY KEY,
+  description  TEXT    NOT NULL DEFAULT '',
+  permissions  TEXT    NOT NULL CHECK (json_valid(permissions)),
+  builtin      BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO role (name, description, permissions, builtin) VALUES
+  ('admin', 'Full access', '["patient:read", "patient:write", "user:read", "user:manage", "role:manage", "profile:write", "clinic:manage"]', TRUE),
+  ('doctor', 'Reads and writes patient records', '["patient:read", "patient:write", "profile:write"]', TRUE),
+  ('nurse', 'Reads patient records', '["patient:read", "profile:write"]', FALSE),
+  ('receptionist', 'Registers and updates patients', '["patient:read", "patient:write", "profile:write"]', FALSE),
+  ('billing', 'Reads patient records for invoicing', '["patient:read", "profile:write"]', FALSE);
+
+CREATE TABLE api_key (
+  id            INTEGER   PRIMARY KEY AUTOINCREMENT,
+  user_id       INTEGER   NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  clinic_id     INTEGER   NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  name          TEXT      NOT NULL,
+  prefix        TEXT      NOT NULL UNIQUE,
+  hash          TEXT      NOT NULL,
+  scopes        TEXT      NOT NULL CHECK (json_valid(scopes)),
+  expires_at    TIMESTAMP,
+  last_used_at  TIMESTAMP,
+  revoked_at    TIMESTAMP,
+  created_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX api_key_user_id_idx ON api_key (user_id);
+
+CREATE TABLE idempotency_key (
+  subject       TEXT      NOT NULL,
+  key           TEXT      NOT NULL,
+  fingerprint   TEXT      NOT NULL,
+  completed     BOOLEAN   NOT NULL DEFAULT FALSE,
+  status_code   INTEGER,
+  content_type  TEXT,
+  body          BLOB,
+  created_at    TIMESTAMP NOT NULL,
+  expires_at    TIMESTAMP NOT NULL,
+  PRIMARY KEY (subject, key)
+);
+
+CREATE INDEX idempotency_key_expires_at_idx ON idempotency_key (expires_at);
+
+DELETE FROM sqlite_sequence WHERE name IN ('clinic', 'patient', 'users');
+INSERT INTO sqlite_sequence (name, seq) VALUES ('clinic', 10000), ('patient', 10000), ('users', 10000);
+
+-- An emptied table numbers its rows from 10001 again, the integration fixtures empty the tables
+-- with DELETE which, unlike the PostgreSQL ones, can not restart the sequences
+CREATE TRIGGER clinic_restart_sequence AFTER DELETE ON clinic WHEN NOT EXISTS (SELECT 1 FROM clinic)
+BEGIN
+  UPDATE sqlite_sequence SET seq = 10000 WHERE name = 'clinic';
+END;
+
+CREATE TRIGGER patient_restart_sequence AFTER DELETE ON patient WHEN NOT EXISTS (SELECT 1 FROM patient)
+BEGIN
+  UPDATE sqlite_sequence SET seq = 10000 WHERE name = 'patient';
+END;
+
+CREATE TRIGGER users_restart_sequence AFTER DELETE ON users WHEN NOT EXISTS (SELECT 1 FROM users)
+BEGIN
+  UPDATE sqlite_sequence SET seq = 10000 WHERE name = 'users';
+END;
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS users_restart_sequence;
+DROP TRIGGER IF EXISTS patient_restart_sequence;
+DROP TRIGGER IF EXISTS clinic_restart_sequence;
+DROP TABLE IF EXISTS idempotency_key;
+DROP TABLE IF EXISTS api_key;
+DROP TABLE IF EXISTS role;
+DROP TABLE IF EXISTS password_history;
+DROP TABLE IF EXISTS login_attempt;
+DROP TABLE IF EXISTS refresh_token;
+DROP TABLE IF EXISTS clinic_member;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS patient;
+DROP TABLE IF EXISTS clinic;
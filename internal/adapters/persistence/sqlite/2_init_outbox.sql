@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE outbox_event (
+  id               INTEGER   PRIMARY KEY AUTOINCREMENT,
+  type             TEXT      NOT NULL,
+  clinic_id        INTEGER   NOT NULL,
+  payload          TEXT      NOT NULL CHECK (json_valid(payload)),
+  occurred_at      TIMESTAMP NOT NULL,
+  attempts         INTEGER   NOT NULL DEFAULT 0,
+  next_attempt_at  TIMESTAMP NOT NULL,
+  locked_until     TIMESTAMP,
+  last_error       TEXT
+);
+
+CREATE INDEX outbox_event_next_attempt_at_idx ON outbox_event (next_attempt_at);
+
+-- +migrate Down
+DROP TABLE IF EXISTS outbox_event;
@@ -0,0 +1,58 @@
+-- +migrate Up
+CREATE TABLE webhook_subscription (
+  id           INTEGER   PRIMARY KEY AUTOINCREMENT,
+  clinic_id    INTEGER   NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  url          TEXT      NOT NULL,
+  event_types  TEXT      NOT NULL CHECK (json_valid(event_types)),
+  secret       TEXT      NOT NULL,
+  created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX webhook_subscription_clinic_id_idx ON webhook_subscription (clinic_id);
+
+CREATE TABLE webhook_delivery (
+  id               INTEGER   PRIMARY KEY AUTOINCREMENT,
+  subscription_id  INTEGER   NOT NULL REFERENCES webhook_subscription(id) ON DELETE CASCADE,
+  clinic_id        INTEGER   NOT NULL,
+  event_id         INTEGER   NOT NULL,
+  event_type       TEXT      NOT NULL,
+  payload          TEXT      NOT NULL CHECK (json_valid(payload)),
+  status           TEXT      NOT NULL,
+  attempt_count    INTEGER   NOT NULL DEFAULT 0,
+  next_attempt_at  TIMESTAMP NOT NULL,
+  locked_until     TIMESTAMP,
+  created_at       TIMESTAMP NOT NULL,
+  UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX webhook_delivery_pending_idx ON webhook_delivery (next_attempt_at) WHERE status = 'pending';
+
+CREATE TABLE webhook_attempt (
+  id             INTEGER   PRIMARY KEY AUTOINCREMENT,
+  delivery_id    INTEGER   NOT NULL REFERENCES webhook_delivery(id) ON DELETE CASCADE,
+  status_code    INTEGER,
+  response_body  TEXT,
+  error          TEXT,
+  duration_ms    INTEGER   NOT NULL,
+  attempted_at   TIMESTAMP NOT NULL
+);
+
+CREATE INDEX webhook_attempt_delivery_id_idx ON webhook_attempt (delivery_id);
+
+INSERT INTO sqlite_sequence (name, seq) VALUES ('webhook_subscription', 10000);
+
+-- An emptied table numbers its rows from 10001 again, like the tables of 1_init.sql
+CREATE TRIGGER webhook_subscription_restart_sequence AFTER DELETE ON webhook_subscription WHEN NOT EXISTS (SELECT 1 FROM webhook_subscription)
+BEGIN
+  UPDATE sqlite_sequence SET seq = 10000 WHERE name = 'webhook_subscription';
+END;
+
+UPDATE role SET permissions = json_insert(permissions, '$[#]', 'webhook:manage') WHERE name = 'admin';
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS webhook_subscription_restart_sequence;
+UPDATE role SET permissions = (SELECT json_group_array(value) FROM json_each(role.permissions) WHERE value <> 'webhook:manage') WHERE name = 'admin';
+DROP TABLE IF EXISTS webhook_attempt;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook_subscription;
+DELETE FROM sqlite_sequence WHERE name = 'webhook_subscription';
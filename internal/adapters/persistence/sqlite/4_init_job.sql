@@ -0,0 +1,25 @@
+-- +migrate Up
+CREATE TABLE job (
+  id            INTEGER   PRIMARY KEY AUTOINCREMENT,
+  kind          TEXT      NOT NULL,
+  clinic_id     INTEGER   REFERENCES clinic(id) ON DELETE CASCADE,
+  payload       TEXT      NOT NULL CHECK (json_valid(payload)),
+  unique_key    TEXT      UNIQUE,
+  state         TEXT      NOT NULL,
+  attempts      INTEGER   NOT NULL DEFAULT 0,
+  max_attempts  INTEGER   NOT NULL,
+  run_at        TIMESTAMP NOT NULL,
+  locked_until  TIMESTAMP,
+  last_error    TEXT,
+  created_at    TIMESTAMP NOT NULL,
+  finished_at   TIMESTAMP
+);
+
+CREATE INDEX job_due_idx ON job (run_at) WHERE state IN ('queued', 'running');
+CREATE INDEX job_clinic_id_idx ON job (clinic_id);
+
+UPDATE role SET permissions = json_insert(permissions, '$[#]', 'job:read') WHERE name = 'admin';
+
+-- +migrate Down
+UPDATE role SET permissions = (SELECT json_group_array(value) FROM json_each(role.permissions) WHERE value <> 'job:read') WHERE name = 'admin';
+DROP TABLE IF EXISTS job;
@@ -0,0 +1,18 @@
+-- +migrate Up
+CREATE TABLE auth_session (
+  id          INTEGER   PRIMARY KEY AUTOINCREMENT,
+  user_id     INTEGER   NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  clinic_id   INTEGER   NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  method      TEXT      NOT NULL,
+  started_at  TIMESTAMP NOT NULL,
+  ended_at    TIMESTAMP
+);
+
+CREATE INDEX auth_session_clinic_id_started_at_idx ON auth_session (clinic_id, started_at);
+CREATE INDEX auth_session_user_id_idx ON auth_session (user_id) WHERE ended_at IS NULL;
+CREATE INDEX auth_session_ended_at_idx ON auth_session (ended_at);
+CREATE INDEX refresh_token_expires_at_idx ON refresh_token (expires_at);
+
+-- +migrate Down
+DROP INDEX IF EXISTS refresh_token_expires_at_idx;
+DROP TABLE IF EXISTS auth_session;
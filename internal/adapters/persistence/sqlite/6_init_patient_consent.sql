@@ -0,0 +1,28 @@
+-- +migrate Up
+CREATE TABLE patient_consent (
+  id            INTEGER   PRIMARY KEY AUTOINCREMENT,
+  clinic_id     INTEGER   NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  patient_id    INTEGER   NOT NULL REFERENCES patient(id) ON DELETE CASCADE,
+  purpose       TEXT      NOT NULL,
+  status        TEXT      NOT NULL,
+  collected_by  INTEGER   REFERENCES users(id) ON DELETE SET NULL,
+  recorded_at   TIMESTAMP NOT NULL,
+  document_ref  TEXT
+);
+
+CREATE INDEX patient_consent_patient_id_idx ON patient_consent (patient_id);
+CREATE INDEX patient_consent_clinic_id_purpose_idx ON patient_consent (clinic_id, purpose, patient_id);
+CREATE INDEX patient_consent_collected_by_idx ON patient_consent (collected_by);
+
+INSERT INTO sqlite_sequence (name, seq) VALUES ('patient_consent', 10000);
+
+-- An emptied table numbers its rows from 10001 again, like the tables of 1_init.sql
+CREATE TRIGGER patient_consent_restart_sequence AFTER DELETE ON patient_consent WHEN NOT EXISTS (SELECT 1 FROM patient_consent)
+BEGIN
+  UPDATE sqlite_sequence SET seq = 10000 WHERE name = 'patient_consent';
+END;
+
+-- +migrate Down
+DROP TRIGGER IF EXISTS patient_consent_restart_sequence;
+DROP TABLE IF EXISTS patient_consent;
+DELETE FROM sqlite_sequence WHERE name = 'patient_consent';
@@ -0,0 +1,34 @@
+-- +migrate Up
+CREATE TABLE patient_erasure (
+  id            INTEGER   PRIMARY KEY AUTOINCREMENT,
+  clinic_id     INTEGER   NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  patient_id    INTEGER   NOT NULL REFERENCES patient(id) ON DELETE CASCADE,
+  status        TEXT      NOT NULL,
+  reason        TEXT,
+  requested_by  INTEGER   REFERENCES users(id) ON DELETE SET NULL,
+  requested_at  TIMESTAMP NOT NULL,
+  reviewed_by   INTEGER   REFERENCES users(id) ON DELETE SET NULL,
+  reviewed_at   TIMESTAMP
+);
+
+CREATE INDEX patient_erasure_patient_id_idx ON patient_erasure (patient_id);
+CREATE INDEX patient_erasure_clinic_id_status_idx ON patient_erasure (clinic_id, status);
+CREATE INDEX patient_erasure_requested_by_idx ON patient_erasure (requested_by);
+CREATE INDEX patient_erasure_reviewed_by_idx ON patient_erasure (reviewed_by);
+CREATE UNIQUE INDEX patient_erasure_pending_idx ON patient_erasure (patient_id) WHERE status = 'pending';
+
+INSERT INTO sqlite_sequence (name, seq) VALUES ('patient_erasure', 10000);
+
+-- An emptied table numbers its rows from 10001 again, like the tables of 1_init.sql
+CREATE TRIGGER patient_erasure_restart_sequence AFTER DELETE ON patient_erasure WHEN NOT EXISTS (SELECT 1 FROM patient_erasure)
+BEGIN
+  UPDATE sqlite_sequence SET seq = 10000 WHERE name = 'patient_erasure';
+END;
+
+UPDATE role SET permissions = json_insert(permissions, '$[#]', 'privacy:manage') WHERE name = 'admin';
+
+-- +migrate Down
+UPDATE role SET permissions = (SELECT json_group_array(value) FROM json_each(role.permissions) WHERE value <> 'privacy:manage') WHERE name = 'admin';
+DROP TRIGGER IF EXISTS patient_erasure_restart_sequence;
+DROP TABLE IF EXISTS patient_erasure;
+DELETE FROM sqlite_sequence WHERE name = 'patient_erasure';
@@ -0,0 +1,32 @@
+-- +migrate Up
+-- Each clinic manages its own roles, the existing ones are copied to every clinic.
+-- SQLite can not change a primary key, the table is rebuilt
+CREATE TABLE role_clinic (
+  clinic_id    INTEGER NOT NULL REFERENCES clinic(id) ON DELETE CASCADE,
+  name         TEXT    NOT NULL,
+  description  TEXT    NOT NULL DEFAULT '',
+  permissions  TEXT    NOT NULL CHECK (json_valid(permissions)),
+  builtin      BOOLEAN NOT NULL DEFAULT FALSE,
+  PRIMARY KEY (clinic_id, name)
+);
+
+INSERT INTO role_clinic (clinic_id, name, description, permissions, builtin)
+  SELECT clinic.id, role.name, role.description, role.permissions, role.builtin
+  FROM clinic CROSS JOIN role;
+
+DROP TABLE role;
+ALTER TABLE role_clinic RENAME TO role;
+
+-- +migrate Down
+CREATE TABLE role_shared (
+  name         TEXT    PRIMARY KEY,
+  description  TEXT    NOT NULL DEFAULT '',
+  permissions  TEXT    NOT NULL CHECK (json_valid(permissions)),
+  builtin      BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO role_shared (name, description, permissions, builtin)
+  SELECT name, description, permissions, builtin FROM role WHERE clinic_id = 1;
+
+DROP TABLE role;
+ALTER TABLE role_shared RENAME TO role;
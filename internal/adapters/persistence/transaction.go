@@ -0,0 +1,83 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/driver/pgdriver"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+
+	"github.com/sopial42/cleanic/internal/services/transaction"
+)
+
+// maxTxAttempts bounds the runs of a unit of work failing on serialization
+const maxTxAttempts = 3
+
+type txKey struct{}
+
+type unitOfWork struct {
+	client *bun.DB
+}
+
+// NewUnitOfWork runs the units of work in serializable transactions of client,
+// the adapters built on client take part in them through DB
+func NewUnitOfWork(client *bun.DB) transaction.UnitOfWork {
+	return &unitOfWork{client: client}
+}
+
+// DB returns the transaction of the unit of work running in ctx, or client outside of one
+func DB(ctx context.Context, client *bun.DB) bun.IDB {
+	if tx, ok := ctx.Value(txKey{}).(bun.Tx); ok {
+		return tx
+	}
+
+	return client
+}
+
+func (u *unitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(bun.Tx); ok {
+		return fn(ctx)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := u.client.RunInTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable}, func(ctx context.Context, tx bun.Tx) error {
+			return fn(context.WithValue(ctx, txKey{}, tx))
+		})
+		if err == nil || !isSerializationFailure(err) {
+			return err
+		}
+
+		if attempt == maxTxAttempts {
+			return fmt.Errorf("unable to commit after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 10 * time.Millisecond):
+		}
+	}
+}
+
+// isSerializationFailure tells whether the transaction lost against a concurrent one and may succeed if run again
+func isSerializationFailure(err error) bool {
+	var pgErr pgdriver.Error
+	if errors.As(err, &pgErr) {
+		// serialization_failure and deadlock_detected
+		code := pgErr.Field('C')
+		return code == "40001" || code == "40P01"
+	}
+
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code() & 0xff
+		return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+	}
+
+	return false
+}
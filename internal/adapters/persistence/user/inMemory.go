@@ -0,0 +1,239 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) userSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+// Insert creates the user as a member of the context clinic, with the roles of newUser
+func (m *inMemory) Insert(ctx context.Context, newUser user.User) (user.User, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to create a new user: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckClinic(clinicID); err != nil {
+		return user.User{}, fmt.Errorf("unable to create a new user: %w", err)
+	}
+
+	if newUser.Password == "" {
+		return user.User{}, fmt.Errorf("unable to create a new user: empty password")
+	}
+
+	if err := m.checkUniqueEmail(newUser); err != nil {
+		return user.User{}, fmt.Errorf("unable to create a new user: %w", err)
+	}
+
+	if newUser.ID == 0 {
+		newUser.ID = user.ID(m.db.NextID("users"))
+	}
+
+	if _, found := m.db.Users[newUser.ID]; found {
+		return user.User{}, fmt.Errorf("unable to create a new user: duplicate id %d", newUser.ID)
+	}
+
+	if newUser.PasswordChangedAt.IsZero() {
+		newUser.PasswordChangedAt = time.Now()
+	}
+
+	newUser.Roles = append(user.Roles{}, newUser.Roles...)
+	m.db.Users[newUser.ID] = withoutRoles(newUser)
+	m.db.ClinicMembers[persistence.ClinicMemberKey{ClinicID: clinicID, UserID: newUser.ID}] = append(user.Roles{}, newUser.Roles...)
+	return newUser, nil
+}
+
+// ListUsers only returns the members of the context clinic
+func (m *inMemory) ListUsers(ctx context.Context) ([]user.User, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list users: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	users := []user.User{}
+	for key, roles := range m.db.ClinicMembers {
+		if key.ClinicID == clinicID {
+			users = append(users, withRoles(m.db.Users[key.UserID], roles))
+		}
+	}
+
+	slices.SortFunc(users, func(a, b user.User) int { return cmp.Compare(a.ID, b.ID) })
+	return users, nil
+}
+
+// GetUserByID fails for the users that are not members of the context clinic
+func (m *inMemory) GetUserByID(ctx context.Context, id user.ID) (user.User, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to get user by ID: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	return m.getMember(clinicID, id)
+}
+
+// GetUserByEmail is not scoped as it identifies the user before any clinic is chosen, the roles are left empty
+func (m *inMemory) GetUserByEmail(ctx context.Context, email user.Email) (user.User, error) {
+	defer m.db.RLock(ctx)()
+
+	for _, existing := range m.db.Users {
+		if existing.Email == email {
+			return existing, nil
+		}
+	}
+
+	return user.User{}, fmt.Errorf("unable to get user by email: %w", sql.ErrNoRows)
+}
+
+// UpdateUser only updates the non zero email, password and password change time,
+// of the members of the context clinic when there is one
+func (m *inMemory) UpdateUser(ctx context.Context, updatedUser user.User) (user.User, error) {
+	clinicID, clinicErr := clinic.IDFromContext(ctx)
+
+	defer m.db.Lock(ctx)()
+
+	existing, found := m.db.Users[updatedUser.ID]
+	if !found {
+		return user.User{}, fmt.Errorf("unable to update user: %w", sql.ErrNoRows)
+	}
+
+	if clinicErr == nil {
+		if _, err := m.getMember(clinicID, existing.ID); err != nil {
+			return user.User{}, fmt.Errorf("unable to update user: %w", err)
+		}
+	}
+
+	existing.Email = cmp.Or(updatedUser.Email, existing.Email)
+	existing.Password = cmp.Or(updatedUser.Password, existing.Password)
+	if !updatedUser.PasswordChangedAt.IsZero() {
+		existing.PasswordChangedAt = updatedUser.PasswordChangedAt
+	}
+
+	if err := m.checkUniqueEmail(existing); err != nil {
+		return user.User{}, fmt.Errorf("unable to update user: %w", err)
+	}
+
+	m.db.Users[existing.ID] = existing
+
+	// The password rotation runs before any clinic is chosen
+	if clinicErr != nil {
+		return existing, nil
+	}
+
+	return m.getMember(clinicID, existing.ID)
+}
+
+// UpdateUserRoles will only update user roles in the context clinic
+func (m *inMemory) UpdateUserRoles(ctx context.Context, updatedUser user.User) (user.User, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to update user roles: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	key := persistence.ClinicMemberKey{ClinicID: clinicID, UserID: updatedUser.ID}
+	if _, found := m.db.ClinicMembers[key]; !found {
+		return user.User{}, fmt.Errorf("unable to update user roles: user %d is not a member of clinic %d", updatedUser.ID, clinicID)
+	}
+
+	m.db.ClinicMembers[key] = append(user.Roles{}, updatedUser.Roles...)
+	return m.getMember(clinicID, updatedUser.ID)
+}
+
+// DeleteUser removes the user from the context clinic, the user itself is deleted along with its last membership
+func (m *inMemory) DeleteUser(ctx context.Context, userIDToDelete user.ID) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete user id: %d, err: %w", userIDToDelete, err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	delete(m.db.ClinicMembers, persistence.ClinicMemberKey{ClinicID: clinicID, UserID: userIDToDelete})
+	for key := range m.db.ClinicMembers {
+		if key.UserID == userIDToDelete {
+			return nil
+		}
+	}
+
+	m.db.DeleteUser(userIDToDelete)
+	return nil
+}
+
+func (m *inMemory) ListPasswordHistory(ctx context.Context, userID user.ID, limit int) ([]user.Password, error) {
+	defer m.db.RLock(ctx)()
+
+	// the history is stored oldest first
+	history := m.db.PasswordHistory[userID]
+	hashes := make([]user.Password, 0, len(history))
+	for i := len(history) - 1; i >= 0 && (limit <= 0 || len(hashes) < limit); i-- {
+		hashes = append(hashes, history[i])
+	}
+
+	return hashes, nil
+}
+
+func (m *inMemory) InsertPasswordHistory(ctx context.Context, userID user.ID, hash user.Password) error {
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckUser(userID); err != nil {
+		return fmt.Errorf("unable to insert password history: %w", err)
+	}
+
+	m.db.PasswordHistory[userID] = append(m.db.PasswordHistory[userID], hash)
+	return nil
+}
+
+// getMember joins the roles of the user in the clinic, the lock must be held
+func (m *inMemory) getMember(clinicID clinic.ID, id user.ID) (user.User, error) {
+	roles, found := m.db.ClinicMembers[persistence.ClinicMemberKey{ClinicID: clinicID, UserID: id}]
+	if !found {
+		return user.User{}, fmt.Errorf("unable to get user by ID: %w", sql.ErrNoRows)
+	}
+
+	return withRoles(m.db.Users[id], roles), nil
+}
+
+// checkUniqueEmail is the email unique constraint, the lock must be held
+func (m *inMemory) checkUniqueEmail(u user.User) error {
+	for _, existing := range m.db.Users {
+		if existing.Email == u.Email && existing.ID != u.ID {
+			return fmt.Errorf("duplicate key value violates unique constraint \"users_email_key\"")
+		}
+	}
+
+	return nil
+}
+
+// withoutRoles is the stored user, its roles are stored per clinic
+func withoutRoles(u user.User) user.User {
+	u.Roles = user.Roles{}
+	return u
+}
+
+func withRoles(u user.User, roles user.Roles) user.User {
+	u.Roles = append(user.Roles{}, roles...)
+	return u
+}
@@ -2,10 +2,13 @@ package persistence
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/uptrace/bun"
 
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	user "github.com/sopial42/cleanic/internal/domains/user"
 	userSVC "github.com/sopial42/cleanic/internal/services/user"
 )
@@ -18,30 +21,51 @@ func NewPGClient(client *bun.DB) userSVC.Persistence {
 	return &pgPersistence{clientDB: client}
 }
 
+// Insert creates the user as a member of the context clinic, with the roles of newUser
 func (p *pgPersistence) Insert(ctx context.Context, newUser user.User) (user.User, error) {
-	userDAO := userFromDomainToDAO(newUser)
-
-	_, err := p.clientDB.NewInsert().
-		Model(&userDAO).
-		Returning("*").
-		Exec(ctx)
+	clinicID, err := clinic.IDFromContext(ctx)
 	if err != nil {
-		return user.User{}, fmt.Errorf("err: %w", err)
+		return user.User{}, fmt.Errorf("unable to create a new user: %w", err)
 	}
 
-	// ID == 0 means that the insert failed
-	if userDAO.ID == 0 {
-		return user.User{}, fmt.Errorf("unable to create a new user: %+v", userDAO)
+	userDAO := userFromDomainToDAO(newUser)
+	err = persistence.DB(ctx, p.clientDB).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewInsert().
+			Model(&userDAO).
+			Returning("*").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		// ID == 0 means that the insert failed
+		if userDAO.ID == 0 {
+			return fmt.Errorf("no id returned: %+v", userDAO)
+		}
+
+		memberDAO := clinicMemberDAO{ClinicID: int64(clinicID), UserID: userDAO.ID, Roles: userDAO.Roles}
+		_, err = tx.NewInsert().
+			Model(&memberDAO).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to create a new user: %w", err)
 	}
 
 	return userFromDAOToDomain(userDAO), nil
 }
 
+// ListUsers only returns the members of the context clinic
 func (p *pgPersistence) ListUsers(ctx context.Context) ([]user.User, error) {
 	var userDAOs []UserDAO
 
-	request := p.clientDB.NewSelect().Model(&userDAOs)
-	err := request.Scan(ctx)
+	request, err := p.selectMembers(ctx, &userDAOs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list users: %w", err)
+	}
+
+	err = request.Order("u.id ASC").Scan(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("unable to list users: %w", err)
 	}
@@ -49,13 +73,16 @@ func (p *pgPersistence) ListUsers(ctx context.Context) ([]user.User, error) {
 	return userFromDAOsToDomains(userDAOs), nil
 }
 
+// GetUserByID fails for the users that are not members of the context clinic
 func (p *pgPersistence) GetUserByID(ctx context.Context, id user.ID) (user.User, error) {
 	var userDAO UserDAO
 
-	err := p.clientDB.NewSelect().
-		Model(&userDAO).
-		Where("id = ?", id).
-		Scan(ctx)
+	request, err := p.selectMembers(ctx, &userDAO)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to get user by ID: %w", err)
+	}
+
+	err = request.Where("u.id = ?", id).Scan(ctx)
 	if err != nil {
 		return user.User{}, fmt.Errorf("unable to get user by ID: %w", err)
 	}
@@ -67,10 +94,11 @@ func (p *pgPersistence) GetUserByID(ctx context.Context, id user.ID) (user.User,
 	return userFromDAOToDomain(userDAO), nil
 }
 
+// GetUserByEmail is not scoped as it identifies the user before any clinic is chosen, the roles are left empty
 func (p *pgPersistence) GetUserByEmail(ctx context.Context, email user.Email) (user.User, error) {
 	var userDAO UserDAO
 
-	err := p.clientDB.NewSelect().
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
 		Model(&userDAO).
 		Where("email = ?", email).
 		Scan(ctx)
@@ -86,49 +114,139 @@ func (p *pgPersistence) GetUserByEmail(ctx context.Context, email user.Email) (u
 }
 
 // UpdateUser perform basic updates on a role but never update roles or ID for safety purpose
+// The email and the password are shared by the clinics, the returned roles are the ones of the context clinic
+// Only the members of the context clinic are updated, the password rotation runs before any clinic is chosen
 func (p *pgPersistence) UpdateUser(ctx context.Context, updatedUser user.User) (user.User, error) {
 	userDAO := userFromDomainToDAO(updatedUser)
-	userDAO.Roles = []string{}
+	clinicID, clinicErr := clinic.IDFromContext(ctx)
 
-	_, err := p.clientDB.NewUpdate().
+	query := persistence.DB(ctx, p.clientDB).NewUpdate().
 		Model(&userDAO).
 		Where("id = ?", updatedUser.ID).
 		OmitZero().
-		ExcludeColumn("roles", "id").
-		Returning("*").
-		Exec(ctx)
+		ExcludeColumn("id", "service_account").
+		Returning("*")
+	if clinicErr == nil {
+		query = query.Where("EXISTS (SELECT 1 FROM clinic_member WHERE clinic_member.user_id = u.id AND clinic_member.clinic_id = ?)", clinicID)
+	}
+
+	result, err := query.Exec(ctx)
 	if err != nil {
 		return user.User{}, fmt.Errorf("unable to update user: %w", err)
 	}
 
-	return userFromDAOToDomain(userDAO), nil
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return user.User{}, fmt.Errorf("unable to update user %d: %w", updatedUser.ID, sql.ErrNoRows)
+	}
+
+	if clinicErr != nil {
+		return userFromDAOToDomain(userDAO), nil
+	}
+
+	return p.GetUserByID(ctx, updatedUser.ID)
 }
 
-// UpdateUserRoles will only update user roles
+// UpdateUserRoles will only update user roles in the context clinic
 func (p *pgPersistence) UpdateUserRoles(ctx context.Context, updatedUser user.User) (user.User, error) {
-	userDAO := userFromDomainToDAO(updatedUser)
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to update user roles: %w", err)
+	}
 
-	_, err := p.clientDB.NewUpdate().
-		Model(&userDAO).
-		Where("id = ?", updatedUser.ID).
-		OmitZero().
+	memberDAO := clinicMemberFromDomainToDAO(clinicID, updatedUser)
+	result, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model(&memberDAO).
 		Column("roles").
-		Returning("*").
+		WherePK().
 		Exec(ctx)
 	if err != nil {
 		return user.User{}, fmt.Errorf("unable to update user roles: %w", err)
 	}
 
-	return userFromDAOToDomain(userDAO), nil
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return user.User{}, fmt.Errorf("unable to update user roles: user %d is not a member of clinic %d", updatedUser.ID, clinicID)
+	}
+
+	return p.GetUserByID(ctx, updatedUser.ID)
 }
 
+// DeleteUser removes the user from the context clinic, the user itself is deleted along with its last membership
 func (p *pgPersistence) DeleteUser(ctx context.Context, userIDToDelete user.ID) error {
-	_, err := p.clientDB.NewDelete().
-		Model((*UserDAO)(nil)).
-		Where("id = ?", userIDToDelete).
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete user id: %d, err: %w", userIDToDelete, err)
+	}
+
+	err = persistence.DB(ctx, p.clientDB).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*clinicMemberDAO)(nil)).
+			Where("clinic_id = ?", clinicID).
+			Where("user_id = ?", userIDToDelete).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*UserDAO)(nil)).
+			Where("id = ?", userIDToDelete).
+			Where("NOT EXISTS (SELECT 1 FROM clinic_member WHERE clinic_member.user_id = u.id)").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to delete user id: %d, err: %w", userIDToDelete, err)
+	}
+
+	return nil
+}
+
+// selectMembers joins the roles of the users in the context clinic, the other users are filtered out
+func (p *pgPersistence) selectMembers(ctx context.Context, model any) (*bun.SelectQuery, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(model).
+		ColumnExpr("u.*").
+		ColumnExpr("m.roles").
+		Join("JOIN clinic_member AS m ON m.user_id = u.id").
+		Where("m.clinic_id = ?", clinicID), nil
+}
+
+func (p *pgPersistence) ListPasswordHistory(ctx context.Context, userID user.ID, limit int) ([]user.Password, error) {
+	var historyDAOs []passwordHistoryDAO
+	err := persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&historyDAOs).
+		Where("user_id = ?", userID).
+		OrderExpr("created_at DESC, id DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list password history: %w", err)
+	}
+
+	hashes := make([]user.Password, len(historyDAOs))
+	for i, historyDAO := range historyDAOs {
+		hashes[i] = user.Password(historyDAO.Password)
+	}
+
+	return hashes, nil
+}
+
+func (p *pgPersistence) InsertPasswordHistory(ctx context.Context, userID user.ID, hash user.Password) error {
+	historyDAO := passwordHistoryDAO{
+		UserID:   int64(userID),
+		Password: string(hash),
+	}
+
+	_, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&historyDAO).
 		Exec(ctx)
 	if err != nil {
-		return fmt.Errorf("unable to delete patient id: %d, err: %w", userIDToDelete, err)
+		return fmt.Errorf("unable to insert password history: %w", err)
 	}
 
 	return nil
@@ -1,24 +1,49 @@
 package persistence
 
 import (
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	user "github.com/sopial42/cleanic/internal/domains/user"
 	"github.com/uptrace/bun"
 )
 
 type UserDAO struct {
-	bun.BaseModel `bun:"table:users"`
+	bun.BaseModel `bun:"table:users,alias:u"`
+
+	ID                int64     `bun:"id,pk,autoincrement"`
+	Email             string    `bun:"email,notnull,unique"`
+	Password          string    `bun:"password,notnull"`
+	PasswordChangedAt time.Time `bun:"password_changed_at,nullzero,notnull,default:current_timestamp"`
+	ServiceAccount    bool      `bun:"service_account,notnull"`
+	// Roles are stored per clinic in clinic_member
+	Roles []string `bun:"roles,type:jsonb,scanonly"`
+}
 
-	ID       int64    `bun:"id,pk,autoincrement"`
-	Email    string   `bun:"email,notnull,unique"`
-	Password string   `bun:"password,notnull"`
+type clinicMemberDAO struct {
+	bun.BaseModel `bun:"table:clinic_member"`
+
+	ClinicID int64    `bun:"clinic_id,pk"`
+	UserID   int64    `bun:"user_id,pk"`
 	Roles    []string `bun:"roles,type:jsonb,notnull"`
 }
 
+type passwordHistoryDAO struct {
+	bun.BaseModel `bun:"table:password_history"`
+
+	ID        int64     `bun:"id,pk,autoincrement"`
+	UserID    int64     `bun:"user_id,notnull"`
+	Password  string    `bun:"password,notnull"`
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
 func userFromDomainToDAO(user user.User) UserDAO {
 	userDAO := UserDAO{
-		ID:       int64(user.ID),
-		Email:    string(user.Email),
-		Password: string(user.Password),
+		ID:                int64(user.ID),
+		Email:             string(user.Email),
+		Password:          string(user.Password),
+		PasswordChangedAt: user.PasswordChangedAt,
+		ServiceAccount:    user.ServiceAccount,
 	}
 
 	roles := make([]string, len(user.Roles))
@@ -30,11 +55,21 @@ func userFromDomainToDAO(user user.User) UserDAO {
 	return userDAO
 }
 
+func clinicMemberFromDomainToDAO(clinicID clinic.ID, member user.User) clinicMemberDAO {
+	return clinicMemberDAO{
+		ClinicID: int64(clinicID),
+		UserID:   int64(member.ID),
+		Roles:    userFromDomainToDAO(member).Roles,
+	}
+}
+
 func userFromDAOToDomain(userDAO UserDAO) user.User {
 	domainUser := user.User{
-		ID:       user.ID(userDAO.ID),
-		Email:    user.Email(userDAO.Email),
-		Password: user.Password(userDAO.Password),
+		ID:                user.ID(userDAO.ID),
+		Email:             user.Email(userDAO.Email),
+		Password:          user.Password(userDAO.Password),
+		PasswordChangedAt: userDAO.PasswordChangedAt,
+		ServiceAccount:    userDAO.ServiceAccount,
 	}
 
 	// Convert roles from string slice to domain roles
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	userSVC "github.com/sopial42/cleanic/internal/services/user"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient, bun renders them for the sqlite dialect
+func NewSQLiteClient(client *bun.DB) userSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
@@ -0,0 +1,245 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+type inMemory struct {
+	db *persistence.InMemoryDB
+}
+
+func NewInMemoryClient(db *persistence.InMemoryDB) webhookSVC.Persistence {
+	return &inMemory{db: db}
+}
+
+// InsertSubscription binds the subscription to the context clinic
+func (m *inMemory) InsertSubscription(ctx context.Context, newSubscription webhook.Subscription) (webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to insert webhook subscription: %w", err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	if err := m.db.CheckClinic(clinicID); err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to insert webhook subscription: %w", err)
+	}
+
+	newSubscription.ID = webhook.ID(m.db.NextID("webhook_subscription"))
+	newSubscription.ClinicID = clinicID
+	if newSubscription.CreatedAt.IsZero() {
+		newSubscription.CreatedAt = time.Now()
+	}
+
+	m.db.WebhookSubscriptions[newSubscription.ID] = cloneSubscription(newSubscription)
+	return cloneSubscription(newSubscription), nil
+}
+
+func (m *inMemory) ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook subscriptions: %w", err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	var subscriptions []webhook.Subscription
+	for _, subscription := range m.db.WebhookSubscriptions {
+		if subscription.ClinicID == clinicID {
+			subscriptions = append(subscriptions, cloneSubscription(subscription))
+		}
+	}
+
+	slices.SortFunc(subscriptions, func(a, b webhook.Subscription) int { return cmp.Compare(a.ID, b.ID) })
+	return subscriptions, nil
+}
+
+func (m *inMemory) GetSubscription(ctx context.Context, id webhook.ID) (webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to get webhook subscription %d: %w", id, err)
+	}
+
+	defer m.db.RLock(ctx)()
+
+	subscription, found := m.db.WebhookSubscriptions[id]
+	if !found || subscription.ClinicID != clinicID {
+		return webhook.Subscription{}, fmt.Errorf("unable to get webhook subscription %d: %w", id, sql.ErrNoRows)
+	}
+
+	return cloneSubscription(subscription), nil
+}
+
+func (m *inMemory) UpdateSubscription(ctx context.Context, subscription webhook.Subscription) (webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to update webhook subscription %d: %w", subscription.ID, err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	current, found := m.db.WebhookSubscriptions[subscription.ID]
+	if !found || current.ClinicID != clinicID {
+		return webhook.Subscription{}, fmt.Errorf("unable to update webhook subscription %d: %w", subscription.ID, sql.ErrNoRows)
+	}
+
+	current.URL = subscription.URL
+	current.EventTypes = subscription.EventTypes
+	current.Secret = subscription.Secret
+	m.db.WebhookSubscriptions[current.ID] = cloneSubscription(current)
+	return cloneSubscription(current), nil
+}
+
+func (m *inMemory) DeleteSubscription(ctx context.Context, id webhook.ID) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete webhook subscription %d: %w", id, err)
+	}
+
+	defer m.db.Lock(ctx)()
+
+	subscription, found := m.db.WebhookSubscriptions[id]
+	if !found || subscription.ClinicID != clinicID {
+		return fmt.Errorf("unable to delete webhook subscription %d: %w", id, sql.ErrNoRows)
+	}
+
+	delete(m.db.WebhookSubscriptions, id)
+	for deliveryID, row := range m.db.WebhookDeliveries {
+		if row.SubscriptionID == id {
+			delete(m.db.WebhookDeliveries, deliveryID)
+		}
+	}
+
+	return nil
+}
+
+func (m *inMemory) InsertDeliveries(ctx context.Context, deliveries []webhook.Delivery) error {
+	defer m.db.Lock(ctx)()
+
+	for _, delivery := range deliveries {
+		if _, found := m.db.WebhookSubscriptions[delivery.SubscriptionID]; !found {
+			return fmt.Errorf("unable to insert webhook deliveries: violates foreign key constraint: webhook subscription %d does not exist", delivery.SubscriptionID)
+		}
+
+		if m.recorded(delivery) {
+			continue
+		}
+
+		delivery.ID = webhook.DeliveryID(m.db.NextID("webhook_delivery"))
+		delivery.Payload = slices.Clone(delivery.Payload)
+		delivery.Attempts = nil
+		m.db.WebhookDeliveries[delivery.ID] = persistence.WebhookDeliveryRow{Delivery: delivery}
+	}
+
+	return nil
+}
+
+// recorded is the (subscription_id, event_id) unique constraint, the lock must be held
+func (m *inMemory) recorded(delivery webhook.Delivery) bool {
+	for _, row := range m.db.WebhookDeliveries {
+		if row.SubscriptionID == delivery.SubscriptionID && row.EventID == delivery.EventID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *inMemory) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error) {
+	defer m.db.Lock(ctx)()
+
+	var due []persistence.WebhookDeliveryRow
+	for _, row := range m.db.WebhookDeliveries {
+		if row.Status != webhook.StatusPending || row.NextAttemptAt.After(now) || row.LockedUntil.After(now) {
+			continue
+		}
+		due = append(due, row)
+	}
+
+	slices.SortFunc(due, func(a, b persistence.WebhookDeliveryRow) int { return cmp.Compare(a.ID, b.ID) })
+	due = due[:min(len(due), limit)]
+	deliveries := make([]webhook.Delivery, 0, len(due))
+	for _, row := range due {
+		row.LockedUntil = now.Add(lease)
+		m.db.WebhookDeliveries[row.ID] = row
+		deliveries = append(deliveries, cloneDelivery(row.Delivery, false))
+	}
+
+	return deliveries, nil
+}
+
+func (m *inMemory) RecordAttempt(ctx context.Context, deliveryID webhook.DeliveryID, attempt webhook.Attempt, status webhook.Status, nextAttemptAt time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.WebhookDeliveries[deliveryID]
+	if !found {
+		return fmt.Errorf("unable to record attempt of webhook delivery %d: violates foreign key constraint: webhook delivery %d does not exist", deliveryID, deliveryID)
+	}
+
+	row.Attempts = append(slices.Clone(row.Attempts), attempt)
+	row.Status = status
+	row.AttemptCount++
+	row.NextAttemptAt = nextAttemptAt
+	row.LockedUntil = time.Time{}
+	m.db.WebhookDeliveries[deliveryID] = row
+	return nil
+}
+
+// ListDeliveries is not scoped, the service checks the subscription belongs to the context clinic
+func (m *inMemory) ListDeliveries(ctx context.Context, id webhook.ID, status webhook.Status, limit int) ([]webhook.Delivery, error) {
+	defer m.db.RLock(ctx)()
+
+	var deliveries []webhook.Delivery
+	for _, row := range m.db.WebhookDeliveries {
+		if row.SubscriptionID != id || (status != "" && row.Status != status) {
+			continue
+		}
+		deliveries = append(deliveries, cloneDelivery(row.Delivery, true))
+	}
+
+	slices.SortFunc(deliveries, func(a, b webhook.Delivery) int { return cmp.Compare(b.ID, a.ID) })
+	return deliveries[:min(len(deliveries), limit)], nil
+}
+
+func (m *inMemory) ResetDelivery(ctx context.Context, id webhook.ID, deliveryID webhook.DeliveryID, now time.Time) error {
+	defer m.db.Lock(ctx)()
+
+	row, found := m.db.WebhookDeliveries[deliveryID]
+	if !found || row.SubscriptionID != id || row.Status != webhook.StatusDead {
+		return fmt.Errorf("unable to reset webhook delivery %d: %w", deliveryID, sql.ErrNoRows)
+	}
+
+	row.Status = webhook.StatusPending
+	row.AttemptCount = 0
+	row.NextAttemptAt = now
+	row.LockedUntil = time.Time{}
+	m.db.WebhookDeliveries[deliveryID] = row
+	return nil
+}
+
+func cloneSubscription(subscription webhook.Subscription) webhook.Subscription {
+	subscription.EventTypes = slices.Clone(subscription.EventTypes)
+	return subscription
+}
+
+// cloneDelivery leaves the attempts out like the claim query does, unless withAttempts
+func cloneDelivery(delivery webhook.Delivery, withAttempts bool) webhook.Delivery {
+	delivery.Payload = slices.Clone(delivery.Payload)
+	if !withAttempts {
+		delivery.Attempts = nil
+		return delivery
+	}
+
+	delivery.Attempts = slices.Clone(delivery.Attempts)
+	return delivery
+}
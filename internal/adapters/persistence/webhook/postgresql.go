@@ -0,0 +1,286 @@
+package persistence
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+type pgPersistence struct {
+	clientDB *bun.DB
+	// skipLocked lets concurrent deliverers claim distinct deliveries instead of waiting on each other
+	skipLocked bool
+}
+
+func NewPGClient(client *bun.DB) webhookSVC.Persistence {
+	return &pgPersistence{clientDB: client, skipLocked: true}
+}
+
+// InsertSubscription binds the subscription to the context clinic
+func (p *pgPersistence) InsertSubscription(ctx context.Context, newSubscription webhook.Subscription) (webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to insert webhook subscription: %w", err)
+	}
+
+	subscriptionDAO := subscriptionFromDomainToDAO(newSubscription)
+	subscriptionDAO.ClinicID = int64(clinicID)
+	_, err = persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&subscriptionDAO).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to insert webhook subscription: %w", err)
+	}
+
+	return subscriptionFromDAOToDomain(subscriptionDAO), nil
+}
+
+func (p *pgPersistence) ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook subscriptions: %w", err)
+	}
+
+	var subscriptionDAOs []subscriptionDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&subscriptionDAOs).
+		Where("clinic_id = ?", clinicID).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook subscriptions: %w", err)
+	}
+
+	subscriptions := make([]webhook.Subscription, 0, len(subscriptionDAOs))
+	for _, subscriptionDAO := range subscriptionDAOs {
+		subscriptions = append(subscriptions, subscriptionFromDAOToDomain(subscriptionDAO))
+	}
+
+	return subscriptions, nil
+}
+
+func (p *pgPersistence) GetSubscription(ctx context.Context, id webhook.ID) (webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to get webhook subscription %d: %w", id, err)
+	}
+
+	var subscriptionDAO subscriptionDAO
+	err = persistence.DB(ctx, p.clientDB).NewSelect().
+		Model(&subscriptionDAO).
+		Where("id = ?", id).
+		Where("clinic_id = ?", clinicID).
+		Scan(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to get webhook subscription %d: %w", id, err)
+	}
+
+	return subscriptionFromDAOToDomain(subscriptionDAO), nil
+}
+
+func (p *pgPersistence) UpdateSubscription(ctx context.Context, subscription webhook.Subscription) (webhook.Subscription, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to update webhook subscription %d: %w", subscription.ID, err)
+	}
+
+	subscriptionDAO := subscriptionFromDomainToDAO(subscription)
+	result, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model(&subscriptionDAO).
+		Column("url", "event_types", "secret").
+		Where("id = ?", subscription.ID).
+		Where("clinic_id = ?", clinicID).
+		Returning("*").
+		Exec(ctx)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to update webhook subscription %d: %w", subscription.ID, err)
+	}
+
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return webhook.Subscription{}, fmt.Errorf("unable to update webhook subscription %d: %w", subscription.ID, sql.ErrNoRows)
+	}
+
+	return subscriptionFromDAOToDomain(subscriptionDAO), nil
+}
+
+func (p *pgPersistence) DeleteSubscription(ctx context.Context, id webhook.ID) error {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete webhook subscription %d: %w", id, err)
+	}
+
+	result, err := persistence.DB(ctx, p.clientDB).NewDelete().
+		Model((*subscriptionDAO)(nil)).
+		Where("id = ?", id).
+		Where("clinic_id = ?", clinicID).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to delete webhook subscription %d: %w", id, err)
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return fmt.Errorf("unable to delete webhook subscription %d: %w", id, sql.ErrNoRows)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) InsertDeliveries(ctx context.Context, deliveries []webhook.Delivery) error {
+	deliveryDAOs := make([]deliveryDAO, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		deliveryDAOs = append(deliveryDAOs, deliveryFromDomainToDAO(delivery))
+	}
+
+	_, err := persistence.DB(ctx, p.clientDB).NewInsert().
+		Model(&deliveryDAOs).
+		On("CONFLICT (subscription_id, event_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to insert webhook deliveries: %w", err)
+	}
+
+	return nil
+}
+
+func (p *pgPersistence) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error) {
+	db := persistence.DB(ctx, p.clientDB)
+	dueIDs := db.NewSelect().
+		Model((*deliveryDAO)(nil)).
+		Column("id").
+		Where("status = ?", webhook.StatusPending).
+		Where("next_attempt_at <= ?", now).
+		Where("locked_until IS NULL OR locked_until <= ?", now).
+		Order("id ASC").
+		Limit(limit)
+	if p.skipLocked {
+		dueIDs = dueIDs.For("UPDATE SKIP LOCKED")
+	}
+
+	var deliveryDAOs []deliveryDAO
+	_, err := db.NewUpdate().
+		Model((*deliveryDAO)(nil)).
+		Set("locked_until = ?", now.Add(lease)).
+		Where("id IN (?)", dueIDs).
+		Returning("*").
+		Exec(ctx, &deliveryDAOs)
+	if err != nil {
+		return nil, fmt.Errorf("unable to claim due webhook deliveries: %w", err)
+	}
+
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(deliveryDAOs, func(a, b deliveryDAO) int { return cmp.Compare(a.ID, b.ID) })
+	deliveries := make([]webhook.Delivery, 0, len(deliveryDAOs))
+	for _, deliveryDAO := range deliveryDAOs {
+		deliveries = append(deliveries, deliveryFromDAOToDomain(deliveryDAO))
+	}
+
+	return deliveries, nil
+}
+
+func (p *pgPersistence) RecordAttempt(ctx context.Context, deliveryID webhook.DeliveryID, attempt webhook.Attempt, status webhook.Status, nextAttemptAt time.Time) error {
+	attemptDAO := attemptFromDomainToDAO(deliveryID, attempt)
+	err := persistence.DB(ctx, p.clientDB).RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.NewInsert().Model(&attemptDAO).Exec(ctx); err != nil {
+			return err
+		}
+
+		_, err := tx.NewUpdate().
+			Model((*deliveryDAO)(nil)).
+			Set("status = ?", status).
+			Set("attempt_count = attempt_count + 1").
+			Set("next_attempt_at = ?", nextAttemptAt).
+			Set("locked_until = NULL").
+			Where("id = ?", deliveryID).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to record attempt of webhook delivery %d: %w", deliveryID, err)
+	}
+
+	return nil
+}
+
+// ListDeliveries is not scoped, the service checks the subscription belongs to the context clinic
+func (p *pgPersistence) ListDeliveries(ctx context.Context, id webhook.ID, status webhook.Status, limit int) ([]webhook.Delivery, error) {
+	db := persistence.DB(ctx, p.clientDB)
+	var deliveryDAOs []deliveryDAO
+	query := db.NewSelect().
+		Model(&deliveryDAOs).
+		Where("subscription_id = ?", id).
+		Order("id DESC").
+		Limit(limit)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Scan(ctx); err != nil {
+		return nil, fmt.Errorf("unable to list deliveries of webhook subscription %d: %w", id, err)
+	}
+
+	if len(deliveryDAOs) == 0 {
+		return nil, nil
+	}
+
+	deliveryIDs := make([]int64, 0, len(deliveryDAOs))
+	for _, deliveryDAO := range deliveryDAOs {
+		deliveryIDs = append(deliveryIDs, deliveryDAO.ID)
+	}
+
+	var attemptDAOs []attemptDAO
+	err := db.NewSelect().
+		Model(&attemptDAOs).
+		Where("delivery_id IN (?)", bun.In(deliveryIDs)).
+		Order("id ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list attempts of webhook subscription %d: %w", id, err)
+	}
+
+	attempts := map[int64][]webhook.Attempt{}
+	for _, attemptDAO := range attemptDAOs {
+		attempts[attemptDAO.DeliveryID] = append(attempts[attemptDAO.DeliveryID], attemptFromDAOToDomain(attemptDAO))
+	}
+
+	deliveries := make([]webhook.Delivery, 0, len(deliveryDAOs))
+	for _, deliveryDAO := range deliveryDAOs {
+		delivery := deliveryFromDAOToDomain(deliveryDAO)
+		delivery.Attempts = attempts[deliveryDAO.ID]
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+func (p *pgPersistence) ResetDelivery(ctx context.Context, id webhook.ID, deliveryID webhook.DeliveryID, now time.Time) error {
+	result, err := persistence.DB(ctx, p.clientDB).NewUpdate().
+		Model((*deliveryDAO)(nil)).
+		Set("status = ?", webhook.StatusPending).
+		Set("attempt_count = 0").
+		Set("next_attempt_at = ?", now).
+		Set("locked_until = NULL").
+		Where("id = ?", deliveryID).
+		Where("subscription_id = ?", id).
+		Where("status = ?", webhook.StatusDead).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to reset webhook delivery %d: %w", deliveryID, err)
+	}
+
+	if reset, _ := result.RowsAffected(); reset == 0 {
+		return fmt.Errorf("unable to reset webhook delivery %d: %w", deliveryID, sql.ErrNoRows)
+	}
+
+	return nil
+}
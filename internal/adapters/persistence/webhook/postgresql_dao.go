@@ -0,0 +1,135 @@
+package persistence
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/uptrace/bun"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+)
+
+type subscriptionDAO struct {
+	bun.BaseModel `bun:"table:webhook_subscription,alias:webhook_subscription"`
+
+	ID         int64     `bun:"id,pk,autoincrement"`
+	ClinicID   int64     `bun:"clinic_id,notnull"`
+	URL        string    `bun:"url,notnull"`
+	EventTypes []string  `bun:"event_types,type:jsonb,notnull"`
+	Secret     string    `bun:"secret,notnull"`
+	CreatedAt  time.Time `bun:"created_at,nullzero,notnull,default:current_timestamp"`
+}
+
+type deliveryDAO struct {
+	bun.BaseModel `bun:"table:webhook_delivery,alias:webhook_delivery"`
+
+	ID             int64  `bun:"id,pk,autoincrement"`
+	SubscriptionID int64  `bun:"subscription_id,notnull"`
+	ClinicID       int64  `bun:"clinic_id,notnull"`
+	EventID        int64  `bun:"event_id,notnull"`
+	EventType      string `bun:"event_type,notnull"`
+	// Payload is sent as text, PostgreSQL casts it to jsonb
+	Payload       string    `bun:"payload,notnull"`
+	Status        string    `bun:"status,notnull"`
+	AttemptCount  int       `bun:"attempt_count,notnull"`
+	NextAttemptAt time.Time `bun:"next_attempt_at,notnull"`
+	LockedUntil   time.Time `bun:"locked_until,nullzero"`
+	CreatedAt     time.Time `bun:"created_at,notnull"`
+}
+
+type attemptDAO struct {
+	bun.BaseModel `bun:"table:webhook_attempt,alias:webhook_attempt"`
+
+	ID           int64     `bun:"id,pk,autoincrement"`
+	DeliveryID   int64     `bun:"delivery_id,notnull"`
+	StatusCode   int       `bun:"status_code,nullzero"`
+	ResponseBody string    `bun:"response_body,nullzero"`
+	Error        string    `bun:"error,nullzero"`
+	DurationMS   int64     `bun:"duration_ms,notnull"`
+	AttemptedAt  time.Time `bun:"attempted_at,notnull"`
+}
+
+func subscriptionFromDomainToDAO(s webhook.Subscription) subscriptionDAO {
+	eventTypes := make([]string, len(s.EventTypes))
+	for i, eventType := range s.EventTypes {
+		eventTypes[i] = string(eventType)
+	}
+
+	return subscriptionDAO{
+		ID:         int64(s.ID),
+		ClinicID:   int64(s.ClinicID),
+		URL:        s.URL,
+		EventTypes: eventTypes,
+		Secret:     s.Secret,
+		CreatedAt:  s.CreatedAt,
+	}
+}
+
+func subscriptionFromDAOToDomain(subscriptionDAO subscriptionDAO) webhook.Subscription {
+	eventTypes := make([]event.Type, len(subscriptionDAO.EventTypes))
+	for i, eventType := range subscriptionDAO.EventTypes {
+		eventTypes[i] = event.Type(eventType)
+	}
+
+	return webhook.Subscription{
+		ID:         webhook.ID(subscriptionDAO.ID),
+		ClinicID:   clinic.ID(subscriptionDAO.ClinicID),
+		URL:        subscriptionDAO.URL,
+		EventTypes: eventTypes,
+		Secret:     subscriptionDAO.Secret,
+		CreatedAt:  subscriptionDAO.CreatedAt,
+	}
+}
+
+func deliveryFromDomainToDAO(d webhook.Delivery) deliveryDAO {
+	return deliveryDAO{
+		ID:             int64(d.ID),
+		SubscriptionID: int64(d.SubscriptionID),
+		ClinicID:       int64(d.ClinicID),
+		EventID:        int64(d.EventID),
+		EventType:      string(d.EventType),
+		Payload:        string(d.Payload),
+		Status:         string(d.Status),
+		AttemptCount:   d.AttemptCount,
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+	}
+}
+
+func deliveryFromDAOToDomain(deliveryDAO deliveryDAO) webhook.Delivery {
+	return webhook.Delivery{
+		ID:             webhook.DeliveryID(deliveryDAO.ID),
+		SubscriptionID: webhook.ID(deliveryDAO.SubscriptionID),
+		ClinicID:       clinic.ID(deliveryDAO.ClinicID),
+		EventID:        event.ID(deliveryDAO.EventID),
+		EventType:      event.Type(deliveryDAO.EventType),
+		Payload:        json.RawMessage(deliveryDAO.Payload),
+		Status:         webhook.Status(deliveryDAO.Status),
+		AttemptCount:   deliveryDAO.AttemptCount,
+		NextAttemptAt:  deliveryDAO.NextAttemptAt,
+		CreatedAt:      deliveryDAO.CreatedAt,
+	}
+}
+
+func attemptFromDomainToDAO(deliveryID webhook.DeliveryID, a webhook.Attempt) attemptDAO {
+	return attemptDAO{
+		DeliveryID:   int64(deliveryID),
+		StatusCode:   a.StatusCode,
+		ResponseBody: a.ResponseBody,
+		Error:        a.Error,
+		DurationMS:   a.DurationMS,
+		AttemptedAt:  a.AttemptedAt,
+	}
+}
+
+func attemptFromDAOToDomain(attemptDAO attemptDAO) webhook.Attempt {
+	return webhook.Attempt{
+		StatusCode:   attemptDAO.StatusCode,
+		ResponseBody: attemptDAO.ResponseBody,
+		Error:        attemptDAO.Error,
+		DurationMS:   attemptDAO.DurationMS,
+		AttemptedAt:  attemptDAO.AttemptedAt,
+	}
+}
@@ -0,0 +1,12 @@
+package persistence
+
+import (
+	"github.com/uptrace/bun"
+
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+// NewSQLiteClient runs the queries of NewPGClient without the row locks, SQLite has a single writer anyway
+func NewSQLiteClient(client *bun.DB) webhookSVC.Persistence {
+	return &pgPersistence{clientDB: client}
+}
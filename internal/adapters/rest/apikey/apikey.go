@@ -0,0 +1,167 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
+)
+
+type apiKeyHandler struct {
+	aService apiKeySVC.Service
+}
+
+func SetHandler(e *echo.Echo, service apiKeySVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	a := &apiKeyHandler{
+		service,
+	}
+
+	// acting on the keys of another user is checked by the service
+	requireProfileWrite := access.RequirePermissions(user.Permissions{user.PermissionProfileWrite})
+	apiV1 := e.Group("/api/v1")
+	{
+		apiV1.GET("/apikeys", a.getAPIKeys, requireProfileWrite)
+		apiV1.POST("/apikey", a.createAPIKey, requireProfileWrite, spec.ValidateBody(), idempotency.IdempotentSecret())
+		apiV1.DELETE("/apikey/:id", a.revokeAPIKey, requireProfileWrite)
+	}
+
+	a.setV2Routes(e, requireProfileWrite, idempotency, spec)
+}
+
+// APIKeyInput creates a key for the requesting user unless user_id targets a service account
+type APIKeyInput struct {
+	UserID    user.ID          `json:"user_id"`
+	Name      string           `json:"name" openapi:"required"`
+	Scopes    user.Permissions `json:"scopes"`
+	ExpiresAt *time.Time       `json:"expires_at"`
+}
+
+// APIKeyCreated is the only response carrying the plain key
+type APIKeyCreated struct {
+	apikey.APIKey
+	Key apikey.PlainKey `json:"key"`
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"apikey"}
+	profileWrite := user.Permissions{user.PermissionProfileWrite}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/apikeys",
+			Summary:     "List the API keys of the requesting user or of a service account",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters: []openapi.Parameter{{
+				Name:        "user_id",
+				In:          openapi.InQuery,
+				Description: "Service account owning the keys, defaults to the requesting user",
+				Example:     user.ID(0),
+			}},
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []apikey.APIKey{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/apikey",
+			Summary:     "Create an API key, the plain key is only returned once",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Idempotent:  true,
+			Request:     APIKeyInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: APIKeyCreated{}},
+				{Status: http.StatusGone, Description: "Retried with the same Idempotency-Key, the secret is only returned once"},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/apikey/:id",
+			Summary:     "Revoke an API key",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters:  []openapi.Parameter{{Name: "id", In: openapi.InPath, Example: apikey.ID(0)}},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}, operationsV2()...)
+}
+
+func (a *apiKeyHandler) getAPIKeys(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	var ownerID int64
+	if userIDParam := context.QueryParam("user_id"); userIDParam != "" {
+		ownerID, err = strconv.ParseInt(userIDParam, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+
+	keys, err := a.aService.ListAPIKeys(ctx, reqUserID, user.ID(ownerID))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to list api keys: %w", err))
+	}
+
+	return context.JSON(http.StatusOK, keys)
+}
+
+func (a *apiKeyHandler) createAPIKey(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	apiKeyInput := new(APIKeyInput)
+	if err := context.Bind(apiKeyInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse api key input: %w", err))
+	}
+
+	keyCreated, plainKey, err := a.aService.CreateAPIKey(ctx, reqUserID, apikey.APIKey{
+		UserID:    apiKeyInput.UserID,
+		Name:      apiKeyInput.Name,
+		Scopes:    apiKeyInput.Scopes,
+		ExpiresAt: apiKeyInput.ExpiresAt,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return context.JSON(http.StatusCreated, APIKeyCreated{APIKey: keyCreated, Key: plainKey})
+}
+
+func (a *apiKeyHandler) revokeAPIKey(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	idParam := context.Param("id")
+	keyID, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	if err := a.aService.RevokeAPIKey(ctx, reqUserID, apikey.ID(keyID)); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to revoke api key: %w", err))
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
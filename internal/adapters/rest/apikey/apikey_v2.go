@@ -0,0 +1,119 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+func (a *apiKeyHandler) setV2Routes(e *echo.Echo, requireProfileWrite echo.MiddlewareFunc, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/apikeys", a.listAPIKeysV2, requireProfileWrite)
+		apiV2.POST("/apikeys", a.createAPIKeyV2, requireProfileWrite, spec.ValidateBody(), idempotency.IdempotentSecret())
+		apiV2.DELETE("/apikeys/:id", a.revokeAPIKey, requireProfileWrite)
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"apikey"}
+	profileWrite := user.Permissions{user.PermissionProfileWrite}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/apikeys",
+			Summary:     "List the API keys of the requesting user or of a service account",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters: []openapi.Parameter{{
+				Name:        "user_id",
+				In:          openapi.InQuery,
+				Description: "Service account owning the keys, defaults to the requesting user",
+				Example:     user.ID(0),
+			}},
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: envelope.List[apikey.APIKey]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/apikeys",
+			Summary:     "Create an API key, the plain key is only returned once",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Idempotent:  true,
+			Request:     APIKeyInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: envelope.Data[APIKeyCreated]{}},
+				{Status: http.StatusGone, Description: "Retried with the same Idempotency-Key, the secret is only returned once"},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v2/apikeys/:id",
+			Summary:     "Revoke an API key",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters:  []openapi.Parameter{{Name: "id", In: openapi.InPath, Example: apikey.ID(0)}},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}
+}
+
+func (a *apiKeyHandler) listAPIKeysV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	var ownerID int64
+	if userIDParam := context.QueryParam("user_id"); userIDParam != "" {
+		ownerID, err = strconv.ParseInt(userIDParam, 10, 64)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+	}
+
+	keys, err := a.aService.ListAPIKeys(ctx, reqUserID, user.ID(ownerID))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to list api keys: %w", err))
+	}
+
+	return envelope.JSONList(context, keys)
+}
+
+func (a *apiKeyHandler) createAPIKeyV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	apiKeyInput := new(APIKeyInput)
+	if err := context.Bind(apiKeyInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse api key input: %w", err))
+	}
+
+	keyCreated, plainKey, err := a.aService.CreateAPIKey(ctx, reqUserID, apikey.APIKey{
+		UserID:    apiKeyInput.UserID,
+		Name:      apiKeyInput.Name,
+		Scopes:    apiKeyInput.Scopes,
+		ExpiresAt: apiKeyInput.ExpiresAt,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/apikeys/%d", keyCreated.ID), APIKeyCreated{APIKey: keyCreated, Key: plainKey})
+}
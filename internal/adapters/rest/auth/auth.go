@@ -1,24 +1,54 @@
 package rest
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
 
 	authMiddleware "github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
 	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
 	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
 	"github.com/sopial42/cleanic/internal/config"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
 	user "github.com/sopial42/cleanic/internal/domains/user"
 	authSVC "github.com/sopial42/cleanic/internal/services/auth"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
 )
 
+// oidcSessionName is a dedicated session as the main one is SameSite strict
+// and would not be sent back on the identity provider redirect
+const oidcSessionName = "oidc"
+
+const (
+	oidcStateKey        = "state"
+	oidcNonceKey        = "nonce"
+	oidcCodeVerifierKey = "code_verifier"
+	oidcChallengeMaxAge = 600
+)
+
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+var daysParameter = openapi.Parameter{
+	Name:        "days",
+	In:          openapi.InQuery,
+	Description: "Number of days covered, today included, from 1 to 365, 30 by default",
+	Example:     defaultStatsDays,
+}
+
 type authHandler struct {
 	authService   authSVC.Service
 	cookiesConfig config.CookieStoreConfig
+	oidcConfig    config.OIDCConfig
 }
 
 type AccessTokenResponse struct {
@@ -32,29 +62,162 @@ type AccessTokenResponse struct {
 // avoid the used default tag `json:"-"` in case user needs a pwd update
 type UserUpdateInput struct {
 	ID       user.ID       `json:"id"`
-	Email    user.Email    `json:"email"`
-	Password user.Password `json:"password"`
+	Email    user.Email    `json:"email" openapi:"required"`
+	Password user.Password `json:"password" openapi:"required"`
 }
 
-func SetHandler(e *echo.Echo, config config.CookieStoreConfig, service authSVC.Service, refreshMiddleware authMiddleware.AuthRefreshMiddleware) {
+// PasswordRotationInput carries the current credentials along with the new password
+type PasswordRotationInput struct {
+	Email       user.Email    `json:"email" openapi:"required"`
+	Password    user.Password `json:"password" openapi:"required"`
+	NewPassword user.Password `json:"new_password" openapi:"required"`
+}
+
+// UnlockInput targets an account, a client IP or both
+type UnlockInput struct {
+	Email user.Email `json:"email"`
+	IP    string     `json:"ip"`
+}
+
+func SetHandler(e *echo.Echo, config config.CookieStoreConfig, oidcConfig config.OIDCConfig, service authSVC.Service, refreshMiddleware authMiddleware.AuthRefreshMiddleware, accessMiddleware authMiddleware.AuthAccessMiddleware, rateLimits *authMiddleware.RateLimitMiddleware, idempotency *authMiddleware.IdempotencyMiddleware, spec *openapi.Spec) {
 	u := &authHandler{
 		service,
 		config,
+		oidcConfig,
 	}
+
+	requireUserManage := accessMiddleware.RequirePermissions(user.Permissions{user.PermissionUserManage})
+	// the routes without access token are limited per client IP
+	limitByIP := rateLimits.LimitByIP()
 	apiV1 := e.Group("/api/v1")
 	{
-		apiV1.POST("/auth/signup", u.register)
-		apiV1.POST("/auth/login", u.login)
-		apiV1.POST("/auth/refresh", u.refresh, refreshMiddleware.RequireRefreshToken())
-		apiV1.POST("/auth/logout", u.logout, refreshMiddleware.RequireRefreshToken())
+		apiV1.POST("/auth/signup", u.register, limitByIP, spec.ValidateBody(), idempotency.Idempotent())
+		apiV1.POST("/auth/login", u.login, limitByIP, spec.ValidateBody())
+		apiV1.POST("/auth/refresh", u.refresh, limitByIP, refreshMiddleware.RequireRefreshToken())
+		apiV1.POST("/auth/logout", u.logout, limitByIP, refreshMiddleware.RequireRefreshToken())
+		apiV1.POST("/auth/password/rotate", u.rotatePassword, limitByIP, spec.ValidateBody())
+		apiV1.POST("/auth/unlock", u.unlock, requireUserManage, spec.ValidateBody())
+		apiV1.GET("/auth/sessions/stats", u.getSessionStats, requireUserManage)
+		if oidcConfig.Enabled() {
+			apiV1.GET("/auth/oidc/login", u.oidcLogin, limitByIP)
+			apiV1.GET("/auth/oidc/callback", u.oidcCallback, limitByIP)
+		}
 	}
+
+	u.setV2Routes(e, limitByIP, requireUserManage, accessMiddleware.RequirePermissions(user.Permissions{}), refreshMiddleware, idempotency, spec)
+}
+
+// Operations documents the routes set by SetHandler, the SSO routes only exist when OIDC is enabled
+func Operations(oidcConfig config.OIDCConfig) []openapi.Operation {
+	tags := []string{"auth"}
+	operations := []openapi.Operation{
+		{
+			Method:     http.MethodPost,
+			Path:       "/api/v1/auth/signup",
+			Summary:    "Register a user with the default role",
+			Tags:       tags,
+			Idempotent: true,
+			Request:    UserUpdateInput{},
+			Responses:  []openapi.Response{{Status: http.StatusCreated, Body: user.User{}}},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/api/v1/auth/login",
+			Summary: "Log in with a password, the refresh token is set in the session cookie",
+			Tags:    tags,
+			Request: UserUpdateInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: AccessTokenResponse{}},
+				{Status: http.StatusTooManyRequests, Description: "Too many failed attempts, see Retry-After"},
+			},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/api/v1/auth/refresh",
+			Summary:   "Rotate the refresh token and get a new access token",
+			Tags:      tags,
+			Auth:      openapi.AuthRefresh,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: AccessTokenResponse{}}},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/api/v1/auth/logout",
+			Summary:   "Revoke the refresh tokens and clear the session cookie",
+			Tags:      tags,
+			Auth:      openapi.AuthRefresh,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: ""}},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/api/v1/auth/password/rotate",
+			Summary:   "Change the password with the current credentials, also allowed once the password expired",
+			Tags:      tags,
+			Request:   PasswordRotationInput{},
+			Responses: []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/auth/unlock",
+			Summary:     "Clear the failed login attempts of an account, a client IP or both",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionUserManage},
+			Request:     UnlockInput{},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/auth/sessions/stats",
+			Summary:     "Report the active sessions per user, the logins per day and the average session lifetime of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionUserManage},
+			Parameters:  []openapi.Parameter{daysParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: auth.SessionStats{}}},
+		},
+	}
+
+	if oidcConfig.Enabled() {
+		operations = append(operations,
+			openapi.Operation{
+				Method:    http.MethodGet,
+				Path:      "/api/v1/auth/oidc/login",
+				Summary:   "Start a login with the identity provider",
+				Tags:      tags,
+				Responses: []openapi.Response{{Status: http.StatusFound, Description: "Redirects to the identity provider"}},
+			},
+			openapi.Operation{
+				Method:  http.MethodGet,
+				Path:    "/api/v1/auth/oidc/callback",
+				Summary: "Complete a login with the identity provider, the refresh token is set in the session cookie",
+				Tags:    tags,
+				Parameters: []openapi.Parameter{
+					{Name: "code", In: openapi.InQuery, Example: ""},
+					{Name: "state", In: openapi.InQuery, Example: ""},
+					{Name: "error", In: openapi.InQuery, Description: "Set by the identity provider on refusal", Example: ""},
+				},
+				Responses: []openapi.Response{{Status: http.StatusFound, Description: "Redirects to the front-end"}},
+			},
+		)
+	}
+
+	return append(operations, operationsV2(oidcConfig.Enabled())...)
 }
 
 func (a *authHandler) register(context echo.Context) error {
+	userCreated, err := a.signup(context)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusCreated, userCreated)
+}
+
+func (a *authHandler) signup(context echo.Context) (user.User, error) {
 	ctx := context.Request().Context()
 	newUserInput := new(UserUpdateInput)
 	if err := context.Bind(newUserInput); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, err)
+		return user.User{}, echo.NewHTTPError(http.StatusBadRequest, err)
 	}
 
 	newUser := user.User{
@@ -64,81 +227,123 @@ func (a *authHandler) register(context echo.Context) error {
 
 	userCreated, err := a.authService.Signup(ctx, newUser)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, err)
+		return user.User{}, echo.NewHTTPError(http.StatusInternalServerError, err)
 	}
 
-	return context.JSON(http.StatusCreated, userCreated)
+	return userCreated, nil
 }
 
 func (a *authHandler) login(context echo.Context) error {
-	ctx := context.Request().Context()
 	newUserInput := new(UserUpdateInput)
 	if err := context.Bind(newUserInput); err != nil {
 		return context.JSON(http.StatusBadRequest, fmt.Errorf("unable to parse input: %w", err))
 	}
 
-	newUser := user.User{
+	tokens, err := a.startSession(context, user.User{
 		Email:    newUserInput.Email,
 		Password: newUserInput.Password,
+	})
+	if err != nil {
+		return err
 	}
 
-	refreshToken, accessToken, err := a.authService.Login(ctx, newUser)
+	return context.JSON(http.StatusOK, tokens)
+}
+
+// startSession logs in, sets the refresh token cookie and returns the access token
+func (a *authHandler) startSession(context echo.Context, credentials user.User) (AccessTokenResponse, error) {
+	ctx := context.Request().Context()
+	refreshToken, accessToken, err := a.authService.Login(ctx, credentials, context.RealIP())
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to login: %w", err))
+		return AccessTokenResponse{}, credentialsError(context, err, "login")
 	}
 
 	sess, err := session.Get(authMiddleware.SessionName, context)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
 	}
 
 	sess.Options = &sessions.Options{
 		Domain:   a.cookiesConfig.Domain,
 		HttpOnly: true,
-		MaxAge:   a.cookiesConfig.MaxAgeSeconds,
+		MaxAge:   int(a.cookiesConfig.MaxAge.Seconds()),
 		Path:     a.cookiesConfig.Domain,
 		SameSite: http.SameSite(a.cookiesConfig.SameSite),
 	}
 
 	sess.Values[authMiddleware.RefreshTokenCookieName] = string(refreshToken.SignedToken)
 	if err := sess.Save(context.Request(), context.Response()); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save session: %w", err))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save session: %w", err))
 	}
 
-	return context.JSON(http.StatusOK, AccessTokenResponse{
+	return AccessTokenResponse{
 		Token:            accessToken.SignedToken,
 		Type:             accessToken.Type,
-		ExpiresInSeconds: int64(accessToken.ExpirationDurationMin.Seconds()),
-	})
+		ExpiresInSeconds: int64(accessToken.ExpirationDuration.Seconds()),
+	}, nil
+}
+
+// credentialsError keeps credentials failures uniform, whatever the reason behind them
+func credentialsError(context echo.Context, err error, action string) error {
+	var tooManyAttempts *authSVC.TooManyAttemptsError
+	if errors.As(err, &tooManyAttempts) {
+		retryAfterSeconds := int64(tooManyAttempts.RetryAfter.Seconds()) + 1
+		context.Response().Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds, 10))
+		return echo.NewHTTPError(http.StatusTooManyRequests, tooManyAttempts)
+	}
+
+	if errors.Is(err, authSVC.ErrInvalidCredentials) {
+		return echo.NewHTTPError(http.StatusUnauthorized, authSVC.ErrInvalidCredentials)
+	}
+
+	if errors.Is(err, authSVC.ErrPasswordExpired) {
+		return echo.NewHTTPError(http.StatusForbidden, authSVC.ErrPasswordExpired)
+	}
+
+	if errors.Is(err, clinicSVC.ErrNotMember) {
+		return echo.NewHTTPError(http.StatusForbidden, clinicSVC.ErrNotMember)
+	}
+
+	return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to %s: %w", action, err))
 }
 
 func (a *authHandler) refresh(context echo.Context) error {
+	tokens, err := a.rotateSession(context)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, tokens)
+}
+
+// rotateSession replaces the refresh token cookie and returns a new access token
+func (a *authHandler) rotateSession(context echo.Context) (AccessTokenResponse, error) {
 	ctx := context.Request().Context()
 	sess, err := session.Get(authMiddleware.SessionName, context)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
 	}
 
 	currentSignedRefreshTokenValue := sess.Values[authMiddleware.RefreshTokenCookieName]
 	if currentSignedRefreshTokenValue == nil {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("handler unable to get refreshToken from session, not found / nil token"))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("handler unable to get refreshToken from session, not found / nil token"))
 	}
 
 	currentSignedRefreshToken, ok := currentSignedRefreshTokenValue.(string)
 	if !ok {
-		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse refreshToken from session: %w", err))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse refreshToken from session: %w", err))
 	}
 
 	// Refresh token in the database
 	refreshToken, accessToken, err := a.authService.Refresh(ctx, utils.SignedRefreshToken(currentSignedRefreshToken))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to refresh tokens: %w", err))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to refresh tokens: %w", err))
 	}
 
 	sess.Options = &sessions.Options{
 		Domain:   a.cookiesConfig.Domain,
 		HttpOnly: true,
-		MaxAge:   a.cookiesConfig.MaxAgeSeconds,
+		MaxAge:   int(a.cookiesConfig.MaxAge.Seconds()),
 		Path:     a.cookiesConfig.Domain,
 		SameSite: http.SameSite(a.cookiesConfig.SameSite),
 		Secure:   a.cookiesConfig.Secure,
@@ -146,18 +351,27 @@ func (a *authHandler) refresh(context echo.Context) error {
 
 	sess.Values[authMiddleware.RefreshTokenCookieName] = string(refreshToken.SignedToken)
 	if err := sess.Save(context.Request(), context.Response()); err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save refresh tokens: %w", err))
+		return AccessTokenResponse{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save refresh tokens: %w", err))
 	}
 
-	return context.JSON(http.StatusOK, AccessTokenResponse{
+	return AccessTokenResponse{
 		Token:            accessToken.SignedToken,
 		Type:             accessToken.Type,
-		ExpiresInSeconds: int64(accessToken.ExpirationDurationMin.Seconds()),
-	})
+		ExpiresInSeconds: int64(accessToken.ExpirationDuration.Seconds()),
+	}, nil
 }
 
 // Refresh handler is protected by the jwtRefresh middleware
 func (a *authHandler) logout(context echo.Context) error {
+	if err := a.endSession(context); err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, "logged out")
+}
+
+// endSession revokes the refresh tokens of the user and clears the cookie
+func (a *authHandler) endSession(context echo.Context) error {
 	ctx := context.Request().Context()
 	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
 	if err != nil {
@@ -177,9 +391,164 @@ func (a *authHandler) logout(context echo.Context) error {
 		MaxAge: -1,
 	}
 
+	return sess.Save(context.Request(), context.Response())
+}
+
+// rotatePassword is not protected by a token as an expired password cannot login anymore
+func (a *authHandler) rotatePassword(context echo.Context) error {
+	ctx := context.Request().Context()
+	rotationInput := new(PasswordRotationInput)
+	if err := context.Bind(rotationInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse password rotation input: %w", err))
+	}
+
+	loginUser := user.User{
+		Email:    rotationInput.Email,
+		Password: rotationInput.Password,
+	}
+
+	if err := a.authService.RotatePassword(ctx, loginUser, rotationInput.NewPassword, context.RealIP()); err != nil {
+		return credentialsError(context, err, "rotate password")
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
+
+// oidcLogin keeps the challenge in a short lived cookie and redirects to the identity provider
+func (a *authHandler) oidcLogin(context echo.Context) error {
+	ctx := context.Request().Context()
+	challenge, err := a.authService.StartSSO(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to start SSO: %w", err))
+	}
+
+	sess, err := session.Get(oidcSessionName, context)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
+	}
+
+	sess.Options = &sessions.Options{
+		Domain:   a.cookiesConfig.Domain,
+		HttpOnly: true,
+		MaxAge:   oidcChallengeMaxAge,
+		Path:     "/",
+		SameSite: http.SameSiteLaxMode,
+		Secure:   a.cookiesConfig.Secure,
+	}
+
+	sess.Values[oidcStateKey] = challenge.State
+	sess.Values[oidcNonceKey] = challenge.Nonce
+	sess.Values[oidcCodeVerifierKey] = challenge.CodeVerifier
 	if err := sess.Save(context.Request(), context.Response()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save session: %w", err))
+	}
+
+	return context.Redirect(http.StatusFound, challenge.AuthURL)
+}
+
+// oidcCallback sets the refresh token cookie like a login does, then redirects to the front-end
+// which gets its access token through the refresh route
+func (a *authHandler) oidcCallback(context echo.Context) error {
+	ctx := context.Request().Context()
+	if idpError := context.QueryParam("error"); idpError != "" {
+		return echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("%w: %s", authSVC.ErrSSORejected, idpError))
+	}
+
+	oidcSess, err := session.Get(oidcSessionName, context)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
+	}
+
+	challenge := auth.SSOChallenge{}
+	challenge.State, _ = oidcSess.Values[oidcStateKey].(string)
+	challenge.Nonce, _ = oidcSess.Values[oidcNonceKey].(string)
+	challenge.CodeVerifier, _ = oidcSess.Values[oidcCodeVerifierKey].(string)
+
+	// The challenge is single use
+	oidcSess.Options = &sessions.Options{
+		MaxAge: -1,
+		Path:   "/",
+	}
+
+	if err := oidcSess.Save(context.Request(), context.Response()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save session: %w", err))
+	}
+
+	refreshToken, _, err := a.authService.CompleteSSO(ctx, challenge, context.QueryParam("state"), context.QueryParam("code"))
+	if err != nil {
+		if errors.Is(err, authSVC.ErrSSORejected) {
+			return echo.NewHTTPError(http.StatusUnauthorized, err)
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to complete SSO: %w", err))
+	}
+
+	sess, err := session.Get(authMiddleware.SessionName, context)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
+	}
+
+	sess.Options = &sessions.Options{
+		Domain:   a.cookiesConfig.Domain,
+		HttpOnly: true,
+		MaxAge:   int(a.cookiesConfig.MaxAge.Seconds()),
+		Path:     a.cookiesConfig.Domain,
+		SameSite: http.SameSite(a.cookiesConfig.SameSite),
+		Secure:   a.cookiesConfig.Secure,
+	}
+
+	sess.Values[authMiddleware.RefreshTokenCookieName] = string(refreshToken.SignedToken)
+	if err := sess.Save(context.Request(), context.Response()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save session: %w", err))
+	}
+
+	return context.Redirect(http.StatusFound, a.oidcConfig.PostLoginRedirectURL)
+}
+
+// unlock is restricted to user managers and clears failed login attempts
+func (a *authHandler) unlock(context echo.Context) error {
+	ctx := context.Request().Context()
+	unlockInput := new(UnlockInput)
+	if err := context.Bind(unlockInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse unlock input: %w", err))
+	}
+
+	if err := a.authService.Unlock(ctx, unlockInput.Email, unlockInput.IP); err != nil {
+		if errors.Is(err, authSVC.ErrUnlockTargetRequired) {
+			return echo.NewHTTPError(http.StatusBadRequest, err)
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to unlock: %w", err))
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
+
+func (a *authHandler) getSessionStats(context echo.Context) error {
+	stats, err := a.sessionStats(context)
+	if err != nil {
 		return err
 	}
 
-	return context.JSON(http.StatusOK, "logged out")
+	return context.JSON(http.StatusOK, stats)
+}
+
+// sessionStats covers the last days given by the days query parameter, 30 by default
+func (a *authHandler) sessionStats(context echo.Context) (auth.SessionStats, error) {
+	days := defaultStatsDays
+	if daysParam := context.QueryParam("days"); daysParam != "" {
+		var err error
+		days, err = strconv.Atoi(daysParam)
+		if err != nil || days < 1 || days > maxStatsDays {
+			return auth.SessionStats{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("days must be a number between 1 and %d", maxStatsDays))
+		}
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1-days)
+	stats, err := a.authService.SessionStats(context.Request().Context(), since)
+	if err != nil {
+		return auth.SessionStats{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session stats: %w", err))
+	}
+
+	return stats, nil
 }
@@ -0,0 +1,252 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+
+	authMiddleware "github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+)
+
+// SwitchClinicInput targets one of the clinics of the requesting user
+type SwitchClinicInput struct {
+	ClinicID clinic.ID `json:"clinic_id" openapi:"required"`
+}
+
+// setV2Routes shares the v1 logic, only the bodies are wrapped in envelopes
+func (a *authHandler) setV2Routes(e *echo.Echo, limitByIP, requireUserManage, requireAccess echo.MiddlewareFunc, refreshMiddleware authMiddleware.AuthRefreshMiddleware, idempotency *authMiddleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.POST("/auth/signup", a.signupV2, limitByIP, spec.ValidateBody(), idempotency.Idempotent())
+		apiV2.POST("/auth/login", a.loginV2, limitByIP, spec.ValidateBody())
+		apiV2.POST("/auth/refresh", a.refreshV2, limitByIP, refreshMiddleware.RequireRefreshToken())
+		apiV2.POST("/auth/logout", a.logoutV2, limitByIP, refreshMiddleware.RequireRefreshToken())
+		apiV2.POST("/auth/password/rotate", a.rotatePassword, limitByIP, spec.ValidateBody())
+		apiV2.POST("/auth/unlock", a.unlock, requireUserManage, spec.ValidateBody())
+		apiV2.GET("/auth/sessions/stats", a.getSessionStatsV2, requireUserManage)
+		apiV2.POST("/auth/switch-clinic", a.switchClinic, requireAccess, spec.ValidateBody())
+		if a.oidcConfig.Enabled() {
+			apiV2.GET("/auth/oidc/login", a.oidcLogin, limitByIP)
+			apiV2.GET("/auth/oidc/callback", a.oidcCallback, limitByIP)
+		}
+	}
+}
+
+func operationsV2(oidcEnabled bool) []openapi.Operation {
+	tags := []string{"auth"}
+	operations := []openapi.Operation{
+		{
+			Method:     http.MethodPost,
+			Path:       "/api/v2/auth/signup",
+			Summary:    "Register a user with the default role, Location points to it",
+			Tags:       tags,
+			Idempotent: true,
+			Request:    UserUpdateInput{},
+			Responses:  []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[user.User]{}}},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/api/v2/auth/login",
+			Summary: "Log in with a password, the refresh token is set in the session cookie",
+			Tags:    tags,
+			Request: UserUpdateInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: envelope.Data[AccessTokenResponse]{}},
+				{Status: http.StatusTooManyRequests, Description: "Too many failed attempts, see Retry-After"},
+			},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/api/v2/auth/refresh",
+			Summary:   "Rotate the refresh token and get a new access token",
+			Tags:      tags,
+			Auth:      openapi.AuthRefresh,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[AccessTokenResponse]{}}},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/api/v2/auth/logout",
+			Summary:   "Revoke the refresh tokens and clear the session cookie",
+			Tags:      tags,
+			Auth:      openapi.AuthRefresh,
+			Responses: []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:    http.MethodPost,
+			Path:      "/api/v2/auth/password/rotate",
+			Summary:   "Change the password with the current credentials, also allowed once the password expired",
+			Tags:      tags,
+			Request:   PasswordRotationInput{},
+			Responses: []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/auth/unlock",
+			Summary:     "Clear the failed login attempts of an account, a client IP or both",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionUserManage},
+			Request:     UnlockInput{},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/auth/sessions/stats",
+			Summary:     "Report the active sessions per user, the logins per day and the average session lifetime of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionUserManage},
+			Parameters:  []openapi.Parameter{daysParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[auth.SessionStats]{}}},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/api/v2/auth/switch-clinic",
+			Summary: "Exchange the session for one scoped to another clinic of the requesting user",
+			Tags:    tags,
+			Auth:    openapi.AuthAccess,
+			Request: SwitchClinicInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: envelope.Data[AccessTokenResponse]{}},
+				{Status: http.StatusForbidden, Description: "Not a member of the clinic"},
+			},
+		},
+	}
+
+	if oidcEnabled {
+		operations = append(operations,
+			openapi.Operation{
+				Method:    http.MethodGet,
+				Path:      "/api/v2/auth/oidc/login",
+				Summary:   "Start a login with the identity provider",
+				Tags:      tags,
+				Responses: []openapi.Response{{Status: http.StatusFound, Description: "Redirects to the identity provider"}},
+			},
+			openapi.Operation{
+				Method:  http.MethodGet,
+				Path:    "/api/v2/auth/oidc/callback",
+				Summary: "Complete a login with the identity provider, the refresh token is set in the session cookie",
+				Tags:    tags,
+				Parameters: []openapi.Parameter{
+					{Name: "code", In: openapi.InQuery, Example: ""},
+					{Name: "state", In: openapi.InQuery, Example: ""},
+					{Name: "error", In: openapi.InQuery, Description: "Set by the identity provider on refusal", Example: ""},
+				},
+				Responses: []openapi.Response{{Status: http.StatusFound, Description: "Redirects to the front-end"}},
+			},
+		)
+	}
+
+	return operations
+}
+
+func (a *authHandler) signupV2(context echo.Context) error {
+	userCreated, err := a.signup(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/users/%d", userCreated.ID), userCreated)
+}
+
+func (a *authHandler) loginV2(context echo.Context) error {
+	credentialsInput := new(UserUpdateInput)
+	if err := context.Bind(credentialsInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse input: %w", err))
+	}
+
+	tokens, err := a.startSession(context, user.User{
+		Email:    credentialsInput.Email,
+		Password: credentialsInput.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSON(context, http.StatusOK, tokens)
+}
+
+func (a *authHandler) refreshV2(context echo.Context) error {
+	tokens, err := a.rotateSession(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSON(context, http.StatusOK, tokens)
+}
+
+func (a *authHandler) logoutV2(context echo.Context) error {
+	if err := a.endSession(context); err != nil {
+		return err
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
+
+// switchClinic replaces the refresh token cookie, the previous access token stays valid until it expires
+func (a *authHandler) switchClinic(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	switchInput := new(SwitchClinicInput)
+	if err := context.Bind(switchInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse input: %w", err))
+	}
+
+	refreshToken, accessToken, err := a.authService.SwitchClinic(ctx, reqUserID, switchInput.ClinicID)
+	if err != nil {
+		if errors.Is(err, clinicSVC.ErrNotMember) {
+			return echo.NewHTTPError(http.StatusForbidden, clinicSVC.ErrNotMember)
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to switch clinic: %w", err))
+	}
+
+	sess, err := session.Get(authMiddleware.SessionName, context)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get session: %w", err))
+	}
+
+	sess.Options = &sessions.Options{
+		Domain:   a.cookiesConfig.Domain,
+		HttpOnly: true,
+		MaxAge:   int(a.cookiesConfig.MaxAge.Seconds()),
+		Path:     a.cookiesConfig.Domain,
+		SameSite: http.SameSite(a.cookiesConfig.SameSite),
+		Secure:   a.cookiesConfig.Secure,
+	}
+
+	sess.Values[authMiddleware.RefreshTokenCookieName] = string(refreshToken.SignedToken)
+	if err := sess.Save(context.Request(), context.Response()); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to save session: %w", err))
+	}
+
+	return envelope.JSON(context, http.StatusOK, AccessTokenResponse{
+		Token:            accessToken.SignedToken,
+		Type:             accessToken.Type,
+		ExpiresInSeconds: int64(accessToken.ExpirationDuration.Seconds()),
+	})
+}
+
+func (a *authHandler) getSessionStatsV2(context echo.Context) error {
+	stats, err := a.sessionStats(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSON(context, http.StatusOK, stats)
+}
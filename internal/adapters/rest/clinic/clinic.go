@@ -0,0 +1,190 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+)
+
+type clinicHandler struct {
+	cService clinicSVC.Service
+}
+
+// ClinicInput creates a clinic, its creator becomes its admin
+type ClinicInput struct {
+	Name string `json:"name" openapi:"required"`
+}
+
+// MemberInput gives an existing user a role set in the clinic
+type MemberInput struct {
+	Email user.Email `json:"email" openapi:"required"`
+	Roles user.Roles `json:"roles"`
+}
+
+// SetHandler only sets v2 routes, clinics came after the v1 deprecation
+func SetHandler(e *echo.Echo, service clinicSVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	c := &clinicHandler{
+		service,
+	}
+
+	// every user can list its own clinics
+	requireAccess := access.RequirePermissions(user.Permissions{})
+	requireClinicManage := access.RequirePermissions(user.Permissions{user.PermissionClinicManage})
+	requireUserManage := access.RequirePermissions(user.Permissions{user.PermissionUserManage})
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/clinics", c.listClinics, requireAccess)
+		apiV2.GET("/clinics/:id", c.getClinic, requireAccess)
+		apiV2.POST("/clinics", c.createClinic, requireClinicManage, spec.ValidateBody(), idempotency.Idempotent())
+		apiV2.POST("/clinics/:id/members", c.addMember, requireUserManage, spec.ValidateBody(), idempotency.Idempotent())
+	}
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"clinic"}
+	idParameter := openapi.Parameter{Name: "id", In: openapi.InPath, Example: clinic.ID(0)}
+	return []openapi.Operation{
+		{
+			Method:    http.MethodGet,
+			Path:      "/api/v2/clinics",
+			Summary:   "List the clinics of the requesting user with its roles in each of them",
+			Tags:      tags,
+			Auth:      openapi.AuthAccess,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: envelope.List[clinic.Membership]{}}},
+		},
+		{
+			Method:     http.MethodGet,
+			Path:       "/api/v2/clinics/:id",
+			Summary:    "Get a clinic of the requesting user with its roles in it",
+			Tags:       tags,
+			Auth:       openapi.AuthAccess,
+			Parameters: []openapi.Parameter{idParameter},
+			Responses:  []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[clinic.Membership]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/clinics",
+			Summary:     "Create a clinic, the requesting user becomes its admin",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionClinicManage},
+			Idempotent:  true,
+			Request:     ClinicInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[clinic.Clinic]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/clinics/:id/members",
+			Summary:     "Add an existing user to the clinic of the access token",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionUserManage},
+			Idempotent:  true,
+			Parameters:  []openapi.Parameter{idParameter},
+			Request:     MemberInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[clinic.Membership]{}}},
+		},
+	}
+}
+
+func (c *clinicHandler) listClinics(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	memberships, err := c.cService.ListMemberships(ctx, reqUserID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to list clinics: %w", err))
+	}
+
+	return envelope.JSONList(context, memberships)
+}
+
+func (c *clinicHandler) getClinic(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	clinicID, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	membership, err := c.cService.GetMembership(ctx, reqUserID, clinic.ID(clinicID))
+	if err != nil {
+		// the clinics of the others are not disclosed
+		if errors.Is(err, clinicSVC.ErrNotMember) {
+			return echo.NewHTTPError(http.StatusNotFound, fmt.Errorf("clinic %d not found", clinicID))
+		}
+
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get clinic: %w", err))
+	}
+
+	return envelope.JSON(context, http.StatusOK, membership)
+}
+
+func (c *clinicHandler) createClinic(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	clinicInput := new(ClinicInput)
+	if err := context.Bind(clinicInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse clinic input: %w", err))
+	}
+
+	clinicCreated, err := c.cService.CreateClinic(ctx, reqUserID, clinic.Clinic{Name: clinicInput.Name})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/clinics/%d", clinicCreated.ID), clinicCreated)
+}
+
+// addMember only acts on the clinic of the access token, the path keeps the resource explicit
+func (c *clinicHandler) addMember(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqClinicID, err := contextUtils.GetClinicIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to get clinic: %w", err))
+	}
+
+	clinicID, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	if clinic.ID(clinicID) != reqClinicID {
+		return echo.NewHTTPError(http.StatusForbidden, fmt.Errorf("unable to add member to clinic %d, switch to it first", clinicID))
+	}
+
+	memberInput := new(MemberInput)
+	if err := context.Bind(memberInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse member input: %w", err))
+	}
+
+	membership, err := c.cService.AddMember(ctx, memberInput.Email, memberInput.Roles)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/users/%d", membership.UserID), membership)
+}
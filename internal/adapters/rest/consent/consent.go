@@ -0,0 +1,180 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	consentSVC "github.com/sopial42/cleanic/internal/services/consent"
+)
+
+type consentHandler struct {
+	cService consentSVC.Service
+}
+
+// ConsentInput grants or revokes a purpose, the consent is collected by the authenticated user
+type ConsentInput struct {
+	Purpose     consent.Purpose `json:"purpose" openapi:"required"`
+	Status      consent.Status  `json:"status" openapi:"required"`
+	DocumentRef string          `json:"document_ref"`
+}
+
+func SetHandler(e *echo.Echo, service consentSVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	c := &consentHandler{
+		service,
+	}
+
+	requirePatientRead := access.RequirePermissions(user.Permissions{user.PermissionPatientRead})
+	requirePatientWrite := access.RequirePermissions(user.Permissions{user.PermissionPatientWrite})
+	apiV1 := e.Group("/api/v1")
+	{
+		apiV1.GET("/patients/:id/consents", c.getCurrentConsents, requirePatientRead)
+		apiV1.GET("/patients/:id/consents/history", c.getConsentHistory, requirePatientRead)
+		apiV1.POST("/patients/:id/consents", c.recordConsent, requirePatientWrite, spec.ValidateBody(), idempotency.Idempotent())
+	}
+
+	c.setV2Routes(e, requirePatientRead, requirePatientWrite, idempotency, spec)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"consent"}
+	read := user.Permissions{user.PermissionPatientRead}
+	write := user.Permissions{user.PermissionPatientWrite}
+	idParameter := openapi.Parameter{Name: "id", In: openapi.InPath, Example: patient.ID(0)}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/patients/:id/consents",
+			Summary:     "Get the latest consent of a patient for each purpose recorded",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []consent.Consent{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/patients/:id/consents/history",
+			Summary:     "List the consents ever recorded for a patient, newest first",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []consent.Consent{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/patients/:id/consents",
+			Summary:     "Record that a patient granted or revoked a purpose",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Idempotent:  true,
+			Parameters:  []openapi.Parameter{idParameter},
+			Request:     ConsentInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: consent.Consent{}}},
+		},
+	}, operationsV2()...)
+}
+
+func (c *consentHandler) getCurrentConsents(context echo.Context) error {
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	consents, err := c.cService.CurrentConsents(context.Request().Context(), patientID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return context.JSON(http.StatusOK, consents)
+}
+
+func (c *consentHandler) getConsentHistory(context echo.Context) error {
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	history, err := c.cService.ListConsents(context.Request().Context(), patientID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	if history == nil {
+		history = []consent.Consent{}
+	}
+
+	return context.JSON(http.StatusOK, history)
+}
+
+func (c *consentHandler) recordConsent(context echo.Context) error {
+	consentRecorded, err := c.record(context)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusCreated, consentRecorded)
+}
+
+func (c *consentHandler) record(context echo.Context) (consent.Consent, error) {
+	ctx := context.Request().Context()
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return consent.Consent{}, err
+	}
+
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return consent.Consent{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	consentInput := new(ConsentInput)
+	if err := context.Bind(consentInput); err != nil {
+		return consent.Consent{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse consent input: %w", err))
+	}
+
+	consentRecorded, err := c.cService.RecordConsent(ctx, consent.Consent{
+		PatientID:   patientID,
+		Purpose:     consentInput.Purpose,
+		Status:      consentInput.Status,
+		CollectedBy: reqUserID,
+		DocumentRef: consentInput.DocumentRef,
+	})
+	if err != nil {
+		return consent.Consent{}, httpError(err)
+	}
+
+	return consentRecorded, nil
+}
+
+func patientIDParam(context echo.Context) (patient.ID, error) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid patient id: %w", err))
+	}
+
+	return patient.ID(id), nil
+}
+
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, consentSVC.ErrPatientNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	case errors.Is(err, consentSVC.ErrInvalidConsent):
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+}
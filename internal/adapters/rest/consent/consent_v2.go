@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+func (c *consentHandler) setV2Routes(e *echo.Echo, requirePatientRead, requirePatientWrite echo.MiddlewareFunc, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/patients/:id/consents", c.listCurrentConsentsV2, requirePatientRead)
+		apiV2.GET("/patients/:id/consents/history", c.listConsentHistoryV2, requirePatientRead)
+		apiV2.POST("/patients/:id/consents", c.recordConsentV2, requirePatientWrite, spec.ValidateBody(), idempotency.Idempotent())
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"consent"}
+	read := user.Permissions{user.PermissionPatientRead}
+	write := user.Permissions{user.PermissionPatientWrite}
+	idParameter := openapi.Parameter{Name: "id", In: openapi.InPath, Example: patient.ID(0)}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/patients/:id/consents",
+			Summary:     "Get the latest consent of a patient for each purpose recorded",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[consent.Consent]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/patients/:id/consents/history",
+			Summary:     "List the consents ever recorded for a patient, newest first",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[consent.Consent]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/patients/:id/consents",
+			Summary:     "Record that a patient granted or revoked a purpose, Location points to the history",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Idempotent:  true,
+			Parameters:  []openapi.Parameter{idParameter},
+			Request:     ConsentInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[consent.Consent]{}}},
+		},
+	}
+}
+
+func (c *consentHandler) listCurrentConsentsV2(context echo.Context) error {
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	consents, err := c.cService.CurrentConsents(context.Request().Context(), patientID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return envelope.JSONList(context, consents)
+}
+
+func (c *consentHandler) listConsentHistoryV2(context echo.Context) error {
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	history, err := c.cService.ListConsents(context.Request().Context(), patientID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return envelope.JSONList(context, history)
+}
+
+func (c *consentHandler) recordConsentV2(context echo.Context) error {
+	consentRecorded, err := c.record(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/patients/%d/consents/history", consentRecorded.PatientID), consentRecorded)
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/domains/health"
+	healthSVC "github.com/sopial42/cleanic/internal/services/health"
+)
+
+type healthHandler struct {
+	hService healthSVC.Service
+}
+
+// SetHandler registers the probes at the root, outside of the versioned API and without auth
+func SetHandler(e *echo.Echo, service healthSVC.Service) {
+	h := &healthHandler{
+		service,
+	}
+
+	e.GET("/healthz", h.getLiveness)
+	e.GET("/readyz", h.getReadiness)
+	e.GET("/version", h.getVersion)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"health"}
+	return []openapi.Operation{
+		{
+			Method:    http.MethodGet,
+			Path:      "/healthz",
+			Summary:   "Liveness probe, it does not depend on the DB",
+			Tags:      tags,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: health.Check{}}},
+		},
+		{
+			Method:  http.MethodGet,
+			Path:    "/readyz",
+			Summary: "Readiness probe",
+			Tags:    tags,
+			Responses: []openapi.Response{
+				{Status: http.StatusOK, Body: health.Report{}},
+				{Status: http.StatusServiceUnavailable, Description: "A check is down or the server is shutting down", Body: health.Report{}},
+			},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/version",
+			Summary:   "Describe the running binary",
+			Tags:      tags,
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: health.BuildInfo{}}},
+		},
+	}
+}
+
+// getLiveness only tells the process serves HTTP, it must not depend on the DB
+// or the orchestrator would restart every replica during a DB outage
+func (h *healthHandler) getLiveness(context echo.Context) error {
+	return context.JSON(http.StatusOK, health.Check{Status: health.StatusUp})
+}
+
+func (h *healthHandler) getReadiness(context echo.Context) error {
+	report := h.hService.Readiness(context.Request().Context())
+	if report.Status != health.StatusUp {
+		return context.JSON(http.StatusServiceUnavailable, report)
+	}
+
+	return context.JSON(http.StatusOK, report)
+}
+
+func (h *healthHandler) getVersion(context echo.Context) error {
+	return context.JSON(http.StatusOK, h.hService.BuildInfo())
+}
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	job "github.com/sopial42/cleanic/internal/domains/job"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	jobSVC "github.com/sopial42/cleanic/internal/services/job"
+)
+
+type jobHandler struct {
+	jService jobSVC.Service
+}
+
+func SetHandler(e *echo.Echo, service jobSVC.Service, access middleware.AuthAccessMiddleware) {
+	j := &jobHandler{
+		service,
+	}
+
+	requireJobRead := access.RequirePermissions(user.Permissions{user.PermissionJobRead})
+	apiV1 := e.Group("/api/v1")
+	{
+		apiV1.GET("/jobs", j.getJobs, requireJobRead)
+	}
+
+	j.setV2Routes(e, requireJobRead)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/jobs",
+			Summary:     "List the latest background jobs of the clinic and the system ones, with their state",
+			Tags:        []string{"job"},
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionJobRead},
+			Parameters:  []openapi.Parameter{stateParameter, kindParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []job.Job{}}},
+		},
+	}, operationsV2()...)
+}
+
+var (
+	stateParameter = openapi.Parameter{
+		Name:        "state",
+		In:          openapi.InQuery,
+		Description: "Only lists the jobs in this state: queued, running, succeeded or dead",
+		Example:     job.StateDead,
+	}
+	kindParameter = openapi.Parameter{
+		Name:        "kind",
+		In:          openapi.InQuery,
+		Description: "Only lists the jobs of this kind",
+		Example:     job.Kind("idempotency.purge"),
+	}
+)
+
+func (j *jobHandler) getJobs(context echo.Context) error {
+	jobs, err := j.jobs(context)
+	if err != nil {
+		return err
+	}
+
+	if jobs == nil {
+		jobs = []job.Job{}
+	}
+
+	return context.JSON(http.StatusOK, jobs)
+}
+
+func (j *jobHandler) jobs(context echo.Context) ([]job.Job, error) {
+	state := job.State(context.QueryParam("state"))
+	if state != "" && !state.IsValid() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unknown job state %q", state))
+	}
+
+	jobs, err := j.jService.ListJobs(context.Request().Context(), state, job.Kind(context.QueryParam("kind")))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,43 @@
+package rest
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	job "github.com/sopial42/cleanic/internal/domains/job"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+func (j *jobHandler) setV2Routes(e *echo.Echo, requireJobRead echo.MiddlewareFunc) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/jobs", j.listJobsV2, requireJobRead)
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/jobs",
+			Summary:     "List the latest background jobs of the clinic and the system ones, with their state",
+			Tags:        []string{"job"},
+			Auth:        openapi.AuthAccess,
+			Permissions: user.Permissions{user.PermissionJobRead},
+			Parameters:  []openapi.Parameter{stateParameter, kindParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[job.Job]{}}},
+		},
+	}
+}
+
+func (j *jobHandler) listJobsV2(context echo.Context) error {
+	jobs, err := j.jobs(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSONList(context, jobs)
+}
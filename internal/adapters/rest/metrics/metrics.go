@@ -0,0 +1,12 @@
+package rest
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// SetHandler exposes the gathered metrics, the engine is expected to listen apart from the public API
+func SetHandler(e *echo.Echo, gatherer prometheus.Gatherer) {
+	e.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})))
+}
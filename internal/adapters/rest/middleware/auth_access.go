@@ -1,49 +1,131 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 
 	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
 	jwtUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
 	"github.com/sopial42/cleanic/internal/config"
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	"github.com/sopial42/cleanic/internal/domains/user"
+	apiKeySVC "github.com/sopial42/cleanic/internal/services/apikey"
 )
 
+// APIKeyScheme is the Authorization scheme used by machine clients, next to Bearer
+const APIKeyScheme = "ApiKey"
+
+// PermissionResolver turns the roles carried by a token into permissions
+type PermissionResolver interface {
+	PermissionsForRoles(ctx context.Context, roles user.Roles) (user.Permissions, error)
+}
+
+// APIKeyAuthenticator resolves an API key to its owner
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, plainKey apikey.PlainKey) (apikey.APIKey, user.User, error)
+}
+
 type AuthAccessMiddleware struct {
-	secret                 []byte
-	TokenExpirationMinutes int
+	tokenConfig config.AccessTokenConfig
+	TokenTTL    time.Duration
+	permissions PermissionResolver
+	apiKeys     APIKeyAuthenticator
+	rateLimits  *RateLimitMiddleware
 }
 
-func NewAuthAccessMiddleware(config config.AccessTokenConfig) AuthAccessMiddleware {
+// NewAuthAccessMiddleware limits the requests per user with rateLimits, nil disables it
+func NewAuthAccessMiddleware(config config.AccessTokenConfig, permissions PermissionResolver, apiKeys APIKeyAuthenticator, rateLimits *RateLimitMiddleware) AuthAccessMiddleware {
 	return AuthAccessMiddleware{
-		secret:                 config.GetSecret(),
-		TokenExpirationMinutes: config.TokenExpirationMinutes,
+		tokenConfig: config,
+		TokenTTL:    config.TokenTTL,
+		permissions: permissions,
+		apiKeys:     apiKeys,
+		rateLimits:  rateLimits,
 	}
 }
 
-func (a *AuthAccessMiddleware) RequireRoles(requiredRoles user.Roles) echo.MiddlewareFunc {
+// RequirePermissions resolves the token roles at request time,
+// so role changes apply without waiting for the access token to expire
+func (a *AuthAccessMiddleware) RequirePermissions(requiredPermissions user.Permissions) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			header := c.Request().Header.Get("Authorization")
-			token, err := jwtUtils.ParseBearerHeader(header)
+
+			var (
+				userID   user.ID
+				clinicID clinic.ID
+				roles    user.Roles
+				scopes   user.Permissions
+				err      error
+			)
+			if strings.HasPrefix(header, APIKeyScheme+" ") {
+				userID, clinicID, roles, scopes, err = a.authenticateAPIKey(c.Request().Context(), header)
+			} else {
+				userID, clinicID, roles, err = a.authenticateBearer(header)
+			}
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("unable to parse authorization header: %w", err))
+				return err
+			}
+
+			if err := a.rateLimits.limitUser(c, userID); err != nil {
+				return err
 			}
 
-			claims, err := jwtUtils.ParseAccessClaims(token, a.secret)
+			// the roles are the ones of the token clinic
+			permissions, err := a.permissions.PermissionsForRoles(clinic.WithID(c.Request().Context(), clinicID), roles)
 			if err != nil {
-				return echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("unable to parse auth token: %w", err))
+				return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to resolve permissions: %w", err))
 			}
 
-			if err := user.ValidateRequiredRoles(requiredRoles, claims.Roles); err != nil {
+			// An API key only grants the part of its owner permissions listed in its scopes
+			if scopes != nil {
+				permissions = permissions.Intersect(scopes)
+			}
+
+			if err := user.ValidateRequiredPermissions(requiredPermissions, permissions); err != nil {
 				return echo.NewHTTPError(http.StatusForbidden, fmt.Errorf("unauthorized resource: %w", err))
 			}
 
-			contextUtils.SetUserIDAndRolesToContext(c, claims.Subject, claims.Roles)
+			contextUtils.SetUserIDAndRolesToContext(c, userID, roles)
+			contextUtils.SetUserPermissionsToContext(c, permissions)
+			contextUtils.SetClinicIDToContext(c, clinicID)
 			return next(c)
 		}
 	}
 }
+
+// authenticateBearer returns the clinic the token is scoped to, the roles are the ones in this clinic
+func (a *AuthAccessMiddleware) authenticateBearer(header string) (user.ID, clinic.ID, user.Roles, error) {
+	token, err := jwtUtils.ParseBearerHeader(header)
+	if err != nil {
+		return 0, 0, nil, echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("unable to parse authorization header: %w", err))
+	}
+
+	claims, err := jwtUtils.ParseAccessClaims(token, a.tokenConfig.GetVerificationSecrets()...)
+	if err != nil {
+		return 0, 0, nil, echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("unable to parse auth token: %w", err))
+	}
+
+	return claims.Subject, claims.ClinicID, claims.Roles, nil
+}
+
+func (a *AuthAccessMiddleware) authenticateAPIKey(ctx context.Context, header string) (user.ID, clinic.ID, user.Roles, user.Permissions, error) {
+	plainKey := apikey.PlainKey(strings.TrimPrefix(header, APIKeyScheme+" "))
+	key, owner, err := a.apiKeys.Authenticate(ctx, plainKey)
+	if err != nil {
+		if errors.Is(err, apiKeySVC.ErrInvalidAPIKey) {
+			return 0, 0, nil, nil, echo.NewHTTPError(http.StatusUnauthorized, fmt.Errorf("unable to authenticate api key: %w", err))
+		}
+
+		return 0, 0, nil, nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate api key: %w", err))
+	}
+
+	return owner.ID, key.ClinicID, owner.Roles, key.Scopes, nil
+}
@@ -16,14 +16,14 @@ const RefreshTokenCookieName = "refresh_token"
 const SessionName = "session"
 
 type AuthRefreshMiddleware struct {
-	secret                 []byte
-	TokenExpirationMinutes int
+	tokenConfig config.RefreshTokenConfig
+	TokenTTL    time.Duration
 }
 
 func NewAuthRefreshMiddleware(config config.RefreshTokenConfig) AuthRefreshMiddleware {
 	return AuthRefreshMiddleware{
-		secret:                 config.GetSecret(),
-		TokenExpirationMinutes: config.TokenExpirationMinutes,
+		tokenConfig: config,
+		TokenTTL:    config.TokenTTL,
 	}
 }
 
@@ -45,7 +45,7 @@ func (a *AuthRefreshMiddleware) RequireRefreshToken() echo.MiddlewareFunc {
 				return fmt.Errorf("unable to parse signed refresh token from session: %w", err)
 			}
 
-			claims, err := jwtUtils.ParseRefreshClaims(jwtUtils.SignedRefreshToken(tokenStr), a.secret)
+			claims, err := jwtUtils.ParseRefreshClaims(jwtUtils.SignedRefreshToken(tokenStr), a.tokenConfig.GetVerificationSecrets()...)
 			if err != nil {
 				return fmt.Errorf("unable to parse refreshToken: %w", err)
 			}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net"
+
+	"github.com/labstack/echo/v4"
+)
+
+// NewIPExtractor decides what RealIP returns, which keys the rate limits, the login lockouts and the
+// idempotency of the anonymous callers. Without trusted proxies it is the peer address, as the
+// X-Forwarded-For and X-Real-IP headers are set by the clients. Behind proxies, X-Forwarded-For is
+// read from the right, skipping the trusted proxies only
+func NewIPExtractor(trustedProxies []*net.IPNet) echo.IPExtractor {
+	if len(trustedProxies) == 0 {
+		return echo.ExtractIPDirect()
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxies := range trustedProxies {
+		options = append(options, echo.TrustIPRange(proxies))
+	}
+
+	return echo.ExtractIPFromXFFHeader(options...)
+}
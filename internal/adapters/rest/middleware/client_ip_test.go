@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPExtractor(t *testing.T) {
+	_, proxies, _ := net.ParseCIDR("10.0.0.0/8")
+	for _, tc := range []struct {
+		name           string
+		trustedProxies []*net.IPNet
+		remoteAddr     string
+		forwardedFor   string
+		expected       string
+	}{
+		{"the headers of the clients are ignored", nil, "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+		{"a trusted proxy forwards the client", []*net.IPNet{proxies}, "10.0.0.2:4000", "198.51.100.1", "198.51.100.1"},
+		{"the hops set by the client are skipped", []*net.IPNet{proxies}, "10.0.0.2:4000", "192.0.2.9, 198.51.100.1", "198.51.100.1"},
+		{"an untrusted peer is the client", []*net.IPNet{proxies}, "203.0.113.7:4000", "198.51.100.1", "203.0.113.7"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/", nil)
+			request.RemoteAddr = tc.remoteAddr
+			request.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			request.Header.Set("X-Real-Ip", "192.0.2.1")
+			if ip := NewIPExtractor(tc.trustedProxies)(request); ip != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, ip)
+			}
+		})
+	}
+}
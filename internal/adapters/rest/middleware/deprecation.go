@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+)
+
+// Deprecation advertises the retirement of the routes under prefix with the Deprecation (RFC 9745)
+// and Sunset (RFC 8594) headers, and links the successor. A zero deprecatedAt disables it
+func Deprecation(prefix string, successor string, deprecatedAt time.Time, sunset time.Time) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if deprecatedAt.IsZero() || !strings.HasPrefix(c.Request().URL.Path, prefix) {
+				return next(c)
+			}
+
+			// set before the handler so that the errors carry them too
+			header := c.Response().Header()
+			header.Set(HeaderDeprecation, fmt.Sprintf("@%d", deprecatedAt.Unix()))
+			if !sunset.IsZero() {
+				header.Set(HeaderSunset, sunset.UTC().Format(http.TimeFormat))
+			}
+			header.Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor))
+
+			return next(c)
+		}
+	}
+}
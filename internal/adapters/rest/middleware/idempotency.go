@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/logging"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// IdempotencyMiddleware replays the response of a creating request retried with the same Idempotency-Key.
+// It is set after the access middleware, the keys are scoped per user, or per client IP on anonymous routes
+type IdempotencyMiddleware struct {
+	service idempotencySVC.Service
+	logger  *slog.Logger
+}
+
+func NewIdempotencyMiddleware(service idempotencySVC.Service) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		service: service,
+		logger:  logging.Component("rest/idempotency"),
+	}
+}
+
+// Idempotent makes the header optional, requests without it are processed as usual
+func (i *IdempotencyMiddleware) Idempotent() echo.MiddlewareFunc {
+	return i.idempotent(false)
+}
+
+// IdempotentSecret is Idempotent for the routes answering a secret shown once, such as an API key.
+// Their successful responses are not stored, a retry answers 410 with the Location of the resource
+// rather than creating it twice or handing the secret again
+func (i *IdempotencyMiddleware) IdempotentSecret() echo.MiddlewareFunc {
+	return i.idempotent(true)
+}
+
+func (i *IdempotencyMiddleware) idempotent(secret bool) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(HeaderIdempotencyKey)
+			if key == "" {
+				return next(c)
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("idempotency key longer than %d characters", maxIdempotencyKeyLength))
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to read body: %w", err))
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := c.Request().Context()
+			subject := idempotencySubject(c)
+			fingerprint := idempotency.NewFingerprint(c.Request().Method, c.Request().URL.Path, body)
+			record, started, err := i.service.Begin(ctx, subject, key, fingerprint)
+			switch {
+			case errors.Is(err, idempotencySVC.ErrKeyReused):
+				return echo.NewHTTPError(http.StatusUnprocessableEntity, err.Error())
+			case errors.Is(err, idempotencySVC.ErrRequestInProgress):
+				return echo.NewHTTPError(http.StatusConflict, err.Error())
+			case err != nil:
+				return echo.NewHTTPError(http.StatusInternalServerError, err)
+			}
+
+			if !started {
+				c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+				if record.Location != "" {
+					c.Response().Header().Set(echo.HeaderLocation, record.Location)
+				}
+				if secret && record.StatusCode < http.StatusMultipleChoices {
+					return echo.NewHTTPError(http.StatusGone, "the response carried a secret shown once, it is not replayed")
+				}
+				return c.Blob(record.StatusCode, record.ContentType, record.Body)
+			}
+
+			// the key is released unless the response is stored, a panic or a failed store must not hold
+			// it in progress until it expires. The request context is left out, the client may be gone
+			storeCtx := context.WithoutCancel(ctx)
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if abortErr := i.service.Abort(storeCtx, subject, key); abortErr != nil {
+					i.logger.ErrorContext(storeCtx, "Unable to release idempotency key", "error", abortErr)
+				}
+			}()
+
+			// the response is written here rather than by the global error handler to be captured
+			capture := &capturingWriter{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = capture
+			if err = next(c); err != nil {
+				c.Error(err)
+			}
+
+			// a server error is not stored, the client retries with the same key
+			status := c.Response().Status
+			if status >= http.StatusInternalServerError {
+				return err
+			}
+
+			record.StatusCode = status
+			record.ContentType = c.Response().Header().Get(echo.HeaderContentType)
+			record.Location = c.Response().Header().Get(echo.HeaderLocation)
+			record.Body = capture.body.Bytes()
+			if secret && status < http.StatusMultipleChoices {
+				record.ContentType, record.Body = "", nil
+			}
+			if completeErr := i.service.Complete(storeCtx, record); completeErr != nil {
+				i.logger.ErrorContext(storeCtx, "Unable to store idempotent response", "error", completeErr)
+				return err
+			}
+			completed = true
+
+			return err
+		}
+	}
+}
+
+func idempotencySubject(c echo.Context) string {
+	if userID, err := contextUtils.GetUserIDFromContext(c.Request().Context()); err == nil {
+		return fmt.Sprintf("user:%d", userID)
+	}
+
+	return "ip:" + c.RealIP()
+}
+
+type capturingWriter struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *capturingWriter) Write(p []byte) (int, error) {
+	w.body.Write(p)
+	return w.ResponseWriter.Write(p)
+}
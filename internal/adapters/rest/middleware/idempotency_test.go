@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	echoMiddleware "github.com/labstack/echo/v4/middleware"
+
+	"github.com/sopial42/cleanic/internal/adapters/persistence"
+	idempotencyPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/idempotency"
+	idempotencySVC "github.com/sopial42/cleanic/internal/services/idempotency"
+)
+
+func TestIdempotentReleasesTheKeyOfAPanickingRequest(t *testing.T) {
+	idempotency := NewIdempotencyMiddleware(idempotencySVC.NewIdempotencyService(idempotencyPersistence.NewInMemoryClient(persistence.NewInMemoryDB()), time.Hour))
+
+	panics := true
+	e := echo.New()
+	e.Use(echoMiddleware.Recover())
+	e.POST("/patient", func(c echo.Context) error {
+		if panics {
+			panic("handler bug")
+		}
+		return c.String(http.StatusCreated, "created")
+	}, idempotency.Idempotent())
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/patient", strings.NewReader(`{}`))
+		req.Header.Set(HeaderIdempotencyKey, "retried")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("panicking request: %d", rec.Code)
+	}
+
+	// the retry is processed again rather than refused as still in progress
+	panics = false
+	if rec := send(); rec.Code != http.StatusCreated || rec.Header().Get(HeaderIdempotentReplayed) != "" {
+		t.Fatalf("retry: %d %v", rec.Code, rec.Header())
+	}
+	if rec := send(); rec.Code != http.StatusCreated || rec.Header().Get(HeaderIdempotentReplayed) != "true" {
+		t.Fatalf("replay: %d %v", rec.Code, rec.Header())
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sopial42/cleanic/internal/adapters/metrics"
+)
+
+// NewMetricsMiddleware counts and times the requests per route template, method and status.
+// The route template, not the URL, is used as label to keep the number of series bounded
+func NewMetricsMiddleware(registerer prometheus.Registerer) echo.MiddlewareFunc {
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route, method and status.",
+	}, []string{"route", "method", "status"})
+	durations := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metrics.Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route, method and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+	registerer.MustRegister(requests, durations)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			status := strconv.Itoa(responseStatus(c, err))
+
+			requests.WithLabelValues(route, c.Request().Method, status).Inc()
+			durations.WithLabelValues(route, c.Request().Method, status).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
+
+// responseStatus anticipates the status the error handler will write once the error is returned
+func responseStatus(c echo.Context, err error) int {
+	if err == nil || c.Response().Committed {
+		return c.Response().Status
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/logging"
+	"github.com/sopial42/cleanic/internal/config"
+	"github.com/sopial42/cleanic/internal/domains/ratelimit"
+	"github.com/sopial42/cleanic/internal/domains/user"
+	rateLimitSVC "github.com/sopial42/cleanic/internal/services/ratelimit"
+)
+
+// RateLimitMiddleware limits the anonymous routes per client IP and the authenticated ones per user,
+// the latter is applied by AuthAccessMiddleware once the user is known
+type RateLimitMiddleware struct {
+	limiter rateLimitSVC.Service
+	config  config.RateLimitConfig
+	logger  *slog.Logger
+}
+
+func NewRateLimitMiddleware(limiter rateLimitSVC.Service, config config.RateLimitConfig) *RateLimitMiddleware {
+	return &RateLimitMiddleware{
+		limiter: limiter,
+		config:  config,
+		logger:  logging.Component("rest/ratelimit"),
+	}
+}
+
+// LimitByIP is set on the routes reachable without access token
+func (r *RateLimitMiddleware) LimitByIP() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if err := r.limit(c, "ip:"+c.RealIP(), r.config.IP); err != nil {
+				return err
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func (r *RateLimitMiddleware) limitUser(c echo.Context, userID user.ID) error {
+	return r.limit(c, fmt.Sprintf("user:%d", userID), r.config.User)
+}
+
+// limit takes a token from the bucket of the subject, or of the subject on this route when it has its own quota
+func (r *RateLimitMiddleware) limit(c echo.Context, subject string, quota config.RateLimitQuota) error {
+	if r == nil || !r.config.Enabled {
+		return nil
+	}
+
+	key := subject
+	if routeQuota, found := r.config.Routes[c.Path()]; found {
+		quota = routeQuota
+		key = subject + ":" + c.Path()
+	}
+
+	decision, err := r.limiter.Allow(c.Request().Context(), key, ratelimit.Quota(quota))
+	if err != nil {
+		// fail open, an unavailable store must not take the whole API down
+		r.logger.ErrorContext(c.Request().Context(), "Unable to check rate limit", "error", err)
+		return nil
+	}
+
+	header := c.Response().Header()
+	header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	header.Set("RateLimit-Reset", seconds(decision.Reset))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", quota.Requests, seconds(quota.Period)))
+
+	if !decision.Allowed {
+		header.Set("Retry-After", seconds(decision.RetryAfter))
+		return echo.NewHTTPError(http.StatusTooManyRequests, "rate limit exceeded, retry later")
+	}
+
+	return nil
+}
+
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	rateLimitPersistence "github.com/sopial42/cleanic/internal/adapters/persistence/ratelimit"
+	"github.com/sopial42/cleanic/internal/config"
+	rateLimitSVC "github.com/sopial42/cleanic/internal/services/ratelimit"
+)
+
+func TestLimitByIP(t *testing.T) {
+	limiter := NewRateLimitMiddleware(rateLimitSVC.NewRateLimitService(rateLimitPersistence.NewInMemoryStore()), config.RateLimitConfig{
+		Enabled: true,
+		IP:      config.RateLimitQuota{Requests: 100, Period: time.Minute},
+		Routes: map[string]config.RateLimitQuota{
+			"/login": {Requests: 1, Period: time.Minute},
+		},
+	})
+
+	e := echo.New()
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusNoContent) }
+	e.POST("/login", ok, limiter.LimitByIP())
+	e.POST("/signup", ok, limiter.LimitByIP())
+
+	send := func(path string, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send("/login", "10.0.0.1"); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("first login: %d %v", rec.Code, rec.Header())
+	}
+
+	rec := send("/login", "10.0.0.1")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" || rec.Header().Get("RateLimit-Policy") != "1;w=60" {
+		t.Fatalf("second login: %d %v", rec.Code, rec.Header())
+	}
+
+	// the route quota has its own bucket, and each IP its own
+	if rec := send("/signup", "10.0.0.1"); rec.Code != http.StatusNoContent || rec.Header().Get("RateLimit-Limit") != "100" {
+		t.Fatalf("signup: %d %v", rec.Code, rec.Header())
+	}
+	if rec := send("/login", "10.0.0.2"); rec.Code != http.StatusNoContent {
+		t.Fatalf("login from another IP: %d", rec.Code)
+	}
+}
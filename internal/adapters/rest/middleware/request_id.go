@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/logging"
+)
+
+// validRequestID bounds the IDs accepted from the callers, they end up in every log line
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._\-]{1,128}$`)
+
+// RequestID keeps the X-Request-ID of the caller, or generates one, puts it in the request
+// context for the logs and sends it back in the response
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := c.Request().Header.Get(echo.HeaderXRequestID)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+		ctx := logging.WithRequestID(c.Request().Context(), requestID)
+		c.SetRequest(c.Request().WithContext(ctx))
+
+		return next(c)
+	}
+}
@@ -0,0 +1,69 @@
+package openapi
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// swaggerUI loads the assets from a CDN to keep them out of the binary
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <title>cleanic API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+type openAPIHandler struct {
+	spec *Spec
+}
+
+// SetHandler serves the document and its Swagger UI at the root, without auth
+func SetHandler(e *echo.Echo, spec *Spec) {
+	h := &openAPIHandler{
+		spec,
+	}
+
+	e.GET("/openapi.json", h.getDocument)
+	e.GET("/docs", h.getSwaggerUI)
+}
+
+func Operations() []Operation {
+	tags := []string{"documentation"}
+	return []Operation{
+		{
+			Method:    http.MethodGet,
+			Path:      "/openapi.json",
+			Summary:   "Get this OpenAPI document",
+			Tags:      tags,
+			Responses: []Response{{Status: http.StatusOK, Body: map[string]any{}}},
+		},
+		{
+			Method:    http.MethodGet,
+			Path:      "/docs",
+			Summary:   "Browse this OpenAPI document with Swagger UI",
+			Tags:      tags,
+			Responses: []Response{{Status: http.StatusOK, Description: "HTML page"}},
+		},
+	}
+}
+
+func (h *openAPIHandler) getDocument(context echo.Context) error {
+	return context.JSONBlob(http.StatusOK, h.spec.Document())
+}
+
+func (h *openAPIHandler) getSwaggerUI(context echo.Context) error {
+	return context.HTML(http.StatusOK, swaggerUI)
+}
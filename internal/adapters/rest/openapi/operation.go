@@ -0,0 +1,63 @@
+package openapi
+
+import (
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// Auth tells which credentials an operation expects
+type Auth int
+
+const (
+	AuthNone Auth = iota
+	// AuthAccess accepts a Bearer access token or an API key
+	AuthAccess
+	// AuthRefresh expects the refresh token cookie
+	AuthRefresh
+)
+
+// Operation documents a route next to the SetHandler registering it
+type Operation struct {
+	Method  string
+	Path    string
+	Summary string
+	Tags    []string
+	Auth    Auth
+	// Permissions are only listed in the description, they are checked by the access middleware
+	Permissions user.Permissions
+	// Idempotent routes accept the Idempotency-Key header
+	Idempotent bool
+	// Parameters describe the query parameters and the path parameters which are not strings,
+	// the other path parameters are read from Path
+	Parameters []Parameter
+	// Request is a value of the JSON body type, nil when the route takes no body
+	Request   any
+	Responses []Response
+	// Error is a value of the error body type, ErrorResponse when nil
+	Error      any
+	Deprecated bool
+}
+
+const (
+	InPath  = "path"
+	InQuery = "query"
+)
+
+type Parameter struct {
+	Name        string
+	In          string
+	Description string
+	// Example is a value of the parameter type
+	Example any
+}
+
+type Response struct {
+	Status      int
+	Description string
+	// Body is a value of the JSON body type, nil when the response has no body
+	Body any
+}
+
+// ErrorResponse is the body of the echo HTTP errors
+type ErrorResponse struct {
+	Message string `json:"message"`
+}
@@ -0,0 +1,230 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Schema is the subset of JSON Schema generated from the Go types
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       any                `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	// AdditionalProperties is the *Schema of the map values, or false for the structs
+	AdditionalProperties any       `json:"additionalProperties,omitempty"`
+	Items                *Schema   `json:"items,omitempty"`
+	AnyOf                []*Schema `json:"anyOf,omitempty"`
+}
+
+const componentsPrefix = "#/components/schemas/"
+
+// requiredTag marks the struct fields which must be present in the request bodies,
+// e.g. `json:"name" openapi:"required"`
+const requiredTag = "required"
+
+var (
+	timeType           = reflect.TypeOf(time.Time{})
+	rawMessageType     = reflect.TypeOf(json.RawMessage{})
+	qualifiedTypeRegex = regexp.MustCompile(`[\w./-]*\.`)
+	componentNameRegex = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// schemaGenerator reflects the JSON encoding of the Go types,
+// named structs become components so that they are described once
+type schemaGenerator struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+func (g *schemaGenerator) schemaOf(value any) (*Schema, error) {
+	return g.schema(reflect.TypeOf(value))
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) (*Schema, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		elem, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return nullable(elem), nil
+	case reflect.Struct:
+		if t == timeType {
+			return &Schema{Type: "string", Format: "date-time"}, nil
+		}
+
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		return g.component(t)
+	case reflect.Slice, reflect.Array:
+		if t == rawMessageType {
+			return &Schema{}, nil
+		}
+
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}, nil
+		}
+
+		items, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: "array", Items: items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+
+		values, err := g.schema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+
+		return &Schema{Type: "object", AdditionalProperties: values}, nil
+	case reflect.Interface:
+		return &Schema{}, nil
+	case reflect.String:
+		return &Schema{Type: "string"}, nil
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}, nil
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}, nil
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}, nil
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}, nil
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// component registers the named struct once and references it
+func (g *schemaGenerator) component(t reflect.Type) (*Schema, error) {
+	if name, found := g.names[t]; found {
+		return &Schema{Ref: componentsPrefix + name}, nil
+	}
+
+	name := componentName(t, false)
+	if _, taken := g.components[name]; taken {
+		name = componentName(t, true)
+	}
+
+	// registered before the properties so that recursive types end on a reference
+	g.names[t] = name
+	g.components[name] = &Schema{}
+	schema, err := g.structSchema(t)
+	if err != nil {
+		return nil, err
+	}
+
+	g.components[name] = schema
+	return &Schema{Ref: componentsPrefix + name}, nil
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) (*Schema, error) {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}, AdditionalProperties: false}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		fieldType := field.Type
+		if field.Anonymous && name == "" {
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+
+			// embedded structs are flattened like encoding/json does
+			if fieldType.Kind() == reflect.Struct {
+				embedded, err := g.structSchema(fieldType)
+				if err != nil {
+					return nil, fmt.Errorf("unable to describe %s: %w", t, err)
+				}
+
+				for property, propertySchema := range embedded.Properties {
+					if _, found := schema.Properties[property]; !found {
+						schema.Properties[property] = propertySchema
+						if slices.Contains(embedded.Required, property) {
+							schema.Required = append(schema.Required, property)
+						}
+					}
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		propertySchema, err := g.schema(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe %s.%s: %w", t, field.Name, err)
+		}
+
+		schema.Properties[name] = propertySchema
+		if field.Tag.Get("openapi") == requiredTag {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema, nil
+}
+
+// componentName strips the package paths of the generic arguments,
+// qualified prefixes the package name to tell apart the types sharing a name
+func componentName(t reflect.Type, qualified bool) string {
+	name := qualifiedTypeRegex.ReplaceAllString(t.Name(), "")
+	name = strings.Trim(componentNameRegex.ReplaceAllString(name, "_"), "_")
+	if !qualified {
+		return name
+	}
+
+	pkg := []rune(path.Base(t.PkgPath()))
+	pkg[0] = unicode.ToUpper(pkg[0])
+	return string(pkg) + name
+}
+
+func nullable(schema *Schema) *Schema {
+	if schema.Ref == "" && schema.Type == nil {
+		// any value already includes null
+		return schema
+	}
+
+	if schema.Ref != "" {
+		return &Schema{AnyOf: []*Schema{schema, {Type: "null"}}}
+	}
+
+	nullableSchema := *schema
+	nullableSchema.Type = []any{schema.Type, "null"}
+	return &nullableSchema
+}
@@ -0,0 +1,318 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	Version = "3.1.0"
+
+	bearerScheme  = "bearerAuth"
+	apiKeyScheme  = "apiKeyAuth"
+	refreshScheme = "refreshCookie"
+)
+
+var pathParamRegex = regexp.MustCompile(`:(\w+)`)
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// Spec is the OpenAPI document of the operations, it also validates the request bodies against it
+type Spec struct {
+	document   []byte
+	operations map[string]Operation
+	requests   map[string]*Schema
+	components map[string]*Schema
+}
+
+type document struct {
+	OpenAPI    string                                `json:"openapi"`
+	Info       Info                                  `json:"info"`
+	Paths      map[string]map[string]operationObject `json:"paths"`
+	Components componentsObject                      `json:"components"`
+}
+
+type componentsObject struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+type operationObject struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []parameterObject     `json:"parameters,omitempty"`
+	RequestBody *bodyObject           `json:"requestBody,omitempty"`
+	Responses   map[string]bodyObject `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type parameterObject struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// bodyObject is used for both request bodies and responses
+type bodyObject struct {
+	Description string                     `json:"description,omitempty"`
+	Required    bool                       `json:"required,omitempty"`
+	Content     map[string]mediaTypeObject `json:"content,omitempty"`
+}
+
+type mediaTypeObject struct {
+	Schema *Schema `json:"schema"`
+}
+
+// NewSpec fails when an operation is declared twice or carries a type that cannot be described
+func NewSpec(info Info, operations []Operation) (*Spec, error) {
+	generator := newSchemaGenerator()
+	errorSchema, err := generator.schemaOf(ErrorResponse{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to describe errors: %w", err)
+	}
+
+	spec := &Spec{
+		operations: map[string]Operation{},
+		requests:   map[string]*Schema{},
+	}
+	paths := map[string]map[string]operationObject{}
+	for _, operation := range operations {
+		key := operationKey(operation.Method, operation.Path)
+		if _, found := spec.operations[key]; found {
+			return nil, fmt.Errorf("operation %s declared twice", key)
+		}
+
+		object, err := newOperationObject(generator, operation, errorSchema)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe %s: %w", key, err)
+		}
+
+		spec.operations[key] = operation
+		if object.RequestBody != nil {
+			spec.requests[key] = object.RequestBody.Content[jsonMediaType].Schema
+		}
+
+		path := pathParamRegex.ReplaceAllString(operation.Path, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]operationObject{}
+		}
+		paths[path][strings.ToLower(operation.Method)] = object
+	}
+
+	spec.components = generator.components
+	spec.document, err = json.Marshal(document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   paths,
+		Components: componentsObject{
+			Schemas: generator.components,
+			SecuritySchemes: map[string]securityScheme{
+				bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				apiKeyScheme: {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "Authorization",
+					Description: "ApiKey cln_<prefix>_<secret>",
+				},
+				refreshScheme: {Type: "apiKey", In: "cookie", Name: "session"},
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal the document: %w", err)
+	}
+
+	return spec, nil
+}
+
+// Document is the JSON encoded OpenAPI document
+func (s *Spec) Document() []byte {
+	return s.document
+}
+
+// Operations returns the documented operations keyed by "METHOD path"
+func (s *Spec) Operations() map[string]Operation {
+	return s.operations
+}
+
+const jsonMediaType = "application/json"
+
+func newOperationObject(generator *schemaGenerator, operation Operation, errorSchema *Schema) (operationObject, error) {
+	object := operationObject{
+		OperationID: operationID(operation.Method, operation.Path),
+		Summary:     operation.Summary,
+		Tags:        operation.Tags,
+		Responses:   map[string]bodyObject{},
+		Deprecated:  operation.Deprecated,
+	}
+
+	if len(operation.Permissions) > 0 {
+		object.Description = "Requires the permissions: " + operation.Permissions.String()
+	}
+
+	switch operation.Auth {
+	case AuthAccess:
+		object.Security = []map[string][]string{{bearerScheme: {}}, {apiKeyScheme: {}}}
+	case AuthRefresh:
+		object.Security = []map[string][]string{{refreshScheme: {}}}
+	}
+
+	parameters, err := newParameterObjects(generator, operation)
+	if err != nil {
+		return operationObject{}, err
+	}
+	object.Parameters = parameters
+
+	if operation.Request != nil {
+		schema, err := generator.schemaOf(operation.Request)
+		if err != nil {
+			return operationObject{}, fmt.Errorf("unable to describe the request: %w", err)
+		}
+
+		object.RequestBody = &bodyObject{
+			Required: true,
+			Content:  map[string]mediaTypeObject{jsonMediaType: {Schema: schema}},
+		}
+	}
+
+	for _, response := range operation.Responses {
+		responseObject := bodyObject{Description: response.Description}
+		if responseObject.Description == "" {
+			responseObject.Description = http.StatusText(response.Status)
+		}
+
+		if response.Body != nil {
+			schema, err := generator.schemaOf(response.Body)
+			if err != nil {
+				return operationObject{}, fmt.Errorf("unable to describe the %d response: %w", response.Status, err)
+			}
+
+			responseObject.Content = map[string]mediaTypeObject{jsonMediaType: {Schema: schema}}
+		}
+
+		object.Responses[strconv.Itoa(response.Status)] = responseObject
+	}
+
+	if operation.Error != nil {
+		errorSchema, err = generator.schemaOf(operation.Error)
+		if err != nil {
+			return operationObject{}, fmt.Errorf("unable to describe the errors: %w", err)
+		}
+	}
+
+	object.Responses["default"] = bodyObject{
+		Description: "Error",
+		Content:     map[string]mediaTypeObject{jsonMediaType: {Schema: errorSchema}},
+	}
+
+	return object, nil
+}
+
+// newParameterObjects documents the path parameters as strings unless they are declared
+func newParameterObjects(generator *schemaGenerator, operation Operation) ([]parameterObject, error) {
+	declared := map[string]Parameter{}
+	for _, parameter := range operation.Parameters {
+		declared[parameter.In+":"+parameter.Name] = parameter
+	}
+
+	var parameters []parameterObject
+	for _, match := range pathParamRegex.FindAllStringSubmatch(operation.Path, -1) {
+		parameter, found := declared[InPath+":"+match[1]]
+		if !found {
+			parameter = Parameter{Name: match[1], In: InPath, Example: ""}
+		}
+		delete(declared, InPath+":"+match[1])
+
+		object, err := newParameterObject(generator, parameter)
+		if err != nil {
+			return nil, err
+		}
+		object.Required = true
+		parameters = append(parameters, object)
+	}
+
+	for _, parameter := range operation.Parameters {
+		if _, found := declared[parameter.In+":"+parameter.Name]; !found {
+			continue
+		}
+
+		if parameter.In != InQuery {
+			return nil, fmt.Errorf("parameter %s is not in the path", parameter.Name)
+		}
+
+		object, err := newParameterObject(generator, parameter)
+		if err != nil {
+			return nil, err
+		}
+		parameters = append(parameters, object)
+	}
+
+	if operation.Idempotent {
+		parameters = append(parameters, parameterObject{
+			Name:        "Idempotency-Key",
+			In:          "header",
+			Description: "Replays the response of a previous request sent with the same key",
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	return parameters, nil
+}
+
+func newParameterObject(generator *schemaGenerator, parameter Parameter) (parameterObject, error) {
+	schema, err := generator.schemaOf(parameter.Example)
+	if err != nil {
+		return parameterObject{}, fmt.Errorf("unable to describe the parameter %s: %w", parameter.Name, err)
+	}
+
+	return parameterObject{
+		Name:        parameter.Name,
+		In:          parameter.In,
+		Description: parameter.Description,
+		Schema:      schema,
+	}, nil
+}
+
+func operationKey(method, path string) string {
+	return method + " " + path
+}
+
+// operationID turns "GET /api/v1/patient/:id" into "getApiV1PatientById"
+func operationID(method, path string) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '.' }) {
+		if param, found := strings.CutPrefix(segment, ":"); found {
+			id.WriteString("By")
+			segment = param
+		}
+
+		runes := []rune(segment)
+		runes[0] = unicode.ToUpper(runes[0])
+		id.WriteString(string(runes))
+	}
+
+	return id.String()
+}
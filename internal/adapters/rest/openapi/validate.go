@@ -0,0 +1,188 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ValidateBody rejects the JSON bodies not matching the request schema of the route.
+// It is set after the access middleware on the authenticated routes so that unauthorized requests get a 401,
+// the anonymous signup routes are validated as well, the schemas are public in /openapi.json anyway.
+// The required properties must be set and the unknown properties are refused,
+// null is accepted for the other properties as it leaves the field to its zero value
+func (s *Spec) ValidateBody() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			schema, found := s.requests[operationKey(c.Request().Method, c.Path())]
+			if !found || c.Request().Body == nil {
+				return next(c)
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to read body: %w", err))
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			// an empty body binds like {}, it still has to set the required properties
+			if len(bytes.TrimSpace(body)) == 0 {
+				if err := s.validate(schema, map[string]any{}, "body"); err != nil {
+					return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+				}
+				return next(c)
+			}
+
+			if !strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+				return echo.NewHTTPError(http.StatusUnsupportedMediaType, "request body must be "+echo.MIMEApplicationJSON)
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+			}
+
+			if err := s.validate(schema, value, "body"); err != nil {
+				return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+			}
+
+			return next(c)
+		}
+	}
+}
+
+func (s *Spec) validate(schema *Schema, value any, path string) error {
+	if schema.Ref != "" {
+		component, found := s.components[strings.TrimPrefix(schema.Ref, componentsPrefix)]
+		if !found {
+			return fmt.Errorf("%s: unknown schema %s", path, schema.Ref)
+		}
+
+		return s.validate(component, value, path)
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	if len(schema.AnyOf) > 0 {
+		var errs []error
+		for _, alternative := range schema.AnyOf {
+			err := s.validate(alternative, value, path)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+
+		return errors.Join(errs...)
+	}
+
+	if schema.Type == nil {
+		return nil
+	}
+
+	valueType := jsonType(value)
+	if !slices.ContainsFunc(schemaTypes(schema), func(schemaType string) bool {
+		return schemaType == valueType || schemaType == "number" && valueType == "integer"
+	}) {
+		return fmt.Errorf("%s: expected %s, got %s", path, strings.Join(schemaTypes(schema), " or "), valueType)
+	}
+
+	switch value := value.(type) {
+	case string:
+		if schema.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, value); err != nil {
+				return fmt.Errorf("%s: expected a RFC 3339 date-time", path)
+			}
+		}
+	case map[string]any:
+		for _, property := range schema.Required {
+			if propertyValue, found := value[property]; !found || propertyValue == nil {
+				return fmt.Errorf("%s.%s: required", path, property)
+			}
+		}
+
+		for property, propertyValue := range value {
+			propertySchema, found := schema.Properties[property]
+			if !found {
+				switch additional := schema.AdditionalProperties.(type) {
+				case *Schema:
+					propertySchema = additional
+				case bool:
+					if !additional {
+						return fmt.Errorf("%s.%s: unknown property", path, property)
+					}
+				}
+			}
+
+			if propertySchema == nil {
+				continue
+			}
+
+			if err := s.validate(propertySchema, propertyValue, path+"."+property); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if schema.Items == nil {
+			return nil
+		}
+
+		for i, item := range value {
+			if err := s.validate(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func schemaTypes(schema *Schema) []string {
+	switch schemaType := schema.Type.(type) {
+	case string:
+		return []string{schemaType}
+	case []any:
+		types := make([]string, 0, len(schemaType))
+		for _, t := range schemaType {
+			if name, ok := t.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+
+	return nil
+}
+
+// jsonType names the JSON type of a value decoded with UseNumber
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+
+	return "null"
+}
@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type testItem struct {
+	Name string `json:"name"`
+}
+
+type testInput struct {
+	ID        int64      `json:"id" openapi:"required"`
+	Tags      []string   `json:"tags"`
+	Items     []testItem `json:"items"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+func TestValidateBody(t *testing.T) {
+	spec, err := NewSpec(Info{Title: "test", Version: "test"}, []Operation{{
+		Method:  http.MethodPost,
+		Path:    "/items/:id",
+		Request: testInput{},
+	}})
+	if err != nil {
+		t.Fatalf("unable to build the spec: %s", err)
+	}
+
+	e := echo.New()
+	e.POST("/items/:id", func(c echo.Context) error {
+		input := new(testInput)
+		if err := c.Bind(input); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusNoContent)
+	}, spec.ValidateBody())
+
+	for name, tc := range map[string]struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		"valid":              {echo.MIMEApplicationJSON, `{"id": 1, "tags": ["a"], "items": [{"name": "a"}], "expires_at": "2030-01-01T00:00:00Z"}`, http.StatusNoContent},
+		"partial":            {echo.MIMEApplicationJSON, `{"id": 1, "tags": ["a"]}`, http.StatusNoContent},
+		"missing required":   {echo.MIMEApplicationJSON, `{"tags": ["a"]}`, http.StatusBadRequest},
+		"null required":      {echo.MIMEApplicationJSON, `{"id": null}`, http.StatusBadRequest},
+		"unknown property":   {echo.MIMEApplicationJSON, `{"id": 1, "builtin": true}`, http.StatusBadRequest},
+		"nested unknown":     {echo.MIMEApplicationJSON, `{"id": 1, "items": [{"name": "a", "size": 1}]}`, http.StatusBadRequest},
+		"null":               {echo.MIMEApplicationJSON, `{"id": 1, "tags": null, "expires_at": null}`, http.StatusNoContent},
+		"empty":              {echo.MIMEApplicationJSON, ``, http.StatusBadRequest},
+		"string for array":   {echo.MIMEApplicationJSON, `{"id": 1, "tags": "[a]"}`, http.StatusBadRequest},
+		"float for integer":  {echo.MIMEApplicationJSON, `{"id": 1.5}`, http.StatusBadRequest},
+		"nested type":        {echo.MIMEApplicationJSON, `{"id": 1, "items": [{"name": 1}]}`, http.StatusBadRequest},
+		"invalid date-time":  {echo.MIMEApplicationJSON, `{"id": 1, "expires_at": "tomorrow"}`, http.StatusBadRequest},
+		"array for object":   {echo.MIMEApplicationJSON, `[]`, http.StatusBadRequest},
+		"malformed":          {echo.MIMEApplicationJSON, `{"id": `, http.StatusBadRequest},
+		"not a JSON request": {echo.MIMEApplicationForm, `id=1`, http.StatusUnsupportedMediaType},
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/items/1", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, tc.contentType)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Errorf("expected status %d, got %d: %s", tc.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
@@ -2,12 +2,15 @@ package rest
 
 import (
 	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/gommon/log"
 
+	"github.com/sopial42/cleanic/internal/adapters/logging"
 	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
 	patient "github.com/sopial42/cleanic/internal/domains/patient"
 	"github.com/sopial42/cleanic/internal/domains/user"
 	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
@@ -15,35 +18,133 @@ import (
 
 type PatientHandler struct {
 	patientSVC.Service
+	logger *slog.Logger
 }
 
-func SetHandler(e *echo.Echo, service patientSVC.Service, accessMiddleware middleware.AuthAccessMiddleware) {
+func SetHandler(e *echo.Echo, service patientSVC.Service, accessMiddleware middleware.AuthAccessMiddleware, idempotencyMiddleware *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
 	p := &PatientHandler{
 		service,
+		logging.Component("rest/patient"),
 	}
 
-	requireDoctor := accessMiddleware.RequireRoles(user.Roles{user.RoleDoctor})
+	requirePatientRead := accessMiddleware.RequirePermissions(user.Permissions{user.PermissionPatientRead})
+	requirePatientWrite := accessMiddleware.RequirePermissions(user.Permissions{user.PermissionPatientWrite})
 	apiV1 := e.Group("/api/v1")
 	{
-		apiV1.GET("/patients", p.getPatients, requireDoctor)
-		apiV1.GET("/patient/:id", p.getPatient, requireDoctor)
-		apiV1.POST("/patient", p.createPatient, requireDoctor)
-		apiV1.PATCH("/patient", p.updatePatient, requireDoctor)
-		apiV1.DELETE("/patient/:id", p.deletePatient, requireDoctor)
+		apiV1.GET("/patients", p.getPatients, requirePatientRead)
+		apiV1.GET("/patient/:id", p.getPatient, requirePatientRead)
+		apiV1.POST("/patient", p.createPatient, requirePatientWrite, spec.ValidateBody(), idempotencyMiddleware.Idempotent())
+		apiV1.PATCH("/patient", p.updatePatient, requirePatientWrite, spec.ValidateBody())
+		apiV1.DELETE("/patient/:id", p.deletePatient, requirePatientWrite)
 	}
+
+	p.setV2Routes(e, requirePatientRead, requirePatientWrite, idempotencyMiddleware, spec)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"patient"}
+	read := user.Permissions{user.PermissionPatientRead}
+	write := user.Permissions{user.PermissionPatientWrite}
+	id := []openapi.Parameter{{Name: "id", In: openapi.InPath, Example: patient.ID(0)}}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/patients",
+			Summary:     "List the patients",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  []openapi.Parameter{purposeParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []patient.Patient{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/patient/:id",
+			Summary:     "Get a patient",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: patient.Patient{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/patient",
+			Summary:     "Create a patient",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Idempotent:  true,
+			Request:     patient.Patient{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: patient.Patient{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v1/patient",
+			Summary:     "Update the patient matching the body id",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Request:     patient.Patient{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: patient.Patient{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/patient/:id",
+			Summary:     "Delete a patient",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}, operationsV2()...)
+}
+
+// purposeParameter is how the data-sharing exports only get the patients whose consents allow them
+var purposeParameter = openapi.Parameter{
+	Name:        "purpose",
+	In:          openapi.InQuery,
+	Description: "Only lists the patients whose consents allow this purpose: care, data_sharing, research or marketing",
+	Example:     consent.PurposeDataSharing,
 }
 
 func (h *PatientHandler) getPatients(context echo.Context) error {
 	ctx := context.Request().Context()
-	patients, err := h.GetPatients(ctx)
+	patients, err := h.listPatients(context)
 	if err != nil {
-		log.Error("Error get patients: ", err)
-		return context.JSON(http.StatusInternalServerError, err)
+		h.logger.ErrorContext(ctx, "Error get patients", "error", err)
+		return err
 	}
 
 	return context.JSON(http.StatusOK, patients)
 }
 
+func (h *PatientHandler) listPatients(context echo.Context) ([]patient.Patient, error) {
+	ctx := context.Request().Context()
+	purpose := consent.Purpose(context.QueryParam("purpose"))
+	if purpose == "" {
+		patients, err := h.GetPatients(ctx)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to list patients: %w", err))
+		}
+
+		return patients, nil
+	}
+
+	if !purpose.IsValid() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unknown consent purpose %q", purpose))
+	}
+
+	patients, err := h.GetPatientsAllowing(ctx, purpose)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to list patients: %w", err))
+	}
+
+	return patients, nil
+}
+
 func (h *PatientHandler) getPatient(context echo.Context) error {
 	ctx := context.Request().Context()
 	id := context.Param("id")
@@ -54,13 +155,13 @@ func (h *PatientHandler) getPatient(context echo.Context) error {
 	var patientID int64
 	_, err := fmt.Sscanf(id, "%d", &patientID)
 	if err != nil {
-		log.Error("Error converting id to int64: ", err)
+		h.logger.ErrorContext(ctx, "Error converting id to int64", "error", err)
 		return context.JSON(http.StatusBadRequest, "invalid id format")
 	}
 
 	patient, err := h.GetPatientByID(ctx, patientID)
 	if err != nil {
-		log.Error("Error get patient: ", err)
+		h.logger.ErrorContext(ctx, "Error get patient", "error", err)
 		return context.JSON(http.StatusInternalServerError, err)
 	}
 
@@ -71,13 +172,13 @@ func (h *PatientHandler) createPatient(context echo.Context) error {
 	ctx := context.Request().Context()
 	newPatient := new(patient.Patient)
 	if err := context.Bind(newPatient); err != nil {
-		log.Error("Error bind patient: ", err)
+		h.logger.ErrorContext(ctx, "Error bind patient", "error", err)
 		return context.JSON(http.StatusBadRequest, err)
 	}
 
 	patientCreated, err := h.CreatePatient(ctx, *newPatient)
 	if err != nil {
-		log.Error("Error creating patient: ", err)
+		h.logger.ErrorContext(ctx, "Error creating patient", "error", err)
 		return context.JSON(http.StatusInternalServerError, err)
 	}
 
@@ -88,13 +189,13 @@ func (h *PatientHandler) updatePatient(context echo.Context) error {
 	ctx := context.Request().Context()
 	patient := new(patient.Patient)
 	if err := context.Bind(patient); err != nil {
-		log.Error("Error bind patient: ", err)
+		h.logger.ErrorContext(ctx, "Error bind patient", "error", err)
 		return context.JSON(http.StatusBadRequest, err)
 	}
 
 	patientUpdated, err := h.UpdatePatient(ctx, *patient)
 	if err != nil {
-		log.Error("Error updating patient: ", err)
+		h.logger.ErrorContext(ctx, "Error updating patient", "error", err)
 		return context.JSON(http.StatusInternalServerError, err)
 	}
 
@@ -112,13 +213,13 @@ func (h *PatientHandler) deletePatient(context echo.Context) error {
 	var patientID int64
 	_, err := fmt.Sscanf(id, "%d", &patientID)
 	if err != nil {
-		log.Error("Error converting id to int64: ", err)
+		h.logger.ErrorContext(ctx, "Error converting id to int64", "error", err)
 		return context.JSON(http.StatusBadRequest, "invalid id format")
 	}
 
 	err = h.DeletePatient(ctx, patientID)
 	if err != nil {
-		log.Error("Error deleting patient: ", err)
+		h.logger.ErrorContext(ctx, "Error deleting patient", "error", err)
 		return context.JSON(http.StatusInternalServerError, err)
 	}
 
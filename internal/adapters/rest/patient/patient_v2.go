@@ -0,0 +1,203 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/user"
+	patientSVC "github.com/sopial42/cleanic/internal/services/patient"
+)
+
+// PatientInput is the v2 creation body, the id is only taken from the path
+type PatientInput struct {
+	Firstname string        `json:"firstname" openapi:"required"`
+	Lastname  string        `json:"lastname" openapi:"required"`
+	Email     patient.Email `json:"email" openapi:"required"`
+}
+
+// PatientUpdateInput is the v2 update body, the omitted fields are left unchanged
+type PatientUpdateInput struct {
+	Firstname string        `json:"firstname"`
+	Lastname  string        `json:"lastname"`
+	Email     patient.Email `json:"email"`
+}
+
+func (h *PatientHandler) setV2Routes(e *echo.Echo, requirePatientRead, requirePatientWrite echo.MiddlewareFunc, idempotencyMiddleware *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/patients", h.listPatientsV2, requirePatientRead)
+		apiV2.GET("/patients/:id", h.getPatientV2, requirePatientRead)
+		apiV2.POST("/patients", h.createPatientV2, requirePatientWrite, spec.ValidateBody(), idempotencyMiddleware.Idempotent())
+		apiV2.PATCH("/patients/:id", h.updatePatientV2, requirePatientWrite, spec.ValidateBody())
+		apiV2.DELETE("/patients/:id", h.deletePatientV2, requirePatientWrite)
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"patient"}
+	read := user.Permissions{user.PermissionPatientRead}
+	write := user.Permissions{user.PermissionPatientWrite}
+	id := []openapi.Parameter{{Name: "id", In: openapi.InPath, Example: patient.ID(0)}}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/patients",
+			Summary:     "List the patients",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  []openapi.Parameter{purposeParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[patient.Patient]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/patients/:id",
+			Summary:     "Get a patient",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[patient.Patient]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/patients",
+			Summary:     "Create a patient, Location points to it",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Idempotent:  true,
+			Request:     PatientInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[patient.Patient]{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v2/patients/:id",
+			Summary:     "Update the given fields of a patient",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Parameters:  id,
+			Request:     PatientUpdateInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[patient.Patient]{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v2/patients/:id",
+			Summary:     "Delete a patient",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: write,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}
+}
+
+func (h *PatientHandler) listPatientsV2(context echo.Context) error {
+	patients, err := h.listPatients(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSONList(context, patients)
+}
+
+func (h *PatientHandler) getPatientV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	patientFound, err := h.GetPatientByID(ctx, int64(patientID))
+	if err != nil {
+		return httpError(fmt.Errorf("unable to get patient: %w", err))
+	}
+
+	return envelope.JSON(context, http.StatusOK, patientFound)
+}
+
+func (h *PatientHandler) createPatientV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	patientInput := new(PatientInput)
+	if err := context.Bind(patientInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse patient input: %w", err))
+	}
+
+	patientCreated, err := h.CreatePatient(ctx, patient.Patient{
+		Firstname: patientInput.Firstname,
+		Lastname:  patientInput.Lastname,
+		Email:     patientInput.Email,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to create patient: %w", err))
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/patients/%d", patientCreated.ID), patientCreated)
+}
+
+func (h *PatientHandler) updatePatientV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	patientInput := new(PatientUpdateInput)
+	if err := context.Bind(patientInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse patient input: %w", err))
+	}
+
+	patientUpdated, err := h.UpdatePatient(ctx, patient.Patient{
+		ID:        patientID,
+		Firstname: patientInput.Firstname,
+		Lastname:  patientInput.Lastname,
+		Email:     patientInput.Email,
+	})
+	if err != nil {
+		return httpError(fmt.Errorf("unable to update patient: %w", err))
+	}
+
+	return envelope.JSON(context, http.StatusOK, patientUpdated)
+}
+
+func (h *PatientHandler) deletePatientV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	if err := h.DeletePatient(ctx, int64(patientID)); err != nil {
+		return httpError(fmt.Errorf("unable to delete patient: %w", err))
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
+
+func patientIDParam(context echo.Context) (patient.ID, error) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid patient id: %w", err))
+	}
+
+	return patient.ID(id), nil
+}
+
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, patientSVC.ErrPatientNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,320 @@
+package rest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	privacy "github.com/sopial42/cleanic/internal/domains/privacy"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	privacySVC "github.com/sopial42/cleanic/internal/services/privacy"
+)
+
+type privacyHandler struct {
+	pService privacySVC.Service
+}
+
+// ErasureInput explains the erasure to the second user approving it
+type ErasureInput struct {
+	Reason string `json:"reason"`
+}
+
+func SetHandler(e *echo.Echo, service privacySVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	p := &privacyHandler{
+		service,
+	}
+
+	requirePrivacyManage := access.RequirePermissions(user.Permissions{user.PermissionPrivacyManage})
+	apiV1 := e.Group("/api/v1")
+	{
+		apiV1.GET("/patients/:id/export", p.exportPatient, requirePrivacyManage)
+		apiV1.POST("/patients/:id/erasures", p.requestErasure, requirePrivacyManage, spec.ValidateBody(), idempotency.Idempotent())
+		apiV1.GET("/erasures", p.getErasures, requirePrivacyManage)
+		apiV1.GET("/erasures/:id", p.getErasure, requirePrivacyManage)
+		apiV1.POST("/erasures/:id/approve", p.approveErasure, requirePrivacyManage)
+		apiV1.POST("/erasures/:id/reject", p.rejectErasure, requirePrivacyManage)
+	}
+
+	p.setV2Routes(e, requirePrivacyManage, idempotency, spec)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"privacy"}
+	manage := user.Permissions{user.PermissionPrivacyManage}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/patients/:id/export",
+			Summary:     "Export every record referencing a patient, as a ZIP archive of one JSON file per table",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{patientIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Description: "application/zip archive"}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/patients/:id/erasures",
+			Summary:     "Request the erasure of a patient, carried out once another user approves it",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Parameters:  []openapi.Parameter{patientIDParameter},
+			Request:     ErasureInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: privacy.Erasure{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/erasures",
+			Summary:     "List the erasures of the clinic, newest first",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{statusParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []privacy.Erasure{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/erasures/:id",
+			Summary:     "Get an erasure of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{erasureIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: privacy.Erasure{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/erasures/:id/approve",
+			Summary:     "Approve a pending erasure requested by another user, the patient is pseudonymized",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{erasureIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: privacy.Erasure{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/erasures/:id/reject",
+			Summary:     "Reject a pending erasure requested by another user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{erasureIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: privacy.Erasure{}}},
+		},
+	}, operationsV2()...)
+}
+
+var (
+	patientIDParameter = openapi.Parameter{Name: "id", In: openapi.InPath, Example: patient.ID(0)}
+	erasureIDParameter = openapi.Parameter{Name: "id", In: openapi.InPath, Example: privacy.ErasureID(0)}
+	statusParameter    = openapi.Parameter{
+		Name:        "status",
+		In:          openapi.InQuery,
+		Description: "Only lists the erasures in this status: pending, completed or rejected",
+		Example:     privacy.ErasureStatusPending,
+	}
+)
+
+// exportPatient answers the same archive on both versions, it is not a JSON body to wrap in an envelope
+func (p *privacyHandler) exportPatient(context echo.Context) error {
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	export, err := p.pService.ExportPatient(context.Request().Context(), patientID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	archive, err := zipExport(export)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to archive the export of patient %d: %w", patientID, err))
+	}
+
+	context.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("patient-%d-export.zip", patientID)))
+	return context.Blob(http.StatusOK, "application/zip", archive)
+}
+
+// zipExport writes one indented JSON file per table, in the order of their names
+func zipExport(export privacy.Export) ([]byte, error) {
+	files := export.Files()
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("unable to encode %s: %w", name, err)
+		}
+
+		file, err := writer.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: export.ExportedAt})
+		if err != nil {
+			return nil, fmt.Errorf("unable to add %s: %w", name, err)
+		}
+
+		if _, err := file.Write(content); err != nil {
+			return nil, fmt.Errorf("unable to write %s: %w", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("unable to close the archive: %w", err)
+	}
+
+	return archive.Bytes(), nil
+}
+
+func (p *privacyHandler) requestErasure(context echo.Context) error {
+	erasureRequested, err := p.request(context)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusCreated, erasureRequested)
+}
+
+func (p *privacyHandler) request(context echo.Context) (privacy.Erasure, error) {
+	ctx := context.Request().Context()
+	patientID, err := patientIDParam(context)
+	if err != nil {
+		return privacy.Erasure{}, err
+	}
+
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	erasureInput := new(ErasureInput)
+	if err := context.Bind(erasureInput); err != nil {
+		return privacy.Erasure{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse erasure input: %w", err))
+	}
+
+	erasureRequested, err := p.pService.RequestErasure(ctx, patientID, reqUserID, erasureInput.Reason)
+	if err != nil {
+		return privacy.Erasure{}, httpError(err)
+	}
+
+	return erasureRequested, nil
+}
+
+func (p *privacyHandler) getErasures(context echo.Context) error {
+	erasures, err := p.pService.ListErasures(context.Request().Context(), privacy.ErasureStatus(context.QueryParam("status")))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return context.JSON(http.StatusOK, erasures)
+}
+
+func (p *privacyHandler) getErasure(context echo.Context) error {
+	erasureID, err := erasureIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	erasure, err := p.pService.GetErasure(context.Request().Context(), erasureID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return context.JSON(http.StatusOK, erasure)
+}
+
+func (p *privacyHandler) approveErasure(context echo.Context) error {
+	erasureApproved, err := p.review(context, true)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, erasureApproved)
+}
+
+func (p *privacyHandler) rejectErasure(context echo.Context) error {
+	erasureRejected, err := p.review(context, false)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, erasureRejected)
+}
+
+// review approves or rejects the erasure of the path as the requesting user
+func (p *privacyHandler) review(context echo.Context, approve bool) (privacy.Erasure, error) {
+	ctx := context.Request().Context()
+	erasureID, err := erasureIDParam(context)
+	if err != nil {
+		return privacy.Erasure{}, err
+	}
+
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return privacy.Erasure{}, echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	review := p.pService.RejectErasure
+	if approve {
+		review = p.pService.ApproveErasure
+	}
+
+	erasureReviewed, err := review(ctx, erasureID, reqUserID)
+	if err != nil {
+		return privacy.Erasure{}, httpError(err)
+	}
+
+	return erasureReviewed, nil
+}
+
+func patientIDParam(context echo.Context) (patient.ID, error) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid patient id: %w", err))
+	}
+
+	return patient.ID(id), nil
+}
+
+func erasureIDParam(context echo.Context) (privacy.ErasureID, error) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid erasure id: %w", err))
+	}
+
+	return privacy.ErasureID(id), nil
+}
+
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, privacySVC.ErrPatientNotFound), errors.Is(err, privacySVC.ErrErasureNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	case errors.Is(err, privacySVC.ErrInvalidErasure):
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	case errors.Is(err, privacySVC.ErrSameReviewer):
+		return echo.NewHTTPError(http.StatusForbidden, err)
+	case errors.Is(err, privacySVC.ErrErasureConflict), errors.Is(err, privacySVC.ErrErasureNotPending):
+		return echo.NewHTTPError(http.StatusConflict, err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,145 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	privacy "github.com/sopial42/cleanic/internal/domains/privacy"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+func (p *privacyHandler) setV2Routes(e *echo.Echo, requirePrivacyManage echo.MiddlewareFunc, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/patients/:id/export", p.exportPatient, requirePrivacyManage)
+		apiV2.POST("/patients/:id/erasures", p.requestErasureV2, requirePrivacyManage, spec.ValidateBody(), idempotency.Idempotent())
+		apiV2.GET("/erasures", p.listErasuresV2, requirePrivacyManage)
+		apiV2.GET("/erasures/:id", p.getErasureV2, requirePrivacyManage)
+		apiV2.POST("/erasures/:id/approve", p.approveErasureV2, requirePrivacyManage)
+		apiV2.POST("/erasures/:id/reject", p.rejectErasureV2, requirePrivacyManage)
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"privacy"}
+	manage := user.Permissions{user.PermissionPrivacyManage}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/patients/:id/export",
+			Summary:     "Export every record referencing a patient, as a ZIP archive of one JSON file per table",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{patientIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Description: "application/zip archive"}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/patients/:id/erasures",
+			Summary:     "Request the erasure of a patient, carried out once another user approves it. Location points to the erasure",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Parameters:  []openapi.Parameter{patientIDParameter},
+			Request:     ErasureInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[privacy.Erasure]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/erasures",
+			Summary:     "List the erasures of the clinic, newest first",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{statusParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[privacy.Erasure]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/erasures/:id",
+			Summary:     "Get an erasure of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{erasureIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[privacy.Erasure]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/erasures/:id/approve",
+			Summary:     "Approve a pending erasure requested by another user, the patient is pseudonymized",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{erasureIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[privacy.Erasure]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/erasures/:id/reject",
+			Summary:     "Reject a pending erasure requested by another user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{erasureIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[privacy.Erasure]{}}},
+		},
+	}
+}
+
+func (p *privacyHandler) requestErasureV2(context echo.Context) error {
+	erasureRequested, err := p.request(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/erasures/%d", erasureRequested.ID), erasureRequested)
+}
+
+func (p *privacyHandler) listErasuresV2(context echo.Context) error {
+	erasures, err := p.pService.ListErasures(context.Request().Context(), privacy.ErasureStatus(context.QueryParam("status")))
+	if err != nil {
+		return httpError(err)
+	}
+
+	return envelope.JSONList(context, erasures)
+}
+
+func (p *privacyHandler) getErasureV2(context echo.Context) error {
+	erasureID, err := erasureIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	erasure, err := p.pService.GetErasure(context.Request().Context(), erasureID)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return envelope.JSON(context, http.StatusOK, erasure)
+}
+
+func (p *privacyHandler) approveErasureV2(context echo.Context) error {
+	erasureApproved, err := p.review(context, true)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSON(context, http.StatusOK, erasureApproved)
+}
+
+func (p *privacyHandler) rejectErasureV2(context echo.Context) error {
+	erasureRejected, err := p.review(context, false)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSON(context, http.StatusOK, erasureRejected)
+}
@@ -0,0 +1,179 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	roleSVC "github.com/sopial42/cleanic/internal/services/role"
+)
+
+type roleHandler struct {
+	rService roleSVC.Service
+}
+
+// RoleInput never carries the builtin flag, it is only set by the schema
+type RoleInput struct {
+	Name        user.Role        `json:"name" openapi:"required"`
+	Description string           `json:"description"`
+	Permissions user.Permissions `json:"permissions"`
+}
+
+func SetHandler(e *echo.Echo, service roleSVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	r := &roleHandler{
+		service,
+	}
+
+	requireRoleManage := access.RequirePermissions(user.Permissions{user.PermissionRoleManage})
+	apiV1 := e.Group("/api/v1")
+	{
+		apiV1.GET("/permissions", r.getPermissions, requireRoleManage)
+		apiV1.GET("/roles", r.getRoles, requireRoleManage)
+		apiV1.GET("/role/:name", r.getRole, requireRoleManage)
+		apiV1.POST("/role", r.createRole, requireRoleManage, spec.ValidateBody(), idempotency.Idempotent())
+		apiV1.PATCH("/role", r.updateRole, requireRoleManage, spec.ValidateBody())
+		apiV1.DELETE("/role/:name", r.deleteRole, requireRoleManage)
+	}
+
+	r.setV2Routes(e, requireRoleManage, idempotency, spec)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"role"}
+	manage := user.Permissions{user.PermissionRoleManage}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/permissions",
+			Summary:     "List the permissions a role can grant",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: user.Permissions{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/roles",
+			Summary:     "List the roles",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []user.RoleDefinition{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/role/:name",
+			Summary:     "Get a role",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: user.RoleDefinition{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/role",
+			Summary:     "Create a role",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Request:     RoleInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: user.RoleDefinition{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v1/role",
+			Summary:     "Update the role matching the body name",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Request:     RoleInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: user.RoleDefinition{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/role/:name",
+			Summary:     "Delete a role, the builtin roles cannot be deleted",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}, operationsV2()...)
+}
+
+func (r *roleHandler) getPermissions(context echo.Context) error {
+	return context.JSON(http.StatusOK, user.AvailablePermissions())
+}
+
+func (r *roleHandler) getRoles(context echo.Context) error {
+	ctx := context.Request().Context()
+	roles, err := r.rService.ListRoles(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return context.JSON(http.StatusOK, roles)
+}
+
+func (r *roleHandler) getRole(context echo.Context) error {
+	ctx := context.Request().Context()
+	role, err := r.rService.GetRole(ctx, user.Role(context.Param("name")))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return context.JSON(http.StatusOK, role)
+}
+
+func (r *roleHandler) createRole(context echo.Context) error {
+	ctx := context.Request().Context()
+	roleInput := new(RoleInput)
+	if err := context.Bind(roleInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse role input: %w", err))
+	}
+
+	roleCreated, err := r.rService.CreateRole(ctx, user.RoleDefinition{
+		Name:        roleInput.Name,
+		Description: roleInput.Description,
+		Permissions: roleInput.Permissions,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return context.JSON(http.StatusCreated, roleCreated)
+}
+
+func (r *roleHandler) updateRole(context echo.Context) error {
+	ctx := context.Request().Context()
+	roleInput := new(RoleInput)
+	if err := context.Bind(roleInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse role input: %w", err))
+	}
+
+	roleUpdated, err := r.rService.UpdateRole(ctx, user.RoleDefinition{
+		Name:        roleInput.Name,
+		Description: roleInput.Description,
+		Permissions: roleInput.Permissions,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return context.JSON(http.StatusOK, roleUpdated)
+}
+
+func (r *roleHandler) deleteRole(context echo.Context) error {
+	ctx := context.Request().Context()
+	if err := r.rService.DeleteRole(ctx, user.Role(context.Param("name"))); err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
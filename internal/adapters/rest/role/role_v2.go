@@ -0,0 +1,158 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// RoleUpdateInput is the v2 update body, the name is only taken from the path
+type RoleUpdateInput struct {
+	Description string           `json:"description"`
+	Permissions user.Permissions `json:"permissions"`
+}
+
+func (r *roleHandler) setV2Routes(e *echo.Echo, requireRoleManage echo.MiddlewareFunc, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/permissions", r.listPermissionsV2, requireRoleManage)
+		apiV2.GET("/roles", r.listRolesV2, requireRoleManage)
+		apiV2.GET("/roles/:name", r.getRoleV2, requireRoleManage)
+		apiV2.POST("/roles", r.createRoleV2, requireRoleManage, spec.ValidateBody(), idempotency.Idempotent())
+		apiV2.PATCH("/roles/:name", r.updateRoleV2, requireRoleManage, spec.ValidateBody())
+		apiV2.DELETE("/roles/:name", r.deleteRole, requireRoleManage)
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"role"}
+	manage := user.Permissions{user.PermissionRoleManage}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/permissions",
+			Summary:     "List the permissions a role can grant",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[user.Permission]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/roles",
+			Summary:     "List the roles",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[user.RoleDefinition]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/roles/:name",
+			Summary:     "Get a role",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[user.RoleDefinition]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/roles",
+			Summary:     "Create a role, Location points to it",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Request:     RoleInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[user.RoleDefinition]{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v2/roles/:name",
+			Summary:     "Update the description and the permissions of a role",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Request:     RoleUpdateInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[user.RoleDefinition]{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v2/roles/:name",
+			Summary:     "Delete a role, the builtin roles cannot be deleted",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}
+}
+
+func (r *roleHandler) listPermissionsV2(context echo.Context) error {
+	return envelope.JSONList(context, user.AvailablePermissions())
+}
+
+func (r *roleHandler) listRolesV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	roles, err := r.rService.ListRoles(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.JSONList(context, roles)
+}
+
+func (r *roleHandler) getRoleV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	role, err := r.rService.GetRole(ctx, user.Role(context.Param("name")))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.JSON(context, http.StatusOK, role)
+}
+
+func (r *roleHandler) createRoleV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	roleInput := new(RoleInput)
+	if err := context.Bind(roleInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse role input: %w", err))
+	}
+
+	roleCreated, err := r.rService.CreateRole(ctx, user.RoleDefinition{
+		Name:        roleInput.Name,
+		Description: roleInput.Description,
+		Permissions: roleInput.Permissions,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.Created(context, "/api/v2/roles/"+url.PathEscape(string(roleCreated.Name)), roleCreated)
+}
+
+func (r *roleHandler) updateRoleV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	roleInput := new(RoleUpdateInput)
+	if err := context.Bind(roleInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse role input: %w", err))
+	}
+
+	roleUpdated, err := r.rService.UpdateRole(ctx, user.RoleDefinition{
+		Name:        user.Role(context.Param("name")),
+		Description: roleInput.Description,
+		Permissions: roleInput.Permissions,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.JSON(context, http.StatusOK, roleUpdated)
+}
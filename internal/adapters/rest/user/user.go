@@ -1,6 +1,7 @@
 package rest
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -8,6 +9,7 @@ import (
 	"github.com/labstack/echo/v4"
 
 	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
 	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
 	user "github.com/sopial42/cleanic/internal/domains/user"
 	userSVC "github.com/sopial42/cleanic/internal/services/user"
@@ -17,32 +19,108 @@ type userHandler struct {
 	uService userSVC.Service
 }
 
-func SetHandler(e *echo.Echo, service userSVC.Service, access middleware.AuthAccessMiddleware) {
+func SetHandler(e *echo.Echo, service userSVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
 	u := &userHandler{
 		service,
 	}
 
-	requireAdmin := access.RequireRoles(user.Roles{user.RoleAdmin})
-	requireDoctor := access.RequireRoles(user.Roles{user.RoleDoctor})
+	requireUserRead := access.RequirePermissions(user.Permissions{user.PermissionUserRead})
+	requireUserManage := access.RequirePermissions(user.Permissions{user.PermissionUserManage})
+	// updating or deleting another user is checked by the service
+	requireProfileWrite := access.RequirePermissions(user.Permissions{user.PermissionProfileWrite})
 	apiV1 := e.Group("/api/v1")
 	{
-		apiV1.GET("/users", u.getUsers, requireAdmin)
-		apiV1.GET("/user/:id", u.getUserByID, requireAdmin)
-		apiV1.PATCH("/user/roles", u.updateUserRoles, requireAdmin)
-		apiV1.PATCH("/user", u.updateUser, requireDoctor)
-		apiV1.DELETE("/user/:id", u.deleteUser, requireDoctor)
+		apiV1.GET("/users", u.getUsers, requireUserRead)
+		apiV1.GET("/user/:id", u.getUserByID, requireUserRead)
+		apiV1.PATCH("/user/roles", u.updateUserRoles, requireUserManage, spec.ValidateBody())
+		apiV1.POST("/user/service-account", u.createServiceAccount, requireUserManage, spec.ValidateBody(), idempotency.Idempotent())
+		apiV1.PATCH("/user", u.updateUser, requireProfileWrite, spec.ValidateBody())
+		apiV1.DELETE("/user/:id", u.deleteUser, requireProfileWrite)
 	}
+
+	u.setV2Routes(e, requireUserRead, requireUserManage, requireProfileWrite, idempotency, spec)
 }
 
 // UserUpdateInput is used to parse input for multiples reasons
 // do not handle roles as it is implemented on a dedicated safe route
 // avoid the used default tag `json:"-"` in case user needs a pwd update
 type UserUpdateInput struct {
-	ID       user.ID       `json:"id"`
+	ID       user.ID       `json:"id" openapi:"required"`
 	Email    user.Email    `json:"email"`
 	Password user.Password `json:"password"`
 }
 
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"user"}
+	read := user.Permissions{user.PermissionUserRead}
+	manage := user.Permissions{user.PermissionUserManage}
+	profileWrite := user.Permissions{user.PermissionProfileWrite}
+	id := []openapi.Parameter{{Name: "id", In: openapi.InPath, Example: user.ID(0)}}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/users",
+			Summary:     "List the users",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []user.User{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/user/:id",
+			Summary:     "Get a user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: user.User{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v1/user/roles",
+			Summary:     "Replace the roles of a user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Request:     UserRolesUpdateInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: user.User{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/user/service-account",
+			Summary:     "Create a service account",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Request:     ServiceAccountInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: user.User{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v1/user",
+			Summary:     "Update the email or the password of a user, another user needs user:manage",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Request:     UserUpdateInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: user.User{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/user/:id",
+			Summary:     "Delete a user, another user needs user:manage",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}, operationsV2()...)
+}
+
 func (u *userHandler) getUsers(context echo.Context) error {
 	ctx := context.Request().Context()
 	users, err := u.uService.GetUsers(ctx)
@@ -89,7 +167,7 @@ func (u *userHandler) updateUser(context echo.Context) error {
 
 	userUpdated, err := u.uService.UpdateUser(ctx, reqUserID, newUser)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to update user: %w", err))
+		return httpError(fmt.Errorf("unable to update user: %w", err))
 	}
 
 	return context.JSON(http.StatusOK, userUpdated)
@@ -97,8 +175,8 @@ func (u *userHandler) updateUser(context echo.Context) error {
 
 // UserRolesUpdateInput only handle roles input for safety
 type UserRolesUpdateInput struct {
-	ID    user.ID    `json:"id"`
-	Roles user.Roles `json:"roles"`
+	ID    user.ID    `json:"id" openapi:"required"`
+	Roles user.Roles `json:"roles" openapi:"required"`
 }
 
 // updateUserRoles only handle roles for safety
@@ -127,6 +205,35 @@ func (u *userHandler) updateUserRoles(context echo.Context) error {
 	return context.JSON(http.StatusOK, userUpdated)
 }
 
+// ServiceAccountInput never carries a password, service accounts authenticate with API keys
+type ServiceAccountInput struct {
+	Email user.Email `json:"email" openapi:"required"`
+	Roles user.Roles `json:"roles"`
+}
+
+func (u *userHandler) createServiceAccount(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	serviceAccountInput := new(ServiceAccountInput)
+	if err := context.Bind(serviceAccountInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse service account input: %w", err))
+	}
+
+	userCreated, err := u.uService.CreateServiceAccount(ctx, reqUserID, user.User{
+		Email: serviceAccountInput.Email,
+		Roles: serviceAccountInput.Roles,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to create service account: %w", err))
+	}
+
+	return context.JSON(http.StatusCreated, userCreated)
+}
+
 func (u *userHandler) deleteUser(context echo.Context) error {
 	ctx := context.Request().Context()
 	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
@@ -142,8 +249,17 @@ func (u *userHandler) deleteUser(context echo.Context) error {
 
 	err = u.uService.DeleteUser(ctx, reqUserID, user.ID(idToDelete))
 	if err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to delete user: %w", err))
+		return httpError(fmt.Errorf("unable to delete user: %w", err))
 	}
 
 	return context.NoContent(http.StatusNoContent)
 }
+
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, userSVC.ErrUserNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,224 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	contextUtils "github.com/sopial42/cleanic/internal/adapters/rest/utils/context"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// UserInput is the v2 body, the id is only taken from the path
+type UserInput struct {
+	Email    user.Email    `json:"email"`
+	Password user.Password `json:"password"`
+}
+
+// UserRolesInput replaces every role of the user
+type UserRolesInput struct {
+	Roles user.Roles `json:"roles" openapi:"required"`
+}
+
+func (u *userHandler) setV2Routes(e *echo.Echo, requireUserRead, requireUserManage, requireProfileWrite echo.MiddlewareFunc, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/users", u.listUsersV2, requireUserRead)
+		apiV2.GET("/users/:id", u.getUserV2, requireUserRead)
+		apiV2.PATCH("/users/:id", u.updateUserV2, requireProfileWrite, spec.ValidateBody())
+		apiV2.DELETE("/users/:id", u.deleteUser, requireProfileWrite)
+		apiV2.PUT("/users/:id/roles", u.replaceUserRolesV2, requireUserManage, spec.ValidateBody())
+		apiV2.POST("/service-accounts", u.createServiceAccountV2, requireUserManage, spec.ValidateBody(), idempotency.Idempotent())
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"user"}
+	read := user.Permissions{user.PermissionUserRead}
+	manage := user.Permissions{user.PermissionUserManage}
+	profileWrite := user.Permissions{user.PermissionProfileWrite}
+	id := []openapi.Parameter{{Name: "id", In: openapi.InPath, Example: user.ID(0)}}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/users",
+			Summary:     "List the users",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[user.User]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/users/:id",
+			Summary:     "Get a user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: read,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[user.User]{}}},
+		},
+		{
+			Method:      http.MethodPatch,
+			Path:        "/api/v2/users/:id",
+			Summary:     "Update the email or the password of a user, another user needs user:manage",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters:  id,
+			Request:     UserInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[user.User]{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v2/users/:id",
+			Summary:     "Delete a user, another user needs user:manage",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: profileWrite,
+			Parameters:  id,
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v2/users/:id/roles",
+			Summary:     "Replace the roles of a user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  id,
+			Request:     UserRolesInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[user.User]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/service-accounts",
+			Summary:     "Create a service account, Location points to its user",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Request:     ServiceAccountInput{},
+			Responses:   []openapi.Response{{Status: http.StatusCreated, Body: envelope.Data[user.User]{}}},
+		},
+	}
+}
+
+func (u *userHandler) listUsersV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	users, err := u.uService.GetUsers(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.JSONList(context, users)
+}
+
+func (u *userHandler) getUserV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	userID, err := userIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	userFound, err := u.uService.GetUserByID(ctx, userID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.JSON(context, http.StatusOK, userFound)
+}
+
+func (u *userHandler) updateUserV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	userID, err := userIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	userInput := new(UserInput)
+	if err := context.Bind(userInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse user input: %w", err))
+	}
+
+	userUpdated, err := u.uService.UpdateUser(ctx, reqUserID, user.User{
+		ID:       userID,
+		Email:    userInput.Email,
+		Password: userInput.Password,
+	})
+	if err != nil {
+		return httpError(fmt.Errorf("unable to update user: %w", err))
+	}
+
+	return envelope.JSON(context, http.StatusOK, userUpdated)
+}
+
+func (u *userHandler) replaceUserRolesV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	userID, err := userIDParam(context)
+	if err != nil {
+		return err
+	}
+
+	rolesInput := new(UserRolesInput)
+	if err := context.Bind(rolesInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse user role input: %w", err))
+	}
+
+	userUpdated, err := u.uService.UpdateUserRoles(ctx, reqUserID, user.User{
+		ID:    userID,
+		Roles: rolesInput.Roles,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to update user: %w", err))
+	}
+
+	return envelope.JSON(context, http.StatusOK, userUpdated)
+}
+
+func (u *userHandler) createServiceAccountV2(context echo.Context) error {
+	ctx := context.Request().Context()
+	reqUserID, err := contextUtils.GetUserIDFromContext(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to authenticate user: %w", err))
+	}
+
+	serviceAccountInput := new(ServiceAccountInput)
+	if err := context.Bind(serviceAccountInput); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse service account input: %w", err))
+	}
+
+	userCreated, err := u.uService.CreateServiceAccount(ctx, reqUserID, user.User{
+		Email: serviceAccountInput.Email,
+		Roles: serviceAccountInput.Roles,
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, fmt.Errorf("unable to create service account: %w", err))
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/users/%d", userCreated.ID), userCreated)
+}
+
+func userIDParam(context echo.Context) (user.ID, error) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("invalid user id: %w", err))
+	}
+
+	return user.ID(id), nil
+}
@@ -5,12 +5,14 @@ import (
 	"fmt"
 
 	"github.com/labstack/echo/v4"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	"github.com/sopial42/cleanic/internal/domains/user"
 )
 
 var (
-	UserIDKey    = ContextKey("user_id")
-	UserRolesKey = ContextKey("roles")
+	UserIDKey          = ContextKey("user_id")
+	UserRolesKey       = ContextKey("roles")
+	UserPermissionsKey = ContextKey("permissions")
 )
 
 type ContextKey string
@@ -41,3 +43,33 @@ func SetUserIDAndRolesToContext(ctxEcho echo.Context, userID user.ID, userRoles
 	ctx = context.WithValue(ctx, UserRolesKey, userRoles)
 	ctxEcho.SetRequest(ctxEcho.Request().WithContext(ctx))
 }
+
+func SetUserPermissionsToContext(ctxEcho echo.Context, permissions user.Permissions) {
+	ctx := ctxEcho.Request().Context()
+	ctx = context.WithValue(ctx, UserPermissionsKey, permissions)
+	ctxEcho.SetRequest(ctxEcho.Request().WithContext(ctx))
+}
+
+func GetUserPermissionsFromContext(ctx context.Context) (user.Permissions, error) {
+	raw := ctx.Value(UserPermissionsKey)
+	if raw == nil {
+		return nil, fmt.Errorf("user permissions not found in context")
+	}
+
+	permissions, ok := raw.(user.Permissions)
+	if !ok {
+		return nil, fmt.Errorf("user permissions are not a valid format")
+	}
+
+	return permissions, nil
+}
+
+// SetClinicIDToContext scopes the persistence queries of the request to the clinic
+func SetClinicIDToContext(ctxEcho echo.Context, clinicID clinic.ID) {
+	ctx := clinic.WithID(ctxEcho.Request().Context(), clinicID)
+	ctxEcho.SetRequest(ctxEcho.Request().WithContext(ctx))
+}
+
+func GetClinicIDFromContext(ctx context.Context) (clinic.ID, error) {
+	return clinic.IDFromContext(ctx)
+}
@@ -0,0 +1,57 @@
+package cookiestore
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/sessions"
+
+	"github.com/sopial42/cleanic/internal/config"
+)
+
+// rotatingStore is a cookie store following the rotations of its keyring:
+// cookies are signed with the current secret and the retired ones are still
+// accepted until their grace period ends
+type rotatingStore struct {
+	secrets *config.Keyring
+
+	mu      sync.Mutex
+	version uint64
+	store   *sessions.CookieStore
+}
+
+func NewRotatingCookieStore(secrets *config.Keyring) sessions.Store {
+	return &rotatingStore{secrets: secrets}
+}
+
+func (r *rotatingStore) Get(req *http.Request, name string) (*sessions.Session, error) {
+	return r.current().Get(req, name)
+}
+
+func (r *rotatingStore) New(req *http.Request, name string) (*sessions.Session, error) {
+	return r.current().New(req, name)
+}
+
+func (r *rotatingStore) Save(req *http.Request, w http.ResponseWriter, s *sessions.Session) error {
+	return r.current().Save(req, w, s)
+}
+
+func (r *rotatingStore) current() *sessions.CookieStore {
+	secrets, version := r.secrets.Snapshot()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.store == nil || version != r.version {
+		// hash key only, as before, each secret is paired with no encryption key
+		keyPairs := make([][]byte, 0, 2*len(secrets))
+		for _, secret := range secrets {
+			keyPairs = append(keyPairs, []byte(secret.Reveal()), nil)
+		}
+
+		r.store = sessions.NewCookieStore(keyPairs...)
+		r.version = version
+	}
+
+	return r.store
+}
@@ -0,0 +1,100 @@
+package envelope
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/logging"
+)
+
+// Data wraps a single resource
+type Data[T any] struct {
+	Data T `json:"data"`
+}
+
+// List wraps a collection, the meta leaves room for the pagination
+type List[T any] struct {
+	Data []T      `json:"data"`
+	Meta ListMeta `json:"meta"`
+}
+
+type ListMeta struct {
+	Count int `json:"count"`
+}
+
+// Error wraps the errors, the request id eases the support requests
+type Error struct {
+	Error ErrorBody `json:"error"`
+}
+
+type ErrorBody struct {
+	Status    int    `json:"status"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+func JSON[T any](context echo.Context, status int, data T) error {
+	return context.JSON(status, Data[T]{Data: data})
+}
+
+// JSONList never sends a null data, an empty collection is an empty array
+func JSONList[T any](context echo.Context, items []T) error {
+	if items == nil {
+		items = []T{}
+	}
+
+	return context.JSON(http.StatusOK, List[T]{Data: items, Meta: ListMeta{Count: len(items)}})
+}
+
+// Created points the Location header to the created resource
+func Created[T any](context echo.Context, location string, data T) error {
+	context.Response().Header().Set(echo.HeaderLocation, location)
+	return JSON(context, http.StatusCreated, data)
+}
+
+// NewHTTPErrorHandler wraps the errors of the routes under prefix, the others are left to next
+func NewHTTPErrorHandler(prefix string, next echo.HTTPErrorHandler) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed || !strings.HasPrefix(c.Request().URL.Path, prefix) {
+			next(err, c)
+			return
+		}
+
+		httpError := &echo.HTTPError{Code: http.StatusInternalServerError, Message: http.StatusText(http.StatusInternalServerError)}
+		if errors.As(err, &httpError) {
+			var internal *echo.HTTPError
+			if errors.As(httpError.Internal, &internal) {
+				httpError = internal
+			}
+		}
+
+		var message string
+		switch m := httpError.Message.(type) {
+		case string:
+			message = m
+		case error:
+			message = m.Error()
+		case json.Marshaler:
+			raw, _ := m.MarshalJSON()
+			message = string(raw)
+		default:
+			message = fmt.Sprint(m)
+		}
+
+		if c.Request().Method == http.MethodHead {
+			_ = c.NoContent(httpError.Code)
+			return
+		}
+
+		_ = c.JSON(httpError.Code, Error{Error: ErrorBody{
+			Status:    httpError.Code,
+			Message:   message,
+			RequestID: logging.RequestID(c.Request().Context()),
+		}})
+	}
+}
@@ -1,13 +1,28 @@
 package jwt
 
+import "github.com/golang-jwt/jwt/v5"
+
 var (
 	IDKey       = ClaimsKey("jti")
 	SubjectKey  = ClaimsKey("sub")
 	IssuedAtKey = ClaimsKey("iat")
 	ExpireAtKey = ClaimsKey("exp")
 	RolesKey    = ClaimsKey("roles")
+	ClinicKey   = ClaimsKey("clinic")
 )
 
 type ClaimsKey string
 
 type ClaimParsingFunc = func()
+
+// verificationKeys lets the parser try every secret, current one first
+func verificationKeys(secrets [][]byte) jwt.Keyfunc {
+	return func(_ *jwt.Token) (interface{}, error) {
+		keys := make([]jwt.VerificationKey, len(secrets))
+		for i, secret := range secrets {
+			keys[i] = secret
+		}
+
+		return jwt.VerificationKeySet{Keys: keys}, nil
+	}
+}
@@ -7,52 +7,56 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	"github.com/sopial42/cleanic/internal/domains/user"
 )
 
 const AccessTokenType = "Bearer"
 
 type AccessToken struct {
-	SignedToken           SignedAccessToken
-	ExpirationDurationMin time.Duration
-	Type                  string
-	Claims                AccessTokenClaims
+	SignedToken        SignedAccessToken
+	ExpirationDuration time.Duration
+	Type               string
+	Claims             AccessTokenClaims
 }
 
 type SignedAccessToken string
 
 type AccessTokenSecret []byte
 
-type AccessTokenTTLMin int
+type AccessTokenTTL time.Duration
 
 type AccessTokenClaims struct {
 	Subject   user.ID
 	ExpiresAt int64
 	IssuedAt  int64
-	Roles     user.Roles
+	// Roles are the roles of the subject in the clinic the token is scoped to
+	Roles    user.Roles
+	ClinicID clinic.ID
 }
 
-func NewAccessToken(userID user.ID, roles user.Roles, secret AccessTokenSecret, tokenTTLMin AccessTokenTTLMin) (AccessToken, error) {
-	claims := generateAccessTokenClaims(userID, roles, tokenTTLMin)
+func NewAccessToken(userID user.ID, membership clinic.Membership, secret AccessTokenSecret, tokenTTL AccessTokenTTL) (AccessToken, error) {
+	claims := generateAccessTokenClaims(userID, membership, tokenTTL)
 	token, err := generateSignedAccessToken(claims, secret)
 	if err != nil {
 		return AccessToken{}, fmt.Errorf("unable to generate access token: %w", err)
 	}
 
 	return AccessToken{
-		SignedToken:           token,
-		ExpirationDurationMin: time.Duration(tokenTTLMin) * time.Minute,
-		Type:                  AccessTokenType,
-		Claims:                claims,
+		SignedToken:        token,
+		ExpirationDuration: time.Duration(tokenTTL),
+		Type:               AccessTokenType,
+		Claims:             claims,
 	}, nil
 }
 
-func generateAccessTokenClaims(userID user.ID, roles user.Roles, expirationMinutes AccessTokenTTLMin) AccessTokenClaims {
+func generateAccessTokenClaims(userID user.ID, membership clinic.Membership, tokenTTL AccessTokenTTL) AccessTokenClaims {
 	return AccessTokenClaims{
 		Subject:   userID,
-		ExpiresAt: time.Now().Add(time.Duration(expirationMinutes) * time.Minute).Unix(),
+		ExpiresAt: time.Now().Add(time.Duration(tokenTTL)).Unix(),
 		IssuedAt:  time.Now().Unix(),
-		Roles:     roles,
+		Roles:     membership.Roles,
+		ClinicID:  membership.Clinic.ID,
 	}
 }
 
@@ -62,6 +66,7 @@ func generateSignedAccessToken(claims AccessTokenClaims, secret []byte) (SignedA
 		string(ExpireAtKey): claims.ExpiresAt,
 		string(IssuedAtKey): claims.IssuedAt,
 		string(RolesKey):    claims.Roles.String(),
+		string(ClinicKey):   claims.ClinicID,
 	}
 
 	tokenWithClaims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwtClaims)
@@ -90,10 +95,9 @@ func BearerHeaderStringFromAccessToken(token AccessToken) string {
 	return fmt.Sprintf("Bearer %s", string(token.SignedToken))
 }
 
-func ParseAccessClaims(tokenToParse string, secret []byte) (AccessTokenClaims, error) {
-	token, err := jwt.Parse(tokenToParse, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// ParseAccessClaims accepts a token signed by any of the secrets, to support rotations
+func ParseAccessClaims(tokenToParse string, secrets ...[]byte) (AccessTokenClaims, error) {
+	token, err := jwt.Parse(tokenToParse, verificationKeys(secrets), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil || token == nil {
 		return AccessTokenClaims{}, fmt.Errorf("auth token not valid: %w", err)
@@ -147,10 +151,17 @@ func ParseAccessClaims(tokenToParse string, secret []byte) (AccessTokenClaims, e
 		return AccessTokenClaims{}, fmt.Errorf("unable to parse roles: %w", err)
 	}
 
+	// Clinic, a token without clinic would not be scoped to any tenant
+	clinicFloat, ok := mapClaims[string(ClinicKey)].(float64)
+	if !ok || clinicFloat == 0 {
+		return AccessTokenClaims{}, errors.New("clinic is missing or not a valid float64")
+	}
+
 	return AccessTokenClaims{
 		Subject:   user.ID(idFloat),
 		ExpiresAt: expTime.Unix(),
 		IssuedAt:  iatTime.Unix(),
 		Roles:     currentRoles,
+		ClinicID:  clinic.ID(clinicFloat),
 	}, nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
 	"github.com/sopial42/cleanic/internal/domains/user"
 )
 
@@ -19,17 +20,19 @@ type SignedRefreshToken string
 
 type RefreshTokenSecret []byte
 
-type RefreshTokenTTLDays int
+type RefreshTokenTTL time.Duration
 
 type RefreshTokenClaims struct {
 	ID        uuid.UUID
 	Subject   user.ID
 	ExpiresAt int64
 	IssuedAt  int64
+	// ClinicID is stored along with the token, not signed in it, a refresh keeps the session clinic
+	ClinicID clinic.ID
 }
 
-func NewRefreshToken(userID user.ID, secret RefreshTokenSecret, tokenTTLDays RefreshTokenTTLDays) (RefreshToken, error) {
-	claims := generateRefreshTokenClaims(userID, tokenTTLDays)
+func NewRefreshToken(userID user.ID, clinicID clinic.ID, secret RefreshTokenSecret, tokenTTL RefreshTokenTTL) (RefreshToken, error) {
+	claims := generateRefreshTokenClaims(userID, clinicID, tokenTTL)
 	token, err := generateSignedRefreshToken(claims, secret)
 	if err != nil {
 		return RefreshToken{}, fmt.Errorf("unable to sign refresh with claims: %w", err)
@@ -41,12 +44,13 @@ func NewRefreshToken(userID user.ID, secret RefreshTokenSecret, tokenTTLDays Ref
 	}, nil
 }
 
-func generateRefreshTokenClaims(userID user.ID, tokenTTLDays RefreshTokenTTLDays) RefreshTokenClaims {
+func generateRefreshTokenClaims(userID user.ID, clinicID clinic.ID, tokenTTL RefreshTokenTTL) RefreshTokenClaims {
 	return RefreshTokenClaims{
 		ID:        uuid.New(),
 		Subject:   userID,
-		ExpiresAt: time.Now().Add(time.Duration(tokenTTLDays) * 24 * time.Hour).Unix(),
+		ExpiresAt: time.Now().Add(time.Duration(tokenTTL)).Unix(),
 		IssuedAt:  time.Now().Unix(),
+		ClinicID:  clinicID,
 	}
 }
 
@@ -67,10 +71,9 @@ func generateSignedRefreshToken(claims RefreshTokenClaims, secret RefreshTokenSe
 	return SignedRefreshToken(signedToken), nil
 }
 
-func ParseRefreshClaims(signedToken SignedRefreshToken, secret RefreshTokenSecret) (RefreshTokenClaims, error) {
-	token, err := jwt.Parse(string(signedToken), func(t *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+// ParseRefreshClaims accepts a token signed by any of the secrets, to support rotations
+func ParseRefreshClaims(signedToken SignedRefreshToken, secrets ...[]byte) (RefreshTokenClaims, error) {
+	token, err := jwt.Parse(string(signedToken), verificationKeys(secrets), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil || token == nil {
 		return RefreshTokenClaims{}, fmt.Errorf("refresh token not valid: %w", err)
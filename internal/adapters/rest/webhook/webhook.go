@@ -0,0 +1,322 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+	webhookSVC "github.com/sopial42/cleanic/internal/services/webhook"
+)
+
+type webhookHandler struct {
+	wService webhookSVC.Service
+}
+
+// SubscriptionInput subscribes url to the event types, the secret is generated when empty.
+// On update, an empty secret keeps the current one
+type SubscriptionInput struct {
+	URL        string       `json:"url" openapi:"required"`
+	EventTypes []event.Type `json:"event_types" openapi:"required"`
+	Secret     string       `json:"secret"`
+}
+
+// SubscriptionCreated is the only response carrying the secret
+type SubscriptionCreated struct {
+	webhook.Subscription
+	Secret string `json:"secret"`
+}
+
+func SetHandler(e *echo.Echo, service webhookSVC.Service, access middleware.AuthAccessMiddleware, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	w := &webhookHandler{
+		service,
+	}
+
+	requireWebhookManage := access.RequirePermissions(user.Permissions{user.PermissionWebhookManage})
+	apiV1 := e.Group("/api/v1")
+	{
+		apiV1.GET("/webhooks", w.getSubscriptions, requireWebhookManage)
+		apiV1.GET("/webhooks/:id", w.getSubscription, requireWebhookManage)
+		apiV1.POST("/webhooks", w.createSubscription, requireWebhookManage, spec.ValidateBody(), idempotency.IdempotentSecret())
+		apiV1.PUT("/webhooks/:id", w.updateSubscription, requireWebhookManage, spec.ValidateBody())
+		apiV1.DELETE("/webhooks/:id", w.deleteSubscription, requireWebhookManage)
+		apiV1.GET("/webhooks/:id/deliveries", w.getDeliveries, requireWebhookManage)
+		apiV1.POST("/webhooks/:id/deliveries/:delivery_id/retry", w.retryDelivery, requireWebhookManage)
+	}
+
+	w.setV2Routes(e, requireWebhookManage, idempotency, spec)
+}
+
+// Operations documents the routes set by SetHandler
+func Operations() []openapi.Operation {
+	tags := []string{"webhook"}
+	manage := user.Permissions{user.PermissionWebhookManage}
+	idParameter := openapi.Parameter{Name: "id", In: openapi.InPath, Example: webhook.ID(0)}
+	return append([]openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/webhooks",
+			Summary:     "List the webhook subscriptions of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []webhook.Subscription{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/webhooks/:id",
+			Summary:     "Get a webhook subscription of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: webhook.Subscription{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/webhooks",
+			Summary:     "Subscribe a URL to domain events of the clinic, the signing secret is only returned once",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Request:     SubscriptionInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: SubscriptionCreated{}},
+				{Status: http.StatusGone, Description: "Retried with the same Idempotency-Key, the secret is only returned once"},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v1/webhooks/:id",
+			Summary:     "Update the URL and event types of a webhook subscription, rotate its secret when one is given",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter},
+			Request:     SubscriptionInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: webhook.Subscription{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v1/webhooks/:id",
+			Summary:     "Delete a webhook subscription along with its deliveries",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v1/webhooks/:id/deliveries",
+			Summary:     "List the latest deliveries of a webhook subscription with their attempts and responses",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter, statusParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: []webhook.Delivery{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v1/webhooks/:id/deliveries/:delivery_id/retry",
+			Summary:     "Give a dead delivery another round of attempts",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter, deliveryIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}, operationsV2()...)
+}
+
+var (
+	statusParameter = openapi.Parameter{
+		Name:        "status",
+		In:          openapi.InQuery,
+		Description: "Only lists the deliveries in this status: pending, succeeded or dead",
+		Example:     webhook.StatusDead,
+	}
+	deliveryIDParameter = openapi.Parameter{Name: "delivery_id", In: openapi.InPath, Example: webhook.DeliveryID(0)}
+)
+
+func (w *webhookHandler) getSubscriptions(context echo.Context) error {
+	subscriptions, err := w.wService.ListSubscriptions(context.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if subscriptions == nil {
+		subscriptions = []webhook.Subscription{}
+	}
+
+	return context.JSON(http.StatusOK, subscriptions)
+}
+
+func (w *webhookHandler) getSubscription(context echo.Context) error {
+	id, err := subscriptionID(context)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := w.wService.GetSubscription(context.Request().Context(), id)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return context.JSON(http.StatusOK, subscription)
+}
+
+func (w *webhookHandler) createSubscription(context echo.Context) error {
+	subscriptionCreated, err := w.create(context)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusCreated, subscriptionCreated)
+}
+
+func (w *webhookHandler) create(context echo.Context) (SubscriptionCreated, error) {
+	subscriptionInput := new(SubscriptionInput)
+	if err := context.Bind(subscriptionInput); err != nil {
+		return SubscriptionCreated{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse webhook subscription input: %w", err))
+	}
+
+	subscriptionCreated, err := w.wService.CreateSubscription(context.Request().Context(), webhook.Subscription{
+		URL:        subscriptionInput.URL,
+		EventTypes: subscriptionInput.EventTypes,
+		Secret:     subscriptionInput.Secret,
+	})
+	if err != nil {
+		return SubscriptionCreated{}, httpError(err)
+	}
+
+	return SubscriptionCreated{Subscription: subscriptionCreated, Secret: subscriptionCreated.Secret}, nil
+}
+
+func (w *webhookHandler) updateSubscription(context echo.Context) error {
+	subscriptionUpdated, err := w.update(context)
+	if err != nil {
+		return err
+	}
+
+	return context.JSON(http.StatusOK, subscriptionUpdated)
+}
+
+func (w *webhookHandler) update(context echo.Context) (webhook.Subscription, error) {
+	id, err := subscriptionID(context)
+	if err != nil {
+		return webhook.Subscription{}, err
+	}
+
+	subscriptionInput := new(SubscriptionInput)
+	if err := context.Bind(subscriptionInput); err != nil {
+		return webhook.Subscription{}, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unable to parse webhook subscription input: %w", err))
+	}
+
+	subscriptionUpdated, err := w.wService.UpdateSubscription(context.Request().Context(), webhook.Subscription{
+		ID:         id,
+		URL:        subscriptionInput.URL,
+		EventTypes: subscriptionInput.EventTypes,
+		Secret:     subscriptionInput.Secret,
+	})
+	if err != nil {
+		return webhook.Subscription{}, httpError(err)
+	}
+
+	return subscriptionUpdated, nil
+}
+
+func (w *webhookHandler) deleteSubscription(context echo.Context) error {
+	id, err := subscriptionID(context)
+	if err != nil {
+		return err
+	}
+
+	if err := w.wService.DeleteSubscription(context.Request().Context(), id); err != nil {
+		return httpError(err)
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
+
+func (w *webhookHandler) getDeliveries(context echo.Context) error {
+	deliveries, err := w.deliveries(context)
+	if err != nil {
+		return err
+	}
+
+	if deliveries == nil {
+		deliveries = []webhook.Delivery{}
+	}
+
+	return context.JSON(http.StatusOK, deliveries)
+}
+
+func (w *webhookHandler) deliveries(context echo.Context) ([]webhook.Delivery, error) {
+	id, err := subscriptionID(context)
+	if err != nil {
+		return nil, err
+	}
+
+	status := webhook.Status(context.QueryParam("status"))
+	switch status {
+	case "", webhook.StatusPending, webhook.StatusSucceeded, webhook.StatusDead:
+	default:
+		return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Errorf("unknown delivery status %q", status))
+	}
+
+	deliveries, err := w.wService.ListDeliveries(context.Request().Context(), id, status)
+	if err != nil {
+		return nil, httpError(err)
+	}
+
+	return deliveries, nil
+}
+
+func (w *webhookHandler) retryDelivery(context echo.Context) error {
+	id, err := subscriptionID(context)
+	if err != nil {
+		return err
+	}
+
+	deliveryID, err := strconv.ParseInt(context.Param("delivery_id"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	if err := w.wService.RetryDelivery(context.Request().Context(), id, webhook.DeliveryID(deliveryID)); err != nil {
+		return httpError(err)
+	}
+
+	return context.NoContent(http.StatusNoContent)
+}
+
+func subscriptionID(context echo.Context) (webhook.ID, error) {
+	id, err := strconv.ParseInt(context.Param("id"), 10, 64)
+	if err != nil {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, err)
+	}
+
+	return webhook.ID(id), nil
+}
+
+func httpError(err error) error {
+	switch {
+	case errors.Is(err, webhookSVC.ErrSubscriptionNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, err)
+	case errors.Is(err, webhookSVC.ErrInvalidSubscription):
+		return echo.NewHTTPError(http.StatusBadRequest, err)
+	case errors.Is(err, webhookSVC.ErrDeliveryNotDead):
+		return echo.NewHTTPError(http.StatusConflict, err)
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+}
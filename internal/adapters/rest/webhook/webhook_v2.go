@@ -0,0 +1,159 @@
+package rest
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/sopial42/cleanic/internal/adapters/rest/middleware"
+	"github.com/sopial42/cleanic/internal/adapters/rest/openapi"
+	"github.com/sopial42/cleanic/internal/adapters/rest/utils/envelope"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+)
+
+func (w *webhookHandler) setV2Routes(e *echo.Echo, requireWebhookManage echo.MiddlewareFunc, idempotency *middleware.IdempotencyMiddleware, spec *openapi.Spec) {
+	apiV2 := e.Group("/api/v2")
+	{
+		apiV2.GET("/webhooks", w.listSubscriptionsV2, requireWebhookManage)
+		apiV2.GET("/webhooks/:id", w.getSubscriptionV2, requireWebhookManage)
+		apiV2.POST("/webhooks", w.createSubscriptionV2, requireWebhookManage, spec.ValidateBody(), idempotency.IdempotentSecret())
+		apiV2.PUT("/webhooks/:id", w.updateSubscriptionV2, requireWebhookManage, spec.ValidateBody())
+		apiV2.DELETE("/webhooks/:id", w.deleteSubscription, requireWebhookManage)
+		apiV2.GET("/webhooks/:id/deliveries", w.listDeliveriesV2, requireWebhookManage)
+		apiV2.POST("/webhooks/:id/deliveries/:delivery_id/retry", w.retryDelivery, requireWebhookManage)
+	}
+}
+
+func operationsV2() []openapi.Operation {
+	tags := []string{"webhook"}
+	manage := user.Permissions{user.PermissionWebhookManage}
+	idParameter := openapi.Parameter{Name: "id", In: openapi.InPath, Example: webhook.ID(0)}
+	return []openapi.Operation{
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/webhooks",
+			Summary:     "List the webhook subscriptions of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[webhook.Subscription]{}}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/webhooks/:id",
+			Summary:     "Get a webhook subscription of the clinic",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[webhook.Subscription]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/webhooks",
+			Summary:     "Subscribe a URL to domain events of the clinic, the signing secret is only returned once",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Idempotent:  true,
+			Request:     SubscriptionInput{},
+			Responses: []openapi.Response{
+				{Status: http.StatusCreated, Body: envelope.Data[SubscriptionCreated]{}},
+				{Status: http.StatusGone, Description: "Retried with the same Idempotency-Key, the secret is only returned once"},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/api/v2/webhooks/:id",
+			Summary:     "Update the URL and event types of a webhook subscription, rotate its secret when one is given",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter},
+			Request:     SubscriptionInput{},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.Data[webhook.Subscription]{}}},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/api/v2/webhooks/:id",
+			Summary:     "Delete a webhook subscription along with its deliveries",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/api/v2/webhooks/:id/deliveries",
+			Summary:     "List the latest deliveries of a webhook subscription with their attempts and responses",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter, statusParameter},
+			Responses:   []openapi.Response{{Status: http.StatusOK, Body: envelope.List[webhook.Delivery]{}}},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/api/v2/webhooks/:id/deliveries/:delivery_id/retry",
+			Summary:     "Give a dead delivery another round of attempts",
+			Tags:        tags,
+			Auth:        openapi.AuthAccess,
+			Permissions: manage,
+			Parameters:  []openapi.Parameter{idParameter, deliveryIDParameter},
+			Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+		},
+	}
+}
+
+func (w *webhookHandler) listSubscriptionsV2(context echo.Context) error {
+	subscriptions, err := w.wService.ListSubscriptions(context.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	return envelope.JSONList(context, subscriptions)
+}
+
+func (w *webhookHandler) getSubscriptionV2(context echo.Context) error {
+	id, err := subscriptionID(context)
+	if err != nil {
+		return err
+	}
+
+	subscription, err := w.wService.GetSubscription(context.Request().Context(), id)
+	if err != nil {
+		return httpError(err)
+	}
+
+	return envelope.JSON(context, http.StatusOK, subscription)
+}
+
+func (w *webhookHandler) createSubscriptionV2(context echo.Context) error {
+	subscriptionCreated, err := w.create(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.Created(context, fmt.Sprintf("/api/v2/webhooks/%d", subscriptionCreated.ID), subscriptionCreated)
+}
+
+func (w *webhookHandler) updateSubscriptionV2(context echo.Context) error {
+	subscriptionUpdated, err := w.update(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSON(context, http.StatusOK, subscriptionUpdated)
+}
+
+func (w *webhookHandler) listDeliveriesV2(context echo.Context) error {
+	deliveries, err := w.deliveries(context)
+	if err != nil {
+		return err
+	}
+
+	return envelope.JSONList(context, deliveries)
+}
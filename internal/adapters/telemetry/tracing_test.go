@@ -0,0 +1,44 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+
+	"github.com/sopial42/cleanic/internal/config"
+)
+
+func TestStdoutExporterContinuesIncomingTrace(t *testing.T) {
+	var out bytes.Buffer
+	shutdown, err := SetupTracing(context.Background(), config.TracingConfig{
+		Exporter:    config.TracingExporterStdout,
+		ServiceName: "cleanic-test",
+		SampleRatio: 1,
+	}, &out)
+	if err != nil {
+		t.Fatalf("unable to setup tracing: %v", err)
+	}
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	headers := http.Header{}
+	headers.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(headers))
+
+	_, span := otel.Tracer("test").Start(ctx, "patientSVC.GetPatients")
+	span.End()
+
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("unable to flush spans: %v", err)
+	}
+
+	for _, expected := range []string{"patientSVC.GetPatients", traceID, "cleanic-test"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("exported spans do not contain %q:\n%s", expected, out.String())
+		}
+	}
+}
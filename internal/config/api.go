@@ -0,0 +1,14 @@
+package config
+
+import "time"
+
+// DateLayout is the format of the date settings
+const DateLayout = time.DateOnly
+
+// APIConfig advertises the retirement of /api/v1 now that /api/v2 replaces it
+type APIConfig struct {
+	// V1Deprecation is sent in the Deprecation header, zero disables the v1 deprecation headers
+	V1Deprecation time.Time
+	// V1Sunset is sent in the Sunset header, the date after which /api/v1 may be removed
+	V1Sunset time.Time
+}
@@ -1,101 +1,563 @@
 package config
 
 import (
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"strconv"
-
-	"github.com/joho/godotenv"
+	"strings"
+	"time"
 )
 
 type Config struct {
-	JWT  JWTConfig
-	DB   DBConfig
-	Port string
+	JWT       JWTConfig
+	Login     LoginProtectionConfig
+	Sessions  SessionsConfig
+	Password  PasswordConfig
+	OIDC      OIDCConfig
+	DB        DBConfig
+	RateLimit RateLimitConfig
+	Events    EventsConfig
+	Webhooks  WebhooksConfig
+	Jobs      JobsConfig
+	Log       LogConfig
+	Tracing   TracingConfig
+	API       APIConfig
+	// Storage is postgres, sqlite for a single server, or memory to run the server without database
+	Storage string
+	Port    string
+	// TrustedProxies may set X-Forwarded-For, the client IP is the peer address when empty
+	TrustedProxies []*net.IPNet
+	// MetricsPort serves /metrics apart from the public API, empty disables it
+	MetricsPort string
+	// IdempotencyKeyTTL is how long a response is replayed for a retried Idempotency-Key
+	IdempotencyKeyTTL time.Duration
+	// SecretsRotationGrace is how long retired secrets are still accepted after ReloadSecrets
+	SecretsRotationGrace time.Duration
+	// ShutdownDelay leaves the orchestrator the time to see /readyz fail before the listener closes
+	ShutdownDelay time.Duration
+}
+
+const (
+	StoragePostgres = "postgres"
+	// StorageSQLite keeps the data in a local file, for the practices that run a single server
+	StorageSQLite = "sqlite"
+	// StorageMemory keeps the data in the process, for demos and tests, it is lost on restart
+	StorageMemory = "memory"
+)
+
+// InMemory tells whether the server runs without database
+func (c *Config) InMemory() bool {
+	return c.Storage == StorageMemory
 }
 
 type DBConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
+	Host string
+	Port string
+	User string
+	// Password is read on each new connection so that a reload does not drop the open ones
+	Password *Keyring
 	DBName   string
+	// LogLevel 0 is quiet, 1 logs failed queries, 2 logs every query
+	LogLevel int
+	// MigrationsTable is where the applied migrations are recorded, empty disables the readiness check
+	MigrationsTable string
+	// SQLitePath is the database file of the sqlite storage, it is created on first start
+	SQLitePath string
 }
 
-func Load() *Config {
-	_ = godotenv.Load()
-
-	// jwt token
-	jwtAccessTokenSecret := mustGet("JWT_ACCESS_TOKEN_SECRET")
-	jwtAccessTokenExpirationMinutesEnv := mustGet("JWT_ACCESS_TOKEN_TTL_MIN")
-	jwtRefreshTokenSecret := mustGet("JWT_REFRESH_TOKEN_SECRET")
-	jwtRefreshTokenExpirationMinutesEnv := mustGet("JWT_REFRESH_TOKEN_TTL_DAYS")
-
-	atTTLMinutes, err := strconv.Atoi(jwtAccessTokenExpirationMinutesEnv)
+// Load reads the config file, the environment and the CLI flags, in increasing precedence.
+// All the problems found are returned at once
+func Load(args []string) (*Config, error) {
+	l, err := newLoader(args)
 	if err != nil {
-		log.Fatalf("unable to cast access token ttl :%v", err)
+		return nil, err
 	}
 
-	etTTLDays, err := strconv.Atoi(jwtRefreshTokenExpirationMinutesEnv)
-	if err != nil {
-		log.Fatalf("unable to cast refresh token ttl :%v", err)
+	cfg := l.config()
+	cfg.validate(l)
+	if err := l.Err(); err != nil {
+		return nil, err
 	}
 
-	// JWT Cookies
-	cookieSecret := mustGet("JWT_COOKIE_SECRET")
-	cookieDomain := mustGet("JWT_COOKIE_DOMAIN")
-	cookiePath := mustGet("JWT_COOKIE_PATH")
-	sameSite := mustGet("JWT_COOKIE_SAME_SITE")
-	sameSiteIota := parseSameSite(sameSite)
-	if sameSiteIota == -1 {
-		log.Fatalf("unable to cast cookie same site :%v", sameSite)
-	}
+	return cfg, nil
+}
 
-	cookieMaxAgeSeconds, err := strconv.Atoi(mustGet("JWT_COOKIE_MAX_AGE_SECONDS"))
+// Describe lists the resolved settings with their source, secrets redacted,
+// along with the problems Load would report
+func Describe(args []string) ([]Value, error) {
+	l, err := newLoader(args)
 	if err != nil {
-		log.Fatalf("unable to cast cookie max age seconds :%v", err)
+		return nil, err
 	}
 
-	secure := mustGet("JWT_COOKIE_SECURE")
-	secureBool, err := strconv.ParseBool(secure)
+	cfg := l.config()
+	cfg.validate(l)
+	return l.Values(), l.Err()
+}
+
+// ReloadSecrets reads the secrets again, typically from files mounted by the orchestrator,
+// and rotates the ones that changed. Nothing is rotated when any setting is invalid
+func (c *Config) ReloadSecrets(args []string) error {
+	l, err := newLoader(args)
 	if err != nil {
-		log.Fatalf("unable to cast cookie secure :%v", err)
+		return err
 	}
 
+	l.config().validate(l)
+	if err := l.Err(); err != nil {
+		return err
+	}
+
+	c.JWT.AccessTokenConfig.secrets.Rotate(l.secret("JWT_ACCESS_TOKEN_SECRET"))
+	c.JWT.RefreshTokenConfig.secrets.Rotate(l.secret("JWT_REFRESH_TOKEN_SECRET"))
+	c.JWT.CookieStoreConfig.Secrets.Rotate(l.secret("JWT_COOKIE_SECRET"))
+	c.DB.Password.Rotate(l.secret("DB_PASSWORD"))
+	return nil
+}
+
+func (l *loader) config() *Config {
+	grace := l.duration("SECRETS_ROTATION_GRACE")
 	return &Config{
 		JWT: JWTConfig{
 			AccessTokenConfig: AccessTokenConfig{
-				secret:                 []byte(jwtAccessTokenSecret),
-				TokenExpirationMinutes: atTTLMinutes,
+				secrets:  NewKeyring(l.secret("JWT_ACCESS_TOKEN_SECRET"), grace),
+				TokenTTL: l.duration("JWT_ACCESS_TOKEN_TTL"),
 			},
 			RefreshTokenConfig: RefreshTokenConfig{
-				secret:                 []byte(jwtRefreshTokenSecret),
-				TokenExpirationMinutes: etTTLDays,
+				secrets:  NewKeyring(l.secret("JWT_REFRESH_TOKEN_SECRET"), grace),
+				TokenTTL: l.duration("JWT_REFRESH_TOKEN_TTL"),
 			},
 			CookieStoreConfig: CookieStoreConfig{
-				Domain:        cookieDomain,
-				MaxAgeSeconds: cookieMaxAgeSeconds,
-				Path:          cookiePath,
-				SameSite:      sameSiteIota,
-				Secret:        []byte(cookieSecret),
-				Secure:        secureBool,
+				Domain:   l.string("JWT_COOKIE_DOMAIN"),
+				MaxAge:   l.duration("JWT_COOKIE_MAX_AGE"),
+				Path:     l.string("JWT_COOKIE_PATH"),
+				SameSite: parseSameSite(l.string("JWT_COOKIE_SAME_SITE")),
+				Secrets:  NewKeyring(l.secret("JWT_COOKIE_SECRET"), grace),
+				Secure:   l.bool("JWT_COOKIE_SECURE"),
 			},
 		},
+		Login: LoginProtectionConfig{
+			MaxFailedAttemptsPerAccount: l.int("LOGIN_MAX_FAILED_ATTEMPTS_PER_ACCOUNT"),
+			MaxFailedAttemptsPerIP:      l.int("LOGIN_MAX_FAILED_ATTEMPTS_PER_IP"),
+			LockoutDuration:             l.duration("LOGIN_LOCKOUT"),
+			BackoffAfterAttempts:        l.int("LOGIN_BACKOFF_AFTER_ATTEMPTS"),
+			BackoffBase:                 l.duration("LOGIN_BACKOFF_BASE"),
+			BackoffMax:                  l.duration("LOGIN_BACKOFF_MAX"),
+		},
+		Password: PasswordConfig{
+			MinLength:          l.int("PASSWORD_MIN_LENGTH"),
+			RequireUpper:       l.bool("PASSWORD_REQUIRE_UPPER"),
+			RequireLower:       l.bool("PASSWORD_REQUIRE_LOWER"),
+			RequireDigit:       l.bool("PASSWORD_REQUIRE_DIGIT"),
+			RequireSymbol:      l.bool("PASSWORD_REQUIRE_SYMBOL"),
+			HistorySize:        l.int("PASSWORD_HISTORY_SIZE"),
+			MaxAge:             l.duration("PASSWORD_MAX_AGE"),
+			BreachedHashesFile: l.string("PASSWORD_BREACHED_HASHES_FILE"),
+			Hasher:             l.string("PASSWORD_HASHER"),
+		},
+		OIDC: OIDCConfig{
+			IssuerURL:            l.string("OIDC_ISSUER_URL"),
+			ClientID:             l.string("OIDC_CLIENT_ID"),
+			ClientSecret:         l.secret("OIDC_CLIENT_SECRET"),
+			RedirectURL:          l.string("OIDC_REDIRECT_URL"),
+			PostLoginRedirectURL: l.string("OIDC_POST_LOGIN_REDIRECT_URL"),
+			Scopes:               l.list("OIDC_SCOPES"),
+			GroupsClaim:          l.string("OIDC_GROUPS_CLAIM"),
+			GroupRoles:           l.groupRoles("OIDC_GROUP_ROLES"),
+		},
+		Storage: l.string("STORAGE"),
 		DB: DBConfig{
-			Host:     mustGet("DB_HOST"),
-			Port:     mustGet("DB_PORT"),
-			User:     mustGet("DB_USER"),
-			Password: mustGet("DB_PASSWORD"),
-			DBName:   mustGet("DB_NAME"),
+			Host:            l.string("DB_HOST"),
+			Port:            l.string("DB_PORT"),
+			User:            l.string("DB_USER"),
+			Password:        NewKeyring(l.secret("DB_PASSWORD"), 0),
+			DBName:          l.string("DB_NAME"),
+			LogLevel:        l.int("DB_LOG_LEVEL"),
+			MigrationsTable: l.string("DB_MIGRATIONS_TABLE"),
+			SQLitePath:      l.string("DB_SQLITE_PATH"),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled: l.bool("RATE_LIMIT_ENABLED"),
+			Store:   l.string("RATE_LIMIT_STORE"),
+			IP:      l.quota("RATE_LIMIT_IP"),
+			User:    l.quota("RATE_LIMIT_USER"),
+			Routes:  l.rateLimitRoutes("RATE_LIMIT_ROUTES"),
+		},
+		Events: EventsConfig{
+			WebhookURL:        l.string("EVENTS_WEBHOOK_URL"),
+			NATSURL:           l.string("EVENTS_NATS_URL"),
+			NATSSubjectPrefix: l.string("EVENTS_NATS_SUBJECT_PREFIX"),
+			FilePath:          l.string("EVENTS_FILE_PATH"),
+			DispatchInterval:  l.duration("EVENTS_DISPATCH_INTERVAL"),
+			BatchSize:         l.int("EVENTS_BATCH_SIZE"),
+			RetryBase:         l.duration("EVENTS_RETRY_BASE"),
+			RetryMax:          l.duration("EVENTS_RETRY_MAX"),
+		},
+		Webhooks: WebhooksConfig{
+			Enabled:          l.bool("WEBHOOKS_ENABLED"),
+			Timeout:          l.duration("WEBHOOKS_TIMEOUT"),
+			MaxAttempts:      l.int("WEBHOOKS_MAX_ATTEMPTS"),
+			RetryBase:        l.duration("WEBHOOKS_RETRY_BASE"),
+			RetryMax:         l.duration("WEBHOOKS_RETRY_MAX"),
+			DeliveryInterval: l.duration("WEBHOOKS_DELIVERY_INTERVAL"),
+			BatchSize:        l.int("WEBHOOKS_BATCH_SIZE"),
+			AllowedNetworks:  l.networks("WEBHOOKS_ALLOWED_NETWORKS"),
+		},
+		Sessions: SessionsConfig{
+			PurgeBatchSize:   l.int("SESSIONS_PURGE_BATCH_SIZE"),
+			HistoryRetention: l.duration("SESSIONS_HISTORY_RETENTION"),
+		},
+		Jobs: JobsConfig{
+			Enabled:      l.bool("JOBS_ENABLED"),
+			Concurrency:  l.int("JOBS_CONCURRENCY"),
+			Timeout:      l.duration("JOBS_TIMEOUT"),
+			MaxAttempts:  l.int("JOBS_MAX_ATTEMPTS"),
+			RetryBase:    l.duration("JOBS_RETRY_BASE"),
+			RetryMax:     l.duration("JOBS_RETRY_MAX"),
+			PollInterval: l.duration("JOBS_POLL_INTERVAL"),
+		},
+		Log: LogConfig{
+			Level:  l.level("LOG_LEVEL"),
+			Levels: l.logLevels("LOG_LEVELS"),
 		},
-		Port: mustGet("PORT"),
+		Tracing: TracingConfig{
+			Exporter:     l.string("TRACING_EXPORTER"),
+			ServiceName:  l.string("TRACING_SERVICE_NAME"),
+			OTLPEndpoint: l.string("TRACING_OTLP_ENDPOINT"),
+			OTLPInsecure: l.bool("TRACING_OTLP_INSECURE"),
+			SampleRatio:  l.float("TRACING_SAMPLE_RATIO"),
+		},
+		API: APIConfig{
+			V1Deprecation: l.date("API_V1_DEPRECATION"),
+			V1Sunset:      l.date("API_V1_SUNSET"),
+		},
+		Port:                 l.string("PORT"),
+		MetricsPort:          l.string("METRICS_PORT"),
+		TrustedProxies:       l.networks("TRUSTED_PROXIES"),
+		SecretsRotationGrace: grace,
+		IdempotencyKeyTTL:    l.duration("IDEMPOTENCY_KEY_TTL"),
+		ShutdownDelay:        l.duration("SHUTDOWN_DELAY"),
+	}
+}
+
+// groupRoles parses group1:role1,role2;group2:role3
+func (l *loader) groupRoles(key string) map[string][]string {
+	groupRoles := map[string][]string{}
+	for group, roles := range l.mapping(key, "group1:role1,role2") {
+		groupRoles[group] = strings.Split(roles, ",")
+	}
+
+	return groupRoles
+}
+
+// logLevels parses component1:level;component2:level
+func (l *loader) logLevels(key string) map[string]slog.Level {
+	levels := map[string]slog.Level{}
+	for component, raw := range l.mapping(key, "component:level") {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(raw)); err != nil {
+			l.problem(key, "%q is not one of debug, info, warn, error", raw)
+			continue
+		}
+		levels[component] = level
 	}
+
+	return levels
+}
+
+// rateLimitRoutes parses /route1:10/1m;/route2:5/1h
+func (l *loader) rateLimitRoutes(key string) map[string]RateLimitQuota {
+	quotas := map[string]RateLimitQuota{}
+	for route, raw := range l.mapping(key, "/route:requests/period") {
+		quota, err := parseQuota(raw)
+		if err != nil {
+			l.problem(key, "%s", err)
+			continue
+		}
+		quotas[route] = quota
+	}
+
+	return quotas
+}
+
+// mapping parses key1:value1;key2:value2, example describes the expected format in the problems
+func (l *loader) mapping(key string, example string) map[string]string {
+	values := map[string]string{}
+	for _, mapping := range strings.Split(l.string(key), ";") {
+		if strings.TrimSpace(mapping) == "" {
+			continue
+		}
+
+		// cut on the last colon, the names may contain some such as the route /api/v1/patient/:id
+		separator := strings.LastIndex(mapping, ":")
+		name, value, found := mapping[:max(separator, 0)], mapping[separator+1:], separator >= 0
+		if !found || strings.TrimSpace(name) == "" || strings.TrimSpace(value) == "" {
+			l.problem(key, "%q is not a %s mapping", mapping, example)
+			continue
+		}
+
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+
+	return values
 }
 
-func mustGet(key string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		log.Fatalf("missing required env var: %s", key)
+// validate checks the values that parsed but do not make sense, alone or together
+func (c *Config) validate(l *loader) {
+	positive := []struct {
+		key   string
+		value int
+	}{
+		{"LOGIN_MAX_FAILED_ATTEMPTS_PER_ACCOUNT", c.Login.MaxFailedAttemptsPerAccount},
+		{"LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", c.Login.MaxFailedAttemptsPerIP},
+		{"PASSWORD_MIN_LENGTH", c.Password.MinLength},
+	}
+	for _, setting := range positive {
+		if setting.value <= 0 {
+			l.problem(setting.key, "must be greater than 0")
+		}
+	}
+
+	if c.Login.BackoffAfterAttempts < 0 {
+		l.problem("LOGIN_BACKOFF_AFTER_ATTEMPTS", "must not be negative")
+	}
+
+	if c.Password.HistorySize < 0 {
+		l.problem("PASSWORD_HISTORY_SIZE", "must not be negative")
+	}
+
+	if c.JWT.AccessTokenConfig.TokenTTL <= 0 {
+		l.problem("JWT_ACCESS_TOKEN_TTL", "must be greater than 0")
+	}
+
+	if c.JWT.RefreshTokenConfig.TokenTTL <= c.JWT.AccessTokenConfig.TokenTTL {
+		l.problem("JWT_REFRESH_TOKEN_TTL", "must be longer than JWT_ACCESS_TOKEN_TTL (%s)", c.JWT.AccessTokenConfig.TokenTTL)
+	}
+
+	if c.JWT.CookieStoreConfig.SameSite == -1 {
+		l.problem("JWT_COOKIE_SAME_SITE", "%q is not one of lax, strict, none, default", l.string("JWT_COOKIE_SAME_SITE"))
+	}
+
+	if c.JWT.CookieStoreConfig.SameSite == http.SameSiteNoneMode && !c.JWT.CookieStoreConfig.Secure {
+		l.problem("JWT_COOKIE_SAME_SITE", "none requires JWT_COOKIE_SECURE")
+	}
+
+	if c.Login.LockoutDuration <= 0 {
+		l.problem("LOGIN_LOCKOUT", "must be greater than 0")
+	}
+
+	if c.Login.BackoffBase > c.Login.BackoffMax {
+		l.problem("LOGIN_BACKOFF_BASE", "must not exceed LOGIN_BACKOFF_MAX (%s)", c.Login.BackoffMax)
+	}
+
+	if c.Sessions.PurgeBatchSize <= 0 {
+		l.problem("SESSIONS_PURGE_BATCH_SIZE", "must be greater than 0")
+	}
+
+	if c.Sessions.HistoryRetention <= 0 {
+		l.problem("SESSIONS_HISTORY_RETENTION", "must be greater than 0")
+	}
+
+	if c.IdempotencyKeyTTL <= 0 {
+		l.problem("IDEMPOTENCY_KEY_TTL", "must be greater than 0")
+	}
+
+	if c.SecretsRotationGrace < 0 {
+		l.problem("SECRETS_ROTATION_GRACE", "must not be negative")
 	}
-	return val
+
+	if c.ShutdownDelay < 0 {
+		l.problem("SHUTDOWN_DELAY", "must not be negative")
+	}
+
+	if c.Password.MaxAge < 0 {
+		l.problem("PASSWORD_MAX_AGE", "must not be negative")
+	}
+
+	if c.Password.Hasher != PasswordHasherBcrypt && c.Password.Hasher != PasswordHasherArgon2id {
+		l.problem("PASSWORD_HASHER", "%q is not one of %s, %s", c.Password.Hasher, PasswordHasherBcrypt, PasswordHasherArgon2id)
+	}
+
+	switch c.Storage {
+	case StoragePostgres:
+		if c.DB.Password.Current().Reveal() == "" {
+			l.problem("DB_PASSWORD", "is required with the %s storage", StoragePostgres)
+		}
+	case StorageSQLite:
+		if c.DB.SQLitePath == "" {
+			l.problem("DB_SQLITE_PATH", "is required with the %s storage", StorageSQLite)
+		}
+
+		// the server migrates the file itself and records it there
+		if c.DB.MigrationsTable == "" {
+			l.problem("DB_MIGRATIONS_TABLE", "is required with the %s storage", StorageSQLite)
+		}
+	case StorageMemory:
+	default:
+		l.problem("STORAGE", "%q is not one of %s, %s, %s", c.Storage, StoragePostgres, StorageSQLite, StorageMemory)
+	}
+
+	// the limits are only shared through PostgreSQL
+	if c.Storage != StoragePostgres && c.RateLimit.Store == RateLimitStorePostgres {
+		l.problem("RATE_LIMIT_STORE", "%s requires the %s storage", RateLimitStorePostgres, StoragePostgres)
+	}
+
+	if c.DB.LogLevel < 0 || c.DB.LogLevel > 2 {
+		l.problem("DB_LOG_LEVEL", "must be between 0 and 2")
+	}
+
+	if c.RateLimit.Store != RateLimitStoreMemory && c.RateLimit.Store != RateLimitStorePostgres {
+		l.problem("RATE_LIMIT_STORE", "%q is not one of %s, %s", c.RateLimit.Store, RateLimitStoreMemory, RateLimitStorePostgres)
+	}
+
+	if c.Events.WebhookURL != "" {
+		if u, err := url.Parse(c.Events.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.problem("EVENTS_WEBHOOK_URL", "%q is not an http or https URL", c.Events.WebhookURL)
+		}
+	}
+
+	if c.Events.NATSURL != "" {
+		if u, err := url.Parse(c.Events.NATSURL); err != nil || u.Scheme != "nats" || u.Host == "" {
+			l.problem("EVENTS_NATS_URL", "%q is not a nats://host:port URL", c.Events.NATSURL)
+		}
+	}
+
+	if c.Events.DispatchInterval <= 0 {
+		l.problem("EVENTS_DISPATCH_INTERVAL", "must be greater than 0")
+	}
+
+	if c.Events.BatchSize <= 0 {
+		l.problem("EVENTS_BATCH_SIZE", "must be greater than 0")
+	}
+
+	if c.Events.RetryBase <= 0 {
+		l.problem("EVENTS_RETRY_BASE", "must be greater than 0")
+	}
+
+	if c.Events.RetryBase > c.Events.RetryMax {
+		l.problem("EVENTS_RETRY_BASE", "must not exceed EVENTS_RETRY_MAX (%s)", c.Events.RetryMax)
+	}
+
+	if c.Webhooks.Timeout <= 0 {
+		l.problem("WEBHOOKS_TIMEOUT", "must be greater than 0")
+	}
+
+	if c.Webhooks.DeliveryInterval <= 0 {
+		l.problem("WEBHOOKS_DELIVERY_INTERVAL", "must be greater than 0")
+	}
+
+	if c.Webhooks.RetryBase <= 0 {
+		l.problem("WEBHOOKS_RETRY_BASE", "must be greater than 0")
+	}
+
+	if c.Webhooks.MaxAttempts <= 0 {
+		l.problem("WEBHOOKS_MAX_ATTEMPTS", "must be greater than 0")
+	}
+
+	if c.Webhooks.BatchSize <= 0 {
+		l.problem("WEBHOOKS_BATCH_SIZE", "must be greater than 0")
+	}
+
+	if c.Webhooks.RetryBase > c.Webhooks.RetryMax {
+		l.problem("WEBHOOKS_RETRY_BASE", "must not exceed WEBHOOKS_RETRY_MAX (%s)", c.Webhooks.RetryMax)
+	}
+
+	if c.Jobs.Concurrency <= 0 {
+		l.problem("JOBS_CONCURRENCY", "must be greater than 0")
+	}
+
+	if c.Jobs.Timeout <= 0 {
+		l.problem("JOBS_TIMEOUT", "must be greater than 0")
+	}
+
+	if c.Jobs.MaxAttempts <= 0 {
+		l.problem("JOBS_MAX_ATTEMPTS", "must be greater than 0")
+	}
+
+	if c.Jobs.RetryBase <= 0 {
+		l.problem("JOBS_RETRY_BASE", "must be greater than 0")
+	}
+
+	if c.Jobs.RetryBase > c.Jobs.RetryMax {
+		l.problem("JOBS_RETRY_BASE", "must not exceed JOBS_RETRY_MAX (%s)", c.Jobs.RetryMax)
+	}
+
+	if c.Jobs.PollInterval <= 0 {
+		l.problem("JOBS_POLL_INTERVAL", "must be greater than 0")
+	}
+
+	switch c.Tracing.Exporter {
+	case TracingExporterNone, TracingExporterStdout:
+	case TracingExporterOTLP:
+		if c.Tracing.OTLPEndpoint == "" {
+			l.problem("TRACING_OTLP_ENDPOINT", "is required with the otlp exporter")
+		}
+	default:
+		l.problem("TRACING_EXPORTER", "%q is not one of %s, %s, %s", c.Tracing.Exporter, TracingExporterNone, TracingExporterOTLP, TracingExporterStdout)
+	}
+
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		l.problem("TRACING_SAMPLE_RATIO", "must be between 0 and 1")
+	}
+
+	if !c.API.V1Deprecation.IsZero() && !c.API.V1Sunset.IsZero() && c.API.V1Sunset.Before(c.API.V1Deprecation) {
+		l.problem("API_V1_SUNSET", "must not be before API_V1_DEPRECATION (%s)", c.API.V1Deprecation.Format(DateLayout))
+	}
+
+	ports := []string{"DB_PORT", "PORT"}
+	if c.MetricsPort != "" {
+		ports = append(ports, "METRICS_PORT")
+		if c.MetricsPort == c.Port {
+			l.problem("METRICS_PORT", "must differ from PORT (%s)", c.Port)
+		}
+	}
+	for _, key := range ports {
+		if port, err := strconv.Atoi(l.string(key)); err != nil || port < 1 || port > 65535 {
+			l.problem(key, "%q is not a port between 1 and 65535", l.string(key))
+		}
+	}
+
+	// the other OIDC settings only matter once SSO is enabled
+	if c.OIDC.Enabled() {
+		requiredOIDC := []struct {
+			key   string
+			value string
+		}{
+			{"OIDC_CLIENT_ID", c.OIDC.ClientID},
+			{"OIDC_CLIENT_SECRET", c.OIDC.ClientSecret.Reveal()},
+			{"OIDC_REDIRECT_URL", c.OIDC.RedirectURL},
+			{"OIDC_POST_LOGIN_REDIRECT_URL", c.OIDC.PostLoginRedirectURL},
+			{"OIDC_GROUPS_CLAIM", c.OIDC.GroupsClaim},
+		}
+		for _, setting := range requiredOIDC {
+			if setting.value == "" {
+				l.problem(setting.key, "is required when OIDC_ISSUER_URL is set")
+			}
+		}
+
+		if !containsString(c.OIDC.Scopes, "openid") {
+			l.problem("OIDC_SCOPES", "must contain openid")
+		}
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, existing := range values {
+		if existing == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Config) Address() string {
+	return fmt.Sprintf(":%s", c.Port)
+}
+
+func (c *Config) MetricsAddress() string {
+	return fmt.Sprintf(":%s", c.MetricsPort)
 }
@@ -0,0 +1,215 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "access")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "refresh")
+	t.Setenv("JWT_COOKIE_SECRET", "cookie")
+	t.Setenv("DB_PASSWORD", "db")
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	setRequiredEnv(t)
+	configFile := filepath.Join(t.TempDir(), "cleanic.yaml")
+	content := "server:\n  port: 9000\njwt:\n  access_token:\n    ttl: 10m\n  refresh_token:\n    ttl: 14d\noidc:\n  group_roles:\n    doctors: [doctor]\n"
+	if err := os.WriteFile(configFile, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("PORT", "9001")
+	t.Setenv("JWT_REFRESH_TOKEN_TTL_DAYS", "30")
+
+	cfg, err := Load([]string{"--config", configFile, "--server-port", "9002"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Port != "9002" {
+		t.Errorf("flag should win over env and file, got port %s", cfg.Port)
+	}
+
+	if cfg.JWT.AccessTokenConfig.TokenTTL != 10*time.Minute {
+		t.Errorf("file should win over default, got access ttl %s", cfg.JWT.AccessTokenConfig.TokenTTL)
+	}
+
+	if cfg.JWT.RefreshTokenConfig.TokenTTL != 30*24*time.Hour {
+		t.Errorf("deprecated env alias should win over file and count days, got refresh ttl %s", cfg.JWT.RefreshTokenConfig.TokenTTL)
+	}
+
+	if cfg.Login.LockoutDuration != 15*time.Minute {
+		t.Errorf("default should apply, got lockout %s", cfg.Login.LockoutDuration)
+	}
+
+	if roles := cfg.OIDC.GroupRoles["doctors"]; len(roles) != 1 || roles[0] != "doctor" {
+		t.Errorf("group roles should be read from the file map, got %v", cfg.OIDC.GroupRoles)
+	}
+}
+
+func TestLoadReportsAllProblems(t *testing.T) {
+	t.Setenv("JWT_COOKIE_SECRET", "cookie")
+	t.Setenv("DB_PASSWORD", "db")
+	t.Setenv("PASSWORD_HASHER", "md5")
+	t.Setenv("LOGIN_LOCKOUT", "soon")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy")
+
+	_, err := Load([]string{"--server-port", "0"})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, expected := range []string{
+		"JWT_ACCESS_TOKEN_SECRET is required",
+		"JWT_REFRESH_TOKEN_SECRET is required",
+		`PASSWORD_HASHER (env PASSWORD_HASHER): "md5" is not one of bcrypt, argon2id`,
+		`LOGIN_LOCKOUT (env LOGIN_LOCKOUT): "soon" is not a duration`,
+		`PORT (flag --server-port): "0" is not a port`,
+		`TRUSTED_PROXIES (env TRUSTED_PROXIES): "proxy" is not an IP nor a CIDR`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in:\n%v", expected, err)
+		}
+	}
+}
+
+func TestMemoryStorageNeedsNoDatabase(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PASSWORD", "")
+
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "DB_PASSWORD (unset): is required with the postgres storage") {
+		t.Errorf("the postgres storage should require DB_PASSWORD, got %v", err)
+	}
+
+	cfg, err := Load([]string{"--storage", "memory"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !cfg.InMemory() {
+		t.Errorf("the storage should be memory, got %s", cfg.Storage)
+	}
+
+	t.Setenv("RATE_LIMIT_STORE", "postgres")
+	if _, err := Load([]string{"--storage", "memory"}); err == nil || !strings.Contains(err.Error(), "postgres requires the postgres storage") {
+		t.Errorf("the postgres rate limit store should require the postgres storage, got %v", err)
+	}
+}
+
+func TestSQLiteStorageMigratesItsFile(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PASSWORD", "")
+
+	cfg, err := Load([]string{"--storage", "sqlite"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.DB.SQLitePath != "cleanic.db" {
+		t.Errorf("unexpected default sqlite path %s", cfg.DB.SQLitePath)
+	}
+
+	if _, err := Load([]string{"--storage", "sqlite", "--db-migrations-table", ""}); err == nil || !strings.Contains(err.Error(), "DB_MIGRATIONS_TABLE (flag --db-migrations-table): is required with the sqlite storage") {
+		t.Errorf("the sqlite storage should require DB_MIGRATIONS_TABLE, got %v", err)
+	}
+}
+
+func TestDescribeRedactsSecrets(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PASSWORD", "very-secret")
+
+	values, err := Describe(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, value := range values {
+		if strings.Contains(value.String(), "very-secret") {
+			t.Errorf("secret leaked: %s", value)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	testCases := []struct {
+		raw      string
+		unit     time.Duration
+		expected time.Duration
+		wantErr  bool
+	}{
+		{raw: "5", unit: time.Minute, expected: 5 * time.Minute},
+		{raw: "7", unit: day, expected: 7 * day},
+		{raw: "7d", unit: time.Second, expected: 7 * day},
+		{raw: "90s", unit: day, expected: 90 * time.Second},
+		{raw: "1h30m", unit: time.Minute, expected: 90 * time.Minute},
+		{raw: "xd", unit: time.Minute, wantErr: true},
+		{raw: "ten", unit: time.Minute, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.raw, func(t *testing.T) {
+			got, err := parseDuration(tc.raw, tc.unit)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSecretFilesAndReload(t *testing.T) {
+	setRequiredEnv(t)
+	secretFile := filepath.Join(t.TempDir(), "access_secret")
+	if err := os.WriteFile(secretFile, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "")
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET_FILE", secretFile)
+
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := string(cfg.JWT.AccessTokenConfig.GetSecret()); got != "from-file" {
+		t.Fatalf("expected the secret file content without new line, got %q", got)
+	}
+
+	if err := os.WriteFile(secretFile, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.ReloadSecrets(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := string(cfg.JWT.AccessTokenConfig.GetSecret()); got != "rotated" {
+		t.Errorf("expected the rotated secret to sign, got %q", got)
+	}
+
+	verification := cfg.JWT.AccessTokenConfig.GetVerificationSecrets()
+	if len(verification) != 2 || string(verification[1]) != "from-file" {
+		t.Errorf("expected the previous secret to still verify, got %d secrets", len(verification))
+	}
+
+	if err := os.Remove(secretFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.ReloadSecrets(nil); err == nil {
+		t.Error("expected an error when the secret file disappeared")
+	}
+
+	if got := string(cfg.JWT.AccessTokenConfig.GetSecret()); got != "rotated" {
+		t.Errorf("a failed reload must keep the current secret, got %q", got)
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+// EventsConfig drives the delivery of the domain events recorded in the outbox.
+// Each sink is enabled by its destination, the events are not recorded when none is
+type EventsConfig struct {
+	// WebhookURL receives every event as a JSON POST
+	WebhookURL string
+	// NATSURL is the nats://host:port of a NATS compatible broker
+	NATSURL string
+	// NATSSubjectPrefix is prepended to the event type, such as cleanic.patient.created
+	NATSSubjectPrefix string
+	// FilePath gets every event appended as a JSON line
+	FilePath string
+	// DispatchInterval is the pause of the dispatcher once the outbox is empty
+	DispatchInterval time.Duration
+	BatchSize        int
+	// RetryBase is the delay after the first failed delivery, it doubles up to RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+}
+
+func (e EventsConfig) Enabled() bool {
+	return e.WebhookURL != "" || e.NATSURL != "" || e.FilePath != ""
+}
@@ -0,0 +1,20 @@
+package config
+
+import "time"
+
+// JobsConfig drives the workers running the background jobs, such as the scheduled purges
+type JobsConfig struct {
+	// Enabled runs the workers of this instance, the jobs enqueued meanwhile wait for an instance running them
+	Enabled bool
+	// Concurrency is how many jobs a worker runs at once
+	Concurrency int
+	// Timeout bounds a run of a job, a job still running after it fails
+	Timeout time.Duration
+	// MaxAttempts is how many times a job is run before it is dead-lettered
+	MaxAttempts int
+	// RetryBase is the delay after the first failed run, it doubles up to RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// PollInterval is the pause of a worker once no job is due
+	PollInterval time.Duration
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"net/http"
 	"strings"
+	"time"
 )
 
 type JWTConfig struct {
@@ -12,36 +13,59 @@ type JWTConfig struct {
 }
 
 type CookieStoreConfig struct {
-	Domain        string
-	MaxAgeSeconds int
-	Path          string
+	Domain string
+	MaxAge time.Duration
+	Path   string
 	// SameSiteDefaultMode = 0/1
 	// SameSiteLaxMode = 2
 	// SameSiteStrictMode = 3
 	// SameSiteNoneMode = 4
 	SameSite http.SameSite
-	Secret   []byte
-	Secure   bool
+	// Secrets signs the session cookies, retired ones are still accepted during the grace period
+	Secrets *Keyring
+	Secure  bool
 }
 
 type AccessTokenConfig struct {
-	secret                 []byte
-	TokenExpirationMinutes int
-	Audience               string
+	secrets  *Keyring
+	TokenTTL time.Duration
+	Audience string
 }
 
+// GetSecret returns the secret used to sign new tokens
 func (a *AccessTokenConfig) GetSecret() []byte {
-	return a.secret
+	return []byte(a.secrets.Current().Reveal())
+}
+
+// GetVerificationSecrets also returns the retired secrets still in their grace period
+func (a *AccessTokenConfig) GetVerificationSecrets() [][]byte {
+	return revealAll(a.secrets)
 }
 
 type RefreshTokenConfig struct {
-	secret                 []byte
-	TokenExpirationMinutes int
-	Audience               string
+	secrets  *Keyring
+	TokenTTL time.Duration
+	Audience string
 }
 
+// GetSecret returns the secret used to sign new tokens
 func (r *RefreshTokenConfig) GetSecret() []byte {
-	return r.secret
+	return []byte(r.secrets.Current().Reveal())
+}
+
+// GetVerificationSecrets also returns the retired secrets still in their grace period
+func (r *RefreshTokenConfig) GetVerificationSecrets() [][]byte {
+	return revealAll(r.secrets)
+}
+
+func revealAll(keyring *Keyring) [][]byte {
+	secrets, _ := keyring.Snapshot()
+	revealed := make([][]byte, len(secrets))
+	for i, secret := range secrets {
+		revealed[i] = []byte(secret.Reveal())
+	}
+
+	return revealed
 }
 
 func parseSameSite(value string) http.SameSite {
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"sync"
+	"time"
+)
+
+// Keyring holds a secret that can be rotated at runtime. Signers only use the current
+// secret while verifiers still accept the retired ones until their grace period ends
+type Keyring struct {
+	mu      sync.RWMutex
+	current Secret
+	retired []retiredSecret
+	grace   time.Duration
+	version uint64
+	now     func() time.Time
+}
+
+type retiredSecret struct {
+	secret Secret
+	until  time.Time
+}
+
+func NewKeyring(secret Secret, grace time.Duration) *Keyring {
+	return &Keyring{
+		current: secret,
+		grace:   grace,
+		now:     time.Now,
+	}
+}
+
+func (k *Keyring) Current() Secret {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.current
+}
+
+// Snapshot returns the secrets accepted for verification, current first,
+// and a version that changes whenever that list changes
+func (k *Keyring) Snapshot() ([]Secret, uint64) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	now := k.now()
+	active := k.retired[:0]
+	for _, retired := range k.retired {
+		if now.Before(retired.until) {
+			active = append(active, retired)
+		}
+	}
+
+	if len(active) != len(k.retired) {
+		k.version++
+	}
+	k.retired = active
+
+	secrets := make([]Secret, 0, len(k.retired)+1)
+	secrets = append(secrets, k.current)
+	for _, retired := range k.retired {
+		secrets = append(secrets, retired.secret)
+	}
+
+	return secrets, k.version
+}
+
+// Rotate replaces the current secret, it returns false when the secret did not change
+func (k *Keyring) Rotate(secret Secret) bool {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if secret == k.current {
+		return false
+	}
+
+	if k.grace > 0 {
+		k.retired = append([]retiredSecret{{secret: k.current, until: k.now().Add(k.grace)}}, k.retired...)
+	}
+
+	k.current = secret
+	k.version++
+	return true
+}
+
+func (k *Keyring) String() string {
+	return redacted
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	now := time.Now()
+	keyring := NewKeyring("first", time.Hour)
+	keyring.now = func() time.Time { return now }
+
+	if keyring.Rotate("first") {
+		t.Error("rotating to the same secret should be a no-op")
+	}
+
+	_, initialVersion := keyring.Snapshot()
+	if !keyring.Rotate("second") {
+		t.Fatal("rotation expected")
+	}
+
+	secrets, version := keyring.Snapshot()
+	if keyring.Current() != "second" || len(secrets) != 2 || secrets[0] != "second" || secrets[1] != "first" {
+		t.Fatalf("expected current then retired secret, got %v", secrets)
+	}
+
+	if version == initialVersion {
+		t.Error("version should change on rotation")
+	}
+
+	now = now.Add(time.Hour + time.Second)
+	secrets, expiredVersion := keyring.Snapshot()
+	if len(secrets) != 1 || secrets[0] != "second" {
+		t.Fatalf("retired secret should expire after the grace period, got %v", secrets)
+	}
+
+	if expiredVersion == version {
+		t.Error("version should change when a retired secret expires")
+	}
+}
@@ -0,0 +1,461 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Source tells which layer a value comes from, by increasing precedence
+type Source string
+
+const (
+	SourceNone    Source = "unset"
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// configFileEnv points to the config file when the --config flag is not given
+const configFileEnv = "CLEANIC_CONFIG_FILE"
+
+// Value is a resolved setting, safe to print as secrets are redacted
+type Value struct {
+	Key    string
+	Path   string
+	Value  string
+	Source Source
+	// From names the env var, flag or file the value was read from
+	From string
+}
+
+func (v Value) String() string {
+	if v.From == "" {
+		return fmt.Sprintf("%s=%s (%s)", v.Key, v.Value, v.Source)
+	}
+
+	return fmt.Sprintf("%s=%s (%s %s)", v.Key, v.Value, v.Source, v.From)
+}
+
+type resolved struct {
+	setting setting
+	raw     string
+	source  Source
+	from    string
+	err     error
+}
+
+// loader resolves every setting across the layers and collects the problems
+// found while parsing them, so that they are all reported at once
+type loader struct {
+	values   map[string]resolved
+	problems []error
+}
+
+// newLoader resolves the settings from, by increasing precedence, their defaults,
+// the config file, the environment (and .env) and the CLI flags
+func newLoader(args []string) (*loader, error) {
+	_ = godotenv.Load()
+
+	flagSet := flag.NewFlagSet("cleanic", flag.ContinueOnError)
+	flagSet.SetOutput(io.Discard)
+	configFile := flagSet.String("config", os.Getenv(configFileEnv), "YAML or TOML config file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = flagSet.String(s.flagName(), "", fmt.Sprintf("%s (%s)", s.usage, s.key))
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, fmt.Errorf("unable to parse flags: %w", err)
+	}
+
+	setFlags := map[string]bool{}
+	flagSet.Visit(func(f *flag.Flag) {
+		setFlags[f.Name] = true
+	})
+
+	fileValues := map[string]string{}
+	if *configFile != "" {
+		var err error
+		fileValues, err = readConfigFile(*configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	l := &loader{values: make(map[string]resolved, len(settings))}
+	for _, s := range settings {
+		value := resolved{setting: s, source: SourceNone}
+		switch {
+		case setFlags[s.flagName()]:
+			value.raw, value.source, value.from = *flagValues[s.key], SourceFlag, "--"+s.flagName()
+		case s.lookupEnv(&value):
+		case hasKey(fileValues, s.path):
+			value.raw, value.source, value.from = fileValues[s.path], SourceFile, *configFile
+		case s.secret && hasKey(fileValues, s.secretFilePath()):
+			value.source, value.from = SourceFile, fileValues[s.secretFilePath()]
+			value.raw, value.err = readSecretFile(value.from)
+		case s.def != "":
+			value.raw, value.source = s.def, SourceDefault
+		}
+
+		l.values[s.key] = value
+		if value.err != nil {
+			l.problem(s.key, "%v", value.err)
+			continue
+		}
+
+		if s.required && strings.TrimSpace(value.raw) == "" {
+			l.problems = append(l.problems, fmt.Errorf("%s is required, set it in the environment, the config file (%s) or with --%s", s.key, s.path, s.flagName()))
+		}
+	}
+
+	return l, nil
+}
+
+func (s setting) flagName() string {
+	return strings.NewReplacer(".", "-", "_", "-").Replace(s.path)
+}
+
+func (s setting) secretFilePath() string {
+	return s.path + "_file"
+}
+
+// lookupEnv reads the key, the file named by KEY_FILE for secrets, then the deprecated aliases.
+// Empty variables are ignored so that they do not shadow the config file
+func (s setting) lookupEnv(value *resolved) bool {
+	if s.secret {
+		if path := os.Getenv(s.key + "_FILE"); path != "" {
+			value.source, value.from = SourceEnv, s.key+"_FILE"
+			value.raw, value.err = readSecretFile(path)
+			if os.Getenv(s.key) != "" {
+				value.err = fmt.Errorf("both %s and %s_FILE are set", s.key, s.key)
+			}
+
+			return true
+		}
+	}
+
+	for _, key := range append([]string{s.key}, s.aliases...) {
+		if raw := os.Getenv(key); raw != "" {
+			value.raw, value.source, value.from = raw, SourceEnv, key
+			return true
+		}
+	}
+
+	return false
+}
+
+// readSecretFile trims the trailing new line most editors and secret managers add
+func readSecretFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read secret file: %w", err)
+	}
+
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+func hasKey(values map[string]string, key string) bool {
+	_, found := values[key]
+	return found
+}
+
+// Values lists the resolved settings sorted by key, secrets redacted
+func (l *loader) Values() []Value {
+	values := make([]Value, 0, len(l.values))
+	for key, value := range l.values {
+		display := value.raw
+		if value.setting.secret {
+			display = Secret(value.raw).String()
+		}
+
+		values = append(values, Value{Key: key, Path: value.setting.path, Value: display, Source: value.source, From: value.from})
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values
+}
+
+func (l *loader) Err() error {
+	return errors.Join(l.problems...)
+}
+
+// problem records an invalid value, naming where it comes from without leaking secrets
+func (l *loader) problem(key string, format string, args ...any) {
+	value := l.values[key]
+	where := string(value.source)
+	if value.from != "" {
+		where = fmt.Sprintf("%s %s", value.source, value.from)
+	}
+
+	l.problems = append(l.problems, fmt.Errorf("%s (%s): %s", key, where, fmt.Sprintf(format, args...)))
+}
+
+func (l *loader) string(key string) string {
+	return strings.TrimSpace(l.values[key].raw)
+}
+
+func (l *loader) secret(key string) Secret {
+	return Secret(l.values[key].raw)
+}
+
+func (l *loader) int(key string) int {
+	raw := l.string(key)
+	if raw == "" {
+		return 0
+	}
+
+	val, err := strconv.Atoi(raw)
+	if err != nil {
+		l.problem(key, "%q is not an integer", raw)
+	}
+
+	return val
+}
+
+func (l *loader) float(key string) float64 {
+	raw := l.string(key)
+	if raw == "" {
+		return 0
+	}
+
+	val, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		l.problem(key, "%q is not a number", raw)
+	}
+
+	return val
+}
+
+func (l *loader) bool(key string) bool {
+	raw := l.string(key)
+	if raw == "" {
+		return false
+	}
+
+	val, err := strconv.ParseBool(raw)
+	if err != nil {
+		l.problem(key, "%q is not a boolean", raw)
+	}
+
+	return val
+}
+
+func (l *loader) duration(key string) time.Duration {
+	raw := l.string(key)
+	if raw == "" {
+		return 0
+	}
+
+	val, err := parseDuration(raw, l.values[key].setting.unit)
+	if err != nil {
+		l.problem(key, "%q is not a duration, use a unit such as 90s, 15m, 12h or 7d", raw)
+	}
+
+	return val
+}
+
+func (l *loader) date(key string) time.Time {
+	raw := l.string(key)
+	if raw == "" {
+		return time.Time{}
+	}
+
+	val, err := time.Parse(DateLayout, raw)
+	if err != nil {
+		l.problem(key, "%q is not a date such as 2027-04-30", raw)
+	}
+
+	return val
+}
+
+func (l *loader) level(key string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(l.string(key))); err != nil {
+		l.problem(key, "%q is not one of debug, info, warn, error", l.string(key))
+	}
+
+	return level
+}
+
+func (l *loader) quota(key string) RateLimitQuota {
+	quota, err := parseQuota(l.string(key))
+	if err != nil {
+		l.problem(key, "%s", err)
+	}
+
+	return quota
+}
+
+func (l *loader) list(key string) []string {
+	raw := l.string(key)
+	if raw == "" {
+		return nil
+	}
+
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// networks accepts IPs and CIDRs, such as 10.0.0.1,10.1.0.0/16
+func (l *loader) networks(key string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, value := range l.list(key) {
+		if ip := net.ParseIP(value); ip != nil {
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			l.problem(key, "%q is not an IP nor a CIDR", value)
+			continue
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
+
+// parseDuration accepts Go durations, a number of days (7d),
+// or a bare number expressed in the setting unit
+func parseDuration(raw string, unit time.Duration) (time.Duration, error) {
+	if number, err := strconv.Atoi(raw); err == nil {
+		if unit == 0 {
+			unit = time.Second
+		}
+
+		return time.Duration(number) * unit, nil
+	}
+
+	if days, found := strings.CutSuffix(raw, "d"); found {
+		number, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(number) * day, nil
+	}
+
+	return time.ParseDuration(raw)
+}
+
+// readConfigFile flattens a YAML or TOML file into dotted paths
+func readConfigFile(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	tree := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &tree)
+	case ".toml":
+		err = toml.Unmarshal(content, &tree)
+	default:
+		return nil, fmt.Errorf("unable to read config file %s: unsupported extension, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse config file %s: %w", path, err)
+	}
+
+	knownPaths := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		knownPaths[s.path] = true
+		if s.secret {
+			knownPaths[s.secretFilePath()] = true
+		}
+	}
+
+	values := map[string]string{}
+	var unknownPaths []error
+	flatten("", tree, knownPaths, values, &unknownPaths)
+	if len(unknownPaths) > 0 {
+		return nil, fmt.Errorf("unable to parse config file %s: %w", path, errors.Join(unknownPaths...))
+	}
+
+	return values, nil
+}
+
+// flatten stops on known paths so that lists and maps are kept as a single value,
+// in the same format as the matching environment variable
+func flatten(prefix string, node any, knownPaths map[string]bool, values map[string]string, unknownPaths *[]error) {
+	if knownPaths[prefix] {
+		values[prefix] = formatFileValue(node)
+		return
+	}
+
+	children, isMap := node.(map[string]any)
+	if !isMap {
+		*unknownPaths = append(*unknownPaths, fmt.Errorf("unknown setting: %s", prefix))
+		return
+	}
+
+	for key, child := range children {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		flatten(path, child, knownPaths, values, unknownPaths)
+	}
+}
+
+func formatFileValue(node any) string {
+	switch value := node.(type) {
+	case []any:
+		parts := make([]string, len(value))
+		for i, part := range value {
+			parts[i] = formatFileValue(part)
+		}
+
+		return strings.Join(parts, ",")
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, key := range keys {
+			parts[i] = key + ":" + formatFileValue(value[key])
+		}
+
+		return strings.Join(parts, ";")
+	case time.Time:
+		// unquoted YAML and TOML dates are decoded as timestamps
+		if hour, minute, second := value.Clock(); hour == 0 && minute == 0 && second == 0 && value.Nanosecond() == 0 {
+			return value.Format(DateLayout)
+		}
+
+		return value.Format(time.RFC3339)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
@@ -0,0 +1,10 @@
+package config
+
+import "log/slog"
+
+type LogConfig struct {
+	Level slog.Level
+	// Levels overrides Level per component, a component also applies to its subcomponents
+	// e.g. rest applies to rest/patient
+	Levels map[string]slog.Level
+}
@@ -0,0 +1,21 @@
+package config
+
+import "time"
+
+// LoginProtectionConfig drives the brute-force protection of the login route
+// Failed attempts are counted per account (email) and per client IP
+type LoginProtectionConfig struct {
+	// MaxFailedAttemptsPerAccount locks the account once reached
+	MaxFailedAttemptsPerAccount int
+	// MaxFailedAttemptsPerIP locks the client IP once reached
+	// should be higher than the account one as several users may share an IP
+	MaxFailedAttemptsPerIP int
+	// LockoutDuration is how long a key stays locked, it is also the window
+	// after which a failure counter is reset when no new failure occurs
+	LockoutDuration time.Duration
+	// BackoffAfterAttempts is the number of failures allowed before
+	// exponential backoff starts to apply between attempts
+	BackoffAfterAttempts int
+	BackoffBase          time.Duration
+	BackoffMax           time.Duration
+}
@@ -0,0 +1,23 @@
+package config
+
+// OIDCConfig configures the OpenID Connect single sign-on,
+// it is disabled when IssuerURL is empty
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret Secret
+	// RedirectURL is the callback route of this server registered on the identity provider
+	RedirectURL string
+	// PostLoginRedirectURL is where the browser is sent once the refresh token cookie is set
+	PostLoginRedirectURL string
+	Scopes               []string
+	// GroupsClaim is the ID token claim listing the user groups
+	GroupsClaim string
+	// GroupRoles maps an identity provider group to cleanic roles
+	// when empty, provisioned users only get the default role
+	GroupRoles map[string][]string
+}
+
+func (o OIDCConfig) Enabled() bool {
+	return o.IssuerURL != ""
+}
@@ -0,0 +1,26 @@
+package config
+
+import "time"
+
+const (
+	PasswordHasherBcrypt   = "bcrypt"
+	PasswordHasherArgon2id = "argon2id"
+)
+
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize forbids reusing any of the last N passwords, 0 disables the check
+	HistorySize int
+	// MaxAge forces a rotation once reached, 0 disables the check
+	MaxAge time.Duration
+	// BreachedHashesFile lists SHA-1 hashes of breached passwords, one per line
+	// "HASH" or "HASH:COUNT" as distributed by haveibeenpwned, empty disables the check
+	BreachedHashesFile string
+	// Hasher used for new hashes, bcrypt or argon2id
+	// existing hashes of the other kind are rehashed on login
+	Hasher string
+}
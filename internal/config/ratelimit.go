@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RateLimitStoreMemory   = "memory"
+	RateLimitStorePostgres = "postgres"
+)
+
+// RateLimitQuota allows Requests per Period
+type RateLimitQuota struct {
+	Requests int
+	Period   time.Duration
+}
+
+type RateLimitConfig struct {
+	Enabled bool
+	// Store is memory for a single replica, postgres to share the buckets between replicas
+	Store string
+	// IP applies per client IP on the routes without access token
+	IP RateLimitQuota
+	// User applies per user on the routes with an access token or an API key
+	User RateLimitQuota
+	// Routes overrides IP or User for a route template, such as /api/v1/auth/login
+	Routes map[string]RateLimitQuota
+}
+
+// Shared tells whether the buckets are shared between the replicas
+func (r RateLimitConfig) Shared() bool {
+	return r.Store == RateLimitStorePostgres
+}
+
+// LongestPeriod is the time after which any bucket is full again
+func (r RateLimitConfig) LongestPeriod() time.Duration {
+	longest := max(r.IP.Period, r.User.Period)
+	for _, quota := range r.Routes {
+		longest = max(longest, quota.Period)
+	}
+
+	return longest
+}
+
+// parseQuota reads requests/period such as 10/1m, the period accepts the same units as the durations
+func parseQuota(raw string) (RateLimitQuota, error) {
+	rawRequests, rawPeriod, found := strings.Cut(raw, "/")
+	if !found {
+		return RateLimitQuota{}, fmt.Errorf("%q is not a requests/period quota such as 10/1m", raw)
+	}
+
+	requests, err := strconv.Atoi(strings.TrimSpace(rawRequests))
+	if err != nil || requests <= 0 {
+		return RateLimitQuota{}, fmt.Errorf("%q does not allow a positive number of requests", raw)
+	}
+
+	period, err := parseDuration(strings.TrimSpace(rawPeriod), time.Second)
+	if err != nil || period <= 0 {
+		return RateLimitQuota{}, fmt.Errorf("%q does not have a positive period such as 1m", raw)
+	}
+
+	return RateLimitQuota{Requests: requests, Period: period}, nil
+}
@@ -0,0 +1,27 @@
+package config
+
+const redacted = "******"
+
+// Secret hides its value whenever it is printed, logged or marshaled,
+// Reveal has to be called explicitly to use it
+type Secret string
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,11 @@
+package config
+
+import "time"
+
+// SessionsConfig drives the purge of the expired refresh tokens and of the session history
+type SessionsConfig struct {
+	// PurgeBatchSize is how many rows a purge deletes per statement, so that it never holds long locks
+	PurgeBatchSize int
+	// HistoryRetention is how long an ended session is kept for the analytics
+	HistoryRetention time.Duration
+}
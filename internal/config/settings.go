@@ -0,0 +1,145 @@
+package config
+
+import "time"
+
+// setting describes one configuration value and how to find it in each layer:
+// key is the environment variable, path the dotted location in the config file,
+// the CLI flag is derived from the path (jwt.access_token.ttl -> --jwt-access-token-ttl).
+// Secrets can also be read from a file named by KEY_FILE or by the path_file entry
+type setting struct {
+	key      string
+	path     string
+	def      string
+	required bool
+	secret   bool
+	// unit applies to durations given as a bare number
+	unit time.Duration
+	// aliases are deprecated environment variables still read when key is not set
+	aliases []string
+	usage   string
+}
+
+const day = 24 * time.Hour
+
+var settings = []setting{
+	// JWT
+	{key: "JWT_ACCESS_TOKEN_SECRET", path: "jwt.access_token.secret", required: true, secret: true, usage: "HMAC secret of the access tokens"},
+	{key: "JWT_ACCESS_TOKEN_TTL", path: "jwt.access_token.ttl", def: "5m", unit: time.Minute, aliases: []string{"JWT_ACCESS_TOKEN_TTL_MIN"}, usage: "access tokens lifetime, bare numbers are minutes"},
+	{key: "JWT_REFRESH_TOKEN_SECRET", path: "jwt.refresh_token.secret", required: true, secret: true, usage: "HMAC secret of the refresh tokens"},
+	{key: "JWT_REFRESH_TOKEN_TTL", path: "jwt.refresh_token.ttl", def: "7d", unit: day, aliases: []string{"JWT_REFRESH_TOKEN_TTL_DAYS"}, usage: "refresh tokens lifetime, bare numbers are days"},
+
+	// JWT cookies
+	{key: "JWT_COOKIE_DOMAIN", path: "jwt.cookie.domain", def: "localhost", usage: "domain of the refresh token cookie"},
+	{key: "JWT_COOKIE_MAX_AGE", path: "jwt.cookie.max_age", def: "7d", unit: time.Second, aliases: []string{"JWT_COOKIE_MAX_AGE_SECONDS"}, usage: "refresh token cookie lifetime, bare numbers are seconds"},
+	{key: "JWT_COOKIE_PATH", path: "jwt.cookie.path", def: "/", usage: "path of the refresh token cookie"},
+	{key: "JWT_COOKIE_SECRET", path: "jwt.cookie.secret", required: true, secret: true, usage: "secret of the session cookie store"},
+	{key: "JWT_COOKIE_SECURE", path: "jwt.cookie.secure", def: "true", usage: "only send cookies over HTTPS"},
+	{key: "JWT_COOKIE_SAME_SITE", path: "jwt.cookie.same_site", def: "strict", usage: "lax, strict, none or default"},
+
+	// Login brute-force protection
+	{key: "LOGIN_MAX_FAILED_ATTEMPTS_PER_ACCOUNT", path: "login.max_failed_attempts_per_account", def: "5", usage: "failures locking an account"},
+	{key: "LOGIN_MAX_FAILED_ATTEMPTS_PER_IP", path: "login.max_failed_attempts_per_ip", def: "50", usage: "failures locking a client IP"},
+	{key: "LOGIN_LOCKOUT", path: "login.lockout", def: "15m", unit: time.Minute, aliases: []string{"LOGIN_LOCKOUT_MINUTES"}, usage: "lockout duration, bare numbers are minutes"},
+	{key: "LOGIN_BACKOFF_AFTER_ATTEMPTS", path: "login.backoff_after_attempts", def: "3", usage: "failures allowed before backoff applies"},
+	{key: "LOGIN_BACKOFF_BASE", path: "login.backoff_base", def: "1s", unit: time.Second, aliases: []string{"LOGIN_BACKOFF_BASE_SECONDS"}, usage: "first backoff delay, bare numbers are seconds"},
+	{key: "LOGIN_BACKOFF_MAX", path: "login.backoff_max", def: "60s", unit: time.Second, aliases: []string{"LOGIN_BACKOFF_MAX_SECONDS"}, usage: "maximal backoff delay, bare numbers are seconds"},
+
+	// Sessions
+	{key: "SESSIONS_PURGE_BATCH_SIZE", path: "sessions.purge_batch_size", def: "500", usage: "expired refresh tokens and sessions deleted per statement by the hourly purge"},
+	{key: "SESSIONS_HISTORY_RETENTION", path: "sessions.history_retention", def: "90d", unit: day, usage: "how long an ended session is kept for the analytics, bare numbers are days"},
+
+	// Password policy
+	{key: "PASSWORD_MIN_LENGTH", path: "password.min_length", def: "8", usage: "minimal password length"},
+	{key: "PASSWORD_REQUIRE_UPPER", path: "password.require_upper", def: "false", usage: "require an upper case letter"},
+	{key: "PASSWORD_REQUIRE_LOWER", path: "password.require_lower", def: "false", usage: "require a lower case letter"},
+	{key: "PASSWORD_REQUIRE_DIGIT", path: "password.require_digit", def: "false", usage: "require a digit"},
+	{key: "PASSWORD_REQUIRE_SYMBOL", path: "password.require_symbol", def: "false", usage: "require a symbol"},
+	{key: "PASSWORD_HISTORY_SIZE", path: "password.history_size", def: "3", usage: "previous passwords that can not be reused, 0 disables the check"},
+	{key: "PASSWORD_MAX_AGE", path: "password.max_age", def: "0", unit: day, aliases: []string{"PASSWORD_MAX_AGE_DAYS"}, usage: "forced rotation age, bare numbers are days, 0 disables the rotation"},
+	{key: "PASSWORD_BREACHED_HASHES_FILE", path: "password.breached_hashes_file", usage: "SHA-1 of breached passwords, one per line, empty disables the check"},
+	{key: "PASSWORD_HASHER", path: "password.hasher", def: PasswordHasherBcrypt, usage: "bcrypt or argon2id"},
+
+	// OpenID Connect SSO
+	{key: "OIDC_ISSUER_URL", path: "oidc.issuer_url", usage: "identity provider issuer, empty disables SSO"},
+	{key: "OIDC_CLIENT_ID", path: "oidc.client_id", usage: "client ID registered on the identity provider"},
+	{key: "OIDC_CLIENT_SECRET", path: "oidc.client_secret", secret: true, usage: "client secret registered on the identity provider"},
+	{key: "OIDC_REDIRECT_URL", path: "oidc.redirect_url", usage: "callback route of this server"},
+	{key: "OIDC_POST_LOGIN_REDIRECT_URL", path: "oidc.post_login_redirect_url", usage: "where the browser goes after a SSO login"},
+	{key: "OIDC_SCOPES", path: "oidc.scopes", def: "openid,email,profile", usage: "comma separated scopes"},
+	{key: "OIDC_GROUPS_CLAIM", path: "oidc.groups_claim", def: "groups", usage: "ID token claim listing the user groups"},
+	{key: "OIDC_GROUP_ROLES", path: "oidc.group_roles", usage: "group1:role1,role2;group2:role3"},
+
+	// DB
+	{key: "STORAGE", path: "storage", def: StoragePostgres, usage: "postgres, sqlite for a single server without database server, or memory to run without database, the data is lost on restart"},
+	{key: "DB_HOST", path: "db.host", def: "localhost", usage: "PostgreSQL host"},
+	{key: "DB_PORT", path: "db.port", def: "5432", usage: "PostgreSQL port"},
+	{key: "DB_NAME", path: "db.name", def: "cleanic", usage: "PostgreSQL database"},
+	{key: "DB_USER", path: "db.user", def: "cleanic", usage: "PostgreSQL user"},
+	{key: "DB_PASSWORD", path: "db.password", secret: true, usage: "PostgreSQL password, required with the postgres storage"},
+	{key: "DB_LOG_LEVEL", path: "db.log_level", def: "0", usage: "0 quiet, 1 failed queries, 2 all queries"},
+	{key: "DB_SQLITE_PATH", path: "db.sqlite_path", def: "cleanic.db", usage: "SQLite database file of the sqlite storage, created and migrated on start"},
+	{key: "DB_MIGRATIONS_TABLE", path: "db.migrations_table", def: "gorp_migrations", usage: "table recording the applied migrations, checked by /readyz, empty disables the check"},
+
+	// Idempotency
+	{key: "IDEMPOTENCY_KEY_TTL", path: "idempotency.key_ttl", def: "24h", unit: time.Hour, usage: "how long a response is replayed for a retried Idempotency-Key, bare numbers are hours"},
+
+	// Domain events
+	{key: "EVENTS_WEBHOOK_URL", path: "events.webhook_url", usage: "URL receiving every domain event as a JSON POST, empty disables the sink"},
+	{key: "EVENTS_NATS_URL", path: "events.nats_url", usage: "nats://host:port of a NATS compatible broker the domain events are published to, empty disables the sink"},
+	{key: "EVENTS_NATS_SUBJECT_PREFIX", path: "events.nats_subject_prefix", def: "cleanic", usage: "prefix of the subjects, such as cleanic.patient.created"},
+	{key: "EVENTS_FILE_PATH", path: "events.file_path", usage: "file the domain events are appended to as JSON lines, empty disables the sink"},
+	{key: "EVENTS_DISPATCH_INTERVAL", path: "events.dispatch_interval", def: "5s", unit: time.Second, usage: "how often the outbox is polled once empty, bare numbers are seconds"},
+	{key: "EVENTS_BATCH_SIZE", path: "events.batch_size", def: "100", usage: "events delivered per poll of the outbox"},
+	{key: "EVENTS_RETRY_BASE", path: "events.retry_base", def: "1s", unit: time.Second, usage: "delay after a failed delivery, doubled on each failure, bare numbers are seconds"},
+	{key: "EVENTS_RETRY_MAX", path: "events.retry_max", def: "10m", unit: time.Minute, usage: "maximal delay between two deliveries of an event, bare numbers are minutes"},
+
+	// Webhook subscriptions
+	{key: "WEBHOOKS_ENABLED", path: "webhooks.enabled", def: "true", usage: "deliver the domain events to the webhook subscriptions managed through /api/v1/webhooks"},
+	{key: "WEBHOOKS_TIMEOUT", path: "webhooks.timeout", def: "10s", unit: time.Second, usage: "timeout of a POST to a subscription URL, bare numbers are seconds"},
+	{key: "WEBHOOKS_MAX_ATTEMPTS", path: "webhooks.max_attempts", def: "8", usage: "attempts of a delivery before it is dead-lettered"},
+	{key: "WEBHOOKS_RETRY_BASE", path: "webhooks.retry_base", def: "10s", unit: time.Second, usage: "delay after a failed attempt, doubled on each failure, bare numbers are seconds"},
+	{key: "WEBHOOKS_RETRY_MAX", path: "webhooks.retry_max", def: "1h", unit: time.Minute, usage: "maximal delay between two attempts of a delivery, bare numbers are minutes"},
+	{key: "WEBHOOKS_DELIVERY_INTERVAL", path: "webhooks.delivery_interval", def: "5s", unit: time.Second, usage: "how often the due deliveries are polled once none is left, bare numbers are seconds"},
+	{key: "WEBHOOKS_BATCH_SIZE", path: "webhooks.batch_size", def: "50", usage: "deliveries attempted per poll"},
+	{key: "WEBHOOKS_ALLOWED_NETWORKS", path: "webhooks.allowed_networks", usage: "IPs or CIDRs of the loopback, private or link-local receivers the webhooks may reach, empty only reaches public addresses"},
+
+	// Background jobs
+	{key: "JOBS_ENABLED", path: "jobs.enabled", def: "true", usage: "run the background jobs, such as the scheduled purges, on this instance"},
+	{key: "JOBS_CONCURRENCY", path: "jobs.concurrency", def: "4", usage: "jobs run at once by the instance"},
+	{key: "JOBS_TIMEOUT", path: "jobs.timeout", def: "5m", unit: time.Minute, usage: "timeout of a run of a job, bare numbers are minutes"},
+	{key: "JOBS_MAX_ATTEMPTS", path: "jobs.max_attempts", def: "5", usage: "runs of a job before it is dead-lettered"},
+	{key: "JOBS_RETRY_BASE", path: "jobs.retry_base", def: "30s", unit: time.Second, usage: "delay after a failed run, doubled on each failure, bare numbers are seconds"},
+	{key: "JOBS_RETRY_MAX", path: "jobs.retry_max", def: "1h", unit: time.Minute, usage: "maximal delay between two runs of a job, bare numbers are minutes"},
+	{key: "JOBS_POLL_INTERVAL", path: "jobs.poll_interval", def: "5s", unit: time.Second, usage: "how often the due jobs are polled once none is left, bare numbers are seconds"},
+
+	// Rate limiting
+	{key: "RATE_LIMIT_ENABLED", path: "rate_limit.enabled", def: "true", usage: "limit the requests per client IP and per user"},
+	{key: "RATE_LIMIT_STORE", path: "rate_limit.store", def: RateLimitStoreMemory, usage: "memory for a single replica, postgres to share the limits between replicas"},
+	{key: "RATE_LIMIT_IP", path: "rate_limit.ip", def: "60/1m", usage: "requests/period per client IP on the routes without access token"},
+	{key: "RATE_LIMIT_USER", path: "rate_limit.user", def: "300/1m", usage: "requests/period per user on the routes with an access token or an API key"},
+	{key: "RATE_LIMIT_ROUTES", path: "rate_limit.routes", def: "/api/v1/auth/login:10/1m;/api/v1/auth/signup:5/1h;/api/v1/auth/password/rotate:10/1m;/api/v2/auth/login:10/1m;/api/v2/auth/signup:5/1h;/api/v2/auth/password/rotate:10/1m", usage: "per route quotas, such as /api/v2/auth/login:10/1m;/api/v2/patients:600/1m"},
+
+	// API versions
+	{key: "API_V1_DEPRECATION", path: "api.v1_deprecation", def: "2026-10-19", usage: "date sent in the Deprecation header of the /api/v1 responses, empty disables the deprecation headers"},
+	{key: "API_V1_SUNSET", path: "api.v1_sunset", def: "2027-04-30", usage: "date sent in the Sunset header of the /api/v1 responses, after which /api/v1 may be removed"},
+
+	// Logging
+	{key: "LOG_LEVEL", path: "log.level", def: "info", usage: "debug, info, warn or error"},
+	{key: "LOG_LEVELS", path: "log.levels", usage: "per component levels overriding LOG_LEVEL, such as rest:debug;persistence:warn"},
+
+	// Tracing
+	{key: "TRACING_EXPORTER", path: "tracing.exporter", def: TracingExporterNone, usage: "none, otlp or stdout"},
+	{key: "TRACING_SERVICE_NAME", path: "tracing.service_name", def: "cleanic", usage: "service.name resource attribute of the spans"},
+	{key: "TRACING_OTLP_ENDPOINT", path: "tracing.otlp_endpoint", def: "localhost:4318", usage: "host:port of the collector OTLP/HTTP receiver"},
+	{key: "TRACING_OTLP_INSECURE", path: "tracing.otlp_insecure", def: "true", usage: "send the spans to the collector without TLS"},
+	{key: "TRACING_SAMPLE_RATIO", path: "tracing.sample_ratio", def: "1", usage: "share of the new traces recorded, between 0 and 1"},
+
+	// Secrets rotation
+	{key: "SECRETS_ROTATION_GRACE", path: "secrets.rotation_grace", def: "24h", unit: time.Hour, usage: "how long retired JWT and cookie secrets stay valid after a reload, bare numbers are hours"},
+
+	// Server
+	{key: "PORT", path: "server.port", def: "8080", usage: "HTTP listening port"},
+	{key: "TRUSTED_PROXIES", path: "server.trusted_proxies", usage: "IPs or CIDRs of the reverse proxies whose X-Forwarded-For is trusted, empty uses the peer address as client IP"},
+	{key: "METRICS_PORT", path: "server.metrics_port", def: "9090", usage: "listening port of /metrics, apart from the API, empty disables it"},
+	{key: "SHUTDOWN_DELAY", path: "server.shutdown_delay", def: "5s", unit: time.Second, usage: "how long /readyz fails before the listener closes on SIGTERM, bare numbers are seconds, 0 closes it at once"},
+}
@@ -0,0 +1,23 @@
+package config
+
+const (
+	TracingExporterNone   = "none"
+	TracingExporterOTLP   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// TracingConfig configures the OpenTelemetry traces, they are disabled with the none exporter
+type TracingConfig struct {
+	// Exporter is none, otlp to send the spans to a collector, or stdout to print them
+	Exporter    string
+	ServiceName string
+	// OTLPEndpoint is the host:port of the collector OTLP/HTTP receiver
+	OTLPEndpoint string
+	OTLPInsecure bool
+	// SampleRatio is the share of the new traces recorded, an incoming sampled parent is always followed
+	SampleRatio float64
+}
+
+func (t TracingConfig) Enabled() bool {
+	return t.Exporter != TracingExporterNone
+}
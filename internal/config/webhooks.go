@@ -0,0 +1,24 @@
+package config
+
+import (
+	"net"
+	"time"
+)
+
+// WebhooksConfig drives the delivery of the domain events to the webhook subscriptions of the clinics
+type WebhooksConfig struct {
+	// Enabled records the events for the subscriptions, even when no other events sink is configured
+	Enabled bool
+	// Timeout bounds a POST to a subscription URL
+	Timeout time.Duration
+	// MaxAttempts is how many times a delivery is tried before it is dead-lettered
+	MaxAttempts int
+	// RetryBase is the delay after the first failed attempt, it doubles up to RetryMax
+	RetryBase time.Duration
+	RetryMax  time.Duration
+	// DeliveryInterval is the pause of the deliverer once no delivery is due
+	DeliveryInterval time.Duration
+	BatchSize        int
+	// AllowedNetworks are the only loopback, private or link-local addresses the receivers may resolve to
+	AllowedNetworks []*net.IPNet
+}
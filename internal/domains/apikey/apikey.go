@@ -0,0 +1,84 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+const (
+	// keyMarker starts every key so that leaked keys are easy to spot by secret scanners
+	keyMarker    = "cln"
+	prefixBytes  = 4
+	secretBytes  = 32
+	keySeparator = "_"
+)
+
+// APIKey lets a machine client act as its owner in the key clinic, restricted to the key scopes.
+// Only the SHA-256 of the key is stored, the plain key is shown once at creation
+type APIKey struct {
+	ID         ID               `json:"id"`
+	UserID     user.ID          `json:"user_id"`
+	ClinicID   clinic.ID        `json:"clinic_id"`
+	Name       string           `json:"name"`
+	Prefix     string           `json:"prefix"`
+	Hash       string           `json:"-"`
+	Scopes     user.Permissions `json:"scopes"`
+	ExpiresAt  *time.Time       `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time       `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time       `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+type ID int64
+
+// PlainKey looks like cln_<prefix>_<secret>, the prefix is used to find the key
+type PlainKey string
+
+func (k APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// NewPlainKey generates a random key and returns it with its prefix and hash
+func NewPlainKey() (PlainKey, string, string, error) {
+	prefix := make([]byte, prefixBytes)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", "", fmt.Errorf("unable to generate api key prefix: %w", err)
+	}
+
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", fmt.Errorf("unable to generate api key secret: %w", err)
+	}
+
+	prefixString := hex.EncodeToString(prefix)
+	plainKey := PlainKey(strings.Join([]string{keyMarker, prefixString, base64.RawURLEncoding.EncodeToString(secret)}, keySeparator))
+	return plainKey, prefixString, plainKey.Hash(), nil
+}
+
+// Prefix extracts the lookup prefix of a key
+func (p PlainKey) Prefix() (string, error) {
+	parts := strings.SplitN(string(p), keySeparator, 3)
+	if len(parts) != 3 || parts[0] != keyMarker || len(parts[1]) != 2*prefixBytes || parts[2] == "" {
+		return "", fmt.Errorf("malformed api key")
+	}
+
+	return parts[1], nil
+}
+
+// Hash is enough to protect a key at rest as keys carry 256 random bits, unlike passwords
+func (p PlainKey) Hash() string {
+	sum := sha256.Sum256([]byte(p))
+	return hex.EncodeToString(sum[:])
+}
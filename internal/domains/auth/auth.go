@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	AttemptScopeAccount AttemptScope = "account"
+	AttemptScopeIP      AttemptScope = "ip"
+)
+
+// AttemptScope tells on what the failed login attempts are counted
+type AttemptScope string
+
+// LoginAttempts keeps track of consecutive failed logins for a single key
+// (an email for the account scope, a client IP for the ip scope)
+type LoginAttempts struct {
+	Scope         AttemptScope
+	Key           string
+	Failures      int
+	LastFailureAt time.Time
+	LockedUntil   time.Time
+}
+
+// NewAccountAttemptKey normalizes an email so that case variations share the same counter
+func NewAccountAttemptKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (l LoginAttempts) IsLocked(now time.Time) bool {
+	return now.Before(l.LockedUntil)
+}
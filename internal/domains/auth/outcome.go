@@ -0,0 +1,23 @@
+package auth
+
+const (
+	LoginMethodPassword LoginMethod = "password"
+	LoginMethodSSO      LoginMethod = "sso"
+	// LoginMethodClinicSwitch starts a session in another clinic of the user, it is not counted as a login
+	LoginMethodClinicSwitch LoginMethod = "clinic_switch"
+)
+
+// LoginMethod tells how a user authenticated
+type LoginMethod string
+
+const (
+	OutcomeSuccess            Outcome = "success"
+	OutcomeInvalidCredentials Outcome = "invalid_credentials"
+	OutcomeThrottled          Outcome = "throttled"
+	OutcomePasswordExpired    Outcome = "password_expired"
+	OutcomeRejected           Outcome = "rejected"
+	OutcomeError              Outcome = "error"
+)
+
+// Outcome is the result of an authentication attempt, as reported to the metrics
+type Outcome string
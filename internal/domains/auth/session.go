@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type SessionID int64
+
+// Session is the history of a login into a clinic. It ends on logout, on the next login of the user
+// or when its refresh token expired, a refresh rotates the token within the same session
+type Session struct {
+	ID        SessionID
+	UserID    user.ID
+	ClinicID  clinic.ID
+	Method    LoginMethod
+	StartedAt time.Time
+	// EndedAt is nil while the session is open
+	EndedAt *time.Time
+}
+
+// Lifetime is zero while the session is open
+func (s Session) Lifetime() time.Duration {
+	if s.EndedAt == nil {
+		return 0
+	}
+
+	return s.EndedAt.Sub(s.StartedAt)
+}
+
+// SessionStats summarizes the sessions of a clinic since a given time. A session is active
+// while it is open and the refresh token of its user is still valid in its clinic
+type SessionStats struct {
+	Since          time.Time `json:"since"`
+	ActiveSessions int       `json:"active_sessions"`
+	// AverageLifetimeSeconds is computed over the sessions ended since Since, 0 when none ended
+	AverageLifetimeSeconds int64          `json:"average_lifetime_seconds"`
+	LoginsPerDay           []DailyLogins  `json:"logins_per_day"`
+	Users                  []UserSessions `json:"users"`
+}
+
+// DailyLogins counts the logins of a UTC day, the clinic switches are not logins
+type DailyLogins struct {
+	Day    string `json:"day"`
+	Logins int    `json:"logins"`
+	// Users counts the distinct users who logged in that day
+	Users int `json:"users"`
+}
+
+// UserSessions are listed for the users with a session started, ended or still open since Since
+type UserSessions struct {
+	UserID         user.ID    `json:"user_id"`
+	ActiveSessions int        `json:"active_sessions"`
+	Logins         int        `json:"logins"`
+	LastLoginAt    *time.Time `json:"last_login_at,omitempty"`
+}
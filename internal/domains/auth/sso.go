@@ -0,0 +1,18 @@
+package auth
+
+// SSOChallenge holds the values generated when starting an OpenID Connect login
+// they are kept client side until the identity provider redirects back
+type SSOChallenge struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	AuthURL      string
+}
+
+// ExternalIdentity is the user authenticated by the identity provider
+type ExternalIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
@@ -0,0 +1,32 @@
+package clinic
+
+import (
+	"unicode/utf8"
+
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// DefaultID is the clinic seeded with the schema, self sign-ups and provisioned SSO users join it
+const DefaultID ID = 1
+
+const maxNameLength = 100
+
+// Clinic is the tenant, its patients and staff are never visible from another clinic
+type Clinic struct {
+	ID   ID     `json:"id"`
+	Name string `json:"name"`
+}
+
+type ID int64
+
+func (c Clinic) IsValid() bool {
+	length := utf8.RuneCountInString(c.Name)
+	return length > 0 && length <= maxNameLength
+}
+
+// Membership holds the roles of a user in one clinic, a user can belong to several clinics
+type Membership struct {
+	Clinic Clinic     `json:"clinic"`
+	UserID user.ID    `json:"user_id"`
+	Roles  user.Roles `json:"roles"`
+}
@@ -0,0 +1,25 @@
+package clinic
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoClinic is returned by the scoped queries run without a clinic, they never fall back to every clinic
+var ErrNoClinic = errors.New("no clinic in context")
+
+type contextKey struct{}
+
+// WithID scopes the persistence queries made with the returned context to the clinic
+func WithID(ctx context.Context, id ID) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func IDFromContext(ctx context.Context) (ID, error) {
+	id, ok := ctx.Value(contextKey{}).(ID)
+	if !ok || id == 0 {
+		return 0, ErrNoClinic
+	}
+
+	return id, nil
+}
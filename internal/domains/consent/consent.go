@@ -0,0 +1,81 @@
+package consent
+
+import (
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// Consent is what a patient agreed to, or withdrew, for a processing purpose.
+// The consents are never updated, a revocation is recorded as a new consent
+type Consent struct {
+	ID        ID         `json:"id"`
+	PatientID patient.ID `json:"patient_id"`
+	Purpose   Purpose    `json:"purpose"`
+	Status    Status     `json:"status"`
+	// RecordedAt is when the consent was collected
+	RecordedAt time.Time `json:"recorded_at"`
+	// CollectedBy is 0 once the user who collected the consent is deleted
+	CollectedBy user.ID `json:"collected_by,omitempty"`
+	// DocumentRef points to the signed form or the recording, when there is one
+	DocumentRef string `json:"document_ref,omitempty"`
+}
+
+type ID int64
+
+type Purpose string
+
+const (
+	PurposeCare        Purpose = "care"
+	PurposeDataSharing Purpose = "data_sharing"
+	PurposeResearch    Purpose = "research"
+	PurposeMarketing   Purpose = "marketing"
+)
+
+// availablePurposes tells whether a purpose requires an explicit grant, the other ones are allowed until revoked
+var availablePurposes = map[Purpose]bool{
+	PurposeCare:        false,
+	PurposeDataSharing: false,
+	PurposeResearch:    true,
+	PurposeMarketing:   true,
+}
+
+func (p Purpose) IsValid() bool {
+	_, found := availablePurposes[p]
+	return found
+}
+
+// Allows tells whether the latest consent of a patient for the purpose, nil when none was recorded, allows the processing
+func (p Purpose) Allows(latest *Consent) bool {
+	if latest == nil {
+		return !availablePurposes[p]
+	}
+
+	return latest.Status == StatusGranted
+}
+
+type Status string
+
+const (
+	StatusGranted Status = "granted"
+	StatusRevoked Status = "revoked"
+)
+
+func (s Status) IsValid() bool {
+	return s == StatusGranted || s == StatusRevoked
+}
+
+// Current keeps the latest consent of each purpose, the history is newest first
+func Current(history []Consent) []Consent {
+	seen := map[Purpose]bool{}
+	current := []Consent{}
+	for _, consent := range history {
+		if !seen[consent.Purpose] {
+			seen[consent.Purpose] = true
+			current = append(current, consent)
+		}
+	}
+
+	return current
+}
@@ -0,0 +1,35 @@
+package consent
+
+import "testing"
+
+func TestPurposeAllows(t *testing.T) {
+	granted := &Consent{Status: StatusGranted}
+	revoked := &Consent{Status: StatusRevoked}
+	for _, tc := range []struct {
+		purpose Purpose
+		latest  *Consent
+		allowed bool
+	}{
+		{PurposeDataSharing, nil, true},
+		{PurposeDataSharing, revoked, false},
+		{PurposeResearch, nil, false},
+		{PurposeResearch, granted, true},
+		{PurposeMarketing, revoked, false},
+	} {
+		if allowed := tc.purpose.Allows(tc.latest); allowed != tc.allowed {
+			t.Errorf("%s with %+v: expected %v, got %v", tc.purpose, tc.latest, tc.allowed, allowed)
+		}
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	current := Current([]Consent{
+		{ID: 4, Purpose: PurposeResearch, Status: StatusRevoked},
+		{ID: 3, Purpose: PurposeCare, Status: StatusGranted},
+		{ID: 2, Purpose: PurposeResearch, Status: StatusGranted},
+	})
+
+	if len(current) != 2 || current[0].ID != 4 || current[1].ID != 3 {
+		t.Fatalf("only the newest consent of each purpose should be kept, got %+v", current)
+	}
+}
@@ -0,0 +1,82 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type ID int64
+
+// Type is also the subject the event is published on, after the configured prefix
+type Type string
+
+const (
+	TypePatientCreated   Type = "patient.created"
+	TypePatientUpdated   Type = "patient.updated"
+	TypePatientDeleted   Type = "patient.deleted"
+	TypeUserRolesChanged Type = "user.roles_changed"
+	// TypePatientConsentRecorded carries the consent, so that the partners stop processing a revoked purpose
+	TypePatientConsentRecorded Type = "patient.consent_recorded"
+	// TypePatientErased tells the partners to erase their copy of the patient
+	TypePatientErased Type = "patient.erased"
+)
+
+// Event is a change other systems react to. It is delivered at least once,
+// the consumers recognize a redelivery by its ID
+type Event struct {
+	ID       ID
+	Type     Type
+	ClinicID clinic.ID
+	// Payload is the JSON of the entity after the change
+	Payload    json.RawMessage
+	OccurredAt time.Time
+	// Attempts counts the failed deliveries, the next one is not tried before NextAttemptAt
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+}
+
+// Envelope is what the sinks publish, the delivery state stays in the outbox
+type Envelope struct {
+	ID         ID              `json:"id"`
+	Type       Type            `json:"type"`
+	ClinicID   clinic.ID       `json:"clinic_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+func (e Event) Envelope() Envelope {
+	return Envelope{ID: e.ID, Type: e.Type, ClinicID: e.ClinicID, OccurredAt: e.OccurredAt, Payload: e.Payload}
+}
+
+var availableTypes = map[Type]bool{
+	TypePatientCreated:         true,
+	TypePatientUpdated:         true,
+	TypePatientDeleted:         true,
+	TypeUserRolesChanged:       true,
+	TypePatientConsentRecorded: true,
+	TypePatientErased:          true,
+}
+
+func (t Type) IsValid() bool {
+	return availableTypes[t]
+}
+
+// PatientDeleted is the payload of TypePatientDeleted, the patient is gone
+type PatientDeleted struct {
+	ID int64 `json:"id"`
+}
+
+// PatientErased is the payload of TypePatientErased, the patient is left with a pseudonym
+type PatientErased struct {
+	ID int64 `json:"id"`
+}
+
+// UserRolesChanged is the payload of TypeUserRolesChanged, the roles are the ones in the event clinic
+type UserRolesChanged struct {
+	UserID user.ID    `json:"user_id"`
+	Roles  user.Roles `json:"roles"`
+}
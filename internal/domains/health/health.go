@@ -0,0 +1,40 @@
+package health
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+type Status string
+
+// Check is the result of a single readiness probe
+type Check struct {
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report aggregates the checks, it is up only when every check is up
+type Report struct {
+	Status Status           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+func NewReport(checks map[string]Check) Report {
+	report := Report{Status: StatusUp, Checks: checks}
+	for _, check := range checks {
+		if check.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	return report
+}
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+	Modified  bool   `json:"modified"`
+	GoVersion string `json:"go_version"`
+}
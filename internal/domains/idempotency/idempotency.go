@@ -0,0 +1,38 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Record is the first request made with an idempotency key and, once completed, its response.
+// Keys are scoped by Subject, the user or the client IP, so that a key can not replay the response of someone else
+type Record struct {
+	Subject     string
+	Key         string
+	Fingerprint string
+	// Completed is false while the first request is being processed
+	Completed   bool
+	StatusCode  int
+	ContentType string
+	// Location is the header of the created resource, replayed along with the body
+	Location  string
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// NewFingerprint identifies a request, a retry must have the same one. The path is the requested one
+// rather than the route template, a key reused for another resource id is another request
+func NewFingerprint(method string, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (r Record) IsExpired(now time.Time) bool {
+	return !now.Before(r.ExpiresAt)
+}
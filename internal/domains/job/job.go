@@ -0,0 +1,55 @@
+package job
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+)
+
+// Job is a unit of background work of a kind, run by the handler registered for the kind.
+// A failed run is retried with an exponential backoff until the job runs out of attempts
+type Job struct {
+	ID   ID   `json:"id"`
+	Kind Kind `json:"kind"`
+	// ClinicID is the clinic the job runs for, zero for the system jobs such as the scheduled ones
+	ClinicID clinic.ID `json:"clinic_id,omitempty"`
+	// Payload is the input of the handler, it may carry personal data so it is not listed
+	Payload json.RawMessage `json:"-"`
+	// UniqueKey is set on the jobs enqueued at most once, e.g. once per tick of a schedule
+	UniqueKey   string    `json:"unique_key,omitempty"`
+	State       State     `json:"state"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"max_attempts"`
+	RunAt       time.Time `json:"run_at"`
+	// LastError is the error of the latest failed run
+	LastError  string     `json:"last_error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type ID int64
+
+// Kind names what a job does, e.g. "idempotency.purge"
+type Kind string
+
+// State is queued until a job succeeds or runs out of attempts
+type State string
+
+const (
+	StateQueued State = "queued"
+	// StateRunning is a job claimed by a worker, it is queued again if the worker stops before its lease ends
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	// StateDead is the dead-letter state, the job is not retried anymore
+	StateDead State = "dead"
+)
+
+func (s State) IsValid() bool {
+	switch s {
+	case StateQueued, StateRunning, StateSucceeded, StateDead:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,191 @@
+package job
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule tells when a scheduled job is enqueued. It is a cron expression in UTC,
+// "minute hour day-of-month month day-of-week", each field being *, a value, a range a-b or
+// a comma separated list of those, with an optional /step. Sunday is 0 or 7. As with cron,
+// a day matches either of the day fields when both are restricted.
+// The descriptors @hourly, @daily, @weekly and @every <duration> are accepted too
+type Schedule struct {
+	spec string
+	// every is set for @every, its ticks are aligned on the Unix epoch so that every instance computes the same ones
+	every time.Duration
+
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// anyDayOfMonth and anyDayOfWeek are the * day fields, they do not restrict the other one
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = [5]field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+var descriptors = map[string]string{
+	"@hourly": "0 * * * *",
+	"@daily":  "0 0 * * *",
+	"@weekly": "0 0 * * 0",
+}
+
+// ParseSchedule parses a cron expression or a descriptor
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if rest, found := strings.CutPrefix(spec, "@every "); found {
+		every, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil || every < time.Second {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: @every needs a duration of at least 1s", spec)
+		}
+
+		return Schedule{spec: spec, every: every}, nil
+	}
+
+	expression := spec
+	if descriptor, found := descriptors[spec]; found {
+		expression = descriptor
+	}
+
+	parts := strings.Fields(expression)
+	if len(parts) != len(fields) {
+		return Schedule{}, fmt.Errorf("invalid schedule %q: expected %d fields, got %d", spec, len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, part := range parts {
+		parsed, err := parseField(part, fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+		bits[i] = parsed
+	}
+
+	// 7 is another Sunday
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = bits[4]&^(1<<7) | 1
+	}
+
+	return Schedule{
+		spec:          spec,
+		minute:        bits[0],
+		hour:          bits[1],
+		dayOfMonth:    bits[2],
+		month:         bits[3],
+		dayOfWeek:     bits[4],
+		anyDayOfMonth: parts[2] == "*",
+		anyDayOfWeek:  parts[4] == "*",
+	}, nil
+}
+
+// parseField returns the values matched by the field, as a bit set
+func parseField(part string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(part, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q of the %s", stepPart, f.name)
+			}
+		}
+
+		low, high := f.min, f.max
+		if rangePart != "*" {
+			lowPart, highPart, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(lowPart); err != nil {
+				return 0, fmt.Errorf("invalid %s %q", f.name, rangePart)
+			}
+
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(highPart); err != nil {
+					return 0, fmt.Errorf("invalid %s %q", f.name, rangePart)
+				}
+			} else if hasStep {
+				// "5/15" runs from 5 to the end of the range
+				high = f.max
+			}
+		}
+
+		if low < f.min || high > f.max || low > high {
+			return 0, fmt.Errorf("%s %q out of range %d-%d", f.name, rangePart, f.min, f.max)
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	return bits, nil
+}
+
+// Next returns the first tick strictly after after, in UTC.
+// It returns the zero time when the expression never matches, e.g. on February 30
+func (s Schedule) Next(after time.Time) time.Time {
+	after = after.UTC()
+	if s.every > 0 {
+		return after.Truncate(s.every).Add(s.every)
+	}
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	// the expression matches within 4 years unless it never does
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<t.Month()) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if s.hour&(1<<t.Hour()) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if s.minute&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s Schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := s.dayOfWeek&(1<<t.Weekday()) != 0
+	switch {
+	case s.anyDayOfMonth && s.anyDayOfWeek:
+		return true
+	case s.anyDayOfMonth:
+		return dayOfWeek
+	case s.anyDayOfWeek:
+		return dayOfMonth
+	default:
+		return dayOfMonth || dayOfWeek
+	}
+}
+
+func (s Schedule) String() string {
+	return s.spec
+}
@@ -0,0 +1,44 @@
+package job
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2025, 1, 1, 12, 7, 30, 0, time.UTC)
+	for _, tc := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2025, 1, 1, 12, 15, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2025, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"30 3 * * 1-5", time.Date(2025, 1, 2, 3, 30, 0, 0, time.UTC)},
+		// Sunday
+		{"0 0 * * 7", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		// either day field matches when both are restricted
+		{"0 0 15 * 5", time.Date(2025, 1, 3, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@every 10m", time.Date(2025, 1, 1, 12, 10, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		schedule, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.spec, err)
+		}
+
+		if got := schedule.Next(now); !got.Equal(tc.want) {
+			t.Errorf("next tick of %q: got %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseScheduleRejectsInvalidExpressions(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "@every 1ms", "@yearly"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q should be rejected", spec)
+		}
+	}
+}
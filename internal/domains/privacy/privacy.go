@@ -0,0 +1,111 @@
+package privacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/consent"
+	"github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/domains/webhook"
+)
+
+// Export is every record referencing a patient, what the patient gets on a subject access request
+type Export struct {
+	PatientID  patient.ID
+	ExportedAt time.Time
+	Patient    patient.Patient
+	Consents   []consent.Consent
+	Erasures   []Erasure
+	// Events are the ones of the outbox, the delivered events are no longer stored
+	Events            []event.Envelope
+	WebhookDeliveries []webhook.Delivery
+}
+
+// Files names the records after the tables they come from, one JSON file each
+func (e Export) Files() map[string]any {
+	return map[string]any{
+		"patient.json":          e.Patient,
+		"patient_consent.json":  e.Consents,
+		"patient_erasure.json":  e.Erasures,
+		"outbox_event.json":     e.Events,
+		"webhook_delivery.json": e.WebhookDeliveries,
+	}
+}
+
+// Erasure is a request to erase a patient. It is carried out once approved by a second user,
+// the request is kept as the proof of the erasure
+type Erasure struct {
+	ID        ErasureID     `json:"id"`
+	PatientID patient.ID    `json:"patient_id"`
+	Status    ErasureStatus `json:"status"`
+	Reason    string        `json:"reason,omitempty"`
+	// RequestedBy and ReviewedBy are 0 once their user is deleted
+	RequestedBy user.ID    `json:"requested_by,omitempty"`
+	RequestedAt time.Time  `json:"requested_at"`
+	ReviewedBy  user.ID    `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+}
+
+type ErasureID int64
+
+type ErasureStatus string
+
+const (
+	ErasureStatusPending   ErasureStatus = "pending"
+	ErasureStatusCompleted ErasureStatus = "completed"
+	ErasureStatusRejected  ErasureStatus = "rejected"
+)
+
+func (s ErasureStatus) IsValid() bool {
+	return s == ErasureStatusPending || s == ErasureStatusCompleted || s == ErasureStatusRejected
+}
+
+// PatientReference is the payload field of an event type holding the patient id
+type PatientReference struct {
+	Type  event.Type
+	Field string
+}
+
+// PatientReferences list the events about a patient, a new event type about the patients belongs here
+var PatientReferences = []PatientReference{
+	{Type: event.TypePatientCreated, Field: "id"},
+	{Type: event.TypePatientUpdated, Field: "id"},
+	{Type: event.TypePatientDeleted, Field: "id"},
+	{Type: event.TypePatientErased, Field: "id"},
+	{Type: event.TypePatientConsentRecorded, Field: "patient_id"},
+}
+
+// IdentityEventTypes are the events whose payload is the patient, the erasure replaces it by the pseudonym
+var IdentityEventTypes = []event.Type{event.TypePatientCreated, event.TypePatientUpdated}
+
+// ReferencesPatient tells whether the payload of an event references the patient
+func ReferencesPatient(eventType event.Type, payload json.RawMessage, patientID patient.ID) bool {
+	for _, reference := range PatientReferences {
+		if reference.Type != eventType {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(payload, &fields); err != nil {
+			return false
+		}
+
+		return string(fields[reference.Field]) == strconv.FormatInt(int64(patientID), 10)
+	}
+
+	return false
+}
+
+// Pseudonym replaces the identity of an erased patient, the email stays unique within the clinic
+func Pseudonym(patientID patient.ID) patient.Patient {
+	return patient.Patient{
+		ID:        patientID,
+		Firstname: "erased",
+		Lastname:  "erased",
+		Email:     patient.Email(fmt.Sprintf("erased-%d@erased.invalid", patientID)),
+	}
+}
@@ -0,0 +1,26 @@
+package privacy
+
+import (
+	"testing"
+
+	"github.com/sopial42/cleanic/internal/domains/event"
+)
+
+func TestReferencesPatient(t *testing.T) {
+	for _, tc := range []struct {
+		eventType  event.Type
+		payload    string
+		references bool
+	}{
+		{event.TypePatientCreated, `{"id":10001,"email":"a@gmail.com"}`, true},
+		{event.TypePatientCreated, `{"id":10002}`, false},
+		{event.TypePatientConsentRecorded, `{"id":10001,"patient_id":10002}`, false},
+		{event.TypePatientConsentRecorded, `{"id":1,"patient_id":10001}`, true},
+		{event.TypeUserRolesChanged, `{"id":10001}`, false},
+		{event.TypePatientUpdated, `not json`, false},
+	} {
+		if references := ReferencesPatient(tc.eventType, []byte(tc.payload), 10001); references != tc.references {
+			t.Errorf("%s %s: expected %v, got %v", tc.eventType, tc.payload, tc.references, references)
+		}
+	}
+}
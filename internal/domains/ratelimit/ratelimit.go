@@ -0,0 +1,66 @@
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// Quota allows Requests per Period, as a token bucket of Requests tokens refilled over Period
+type Quota struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Bucket is the state of a token bucket at UpdatedAt
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Decision tells whether a request may go through and feeds the RateLimit-* headers
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token when the request is refused
+	RetryAfter time.Duration
+}
+
+func NewBucket(quota Quota, now time.Time) Bucket {
+	return Bucket{Tokens: float64(quota.Requests), UpdatedAt: now}
+}
+
+// Take refills the bucket for the time elapsed since its last update, then takes a token if one is left
+func (b Bucket) Take(quota Quota, now time.Time) (Bucket, Decision) {
+	capacity := float64(quota.Requests)
+	perSecond := capacity / quota.Period.Seconds()
+
+	if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(capacity, b.Tokens+elapsed*perSecond)
+	}
+	b.UpdatedAt = now
+
+	decision := Decision{Limit: quota.Requests}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.Tokens) / perSecond)
+	}
+
+	decision.Remaining = int(math.Floor(b.Tokens))
+	decision.Reset = secondsToDuration((capacity - b.Tokens) / perSecond)
+
+	return b, decision
+}
+
+// Idle tells whether the bucket is full again, it can then be forgotten
+func (b Bucket) Idle(quota Quota, now time.Time) bool {
+	return now.Sub(b.UpdatedAt) >= quota.Period
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Ceil(seconds * float64(time.Second)))
+}
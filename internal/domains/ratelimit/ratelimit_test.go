@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	quota := Quota{Requests: 2, Period: time.Minute}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	bucket := NewBucket(quota, now)
+
+	bucket, decision := bucket.Take(quota, now)
+	if !decision.Allowed || decision.Remaining != 1 || decision.Reset != 30*time.Second {
+		t.Fatalf("first take: %+v", decision)
+	}
+
+	bucket, _ = bucket.Take(quota, now)
+	bucket, decision = bucket.Take(quota, now)
+	if decision.Allowed || decision.Remaining != 0 || decision.RetryAfter != 30*time.Second {
+		t.Fatalf("take on an empty bucket: %+v", decision)
+	}
+
+	// a token is refilled every 30s
+	_, decision = bucket.Take(quota, now.Add(30*time.Second))
+	if !decision.Allowed || decision.Remaining != 0 {
+		t.Fatalf("take after refill: %+v", decision)
+	}
+
+	if !bucket.Idle(quota, now.Add(time.Minute)) {
+		t.Fatalf("bucket unused for a period should be idle")
+	}
+}
@@ -0,0 +1,56 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// PasswordPolicy holds the rules a new password must follow
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// Validate returns every rule the password breaks at once
+func (p PasswordPolicy) Validate(password Password) error {
+	var err error
+	if len([]rune(password)) < p.MinLength {
+		err = errors.Join(err, fmt.Errorf("must be at least %d characters long", p.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		err = errors.Join(err, errors.New("must contain an uppercase letter"))
+	}
+
+	if p.RequireLower && !hasLower {
+		err = errors.Join(err, errors.New("must contain a lowercase letter"))
+	}
+
+	if p.RequireDigit && !hasDigit {
+		err = errors.Join(err, errors.New("must contain a digit"))
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		err = errors.Join(err, errors.New("must contain a symbol"))
+	}
+
+	return err
+}
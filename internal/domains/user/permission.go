@@ -0,0 +1,142 @@
+package user
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Permissions are bound to code paths so they are fixed, roles are sets of
+// permissions defined at runtime by admins
+const (
+	PermissionPatientRead  Permission = "patient:read"
+	PermissionPatientWrite Permission = "patient:write"
+	PermissionUserRead     Permission = "user:read"
+	// PermissionUserManage allows to act on other users: roles, updates, deletion, unlock
+	PermissionUserManage Permission = "user:manage"
+	PermissionRoleManage Permission = "role:manage"
+	// PermissionProfileWrite allows a user to update or delete its own account
+	PermissionProfileWrite Permission = "profile:write"
+	// PermissionClinicManage allows to create clinics, their creator becomes their admin
+	PermissionClinicManage Permission = "clinic:manage"
+	// PermissionWebhookManage allows to manage the webhook subscriptions of the clinic and to see their deliveries
+	PermissionWebhookManage Permission = "webhook:manage"
+	// PermissionJobRead allows to see the background jobs of the clinic and the system ones
+	PermissionJobRead Permission = "job:read"
+	// PermissionPrivacyManage allows to export the data of a patient, and to request or approve its erasure
+	PermissionPrivacyManage Permission = "privacy:manage"
+)
+
+type Permission string
+
+func (p Permission) String() string {
+	return string(p)
+}
+
+var availablePermissions = map[Permission]bool{
+	PermissionPatientRead:   true,
+	PermissionPatientWrite:  true,
+	PermissionUserRead:      true,
+	PermissionUserManage:    true,
+	PermissionRoleManage:    true,
+	PermissionProfileWrite:  true,
+	PermissionClinicManage:  true,
+	PermissionWebhookManage: true,
+	PermissionJobRead:       true,
+	PermissionPrivacyManage: true,
+}
+
+func (p Permission) IsValid() bool {
+	_, exists := availablePermissions[p]
+	return exists
+}
+
+// AvailablePermissions returns every known permission, sorted
+func AvailablePermissions() Permissions {
+	permissions := make(Permissions, 0, len(availablePermissions))
+	for permission := range availablePermissions {
+		permissions = append(permissions, permission)
+	}
+
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i] < permissions[j] })
+	return permissions
+}
+
+type Permissions []Permission
+
+func (p Permissions) Has(permission Permission) bool {
+	for _, existing := range p {
+		if existing == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Permissions) AreValid() bool {
+	for _, permission := range p {
+		if !permission.IsValid() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// permissions to a single comma separated string
+func (p Permissions) String() string {
+	parts := make([]string, len(p))
+	for i, permission := range p {
+		parts[i] = permission.String()
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// Merge returns the union of both sets without duplicates
+func (p Permissions) Merge(others Permissions) Permissions {
+	merged := append(Permissions{}, p...)
+	for _, permission := range others {
+		if !merged.Has(permission) {
+			merged = append(merged, permission)
+		}
+	}
+
+	return merged
+}
+
+// Intersect keeps the permissions present in both sets
+func (p Permissions) Intersect(others Permissions) Permissions {
+	var intersection Permissions
+	for _, permission := range p {
+		if others.Has(permission) && !intersection.Has(permission) {
+			intersection = append(intersection, permission)
+		}
+	}
+
+	return intersection
+}
+
+func ValidateRequiredPermissions(requiredPermissions Permissions, currentPermissions Permissions) error {
+	var missing Permissions
+	for _, reqPermission := range requiredPermissions {
+		if !currentPermissions.Has(reqPermission) {
+			missing = append(missing, reqPermission)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required permissions: %s", missing.String())
+	}
+
+	return nil
+}
+
+// RoleDefinition is a named set of permissions
+type RoleDefinition struct {
+	Name        Role        `json:"name"`
+	Description string      `json:"description"`
+	Permissions Permissions `json:"permissions"`
+	// Builtin roles are seeded with the schema and cannot be deleted
+	Builtin bool `json:"builtin"`
+}
@@ -2,24 +2,29 @@ package user
 
 import (
 	"errors"
-	"fmt"
 	"regexp"
 	"strings"
+	"time"
 )
 
 const (
-	RoleAdmin            Role = "admin"
-	RoleDoctor           Role = "doctor"
-	MinimalMandatoryRole      = RoleDoctor
-	// isAdminStandaloneRole at will always validate required roles if admin role is detected
-	isAdminStandaloneRole = true
+	// RoleAdmin and RoleDoctor are seeded with the schema, other roles are managed at runtime
+	RoleAdmin  Role = "admin"
+	RoleDoctor Role = "doctor"
+	// DefaultRole is assigned to every new user
+	DefaultRole = RoleDoctor
 )
 
+var roleNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,31}$`)
+
 type User struct {
-	ID       ID       `json:"id"`
-	Email    Email    `json:"email"`
-	Password Password `json:"-"`
-	Roles    Roles    `json:"roles"`
+	ID                ID        `json:"id"`
+	Email             Email     `json:"email"`
+	Password          Password  `json:"-"`
+	PasswordChangedAt time.Time `json:"-"`
+	Roles             Roles     `json:"roles"`
+	// ServiceAccount users are machine clients, they only authenticate with API keys
+	ServiceAccount bool `json:"service_account,omitempty"`
 }
 
 type ID int64
@@ -32,32 +37,28 @@ func (e Email) IsValid() bool {
 
 type Password string
 
-func (p Password) IsValid() bool {
-	return len(p) >= 8
-}
-
 type Roles []Role
 
+// AreValid only checks the roles format, their existence is checked against the stored roles
 func (r Roles) AreValid() bool {
-	foundMandatoryRole := false
+	if len(r) == 0 {
+		return false
+	}
+
 	for _, role := range r {
 		if !role.IsValid() {
 			return false
 		}
-
-		if role == MinimalMandatoryRole {
-			foundMandatoryRole = true
-		}
 	}
 
-	return foundMandatoryRole
+	return true
 }
 
 // parse roles from a string
 func NewRolesFromRolesString(rolesString string) (r Roles, err error) {
 	roles := regexp.MustCompile(",").Split(rolesString, -1)
 	for _, role := range roles {
-		if availableRoles[Role(role)] {
+		if Role(role).IsValid() {
 			r = append(r, Role(role))
 		} else {
 			err = errors.Join(err, errors.New("invalid role: "+role))
@@ -67,28 +68,6 @@ func NewRolesFromRolesString(rolesString string) (r Roles, err error) {
 	return r, err
 }
 
-func ValidateRequiredRoles(requiredRoles Roles, currentRoles Roles) error {
-	var missing Roles
-
-	// isAdminStandaloneRole true will force validation if admin role is present
-	// no matter if other roles are missing
-	if isAdminStandaloneRole && currentRoles.Has(RoleAdmin) {
-		return nil
-	}
-
-	for _, reqRole := range requiredRoles {
-		if !currentRoles.Has(reqRole) {
-			missing = append(missing, reqRole)
-		}
-	}
-
-	if len(missing) > 0 {
-		return fmt.Errorf("missing required roles: %s", missing.String())
-	}
-
-	return nil
-}
-
 func (r Roles) Has(role Role) bool {
 	for _, existing := range r {
 		if existing == role {
@@ -114,12 +93,7 @@ func (r Role) String() string {
 	return string(r)
 }
 
-var availableRoles = map[Role]bool{
-	RoleAdmin:  true,
-	RoleDoctor: true,
-}
-
+// IsValid checks the role name format
 func (r Role) IsValid() bool {
-	_, exists := availableRoles[r]
-	return exists
+	return roleNameRegexp.MatchString(string(r))
 }
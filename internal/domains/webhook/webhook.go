@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	"github.com/sopial42/cleanic/internal/domains/event"
+)
+
+const (
+	// HeaderSignature carries t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+	HeaderSignature = "X-Cleanic-Signature"
+	HeaderEvent     = "X-Cleanic-Event"
+	HeaderDelivery  = "X-Cleanic-Delivery"
+
+	secretBytes = 32
+)
+
+// Subscription lets a partner receive the events of a clinic, signed with its secret.
+// The secret is shown at creation only, the partner keeps it to check the signatures
+type Subscription struct {
+	ID         ID           `json:"id"`
+	ClinicID   clinic.ID    `json:"clinic_id"`
+	URL        string       `json:"url"`
+	EventTypes []event.Type `json:"event_types"`
+	Secret     string       `json:"-"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type ID int64
+
+func (s Subscription) Accepts(eventType event.Type) bool {
+	for _, accepted := range s.EventTypes {
+		if accepted == eventType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Status is pending until a delivery succeeds or runs out of attempts
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusSucceeded Status = "succeeded"
+	// StatusDead is the dead-letter state, the delivery is not retried anymore
+	StatusDead Status = "dead"
+)
+
+// Delivery is an event sent to a subscription, an event is delivered once per subscription
+type Delivery struct {
+	ID             DeliveryID `json:"id"`
+	SubscriptionID ID         `json:"subscription_id"`
+	ClinicID       clinic.ID  `json:"clinic_id"`
+	EventID        event.ID   `json:"event_id"`
+	EventType      event.Type `json:"event_type"`
+	// Payload is the signed body, the event envelope
+	Payload       json.RawMessage `json:"payload"`
+	Status        Status          `json:"status"`
+	AttemptCount  int             `json:"attempt_count"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	// Attempts are only loaded by the listing, oldest first
+	Attempts []Attempt `json:"attempts"`
+}
+
+type DeliveryID int64
+
+// Attempt records a POST to the subscription URL, Error is set when no response was received
+type Attempt struct {
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	DurationMS   int64     `json:"duration_ms"`
+	AttemptedAt  time.Time `json:"attempted_at"`
+}
+
+func (a Attempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// ReceiverAllowed tells whether the webhooks may reach ip. The loopback, private, link-local and
+// unspecified addresses stay internal to the deployment, they are only reached when listed in allowed
+func ReceiverAllowed(ip net.IP, allowed []*net.IPNet) bool {
+	for _, network := range allowed {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsUnspecified()
+}
+
+// NewSecret generates the secret signing the payloads of a subscription
+func NewSecret() (string, error) {
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("unable to generate webhook secret: %w", err)
+	}
+
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// Sign returns the HeaderSignature value, the timestamp is signed to defeat replays
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac(secret, unix, body))
+}
+
+// Verify checks a HeaderSignature value, the receivers should also reject the old timestamps
+func Verify(secret string, signature string, body []byte) (time.Time, error) {
+	var unix, v1 string
+	for _, part := range strings.Split(signature, ",") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			v1 = value
+		}
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed signature timestamp: %w", err)
+	}
+
+	expected, err := hex.DecodeString(v1)
+	if err != nil || !hmac.Equal(expected, mac(secret, unix, body)) {
+		return time.Time{}, fmt.Errorf("signature mismatch")
+	}
+
+	return time.Unix(seconds, 0), nil
+}
+
+func mac(secret string, unix string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(unix))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,183 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	"github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+// lastUsedPrecision avoids a write on every authenticated request
+const lastUsedPrecision = time.Minute
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+type apiKeySVC struct {
+	persistence Persistence
+	users       UserClient
+	roles       RoleClient
+}
+
+func NewAPIKeyService(persistence Persistence, users UserClient, roles RoleClient) Service {
+	return &apiKeySVC{
+		persistence: persistence,
+		users:       users,
+		roles:       roles,
+	}
+}
+
+func (a *apiKeySVC) CreateAPIKey(ctx context.Context, reqUserID user.ID, newKey apikey.APIKey) (apikey.APIKey, apikey.PlainKey, error) {
+	if newKey.UserID == 0 {
+		newKey.UserID = reqUserID
+	}
+
+	if newKey.Name == "" {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to create api key: missing name")
+	}
+
+	if len(newKey.Scopes) == 0 || !newKey.Scopes.AreValid() {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to create api key, invalid scopes: %s", newKey.Scopes.String())
+	}
+
+	if newKey.ExpiresAt != nil && !newKey.ExpiresAt.After(time.Now()) {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to create api key: expiry must be in the future")
+	}
+
+	owner, err := a.users.GetUserByID(ctx, newKey.UserID)
+	if err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to get api key owner: %w", err)
+	}
+
+	// Keys for someone else are only minted for service accounts, never for other humans
+	if owner.ID != reqUserID {
+		if err := a.ensureOwnershipOrUserManage(ctx, reqUserID, owner.ID); err != nil {
+			return apikey.APIKey{}, "", err
+		}
+
+		if !owner.ServiceAccount {
+			return apikey.APIKey{}, "", fmt.Errorf("unable to create api key for another user that is not a service account")
+		}
+	}
+
+	// A key never grants more than its owner currently has
+	ownerPermissions, err := a.roles.PermissionsForRoles(ctx, owner.Roles)
+	if err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to get api key owner permissions: %w", err)
+	}
+
+	if err := user.ValidateRequiredPermissions(newKey.Scopes, ownerPermissions); err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to create api key, scopes exceed owner permissions: %w", err)
+	}
+
+	plainKey, prefix, hash, err := apikey.NewPlainKey()
+	if err != nil {
+		return apikey.APIKey{}, "", err
+	}
+
+	newKey.Prefix = prefix
+	newKey.Hash = hash
+	newKey.LastUsedAt = nil
+	newKey.RevokedAt = nil
+	keyCreated, err := a.persistence.InsertAPIKey(ctx, newKey)
+	if err != nil {
+		return apikey.APIKey{}, "", fmt.Errorf("unable to create api key: %w", err)
+	}
+
+	return keyCreated, plainKey, nil
+}
+
+func (a *apiKeySVC) ListAPIKeys(ctx context.Context, reqUserID user.ID, ownerID user.ID) ([]apikey.APIKey, error) {
+	if ownerID == 0 {
+		ownerID = reqUserID
+	}
+
+	if err := a.ensureOwnershipOrUserManage(ctx, reqUserID, ownerID); err != nil {
+		return nil, err
+	}
+
+	keys, err := a.persistence.ListAPIKeysByUser(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (a *apiKeySVC) RevokeAPIKey(ctx context.Context, reqUserID user.ID, keyID apikey.ID) error {
+	key, err := a.persistence.GetAPIKeyByID(ctx, keyID)
+	if err != nil {
+		return fmt.Errorf("unable to get api key: %w", err)
+	}
+
+	if err := a.ensureOwnershipOrUserManage(ctx, reqUserID, key.UserID); err != nil {
+		return err
+	}
+
+	if key.RevokedAt != nil {
+		return nil
+	}
+
+	if err := a.persistence.RevokeAPIKey(ctx, keyID, time.Now()); err != nil {
+		return fmt.Errorf("unable to revoke api key: %w", err)
+	}
+
+	return nil
+}
+
+func (a *apiKeySVC) Authenticate(ctx context.Context, plainKey apikey.PlainKey) (apikey.APIKey, user.User, error) {
+	prefix, err := plainKey.Prefix()
+	if err != nil {
+		return apikey.APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	key, err := a.persistence.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		return apikey.APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(plainKey.Hash())) != 1 {
+		return apikey.APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !key.IsActive(now) {
+		return apikey.APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	// The owner roles are the ones of the key clinic, a removed member loses its keys
+	owner, err := a.users.GetUserByID(clinic.WithID(ctx, key.ClinicID), key.UserID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apikey.APIKey{}, user.User{}, ErrInvalidAPIKey
+	}
+
+	if err != nil {
+		return apikey.APIKey{}, user.User{}, fmt.Errorf("unable to get api key owner: %w", err)
+	}
+
+	if err := a.persistence.TouchAPIKey(ctx, key.ID, now, now.Add(-lastUsedPrecision)); err != nil {
+		return apikey.APIKey{}, user.User{}, fmt.Errorf("unable to record api key usage: %w", err)
+	}
+
+	return key, owner, nil
+}
+
+func (a *apiKeySVC) ensureOwnershipOrUserManage(ctx context.Context, reqUserID user.ID, ownerID user.ID) error {
+	reqUser, err := a.users.GetUserByID(ctx, reqUserID)
+	if err != nil {
+		return fmt.Errorf("unable to get request's user details: %w", err)
+	}
+
+	reqPermissions, err := a.roles.PermissionsForRoles(ctx, reqUser.Roles)
+	if err != nil {
+		return fmt.Errorf("unable to get request's user permissions: %w", err)
+	}
+
+	return tools.EnsureUserOwnershipOrUserManage(reqUser.ID, reqPermissions, ownerID)
+}
@@ -0,0 +1,36 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/apikey"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type Service interface {
+	// CreateAPIKey returns the plain key, it can not be retrieved afterwards
+	CreateAPIKey(ctx context.Context, reqUserID user.ID, newKey apikey.APIKey) (apikey.APIKey, apikey.PlainKey, error)
+	ListAPIKeys(ctx context.Context, reqUserID user.ID, ownerID user.ID) ([]apikey.APIKey, error)
+	RevokeAPIKey(ctx context.Context, reqUserID user.ID, keyID apikey.ID) error
+	// Authenticate returns an active key and its owner, or ErrInvalidAPIKey
+	Authenticate(ctx context.Context, plainKey apikey.PlainKey) (apikey.APIKey, user.User, error)
+}
+
+type Persistence interface {
+	InsertAPIKey(ctx context.Context, newKey apikey.APIKey) (apikey.APIKey, error)
+	GetAPIKeyByID(ctx context.Context, keyID apikey.ID) (apikey.APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (apikey.APIKey, error)
+	ListAPIKeysByUser(ctx context.Context, userID user.ID) ([]apikey.APIKey, error)
+	RevokeAPIKey(ctx context.Context, keyID apikey.ID, revokedAt time.Time) error
+	// TouchAPIKey only writes when the stored last use is older than the given time
+	TouchAPIKey(ctx context.Context, keyID apikey.ID, usedAt time.Time, olderThan time.Time) error
+}
+
+type UserClient interface {
+	GetUserByID(ctx context.Context, userID user.ID) (user.User, error)
+}
+
+type RoleClient interface {
+	PermissionsForRoles(ctx context.Context, roles user.Roles) (user.Permissions, error)
+}
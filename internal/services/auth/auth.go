@@ -2,40 +2,74 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"time"
 
 	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
 	"github.com/sopial42/cleanic/internal/config"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
 	user "github.com/sopial42/cleanic/internal/domains/user"
-	"golang.org/x/crypto/bcrypt"
+	clinicSVC "github.com/sopial42/cleanic/internal/services/clinic"
+	passwordSVC "github.com/sopial42/cleanic/internal/services/password"
+	"github.com/sopial42/cleanic/internal/services/transaction"
 )
 
+// dummyPassword is hashed at startup and compared against on unknown emails
+// so that a login takes the same time whether the account exists or not
+const dummyPassword = "cleanic-dummy-password"
+
+// ErrPasswordExpired is returned on valid credentials when the password has reached its max age
+var ErrPasswordExpired = errors.New("password expired, rotation required")
+
 type authSVC struct {
-	uClient     UserClient
-	jwtConfig   config.JWTConfig
-	persistence Persistence
+	uClient           UserClient
+	clinics           ClinicClient
+	jwtConfig         config.JWTConfig
+	loginConfig       config.LoginProtectionConfig
+	sessionsConfig    config.SessionsConfig
+	persistence       Persistence
+	passwords         passwordSVC.Service
+	dummyPasswordHash user.Password
+	// idp is nil when single sign-on is disabled
+	idp        IdentityProvider
+	oidcConfig config.OIDCConfig
+	metrics    Metrics
+	uow        transaction.UnitOfWork
 }
 
-func NewAuthService(uClient UserClient, jwtConfig config.JWTConfig, persistence Persistence) Service {
-	return &authSVC{
-		uClient:     uClient,
-		jwtConfig:   jwtConfig,
-		persistence: persistence,
+func NewAuthService(uClient UserClient, clinics ClinicClient, jwtConfig config.JWTConfig, loginConfig config.LoginProtectionConfig, sessionsConfig config.SessionsConfig, persistence Persistence, passwords passwordSVC.Service, idp IdentityProvider, oidcConfig config.OIDCConfig, metrics Metrics, uow transaction.UnitOfWork) (Service, error) {
+	// Unknown emails are checked against it so that they take as long as the wrong passwords
+	dummyPasswordHash, err := passwords.Hash(dummyPassword)
+	if err != nil {
+		return nil, fmt.Errorf("unable to hash the dummy password: %w", err)
 	}
+
+	return &authSVC{
+		uClient:           uClient,
+		clinics:           clinics,
+		jwtConfig:         jwtConfig,
+		loginConfig:       loginConfig,
+		sessionsConfig:    sessionsConfig,
+		persistence:       persistence,
+		passwords:         passwords,
+		dummyPasswordHash: dummyPasswordHash,
+		idp:               idp,
+		oidcConfig:        oidcConfig,
+		metrics:           metrics,
+		uow:               uow,
+	}, nil
 }
 
+// Signup relies on the user service to enforce the password policy, the user joins the default clinic
 func (a *authSVC) Signup(ctx context.Context, newUser user.User) (user.User, error) {
-	if !newUser.Password.IsValid() {
-		return user.User{}, errors.New("invalid password")
-	}
-
 	if !newUser.Email.IsValid() {
 		return user.User{}, errors.New("invalid email")
 	}
 
-	userCreated, err := a.uClient.Create(ctx, newUser)
+	userCreated, err := a.uClient.Create(clinic.WithID(ctx, clinic.DefaultID), newUser)
 	if err != nil {
 		return user.User{}, fmt.Errorf("unable to create a user: %w", err)
 	}
@@ -43,18 +77,96 @@ func (a *authSVC) Signup(ctx context.Context, newUser user.User) (user.User, err
 	return userCreated, nil
 }
 
-func (a *authSVC) Login(ctx context.Context, loginUser user.User) (utils.RefreshToken, utils.AccessToken, error) {
-	userFound, err := a.uClient.GetUserByEmail(ctx, loginUser.Email)
+// Login checks credentials while counting failures per account and per client IP
+// Unknown emails and wrong passwords are indistinguishable for the caller
+func (a *authSVC) Login(ctx context.Context, loginUser user.User, clientIP string) (utils.RefreshToken, utils.AccessToken, error) {
+	refreshToken, accessToken, err := a.login(ctx, loginUser, clientIP)
+	a.recordLogin(auth.LoginMethodPassword, err)
+
+	return refreshToken, accessToken, err
+}
+
+func (a *authSVC) login(ctx context.Context, loginUser user.User, clientIP string) (utils.RefreshToken, utils.AccessToken, error) {
+	userFound, err := a.checkCredentials(ctx, loginUser, clientIP)
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, err
+	}
+
+	if a.passwords.IsExpired(userFound.PasswordChangedAt) {
+		return utils.RefreshToken{}, utils.AccessToken{}, ErrPasswordExpired
+	}
+
+	membership, err := a.firstMembership(ctx, userFound.ID)
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, err
+	}
+
+	return a.openSession(ctx, membership, auth.LoginMethodPassword)
+}
+
+// SwitchClinic replaces the session, the previous refresh token can not be used anymore
+func (a *authSVC) SwitchClinic(ctx context.Context, userID user.ID, clinicID clinic.ID) (utils.RefreshToken, utils.AccessToken, error) {
+	membership, err := a.clinics.GetMembership(ctx, userID, clinicID)
 	if err != nil {
 		return utils.RefreshToken{}, utils.AccessToken{}, err
 	}
 
-	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(userFound.Password), []byte(loginUser.Password)); err != nil {
-		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("invalid password: %w", err)
+	// Service accounts are bound to the clinic of their API keys
+	userFound, err := a.uClient.GetUserByID(clinic.WithID(ctx, clinicID), userID)
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to get user: %w", err)
+	}
+
+	if userFound.ServiceAccount {
+		return utils.RefreshToken{}, utils.AccessToken{}, errors.New("service accounts can not switch clinic")
+	}
+
+	return a.openSession(ctx, membership, auth.LoginMethodClinicSwitch)
+}
+
+// firstMembership is the clinic a login starts in, the other clinics are reached with SwitchClinic
+func (a *authSVC) firstMembership(ctx context.Context, userID user.ID) (clinic.Membership, error) {
+	memberships, err := a.clinics.ListMemberships(ctx, userID)
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to list clinics: %w", err)
+	}
+
+	if len(memberships) == 0 {
+		return clinic.Membership{}, fmt.Errorf("%w: user %d belongs to no clinic", clinicSVC.ErrNotMember, userID)
 	}
 
-	refreshToken, accessToken, err := generateTokens(userFound, a.jwtConfig)
+	return memberships[0], nil
+}
+
+// openSession records a new session in the history along with its tokens, the previous session of the user ends
+func (a *authSVC) openSession(ctx context.Context, membership clinic.Membership, method auth.LoginMethod) (utils.RefreshToken, utils.AccessToken, error) {
+	var refreshToken utils.RefreshToken
+	var accessToken utils.AccessToken
+	err := a.uow.Do(ctx, func(ctx context.Context) error {
+		session := auth.Session{
+			UserID:    membership.UserID,
+			ClinicID:  membership.Clinic.ID,
+			Method:    method,
+			StartedAt: time.Now(),
+		}
+		if err := a.persistence.StartSession(ctx, session); err != nil {
+			return fmt.Errorf("unable to start session: %w", err)
+		}
+
+		var err error
+		refreshToken, accessToken, err = a.startSession(ctx, membership)
+		return err
+	})
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, err
+	}
+
+	return refreshToken, accessToken, nil
+}
+
+// startSession issues the tokens scoped to the membership clinic and stores the refresh token
+func (a *authSVC) startSession(ctx context.Context, membership clinic.Membership) (utils.RefreshToken, utils.AccessToken, error) {
+	refreshToken, accessToken, err := generateTokens(membership, a.jwtConfig)
 	if err != nil {
 		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to generate tokens: %w", err)
 	}
@@ -66,19 +178,92 @@ func (a *authSVC) Login(ctx context.Context, loginUser user.User) (utils.Refresh
 	return refreshToken, accessToken, nil
 }
 
-func (a *authSVC) Logout(ctx context.Context, userID user.ID) error {
-	if err := a.persistence.DeleteRefreshTokenClaims(ctx, userID); err != nil {
-		return fmt.Errorf("unable to delete refresh token: %w", err)
+// RotatePassword lets a user whose password expired set a new one with its current credentials
+func (a *authSVC) RotatePassword(ctx context.Context, loginUser user.User, newPassword user.Password, clientIP string) error {
+	userFound, err := a.checkCredentials(ctx, loginUser, clientIP)
+	if err != nil {
+		return err
+	}
+
+	if err := a.uClient.UpdatePassword(ctx, userFound.ID, newPassword); err != nil {
+		return fmt.Errorf("unable to rotate password: %w", err)
 	}
 
 	return nil
 }
 
+// checkCredentials applies the brute-force protection around the password check
+// and transparently rehashes passwords stored with an outdated hasher
+func (a *authSVC) checkCredentials(ctx context.Context, loginUser user.User, clientIP string) (user.User, error) {
+	now := time.Now()
+	keys := a.loginAttemptKeys(loginUser.Email, clientIP)
+	if err := a.ensureLoginAllowed(ctx, now, keys); err != nil {
+		return user.User{}, err
+	}
+
+	// Only an unknown email counts as a failure, an unavailable database must not lock the accounts out
+	userFound, err := a.uClient.GetUserByEmail(ctx, loginUser.Email)
+	if errors.Is(err, sql.ErrNoRows) {
+		_, _ = a.passwords.Verify(a.dummyPasswordHash, loginUser.Password)
+		return user.User{}, a.failLogin(ctx, now, keys)
+	}
+
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to get user: %w", err)
+	}
+
+	// Check password, service accounts never log in interactively
+	match, needsRehash := a.passwords.Verify(userFound.Password, loginUser.Password)
+	if !match || userFound.ServiceAccount {
+		return user.User{}, a.failLogin(ctx, now, keys)
+	}
+
+	// Only the account counter is reset, a valid login must not clear the failures of a whole IP
+	if err := a.persistence.DeleteLoginAttempts(ctx, keys[0].scope, keys[0].key); err != nil {
+		return user.User{}, fmt.Errorf("unable to reset login attempts: %w", err)
+	}
+
+	if needsRehash {
+		if err := a.uClient.RehashPassword(ctx, userFound.ID, loginUser.Password); err != nil {
+			return user.User{}, fmt.Errorf("unable to rehash password: %w", err)
+		}
+	}
+
+	return userFound, nil
+}
+
+func (a *authSVC) Logout(ctx context.Context, userID user.ID) error {
+	return a.uow.Do(ctx, func(ctx context.Context) error {
+		if err := a.persistence.EndSession(ctx, userID, time.Now()); err != nil {
+			return fmt.Errorf("unable to end session: %w", err)
+		}
+
+		if err := a.persistence.DeleteRefreshTokenClaims(ctx, userID); err != nil {
+			return fmt.Errorf("unable to delete refresh token: %w", err)
+		}
+
+		return nil
+	})
+}
+
 // Refresh ensure the received token is the one associated to the user in DB
 // If yes, rotate tokens and return the new ones
 // Only one token per user is valid at a time
 func (a *authSVC) Refresh(ctx context.Context, signedTokenCandidate utils.SignedRefreshToken) (utils.RefreshToken, utils.AccessToken, error) {
-	claimsCandidate, err := utils.ParseRefreshClaims(signedTokenCandidate, a.jwtConfig.RefreshTokenConfig.GetSecret())
+	refreshToken, accessToken, err := a.refresh(ctx, signedTokenCandidate)
+	if a.metrics != nil {
+		outcome := auth.OutcomeSuccess
+		if err != nil {
+			outcome = auth.OutcomeRejected
+		}
+		a.metrics.RefreshAttempted(outcome)
+	}
+
+	return refreshToken, accessToken, err
+}
+
+func (a *authSVC) refresh(ctx context.Context, signedTokenCandidate utils.SignedRefreshToken) (utils.RefreshToken, utils.AccessToken, error) {
+	claimsCandidate, err := utils.ParseRefreshClaims(signedTokenCandidate, a.jwtConfig.RefreshTokenConfig.GetVerificationSecrets()...)
 	if err != nil {
 		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to parse refresh token: %w", err)
 	}
@@ -91,60 +276,87 @@ func (a *authSVC) Refresh(ctx context.Context, signedTokenCandidate utils.Signed
 		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("candidate refresh token expired")
 	}
 
-	// Get the refresh token from DB,
-	// Only one is valid per user at a time
-	// If the token is not found, it means the user has logged out
-	// or the token has been revoked
-	// then he need to login again
-	peristedClaims, err := a.persistence.GetRefreshTokenClaimsByUserID(ctx, currentUserID)
-	if err != nil {
-		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to get refresh token from DB: %w", err)
-	}
+	// The check and the rotation are one transaction, so that two concurrent refreshes
+	// with the same token can not both succeed
+	var refreshToken utils.RefreshToken
+	var accessToken utils.AccessToken
+	err = a.uow.Do(ctx, func(ctx context.Context) error {
+		// Get the refresh token from DB,
+		// Only one is valid per user at a time
+		// If the token is not found, it means the user has logged out
+		// or the token has been revoked
+		// then he need to login again
+		peristedClaims, err := a.persistence.GetRefreshTokenClaimsByUserID(ctx, currentUserID)
+		if err != nil {
+			return fmt.Errorf("unable to get refresh token from DB: %w", err)
+		}
 
-	// If the refresh token in DB is expired, the user need to login again
-	if peristedClaims.ExpiresAt < time.Now().Unix() {
-		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("stored refresh token expired")
-	}
+		// If the refresh token in DB is expired, the user need to login again
+		if peristedClaims.ExpiresAt < time.Now().Unix() {
+			return fmt.Errorf("stored refresh token expired")
+		}
 
-	// Check if the token ID in DB is the same as the one received
-	// If not, that means the user may have sent a token from another device
-	// then he need to login again on the new device
-	// We could add much more security here by checking the IP address, user agent, etc. and ensure the reason for an old token to be used is legit
-	if peristedClaims.ID != claimsCandidate.ID {
-		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("refresh token candidate is not anymore associated to the user")
-	}
+		// Check if the token ID in DB is the same as the one received
+		// If not, that means the user may have sent a token from another device
+		// then he need to login again on the new device
+		// We could add much more security here by checking the IP address, user agent, etc. and ensure the reason for an old token to be used is legit
+		if peristedClaims.ID != claimsCandidate.ID {
+			return fmt.Errorf("refresh token candidate is not anymore associated to the user")
+		}
+
+		// Get the current roles in the session clinic, the user may have been removed from it
+		membership, err := a.clinics.GetMembership(ctx, currentUserID, peristedClaims.ClinicID)
+		if err != nil {
+			return err
+		}
 
-	// Get user details to get roles
-	userFound, err := a.uClient.GetUserByID(ctx, currentUserID)
+		// Rotate tokens
+		refreshToken, accessToken, err = a.startSession(ctx, membership)
+		return err
+	})
 	if err != nil {
 		return utils.RefreshToken{}, utils.AccessToken{}, err
 	}
 
-	// Rotate tokens
-	refreshToken, accessToken, err := generateTokens(userFound, a.jwtConfig)
-	if err != nil {
-		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to generate tokens: %w", err)
+	return refreshToken, accessToken, nil
+}
+
+// recordLogin classifies the login error, metrics are optional
+func (a *authSVC) recordLogin(method auth.LoginMethod, err error) {
+	if a.metrics == nil {
+		return
 	}
 
-	if err := a.persistence.StoreRefreshTokenClaims(ctx, refreshToken.Claims); err != nil {
-		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to store refresh token: %w", err)
+	var tooManyAttempts *TooManyAttemptsError
+	outcome := auth.OutcomeError
+	switch {
+	case err == nil:
+		outcome = auth.OutcomeSuccess
+	case errors.Is(err, ErrInvalidCredentials):
+		outcome = auth.OutcomeInvalidCredentials
+	case errors.As(err, &tooManyAttempts):
+		outcome = auth.OutcomeThrottled
+	case errors.Is(err, ErrPasswordExpired):
+		outcome = auth.OutcomePasswordExpired
+	case errors.Is(err, ErrSSORejected), errors.Is(err, ErrSSODisabled):
+		outcome = auth.OutcomeRejected
 	}
 
-	return refreshToken, accessToken, nil
+	a.metrics.LoginAttempted(method, outcome)
 }
 
-func generateTokens(user user.User, config config.JWTConfig) (utils.RefreshToken, utils.AccessToken, error) {
+func generateTokens(membership clinic.Membership, config config.JWTConfig) (utils.RefreshToken, utils.AccessToken, error) {
 	refreshToken, err := utils.NewRefreshToken(
-		user.ID, config.RefreshTokenConfig.GetSecret(),
-		utils.RefreshTokenTTLDays(config.RefreshTokenConfig.TokenExpirationMinutes),
+		membership.UserID, membership.Clinic.ID, config.RefreshTokenConfig.GetSecret(),
+		utils.RefreshTokenTTL(config.RefreshTokenConfig.TokenTTL),
 	)
 	if err != nil {
 		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("unable to generate refresh token: %w", err)
 	}
 
 	accessToken, err := utils.NewAccessToken(
-		user.ID, user.Roles, config.AccessTokenConfig.GetSecret(),
-		utils.AccessTokenTTLMin(config.AccessTokenConfig.TokenExpirationMinutes),
+		membership.UserID, membership, config.AccessTokenConfig.GetSecret(),
+		utils.AccessTokenTTL(config.AccessTokenConfig.TokenTTL),
 	)
 
 	return refreshToken, accessToken, err
@@ -2,27 +2,87 @@ package auth
 
 import (
 	"context"
+	"time"
 
 	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
 	user "github.com/sopial42/cleanic/internal/domains/user"
 )
 
 type Service interface {
 	Signup(ctx context.Context, newUser user.User) (user.User, error)
-	Login(ctx context.Context, loginUser user.User) (utils.RefreshToken, utils.AccessToken, error)
+	Login(ctx context.Context, loginUser user.User, clientIP string) (utils.RefreshToken, utils.AccessToken, error)
 	Logout(ctx context.Context, userID user.ID) error
 	Refresh(ctx context.Context, signedToken utils.SignedRefreshToken) (utils.RefreshToken, utils.AccessToken, error)
+	// RotatePassword replaces an expired password, credentials are checked like a login
+	RotatePassword(ctx context.Context, loginUser user.User, newPassword user.Password, clientIP string) error
+	// StartSSO begins an OpenID Connect authorization code flow with PKCE
+	StartSSO(ctx context.Context) (auth.SSOChallenge, error)
+	// CompleteSSO handles the identity provider callback, as an alternative to Login
+	CompleteSSO(ctx context.Context, challenge auth.SSOChallenge, state string, code string) (utils.RefreshToken, utils.AccessToken, error)
+	// Unlock clears failed login attempts of an account and/or a client IP
+	Unlock(ctx context.Context, email user.Email, clientIP string) error
+	// SwitchClinic exchanges the session of a user for tokens scoped to another of its clinics
+	SwitchClinic(ctx context.Context, userID user.ID, clinicID clinic.ID) (utils.RefreshToken, utils.AccessToken, error)
+	// PurgeExpiredSessions deletes the expired refresh tokens and the ended sessions past the retention, it is run by a scheduled job
+	PurgeExpiredSessions(ctx context.Context) error
+	// SessionStats summarizes the sessions of the context clinic since the given time
+	SessionStats(ctx context.Context, since time.Time) (auth.SessionStats, error)
 }
 
 type Persistence interface {
-	// StoreRefreshToken create or rotate the current token associated to a userID
+	// StoreRefreshToken create or rotate the current token associated to a userID, along with its clinic
 	StoreRefreshTokenClaims(ctx context.Context, claims utils.RefreshTokenClaims) error
 	GetRefreshTokenClaimsByUserID(ctx context.Context, userID user.ID) (utils.RefreshTokenClaims, error)
 	DeleteRefreshTokenClaims(ctx context.Context, userID user.ID) error
+	// DeleteExpiredRefreshTokens deletes up to limit tokens expired at now and ends their sessions at their expiry,
+	// it returns how many tokens were deleted
+	DeleteExpiredRefreshTokens(ctx context.Context, now time.Time, limit int) (int, error)
+
+	// StartSession ends the open session of the user, if any, and records the new one
+	StartSession(ctx context.Context, session auth.Session) error
+	// EndSession ends the open session of the user, if any
+	EndSession(ctx context.Context, userID user.ID, at time.Time) error
+	// DeleteEndedSessions deletes up to limit sessions ended before the given time, it returns how many were deleted
+	DeleteEndedSessions(ctx context.Context, before time.Time, limit int) (int, error)
+	// SessionStats counts the sessions of the context clinic started or ended since the given time along with the open ones,
+	// the activity of the sessions is computed at now
+	SessionStats(ctx context.Context, since time.Time, now time.Time) (auth.SessionStats, error)
+
+	// GetLoginAttempts returns an empty LoginAttempts if no failure has been recorded for the key
+	GetLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string) (auth.LoginAttempts, error)
+	// IncrementLoginFailures atomically counts a new failure, restarting from 1
+	// if the previous failure is older than resetWindow
+	IncrementLoginFailures(ctx context.Context, scope auth.AttemptScope, key string, at time.Time, resetWindow time.Duration) (auth.LoginAttempts, error)
+	LockLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string, until time.Time) error
+	DeleteLoginAttempts(ctx context.Context, scope auth.AttemptScope, key string) error
 }
 
 type UserClient interface {
 	Create(ctx context.Context, newUser user.User) (user.User, error)
 	GetUserByEmail(ctx context.Context, email user.Email) (user.User, error)
 	GetUserByID(ctx context.Context, userID user.ID) (user.User, error)
+	UpdatePassword(ctx context.Context, userID user.ID, newPassword user.Password) error
+	RehashPassword(ctx context.Context, userID user.ID, password user.Password) error
+	AssignRoles(ctx context.Context, userID user.ID, roles user.Roles) (user.User, error)
+}
+
+// ClinicClient resolves the clinics a user belongs to, and its roles in each of them
+type ClinicClient interface {
+	ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error)
+	GetMembership(ctx context.Context, userID user.ID, clinicID clinic.ID) (clinic.Membership, error)
+}
+
+// IdentityProvider is an external OpenID Connect provider
+type IdentityProvider interface {
+	AuthCodeURL(challenge auth.SSOChallenge) string
+	// Exchange validates the authorization code and the ID token it returns
+	Exchange(ctx context.Context, challenge auth.SSOChallenge, code string) (auth.ExternalIdentity, error)
+}
+
+// Metrics records the authentication outcomes, it must be safe for concurrent use
+type Metrics interface {
+	LoginAttempted(method auth.LoginMethod, outcome auth.Outcome)
+	RefreshAttempted(outcome auth.Outcome)
 }
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// ErrInvalidCredentials is returned for any unknown email or wrong password
+// so that the caller cannot guess which accounts exist
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrUnlockTargetRequired is returned when an unlock names neither an account nor a client IP
+var ErrUnlockTargetRequired = errors.New("email or ip is required to unlock")
+
+// TooManyAttemptsError is returned when a login is refused before any password check
+// because the account or the client IP is locked or still in backoff
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return "too many failed login attempts, retry later"
+}
+
+type attemptKey struct {
+	scope       auth.AttemptScope
+	key         string
+	maxFailures int
+}
+
+func (a *authSVC) loginAttemptKeys(email user.Email, clientIP string) []attemptKey {
+	keys := []attemptKey{{
+		scope:       auth.AttemptScopeAccount,
+		key:         auth.NewAccountAttemptKey(string(email)),
+		maxFailures: a.loginConfig.MaxFailedAttemptsPerAccount,
+	}}
+
+	if clientIP != "" {
+		keys = append(keys, attemptKey{
+			scope:       auth.AttemptScopeIP,
+			key:         clientIP,
+			maxFailures: a.loginConfig.MaxFailedAttemptsPerIP,
+		})
+	}
+
+	return keys
+}
+
+// ensureLoginAllowed checks every key and returns the longest wait if any of them is blocked
+func (a *authSVC) ensureLoginAllowed(ctx context.Context, now time.Time, keys []attemptKey) error {
+	var retryAfter time.Duration
+	for _, k := range keys {
+		attempts, err := a.persistence.GetLoginAttempts(ctx, k.scope, k.key)
+		if err != nil {
+			return fmt.Errorf("unable to get login attempts: %w", err)
+		}
+
+		if wait := a.retryAfter(attempts, now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+func (a *authSVC) retryAfter(attempts auth.LoginAttempts, now time.Time) time.Duration {
+	if attempts.IsLocked(now) {
+		return attempts.LockedUntil.Sub(now)
+	}
+
+	if attempts.Failures < a.loginConfig.BackoffAfterAttempts {
+		return 0
+	}
+
+	nextAllowedAt := attempts.LastFailureAt.Add(a.backoffDelay(attempts.Failures))
+	if now.Before(nextAllowedAt) {
+		return nextAllowedAt.Sub(now)
+	}
+
+	return 0
+}
+
+// backoffDelay doubles the base delay for each failure past BackoffAfterAttempts
+func (a *authSVC) backoffDelay(failures int) time.Duration {
+	delay := a.loginConfig.BackoffBase
+	for i := a.loginConfig.BackoffAfterAttempts; i < failures && delay < a.loginConfig.BackoffMax; i++ {
+		delay *= 2
+	}
+
+	if delay > a.loginConfig.BackoffMax {
+		return a.loginConfig.BackoffMax
+	}
+
+	return delay
+}
+
+// failLogin records the failure on every key, locks the ones reaching their threshold
+// and always ends up returning ErrInvalidCredentials
+func (a *authSVC) failLogin(ctx context.Context, now time.Time, keys []attemptKey) error {
+	for _, k := range keys {
+		attempts, err := a.persistence.IncrementLoginFailures(ctx, k.scope, k.key, now, a.loginConfig.LockoutDuration)
+		if err != nil {
+			return fmt.Errorf("unable to record failed login: %w", err)
+		}
+
+		if attempts.Failures >= k.maxFailures {
+			if err := a.persistence.LockLoginAttempts(ctx, k.scope, k.key, now.Add(a.loginConfig.LockoutDuration)); err != nil {
+				return fmt.Errorf("unable to lock login attempts: %w", err)
+			}
+		}
+	}
+
+	return ErrInvalidCredentials
+}
+
+func (a *authSVC) Unlock(ctx context.Context, email user.Email, clientIP string) error {
+	if email == "" && clientIP == "" {
+		return ErrUnlockTargetRequired
+	}
+
+	if email != "" {
+		if err := a.persistence.DeleteLoginAttempts(ctx, auth.AttemptScopeAccount, auth.NewAccountAttemptKey(string(email))); err != nil {
+			return fmt.Errorf("unable to unlock account: %w", err)
+		}
+	}
+
+	if clientIP != "" {
+		if err := a.persistence.DeleteLoginAttempts(ctx, auth.AttemptScopeIP, clientIP); err != nil {
+			return fmt.Errorf("unable to unlock ip: %w", err)
+		}
+	}
+
+	return nil
+}
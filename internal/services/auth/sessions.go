@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+)
+
+// PurgeExpiredSessions deletes in batches, each batch is a transaction of its own so that the logins are never blocked for long
+func (a *authSVC) PurgeExpiredSessions(ctx context.Context) error {
+	now := time.Now()
+	for {
+		var deleted int
+		err := a.uow.Do(ctx, func(ctx context.Context) error {
+			var err error
+			deleted, err = a.persistence.DeleteExpiredRefreshTokens(ctx, now, a.sessionsConfig.PurgeBatchSize)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to purge expired refresh tokens: %w", err)
+		}
+
+		if deleted < a.sessionsConfig.PurgeBatchSize {
+			break
+		}
+	}
+
+	retainedSince := now.Add(-a.sessionsConfig.HistoryRetention)
+	for {
+		deleted, err := a.persistence.DeleteEndedSessions(ctx, retainedSince, a.sessionsConfig.PurgeBatchSize)
+		if err != nil {
+			return fmt.Errorf("unable to purge ended sessions: %w", err)
+		}
+
+		if deleted < a.sessionsConfig.PurgeBatchSize {
+			return nil
+		}
+	}
+}
+
+func (a *authSVC) SessionStats(ctx context.Context, since time.Time) (auth.SessionStats, error) {
+	stats, err := a.persistence.SessionStats(ctx, since, time.Now())
+	if err != nil {
+		return auth.SessionStats{}, fmt.Errorf("unable to count sessions: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+var (
+	ErrSSODisabled = errors.New("single sign-on is not configured")
+	// ErrSSORejected wraps every reason for refusing an identity provider callback
+	ErrSSORejected = errors.New("single sign-on rejected")
+)
+
+// ssoPasswordSuffix guarantees provisioned random passwords match any character class rule
+const ssoPasswordSuffix = "aA1!"
+
+// StartSSO generates the state, nonce and PKCE verifier of a new authorization code flow
+func (a *authSVC) StartSSO(_ context.Context) (auth.SSOChallenge, error) {
+	if a.idp == nil {
+		return auth.SSOChallenge{}, ErrSSODisabled
+	}
+
+	var challenge auth.SSOChallenge
+	for _, value := range []*string{&challenge.State, &challenge.Nonce, &challenge.CodeVerifier} {
+		random, err := randomString(32)
+		if err != nil {
+			return auth.SSOChallenge{}, fmt.Errorf("unable to generate SSO challenge: %w", err)
+		}
+		*value = random
+	}
+
+	challenge.AuthURL = a.idp.AuthCodeURL(challenge)
+	return challenge, nil
+}
+
+// CompleteSSO validates the identity provider callback, provisions the user on its first login,
+// syncs its roles from the identity provider groups and issues cleanic tokens
+func (a *authSVC) CompleteSSO(ctx context.Context, challenge auth.SSOChallenge, state string, code string) (utils.RefreshToken, utils.AccessToken, error) {
+	refreshToken, accessToken, err := a.completeSSO(ctx, challenge, state, code)
+	a.recordLogin(auth.LoginMethodSSO, err)
+
+	return refreshToken, accessToken, err
+}
+
+func (a *authSVC) completeSSO(ctx context.Context, challenge auth.SSOChallenge, state string, code string) (utils.RefreshToken, utils.AccessToken, error) {
+	if a.idp == nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, ErrSSODisabled
+	}
+
+	if challenge.State == "" || subtle.ConstantTimeCompare([]byte(state), []byte(challenge.State)) != 1 {
+		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("%w: state mismatch", ErrSSORejected)
+	}
+
+	identity, err := a.idp.Exchange(ctx, challenge, code)
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("%w: %w", ErrSSORejected, err)
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("%w: email missing or not verified", ErrSSORejected)
+	}
+
+	roles, err := a.rolesFromGroups(identity.Groups)
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, fmt.Errorf("%w: %w", ErrSSORejected, err)
+	}
+
+	// A user provisioned just in time is not left behind without its roles nor its session
+	var refreshToken utils.RefreshToken
+	var accessToken utils.AccessToken
+	err = a.uow.Do(ctx, func(ctx context.Context) error {
+		membership, err := a.provisionSSOUser(ctx, user.Email(identity.Email), roles)
+		if err != nil {
+			return err
+		}
+
+		refreshToken, accessToken, err = a.openSession(ctx, membership, auth.LoginMethodSSO)
+		return err
+	})
+	if err != nil {
+		return utils.RefreshToken{}, utils.AccessToken{}, err
+	}
+
+	return refreshToken, accessToken, nil
+}
+
+// rolesFromGroups returns nil when no mapping is configured, roles are then left untouched
+func (a *authSVC) rolesFromGroups(groups []string) (user.Roles, error) {
+	if len(a.oidcConfig.GroupRoles) == 0 {
+		return nil, nil
+	}
+
+	var roles user.Roles
+	for _, group := range groups {
+		for _, roleStr := range a.oidcConfig.GroupRoles[group] {
+			role := user.Role(roleStr)
+			if !role.IsValid() {
+				return nil, fmt.Errorf("invalid role %q mapped to group %q", roleStr, group)
+			}
+
+			if !roles.Has(role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	if len(roles) == 0 {
+		return nil, errors.New("none of the user groups is mapped to a role")
+	}
+
+	return roles, nil
+}
+
+// provisionSSOUser creates the user just in time with an unusable random password in the default clinic,
+// the groups then set its roles in the clinic it logs in
+func (a *authSVC) provisionSSOUser(ctx context.Context, email user.Email, roles user.Roles) (clinic.Membership, error) {
+	// only an unknown email is provisioned, an unavailable database must not create the account twice
+	userFound, err := a.uClient.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return clinic.Membership{}, fmt.Errorf("unable to get user: %w", err)
+	}
+
+	if err != nil {
+		randomPassword, err := randomString(32)
+		if err != nil {
+			return clinic.Membership{}, fmt.Errorf("unable to generate password: %w", err)
+		}
+
+		userFound, err = a.uClient.Create(clinic.WithID(ctx, clinic.DefaultID), user.User{
+			Email:    email,
+			Password: user.Password(randomPassword + ssoPasswordSuffix),
+		})
+		if err != nil {
+			return clinic.Membership{}, fmt.Errorf("unable to provision user: %w", err)
+		}
+	}
+
+	if userFound.ServiceAccount {
+		return clinic.Membership{}, fmt.Errorf("%w: service accounts can not log in", ErrSSORejected)
+	}
+
+	membership, err := a.firstMembership(ctx, userFound.ID)
+	if err != nil {
+		return clinic.Membership{}, err
+	}
+
+	if roles == nil || sameRoles(membership.Roles, roles) {
+		return membership, nil
+	}
+
+	if _, err := a.uClient.AssignRoles(clinic.WithID(ctx, membership.Clinic.ID), userFound.ID, roles); err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to sync user roles: %w", err)
+	}
+
+	membership.Roles = roles
+	return membership, nil
+}
+
+func sameRoles(current user.Roles, expected user.Roles) bool {
+	if len(current) != len(expected) {
+		return false
+	}
+
+	for _, role := range expected {
+		if !current.Has(role) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func randomString(size int) (string, error) {
+	raw := make([]byte, size)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sopial42/cleanic/internal/config"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+func TestRolesFromGroups(t *testing.T) {
+	a := &authSVC{oidcConfig: config.OIDCConfig{GroupRoles: map[string][]string{
+		"doctors": {"doctor"},
+		"admins":  {"admin"},
+		"broken":  {"Not A Role"},
+	}}}
+
+	testCases := []struct {
+		name     string
+		groups   []string
+		expected user.Roles
+		wantErr  bool
+	}{
+		{name: "doctor", groups: []string{"doctors"}, expected: user.Roles{user.RoleDoctor}},
+		{name: "admin", groups: []string{"admins"}, expected: user.Roles{user.RoleAdmin}},
+		{name: "roles are merged", groups: []string{"admins", "doctors"}, expected: user.Roles{user.RoleAdmin, user.RoleDoctor}},
+		{name: "unmapped groups are ignored", groups: []string{"nurses", "doctors"}, expected: user.Roles{user.RoleDoctor}},
+		{name: "no mapped group", groups: []string{"nurses"}, wantErr: true},
+		{name: "invalid mapped role", groups: []string{"broken"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			roles, err := a.rolesFromGroups(tc.groups)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got roles %v", roles)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !sameRoles(roles, tc.expected) {
+				t.Fatalf("expected roles %v, got %v", tc.expected, roles)
+			}
+		})
+	}
+}
+
+func TestRolesFromGroupsWithoutMapping(t *testing.T) {
+	a := &authSVC{}
+	roles, err := a.rolesFromGroups([]string{"doctors"})
+	if err != nil || roles != nil {
+		t.Fatalf("expected roles to be left untouched, got %v, %v", roles, err)
+	}
+}
+
+type fakeIdentityProvider struct{}
+
+func (fakeIdentityProvider) AuthCodeURL(auth.SSOChallenge) string {
+	return "http://idp.test/authorize"
+}
+
+func (fakeIdentityProvider) Exchange(context.Context, auth.SSOChallenge, string) (auth.ExternalIdentity, error) {
+	return auth.ExternalIdentity{Email: "doctor@hospital.test"}, nil
+}
+
+func TestCompleteSSORejections(t *testing.T) {
+	a := &authSVC{idp: fakeIdentityProvider{}}
+	challenge, err := a.StartSSO(context.Background())
+	if err != nil {
+		t.Fatalf("unable to start SSO: %v", err)
+	}
+
+	if _, _, err := a.CompleteSSO(context.Background(), challenge, "forged-state", "code"); !errors.Is(err, ErrSSORejected) {
+		t.Fatalf("state mismatch should be rejected, got %v", err)
+	}
+
+	// fakeIdentityProvider never marks the email as verified
+	if _, _, err := a.CompleteSSO(context.Background(), challenge, challenge.State, "code"); !errors.Is(err, ErrSSORejected) {
+		t.Fatalf("unverified email should be rejected, got %v", err)
+	}
+
+	if _, err := (&authSVC{}).StartSSO(context.Background()); !errors.Is(err, ErrSSODisabled) {
+		t.Fatalf("SSO should be disabled without identity provider, got %v", err)
+	}
+}
+
+// unavailableUsers fails every lookup, the other methods are not expected to be called
+type unavailableUsers struct {
+	UserClient
+	created bool
+}
+
+func (u *unavailableUsers) GetUserByEmail(context.Context, user.Email) (user.User, error) {
+	return user.User{}, context.DeadlineExceeded
+}
+
+func (u *unavailableUsers) Create(context.Context, user.User) (user.User, error) {
+	u.created = true
+	return user.User{}, nil
+}
+
+func TestProvisionSSOUserOnlyCreatesUnknownUsers(t *testing.T) {
+	users := &unavailableUsers{}
+	a := &authSVC{uClient: users}
+	_, err := a.provisionSSOUser(clinic.WithID(context.Background(), clinic.DefaultID), "sso@gmail.com", nil)
+	if !errors.Is(err, context.DeadlineExceeded) || users.created {
+		t.Fatalf("a failed lookup should be returned without provisioning, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+
+	utils "github.com/sopial42/cleanic/internal/adapters/rest/utils/jwt"
+	auth "github.com/sopial42/cleanic/internal/domains/auth"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+var tracer = otel.Tracer("github.com/sopial42/cleanic/internal/services/auth")
+
+type tracedService struct {
+	next Service
+}
+
+// NewTracedService wraps each method of the service in a span
+func NewTracedService(next Service) Service {
+	return &tracedService{next: next}
+}
+
+func (t *tracedService) Signup(ctx context.Context, newUser user.User) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.Signup")
+	userCreated, err := t.next.Signup(ctx, newUser)
+	end(err)
+
+	return userCreated, err
+}
+
+func (t *tracedService) Login(ctx context.Context, loginUser user.User, clientIP string) (utils.RefreshToken, utils.AccessToken, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.Login")
+	refreshToken, accessToken, err := t.next.Login(ctx, loginUser, clientIP)
+	end(err)
+
+	return refreshToken, accessToken, err
+}
+
+func (t *tracedService) Logout(ctx context.Context, userID user.ID) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.Logout")
+	err := t.next.Logout(ctx, userID)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) Refresh(ctx context.Context, signedToken utils.SignedRefreshToken) (utils.RefreshToken, utils.AccessToken, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.Refresh")
+	refreshToken, accessToken, err := t.next.Refresh(ctx, signedToken)
+	end(err)
+
+	return refreshToken, accessToken, err
+}
+
+func (t *tracedService) RotatePassword(ctx context.Context, loginUser user.User, newPassword user.Password, clientIP string) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.RotatePassword")
+	err := t.next.RotatePassword(ctx, loginUser, newPassword, clientIP)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) StartSSO(ctx context.Context) (auth.SSOChallenge, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.StartSSO")
+	challenge, err := t.next.StartSSO(ctx)
+	end(err)
+
+	return challenge, err
+}
+
+func (t *tracedService) CompleteSSO(ctx context.Context, challenge auth.SSOChallenge, state string, code string) (utils.RefreshToken, utils.AccessToken, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.CompleteSSO")
+	refreshToken, accessToken, err := t.next.CompleteSSO(ctx, challenge, state, code)
+	end(err)
+
+	return refreshToken, accessToken, err
+}
+
+func (t *tracedService) Unlock(ctx context.Context, email user.Email, clientIP string) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.Unlock")
+	err := t.next.Unlock(ctx, email, clientIP)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) SwitchClinic(ctx context.Context, userID user.ID, clinicID clinic.ID) (utils.RefreshToken, utils.AccessToken, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.SwitchClinic")
+	refreshToken, accessToken, err := t.next.SwitchClinic(ctx, userID, clinicID)
+	end(err)
+
+	return refreshToken, accessToken, err
+}
+
+func (t *tracedService) PurgeExpiredSessions(ctx context.Context) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.PurgeExpiredSessions")
+	err := t.next.PurgeExpiredSessions(ctx)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) SessionStats(ctx context.Context, since time.Time) (auth.SessionStats, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "authSVC.SessionStats")
+	stats, err := t.next.SessionStats(ctx, since)
+	end(err)
+
+	return stats, err
+}
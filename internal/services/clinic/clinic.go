@@ -0,0 +1,79 @@
+package clinic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// ErrNotMember is returned when a user acts in a clinic it does not belong to
+var ErrNotMember = errors.New("not a member of the clinic")
+
+type clinicSVC struct {
+	persistence Persistence
+	roles       RoleClient
+}
+
+func NewClinicService(persistence Persistence, roles RoleClient) Service {
+	return &clinicSVC{
+		persistence: persistence,
+		roles:       roles,
+	}
+}
+
+func (c *clinicSVC) CreateClinic(ctx context.Context, reqUserID user.ID, newClinic clinic.Clinic) (clinic.Clinic, error) {
+	if !newClinic.IsValid() {
+		return clinic.Clinic{}, fmt.Errorf("unable to create clinic, invalid name: %q", newClinic.Name)
+	}
+
+	clinicCreated, err := c.persistence.InsertClinic(ctx, newClinic, reqUserID, user.Roles{user.RoleAdmin})
+	if err != nil {
+		return clinic.Clinic{}, fmt.Errorf("unable to create clinic: %w", err)
+	}
+
+	return clinicCreated, nil
+}
+
+func (c *clinicSVC) ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error) {
+	memberships, err := c.persistence.ListMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return memberships, nil
+}
+
+func (c *clinicSVC) GetMembership(ctx context.Context, userID user.ID, clinicID clinic.ID) (clinic.Membership, error) {
+	memberships, err := c.persistence.ListMemberships(ctx, userID)
+	if err != nil {
+		return clinic.Membership{}, err
+	}
+
+	for _, membership := range memberships {
+		if membership.Clinic.ID == clinicID {
+			return membership, nil
+		}
+	}
+
+	return clinic.Membership{}, fmt.Errorf("%w: user %d, clinic %d", ErrNotMember, userID, clinicID)
+}
+
+func (c *clinicSVC) AddMember(ctx context.Context, email user.Email, roles user.Roles) (clinic.Membership, error) {
+	if !roles.AreValid() {
+		return clinic.Membership{}, fmt.Errorf("unable to add member, invalid roles: %v", roles)
+	}
+
+	if err := c.roles.EnsureRolesExist(ctx, roles); err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to add member: %w", err)
+	}
+
+	membership, err := c.persistence.InsertMember(ctx, email, roles)
+	if err != nil {
+		return clinic.Membership{}, fmt.Errorf("unable to add member: %w", err)
+	}
+
+	return membership, nil
+}
@@ -0,0 +1,32 @@
+package clinic
+
+import (
+	"context"
+
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type Service interface {
+	// CreateClinic makes the requesting user the admin of the new clinic
+	CreateClinic(ctx context.Context, reqUserID user.ID, newClinic clinic.Clinic) (clinic.Clinic, error)
+	// ListMemberships returns the clinics of a user with its roles in each of them, oldest clinic first
+	ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error)
+	// GetMembership returns ErrNotMember when the user does not belong to the clinic
+	GetMembership(ctx context.Context, userID user.ID, clinicID clinic.ID) (clinic.Membership, error)
+	// AddMember gives an existing user a role set in the context clinic
+	AddMember(ctx context.Context, email user.Email, roles user.Roles) (clinic.Membership, error)
+}
+
+type Persistence interface {
+	// InsertClinic creates the clinic along with the membership of its founder and a copy of the builtin roles
+	InsertClinic(ctx context.Context, newClinic clinic.Clinic, founderID user.ID, founderRoles user.Roles) (clinic.Clinic, error)
+	ListMemberships(ctx context.Context, userID user.ID) ([]clinic.Membership, error)
+	// InsertMember adds the user with the given email to the context clinic
+	InsertMember(ctx context.Context, email user.Email, roles user.Roles) (clinic.Membership, error)
+}
+
+// RoleClient checks the roles against the ones stored by the role service
+type RoleClient interface {
+	EnsureRolesExist(ctx context.Context, roles user.Roles) error
+}
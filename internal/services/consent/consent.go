@@ -0,0 +1,144 @@
+package consent
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/services/transaction"
+)
+
+// maxDocumentRefLength fits a path or an URL to the signed form
+const maxDocumentRefLength = 512
+
+var (
+	ErrInvalidConsent  = errors.New("invalid consent")
+	ErrPatientNotFound = errors.New("patient not found")
+)
+
+type consentService struct {
+	persistence Persistence
+	events      EventClient
+	uow         transaction.UnitOfWork
+}
+
+func NewConsentService(persistence Persistence, events EventClient, uow transaction.UnitOfWork) Service {
+	return &consentService{
+		persistence: persistence,
+		events:      events,
+		uow:         uow,
+	}
+}
+
+func (c *consentService) RecordConsent(ctx context.Context, newConsent consent.Consent) (consent.Consent, error) {
+	if err := validateConsent(newConsent); err != nil {
+		return consent.Consent{}, fmt.Errorf("unable to record consent: %w", err)
+	}
+
+	newConsent.RecordedAt = time.Now().UTC()
+	var consentRecorded consent.Consent
+	err := c.uow.Do(ctx, func(ctx context.Context) error {
+		if err := c.checkPatient(ctx, newConsent.PatientID); err != nil {
+			return err
+		}
+
+		var err error
+		consentRecorded, err = c.persistence.InsertConsent(ctx, newConsent)
+		if err != nil {
+			return fmt.Errorf("unable to record consent: %w", err)
+		}
+
+		return c.events.Emit(ctx, event.TypePatientConsentRecorded, consentRecorded)
+	})
+	if err != nil {
+		return consent.Consent{}, err
+	}
+
+	return consentRecorded, nil
+}
+
+func (c *consentService) ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error) {
+	if err := c.checkPatient(ctx, patientID); err != nil {
+		return nil, err
+	}
+
+	history, err := c.persistence.ListConsents(ctx, patientID)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	return history, nil
+}
+
+func (c *consentService) CurrentConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error) {
+	history, err := c.ListConsents(ctx, patientID)
+	if err != nil {
+		return nil, err
+	}
+
+	return consent.Current(history), nil
+}
+
+func (c *consentService) AllowedPatients(ctx context.Context, purpose consent.Purpose, patientIDs []patient.ID) ([]patient.ID, error) {
+	if !purpose.IsValid() {
+		return nil, fmt.Errorf("%w: unknown purpose %q", ErrInvalidConsent, purpose)
+	}
+
+	latestConsents, err := c.persistence.ListLatestConsents(ctx, purpose)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list consents: %w", err)
+	}
+
+	latest := make(map[patient.ID]consent.Consent, len(latestConsents))
+	for _, latestConsent := range latestConsents {
+		latest[latestConsent.PatientID] = latestConsent
+	}
+
+	allowed := []patient.ID{}
+	for _, patientID := range patientIDs {
+		var patientConsent *consent.Consent
+		if latestConsent, found := latest[patientID]; found {
+			patientConsent = &latestConsent
+		}
+
+		if purpose.Allows(patientConsent) {
+			allowed = append(allowed, patientID)
+		}
+	}
+
+	return allowed, nil
+}
+
+func (c *consentService) checkPatient(ctx context.Context, patientID patient.ID) error {
+	err := c.persistence.CheckPatient(ctx, patientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrPatientNotFound, patientID)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to get patient %d: %w", patientID, err)
+	}
+
+	return nil
+}
+
+func validateConsent(newConsent consent.Consent) error {
+	if !newConsent.Purpose.IsValid() {
+		return fmt.Errorf("%w: unknown purpose %q", ErrInvalidConsent, newConsent.Purpose)
+	}
+
+	if !newConsent.Status.IsValid() {
+		return fmt.Errorf("%w: status must be %s or %s", ErrInvalidConsent, consent.StatusGranted, consent.StatusRevoked)
+	}
+
+	if len(newConsent.DocumentRef) > maxDocumentRefLength {
+		return fmt.Errorf("%w: document_ref exceeds %d characters", ErrInvalidConsent, maxDocumentRefLength)
+	}
+
+	return nil
+}
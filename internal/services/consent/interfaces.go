@@ -0,0 +1,38 @@
+package consent
+
+import (
+	"context"
+
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+)
+
+// Service records the consents of the patients of the context clinic
+type Service interface {
+	// RecordConsent appends a grant or a revocation to the history of the patient
+	RecordConsent(ctx context.Context, newConsent consent.Consent) (consent.Consent, error)
+	// ListConsents returns the history of the patient, newest first
+	ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error)
+	// CurrentConsents returns the latest consent of the patient for each purpose recorded
+	CurrentConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error)
+	// AllowedPatients is the enforcement hook of the processings, such as the data-sharing exports:
+	// it keeps the patients whose consents allow the purpose, in the given order
+	AllowedPatients(ctx context.Context, purpose consent.Purpose, patientIDs []patient.ID) ([]patient.ID, error)
+}
+
+type Persistence interface {
+	// The consents are scoped to the context clinic
+	InsertConsent(ctx context.Context, newConsent consent.Consent) (consent.Consent, error)
+	// ListConsents returns the consents of the patient, newest first
+	ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error)
+	// ListLatestConsents returns the latest consent for the purpose of each patient who has one
+	ListLatestConsents(ctx context.Context, purpose consent.Purpose) ([]consent.Consent, error)
+	// CheckPatient returns sql.ErrNoRows when the patient is not one of the clinic
+	CheckPatient(ctx context.Context, patientID patient.ID) error
+}
+
+// EventClient records the domain events in the outbox, with the ctx of the unit of work of the change
+type EventClient interface {
+	Emit(ctx context.Context, eventType event.Type, payload any) error
+}
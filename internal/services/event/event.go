@@ -0,0 +1,96 @@
+package event
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/config"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+type eventService struct {
+	persistence  Persistence
+	sinks        []Sink
+	eventsConfig config.EventsConfig
+}
+
+func NewEventService(persistence Persistence, sinks []Sink, eventsConfig config.EventsConfig) Service {
+	return &eventService{
+		persistence:  persistence,
+		sinks:        sinks,
+		eventsConfig: eventsConfig,
+	}
+}
+
+func (e *eventService) Emit(ctx context.Context, eventType event.Type, payload any) error {
+	// no dispatcher would ever empty the outbox
+	if len(e.sinks) == 0 {
+		return nil
+	}
+
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to emit %s: %w", eventType, err)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to marshal %s payload: %w", eventType, err)
+	}
+
+	now := time.Now().UTC()
+	_, err = e.persistence.Insert(ctx, event.Event{
+		Type:          eventType,
+		ClinicID:      clinicID,
+		Payload:       body,
+		OccurredAt:    now,
+		NextAttemptAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to emit %s: %w", eventType, err)
+	}
+
+	return nil
+}
+
+func (e *eventService) Dispatch(ctx context.Context) (int, error) {
+	dueEvents, err := e.persistence.ClaimDue(ctx, time.Now().UTC(), tools.ClaimLease, e.eventsConfig.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("unable to claim due events: %w", err)
+	}
+
+	var errs []error
+	for _, due := range dueEvents {
+		if err := e.publish(ctx, due); err != nil {
+			errs = append(errs, err)
+			attempts := due.Attempts + 1
+			nextAttemptAt := time.Now().UTC().Add(tools.BackoffDelay(e.eventsConfig.RetryBase, e.eventsConfig.RetryMax, attempts))
+			if err := e.persistence.Reschedule(ctx, due.ID, attempts, nextAttemptAt, err.Error()); err != nil {
+				errs = append(errs, fmt.Errorf("unable to reschedule event %d: %w", due.ID, err))
+			}
+			continue
+		}
+
+		if err := e.persistence.Delete(ctx, due.ID); err != nil {
+			errs = append(errs, fmt.Errorf("unable to delete delivered event %d: %w", due.ID, err))
+		}
+	}
+
+	return len(dueEvents), errors.Join(errs...)
+}
+
+// publish stops at the first failing sink, the retry publishes the event to every sink again
+func (e *eventService) publish(ctx context.Context, due event.Event) error {
+	for _, sink := range e.sinks {
+		if err := sink.Publish(ctx, due.Envelope()); err != nil {
+			return fmt.Errorf("unable to publish event %d to %s: %w", due.ID, sink.Name(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+)
+
+type Service interface {
+	// Emit records the event in the outbox, ctx must be the one of the unit of work making the change
+	// so that the event is stored if and only if the change is committed
+	Emit(ctx context.Context, eventType event.Type, payload any) error
+	// Dispatch publishes a batch of due events to every sink, it returns how many events it handled.
+	// A failed event is retried later with an exponential backoff, its error is returned along the others
+	Dispatch(ctx context.Context) (int, error)
+}
+
+type Persistence interface {
+	Insert(ctx context.Context, newEvent event.Event) (event.Event, error)
+	// ClaimDue leases up to limit events due at now, oldest first, the other dispatchers skip them until the lease ends
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]event.Event, error)
+	// Delete removes a delivered event
+	Delete(ctx context.Context, id event.ID) error
+	// Reschedule records a failed delivery and releases the lease
+	Reschedule(ctx context.Context, id event.ID, attempts int, nextAttemptAt time.Time, lastError string) error
+}
+
+// Sink delivers the events to another system, it must return once the event is acknowledged
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, envelope event.Envelope) error
+}
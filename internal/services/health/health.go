@@ -0,0 +1,110 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/health"
+)
+
+// checkTimeout bounds each readiness probe so that a hanging DB does not hang the probe
+const checkTimeout = 2 * time.Second
+
+type healthSVC struct {
+	persistence       Persistence
+	expectedMigration string
+	logger            *slog.Logger
+	shuttingDown      atomic.Bool
+	buildInfo         health.BuildInfo
+}
+
+// NewHealthService checks that expectedMigration is applied, an empty one disables the check.
+// The failures are logged, the unauthenticated probes only tell which check is down
+func NewHealthService(persistence Persistence, expectedMigration string, logger *slog.Logger) Service {
+	return &healthSVC{
+		persistence:       persistence,
+		expectedMigration: expectedMigration,
+		logger:            logger,
+		buildInfo:         readBuildInfo(),
+	}
+}
+
+func (h *healthSVC) Readiness(ctx context.Context) health.Report {
+	checks := map[string]health.Check{
+		"shutdown": {Status: health.StatusUp},
+	}
+
+	if h.shuttingDown.Load() {
+		checks["shutdown"] = health.Check{Status: health.StatusDown, Error: "shutdown in progress"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	checks["database"] = h.toCheck(ctx, "database", h.persistence.Ping(ctx), "database unreachable")
+	if h.expectedMigration != "" {
+		checks["migrations"] = h.toCheck(ctx, "migrations", h.checkMigration(ctx), "migrations not applied")
+	}
+
+	return health.NewReport(checks)
+}
+
+func (h *healthSVC) BuildInfo() health.BuildInfo {
+	return h.buildInfo
+}
+
+func (h *healthSVC) MarkShuttingDown() {
+	h.shuttingDown.Store(true)
+}
+
+func (h *healthSVC) checkMigration(ctx context.Context) error {
+	applied, err := h.persistence.MigrationApplied(ctx, h.expectedMigration)
+	if err != nil {
+		return err
+	}
+
+	if !applied {
+		return fmt.Errorf("migration %s is not applied", h.expectedMigration)
+	}
+
+	return nil
+}
+
+// toCheck keeps the driver and network errors, which may name the hosts and the credentials, out of the report
+func (h *healthSVC) toCheck(ctx context.Context, name string, err error, failure string) health.Check {
+	if err != nil {
+		h.logger.ErrorContext(ctx, "Readiness check failed", "check", name, "error", err)
+		return health.Check{Status: health.StatusDown, Error: failure}
+	}
+
+	return health.Check{Status: health.StatusUp}
+}
+
+func readBuildInfo() health.BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return health.BuildInfo{Version: "unknown"}
+	}
+
+	buildInfo := health.BuildInfo{
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			buildInfo.Revision = setting.Value
+		case "vcs.time":
+			buildInfo.BuildTime = setting.Value
+		case "vcs.modified":
+			buildInfo.Modified = setting.Value == "true"
+		}
+	}
+
+	return buildInfo
+}
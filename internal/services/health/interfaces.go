@@ -0,0 +1,21 @@
+package health
+
+import (
+	"context"
+
+	"github.com/sopial42/cleanic/internal/domains/health"
+)
+
+type Service interface {
+	// Readiness tells whether the server can take traffic
+	Readiness(ctx context.Context) health.Report
+	BuildInfo() health.BuildInfo
+	// MarkShuttingDown makes the readiness fail so that the orchestrator stops routing traffic
+	MarkShuttingDown()
+}
+
+type Persistence interface {
+	Ping(ctx context.Context) error
+	// MigrationApplied tells whether the migration is recorded as applied
+	MigrationApplied(ctx context.Context, migrationID string) (bool, error)
+}
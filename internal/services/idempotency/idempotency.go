@@ -0,0 +1,99 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+)
+
+var (
+	// ErrKeyReused is returned when a key comes back with another request
+	ErrKeyReused = errors.New("idempotency key already used for another request")
+	// ErrRequestInProgress is returned while the first request with the key is not completed
+	ErrRequestInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+type idempotencySVC struct {
+	persistence Persistence
+	ttl         time.Duration
+}
+
+// NewIdempotencyService keeps the keys for ttl, a retry after that is processed as a new request
+func NewIdempotencyService(persistence Persistence, ttl time.Duration) Service {
+	return &idempotencySVC{persistence: persistence, ttl: ttl}
+}
+
+func (i *idempotencySVC) Begin(ctx context.Context, subject string, key string, fingerprint string) (idempotency.Record, bool, error) {
+	now := time.Now()
+	record := idempotency.Record{
+		Subject:     subject,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(i.ttl),
+	}
+
+	// the second attempt follows the deletion of an expired record
+	for attempt := 0; attempt < 2; attempt++ {
+		inserted, err := i.persistence.InsertRecord(ctx, record)
+		if err != nil {
+			return idempotency.Record{}, false, fmt.Errorf("unable to reserve idempotency key: %w", err)
+		}
+
+		if inserted {
+			return record, true, nil
+		}
+
+		existing, err := i.persistence.GetRecord(ctx, subject, key)
+		if err != nil {
+			return idempotency.Record{}, false, fmt.Errorf("unable to get idempotency key: %w", err)
+		}
+
+		if existing.IsExpired(now) {
+			if err := i.persistence.DeleteRecord(ctx, subject, key); err != nil {
+				return idempotency.Record{}, false, fmt.Errorf("unable to delete expired idempotency key: %w", err)
+			}
+			continue
+		}
+
+		if existing.Fingerprint != fingerprint {
+			return idempotency.Record{}, false, ErrKeyReused
+		}
+
+		if !existing.Completed {
+			return idempotency.Record{}, false, ErrRequestInProgress
+		}
+
+		return existing, false, nil
+	}
+
+	return idempotency.Record{}, false, ErrRequestInProgress
+}
+
+func (i *idempotencySVC) Complete(ctx context.Context, record idempotency.Record) error {
+	record.Completed = true
+	if err := i.persistence.CompleteRecord(ctx, record); err != nil {
+		return fmt.Errorf("unable to store idempotent response: %w", err)
+	}
+
+	return nil
+}
+
+func (i *idempotencySVC) Abort(ctx context.Context, subject string, key string) error {
+	if err := i.persistence.DeleteRecord(ctx, subject, key); err != nil {
+		return fmt.Errorf("unable to release idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+func (i *idempotencySVC) PurgeExpired(ctx context.Context) error {
+	if err := i.persistence.DeleteExpiredRecords(ctx, time.Now()); err != nil {
+		return fmt.Errorf("unable to purge idempotency keys: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/idempotency"
+)
+
+type Service interface {
+	// Begin reserves the key for a new request, or returns the completed record to replay with started false
+	Begin(ctx context.Context, subject string, key string, fingerprint string) (record idempotency.Record, started bool, err error)
+	// Complete stores the response to replay on retries
+	Complete(ctx context.Context, record idempotency.Record) error
+	// Abort releases the key so that the request can be retried, e.g. after a server error
+	Abort(ctx context.Context, subject string, key string) error
+	// PurgeExpired deletes the expired keys, it is run by a scheduled job
+	PurgeExpired(ctx context.Context) error
+}
+
+type Persistence interface {
+	// InsertRecord returns false when the key is already used by the subject
+	InsertRecord(ctx context.Context, record idempotency.Record) (bool, error)
+	GetRecord(ctx context.Context, subject string, key string) (idempotency.Record, error)
+	CompleteRecord(ctx context.Context, record idempotency.Record) error
+	DeleteRecord(ctx context.Context, subject string, key string) error
+	DeleteExpiredRecords(ctx context.Context, now time.Time) error
+}
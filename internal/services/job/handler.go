@@ -0,0 +1,30 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	job "github.com/sopial42/cleanic/internal/domains/job"
+)
+
+// Handler runs the jobs of a kind. ctx carries the clinic of the job, if any, and ends with the run timeout.
+// An error retries the job unless it wraps ErrPermanent
+type Handler interface {
+	Handle(ctx context.Context, j job.Job) error
+}
+
+// HandlerFunc is a Handler decoding the payload of the job into T, the type given to Enqueue.
+// A payload which does not decode is a permanent error
+type HandlerFunc[T any] func(ctx context.Context, payload T) error
+
+func (f HandlerFunc[T]) Handle(ctx context.Context, j job.Job) error {
+	var payload T
+	if len(j.Payload) > 0 {
+		if err := json.Unmarshal(j.Payload, &payload); err != nil {
+			return fmt.Errorf("%w: unable to decode the payload of job %d: %v", ErrPermanent, j.ID, err)
+		}
+	}
+
+	return f(ctx, payload)
+}
@@ -0,0 +1,38 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	job "github.com/sopial42/cleanic/internal/domains/job"
+)
+
+// Service runs the background jobs. The handlers and the schedules are set up before the workers start
+type Service interface {
+	// Register binds the handler to the kind, the jobs of a kind without handler cannot be enqueued
+	Register(kind job.Kind, handler Handler)
+	// Schedule enqueues a job of the kind, without payload, at every tick of the schedule.
+	// Every instance enqueues the ticks, a tick is enqueued once whatever the number of instances
+	Schedule(kind job.Kind, schedule job.Schedule)
+
+	// Enqueue records a job of the kind due at runAt, it runs for the context clinic when there is one.
+	// ctx may be the one of a unit of work, the job is then enqueued if and only if the change is committed
+	Enqueue(ctx context.Context, kind job.Kind, payload any, runAt time.Time) (job.Job, error)
+	// Work enqueues the due ticks of the schedules, then runs a batch of due jobs at once and returns how many it ran.
+	// The errors of the failed runs are returned along the others, the jobs are retried with an exponential backoff
+	Work(ctx context.Context) (int, error)
+	// ListJobs returns the latest jobs of the context clinic and the system jobs, state and kind filter them when set
+	ListJobs(ctx context.Context, state job.State, kind job.Kind) ([]job.Job, error)
+}
+
+type Persistence interface {
+	// InsertJob returns false, and no job, when the unique key of the job is already used
+	InsertJob(ctx context.Context, newJob job.Job) (job.Job, bool, error)
+	// ClaimDueJobs moves up to limit due jobs of every clinic to running, oldest first, and counts their attempt.
+	// A running job whose lease ended, its worker being stopped meanwhile, is due again
+	ClaimDueJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]job.Job, error)
+	// FinishRun records the outcome of a run and releases the lease, runAt is when a queued job is due again
+	FinishRun(ctx context.Context, id job.ID, state job.State, runAt time.Time, lastError string, finishedAt *time.Time) error
+	// ListJobs returns up to limit jobs of the context clinic and the system jobs, newest first
+	ListJobs(ctx context.Context, state job.State, kind job.Kind, limit int) ([]job.Job, error)
+}
@@ -0,0 +1,201 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/config"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	job "github.com/sopial42/cleanic/internal/domains/job"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+const (
+	// leaseMargin is added to the run timeout, the jobs of a worker stopped meanwhile are claimed again once the lease ends
+	leaseMargin = time.Minute
+	// jobsListed bounds the dashboard to the latest jobs
+	jobsListed = 100
+)
+
+var (
+	// ErrPermanent fails a job without retrying it, e.g. when its payload is invalid
+	ErrPermanent   = errors.New("permanent job failure")
+	ErrUnknownKind = errors.New("unknown job kind")
+)
+
+type jobService struct {
+	persistence Persistence
+	jobsConfig  config.JobsConfig
+	handlers    map[job.Kind]Handler
+	schedules   []*scheduled
+	// mu guards the next ticks of the schedules
+	mu sync.Mutex
+}
+
+// scheduled is a schedule along with its next tick, zero until the first work
+type scheduled struct {
+	kind     job.Kind
+	schedule job.Schedule
+	next     time.Time
+}
+
+func NewJobService(persistence Persistence, jobsConfig config.JobsConfig) Service {
+	return &jobService{
+		persistence: persistence,
+		jobsConfig:  jobsConfig,
+		handlers:    map[job.Kind]Handler{},
+	}
+}
+
+func (j *jobService) Register(kind job.Kind, handler Handler) {
+	j.handlers[kind] = handler
+}
+
+func (j *jobService) Schedule(kind job.Kind, schedule job.Schedule) {
+	j.schedules = append(j.schedules, &scheduled{kind: kind, schedule: schedule})
+}
+
+func (j *jobService) Enqueue(ctx context.Context, kind job.Kind, payload any, runAt time.Time) (job.Job, error) {
+	if _, found := j.handlers[kind]; !found {
+		return job.Job{}, fmt.Errorf("unable to enqueue job: %w %q", ErrUnknownKind, kind)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return job.Job{}, fmt.Errorf("unable to marshal %s payload: %w", kind, err)
+	}
+
+	// the system jobs have no clinic
+	clinicID, _ := clinic.IDFromContext(ctx)
+	jobEnqueued, _, err := j.persistence.InsertJob(ctx, j.newJob(kind, clinicID, body, "", runAt))
+	if err != nil {
+		return job.Job{}, fmt.Errorf("unable to enqueue %s job: %w", kind, err)
+	}
+
+	return jobEnqueued, nil
+}
+
+func (j *jobService) newJob(kind job.Kind, clinicID clinic.ID, payload []byte, uniqueKey string, runAt time.Time) job.Job {
+	return job.Job{
+		Kind:        kind,
+		ClinicID:    clinicID,
+		Payload:     payload,
+		UniqueKey:   uniqueKey,
+		State:       job.StateQueued,
+		MaxAttempts: j.jobsConfig.MaxAttempts,
+		RunAt:       runAt.UTC(),
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+func (j *jobService) Work(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	if err := j.enqueueTicks(ctx, now); err != nil {
+		return 0, err
+	}
+
+	dueJobs, err := j.persistence.ClaimDueJobs(ctx, now, j.jobsConfig.Timeout+leaseMargin, j.jobsConfig.Concurrency)
+	if err != nil {
+		return 0, fmt.Errorf("unable to claim due jobs: %w", err)
+	}
+
+	errs := make([]error, len(dueJobs))
+	var wg sync.WaitGroup
+	for i, due := range dueJobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = j.run(ctx, due)
+		}()
+	}
+	wg.Wait()
+
+	return len(dueJobs), errors.Join(errs...)
+}
+
+// enqueueTicks enqueues the ticks due at now, the ticks missed while the instance was busy or stopped are skipped.
+// The unique key of a tick lets a single instance enqueue it
+func (j *jobService) enqueueTicks(ctx context.Context, now time.Time) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var errs []error
+	for _, s := range j.schedules {
+		if s.next.IsZero() {
+			s.next = s.schedule.Next(now)
+		}
+
+		if s.next.IsZero() || s.next.After(now) {
+			continue
+		}
+
+		uniqueKey := fmt.Sprintf("%s@%s", s.kind, s.next.Format(time.RFC3339))
+		if _, _, err := j.persistence.InsertJob(ctx, j.newJob(s.kind, 0, []byte("null"), uniqueKey, s.next)); err != nil {
+			errs = append(errs, fmt.Errorf("unable to enqueue scheduled %s job: %w", s.kind, err))
+			continue
+		}
+
+		s.next = s.schedule.Next(now)
+	}
+
+	return errors.Join(errs...)
+}
+
+// run calls the handler of the job and records its outcome, the failure is returned along the recording one
+func (j *jobService) run(ctx context.Context, due job.Job) error {
+	runErr := j.handle(ctx, due)
+
+	now := time.Now().UTC()
+	state, runAt, lastError, finishedAt := job.StateSucceeded, due.RunAt, "", &now
+	if runErr != nil {
+		state, runAt, lastError, finishedAt = job.StateQueued, now.Add(tools.BackoffDelay(j.jobsConfig.RetryBase, j.jobsConfig.RetryMax, due.Attempts)), runErr.Error(), nil
+		if errors.Is(runErr, ErrPermanent) || due.Attempts >= due.MaxAttempts {
+			state, runAt, finishedAt = job.StateDead, due.RunAt, &now
+		}
+
+		runErr = fmt.Errorf("job %d of kind %s failed on attempt %d: %w", due.ID, due.Kind, due.Attempts, runErr)
+	}
+
+	if err := j.persistence.FinishRun(ctx, due.ID, state, runAt, lastError, finishedAt); err != nil {
+		return errors.Join(runErr, fmt.Errorf("unable to record the run of job %d: %w", due.ID, err))
+	}
+
+	return runErr
+}
+
+// handle runs the handler within the timeout, a panic fails the run
+func (j *jobService) handle(ctx context.Context, due job.Job) (err error) {
+	handler, found := j.handlers[due.Kind]
+	if !found {
+		// an instance running a newer version may know it
+		return fmt.Errorf("%w %q", ErrUnknownKind, due.Kind)
+	}
+
+	if due.ClinicID != 0 {
+		ctx = clinic.WithID(ctx, due.ClinicID)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, j.jobsConfig.Timeout)
+	defer cancel()
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+
+	return handler.Handle(ctx, due)
+}
+
+func (j *jobService) ListJobs(ctx context.Context, state job.State, kind job.Kind) ([]job.Job, error) {
+	jobs, err := j.persistence.ListJobs(ctx, state, kind, jobsListed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list jobs: %w", err)
+	}
+
+	return jobs, nil
+}
@@ -0,0 +1,164 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/config"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	job "github.com/sopial42/cleanic/internal/domains/job"
+)
+
+// queue is the smallest Persistence running the service, the contract tests cover the adapters
+type queue struct {
+	mu   sync.Mutex
+	jobs []job.Job
+}
+
+func (q *queue) InsertJob(ctx context.Context, newJob job.Job) (job.Job, bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, existing := range q.jobs {
+		if newJob.UniqueKey != "" && existing.UniqueKey == newJob.UniqueKey {
+			return job.Job{}, false, nil
+		}
+	}
+
+	newJob.ID = job.ID(len(q.jobs) + 1)
+	q.jobs = append(q.jobs, newJob)
+	return newJob, true, nil
+}
+
+func (q *queue) ClaimDueJobs(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]job.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var claimed []job.Job
+	for i := range q.jobs {
+		if len(claimed) < limit && q.jobs[i].State == job.StateQueued && !q.jobs[i].RunAt.After(now) {
+			q.jobs[i].State = job.StateRunning
+			q.jobs[i].Attempts++
+			claimed = append(claimed, q.jobs[i])
+		}
+	}
+
+	return claimed, nil
+}
+
+func (q *queue) FinishRun(ctx context.Context, id job.ID, state job.State, runAt time.Time, lastError string, finishedAt *time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.jobs[id-1].State, q.jobs[id-1].RunAt, q.jobs[id-1].LastError, q.jobs[id-1].FinishedAt = state, runAt, lastError, finishedAt
+	return nil
+}
+
+func (q *queue) ListJobs(ctx context.Context, state job.State, kind job.Kind, limit int) ([]job.Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]job.Job{}, q.jobs...), nil
+}
+
+func newService(persistence Persistence) Service {
+	return NewJobService(persistence, config.JobsConfig{
+		Concurrency: 4,
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		RetryBase:   time.Millisecond,
+		RetryMax:    time.Millisecond,
+	})
+}
+
+// workAll runs the due jobs until none is left, waiting for the backoff in between
+func workAll(t *testing.T, service Service) {
+	t.Helper()
+	for range 10 {
+		handled, _ := service.Work(context.Background())
+		if handled == 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+type reminder struct {
+	PatientID int64 `json:"patient_id"`
+}
+
+func TestJobsAreRetriedUntilDead(t *testing.T) {
+	persistence := &queue{}
+	service := newService(persistence)
+
+	var runs []reminder
+	var clinicIDs []clinic.ID
+	service.Register("reminder.send", HandlerFunc[reminder](func(ctx context.Context, payload reminder) error {
+		runs = append(runs, payload)
+		clinicID, _ := clinic.IDFromContext(ctx)
+		clinicIDs = append(clinicIDs, clinicID)
+		if len(runs) < 2 {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	}))
+	service.Register("report.build", HandlerFunc[struct{}](func(ctx context.Context, _ struct{}) error {
+		panic("boom")
+	}))
+
+	ctx := clinic.WithID(context.Background(), clinic.DefaultID)
+	if _, err := service.Enqueue(ctx, "reminder.send", reminder{PatientID: 10001}, time.Now()); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := service.Enqueue(context.Background(), "report.build", nil, time.Now()); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := service.Enqueue(ctx, "unknown", nil, time.Now()); !errors.Is(err, ErrUnknownKind) {
+		t.Fatalf("a kind without handler should not be enqueued, got %v", err)
+	}
+	workAll(t, service)
+
+	if len(runs) != 2 || runs[1].PatientID != 10001 || clinicIDs[1] != clinic.DefaultID {
+		t.Fatalf("the reminder should succeed on its second run for its clinic, got %+v %v", runs, clinicIDs)
+	}
+
+	jobs, _ := service.ListJobs(ctx, "", "")
+	if jobs[0].State != job.StateSucceeded || jobs[0].Attempts != 2 || jobs[0].LastError != "" || jobs[0].FinishedAt == nil {
+		t.Fatalf("unexpected reminder job %+v", jobs[0])
+	}
+	if jobs[1].State != job.StateDead || jobs[1].Attempts != 3 || jobs[1].LastError != "handler panicked: boom" {
+		t.Fatalf("the panicking job should be dead after 3 attempts, got %+v", jobs[1])
+	}
+}
+
+func TestScheduledTicksAreEnqueuedOnce(t *testing.T) {
+	persistence := &queue{}
+	every, err := job.ParseSchedule("@every 1s")
+	if err != nil {
+		t.Fatalf("parse schedule: %v", err)
+	}
+
+	var runs int
+	instances := []Service{newService(persistence), newService(persistence)}
+	for _, instance := range instances {
+		instance.Register("purge", HandlerFunc[struct{}](func(ctx context.Context, _ struct{}) error {
+			runs++
+			return nil
+		}))
+		instance.Schedule("purge", every)
+		// the first work computes the next tick
+		if _, err := instance.Work(context.Background()); err != nil {
+			t.Fatalf("work: %v", err)
+		}
+	}
+
+	time.Sleep(time.Until(every.Next(time.Now())) + 10*time.Millisecond)
+	for _, instance := range instances {
+		if _, err := instance.Work(context.Background()); err != nil {
+			t.Fatalf("work: %v", err)
+		}
+	}
+
+	if runs != 1 || len(persistence.jobs) != 1 || persistence.jobs[0].UniqueKey == "" {
+		t.Fatalf("the tick should run once whatever the number of instances, got %d runs of %+v", runs, persistence.jobs)
+	}
+}
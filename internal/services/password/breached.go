@@ -0,0 +1,62 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // SHA-1 is the format of the breached passwords lists, not used for storage
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// breachedList holds the SHA-1 of known breached passwords, loaded once at startup
+type breachedList map[[sha1.Size]byte]struct{}
+
+// loadBreachedList reads one hex SHA-1 per line, the optional ":COUNT" suffix is ignored
+func loadBreachedList(path string) (breachedList, error) {
+	list := breachedList{}
+	if path == "" {
+		return list, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hexHash, _, _ := strings.Cut(line, ":")
+		raw, err := hex.DecodeString(hexHash)
+		if err != nil || len(raw) != sha1.Size {
+			return nil, fmt.Errorf("invalid SHA-1 at line %d", lineNumber)
+		}
+
+		list[[sha1.Size]byte(raw)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (b breachedList) contains(plain user.Password) bool {
+	if len(b) == 0 {
+		return false
+	}
+
+	_, found := b[sha1.Sum([]byte(plain))] //nolint:gosec // see import
+	return found
+}
@@ -0,0 +1,147 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type bcryptHasher struct {
+	cost int
+}
+
+func newBcryptHasher() Hasher {
+	return &bcryptHasher{cost: bcrypt.DefaultCost}
+}
+
+func (b *bcryptHasher) Hash(plain user.Password) (user.Password, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(plain), b.cost)
+	if err != nil {
+		return "", err
+	}
+
+	return user.Password(hash), nil
+}
+
+func (b *bcryptHasher) Handles(hash user.Password) bool {
+	return strings.HasPrefix(string(hash), "$2a$") ||
+		strings.HasPrefix(string(hash), "$2b$") ||
+		strings.HasPrefix(string(hash), "$2y$")
+}
+
+func (b *bcryptHasher) Verify(hash user.Password, plain user.Password) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)) == nil
+}
+
+func (b *bcryptHasher) NeedsRehash(hash user.Password) bool {
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != b.cost
+}
+
+// argon2idHasher encodes hashes in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+type argon2idHasher struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	saltLen int
+	keyLen  uint32
+}
+
+func newArgon2idHasher() Hasher {
+	// Second recommended option of RFC 9106
+	return &argon2idHasher{
+		memory:  64 * 1024,
+		time:    3,
+		threads: 2,
+		saltLen: 16,
+		keyLen:  32,
+	}
+}
+
+type argon2idParams struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (a *argon2idHasher) Hash(plain user.Password) (user.Password, error) {
+	salt := make([]byte, a.saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("unable to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(plain), salt, a.time, a.memory, a.threads, a.keyLen)
+	return user.Password(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.memory, a.time, a.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+func (a *argon2idHasher) Handles(hash user.Password) bool {
+	return strings.HasPrefix(string(hash), "$argon2id$")
+}
+
+func (a *argon2idHasher) Verify(hash user.Password, plain user.Password) bool {
+	params, err := parseArgon2idHash(hash)
+	if err != nil {
+		return false
+	}
+
+	key := argon2.IDKey([]byte(plain), params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1
+}
+
+func (a *argon2idHasher) NeedsRehash(hash user.Password) bool {
+	params, err := parseArgon2idHash(hash)
+	if err != nil {
+		return true
+	}
+
+	return params.memory != a.memory || params.time != a.time || params.threads != a.threads || uint32(len(params.key)) != a.keyLen
+}
+
+func parseArgon2idHash(hash user.Password) (argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 {
+		return argon2idParams{}, fmt.Errorf("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return argon2idParams{}, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+
+	if version != argon2.Version {
+		return argon2idParams{}, fmt.Errorf("unsupported argon2id version: %d", version)
+	}
+
+	var params argon2idParams
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return argon2idParams{}, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2idParams{}, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return argon2idParams{}, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	params.salt = salt
+	params.key = key
+	return params, nil
+}
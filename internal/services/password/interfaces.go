@@ -0,0 +1,28 @@
+package password
+
+import (
+	"time"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+type Service interface {
+	// Validate checks the policy rules and the breached passwords list
+	Validate(plain user.Password) error
+	Hash(plain user.Password) (user.Password, error)
+	// Verify compares a plain password to a hash of any supported algorithm
+	// needsRehash is true when the hash was not produced with the current hasher settings
+	Verify(hash user.Password, plain user.Password) (match bool, needsRehash bool)
+	IsExpired(changedAt time.Time) bool
+	HistorySize() int
+}
+
+// Hasher is implemented by each supported hashing algorithm
+type Hasher interface {
+	Hash(plain user.Password) (user.Password, error)
+	// Handles tells if the hash has been produced by this algorithm
+	Handles(hash user.Password) bool
+	Verify(hash user.Password, plain user.Password) bool
+	// NeedsRehash tells if the hash parameters differ from the current ones
+	NeedsRehash(hash user.Password) bool
+}
@@ -0,0 +1,101 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/config"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+var ErrBreachedPassword = errors.New("password appears in a known data breach")
+
+type passwordSVC struct {
+	policy      user.PasswordPolicy
+	historySize int
+	maxAge      time.Duration
+	breached    breachedList
+	current     Hasher
+	hashers     []Hasher
+}
+
+func NewPasswordService(cfg config.PasswordConfig) (Service, error) {
+	breached, err := loadBreachedList(cfg.BreachedHashesFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load breached passwords list: %w", err)
+	}
+
+	bcryptH := newBcryptHasher()
+	argon2idH := newArgon2idHasher()
+
+	var current Hasher
+	switch cfg.Hasher {
+	case config.PasswordHasherBcrypt:
+		current = bcryptH
+	case config.PasswordHasherArgon2id:
+		current = argon2idH
+	default:
+		return nil, fmt.Errorf("unknown password hasher: %s", cfg.Hasher)
+	}
+
+	return &passwordSVC{
+		policy: user.PasswordPolicy{
+			MinLength:     cfg.MinLength,
+			RequireUpper:  cfg.RequireUpper,
+			RequireLower:  cfg.RequireLower,
+			RequireDigit:  cfg.RequireDigit,
+			RequireSymbol: cfg.RequireSymbol,
+		},
+		historySize: cfg.HistorySize,
+		maxAge:      cfg.MaxAge,
+		breached:    breached,
+		current:     current,
+		hashers:     []Hasher{bcryptH, argon2idH},
+	}, nil
+}
+
+func (p *passwordSVC) Validate(plain user.Password) error {
+	if err := p.policy.Validate(plain); err != nil {
+		return err
+	}
+
+	if p.breached.contains(plain) {
+		return ErrBreachedPassword
+	}
+
+	return nil
+}
+
+func (p *passwordSVC) Hash(plain user.Password) (user.Password, error) {
+	hash, err := p.current.Hash(plain)
+	if err != nil {
+		return "", fmt.Errorf("unable to hash password: %w", err)
+	}
+
+	return hash, nil
+}
+
+func (p *passwordSVC) Verify(hash user.Password, plain user.Password) (bool, bool) {
+	for _, hasher := range p.hashers {
+		if !hasher.Handles(hash) {
+			continue
+		}
+
+		if !hasher.Verify(hash, plain) {
+			return false, false
+		}
+
+		return true, hasher != p.current || hasher.NeedsRehash(hash)
+	}
+
+	return false, false
+}
+
+func (p *passwordSVC) IsExpired(changedAt time.Time) bool {
+	return p.maxAge > 0 && time.Since(changedAt) > p.maxAge
+}
+
+func (p *passwordSVC) HistorySize() int {
+	return p.historySize
+}
@@ -3,12 +3,16 @@ package patient
 import (
 	"context"
 
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	event "github.com/sopial42/cleanic/internal/domains/event"
 	patient "github.com/sopial42/cleanic/internal/domains/patient"
 )
 
 type Service interface {
 	CreatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	GetPatients(ctx context.Context) ([]patient.Patient, error)
+	// GetPatientsAllowing lists the patients whose consents allow the purpose, for the exports sharing their data
+	GetPatientsAllowing(ctx context.Context, purpose consent.Purpose) ([]patient.Patient, error)
 	GetPatientByID(ctx context.Context, id int64) (patient.Patient, error)
 	UpdatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	DeletePatient(ctx context.Context, id int64) error
@@ -21,3 +25,13 @@ type Persistence interface {
 	UpdatePatient(ctx context.Context, patient patient.Patient) (patient.Patient, error)
 	DeletePatient(ctx context.Context, id int64) error
 }
+
+// ConsentClient enforces the consents of the patients
+type ConsentClient interface {
+	AllowedPatients(ctx context.Context, purpose consent.Purpose, patientIDs []patient.ID) ([]patient.ID, error)
+}
+
+// EventClient records the domain events in the outbox, with the ctx of the unit of work of the change
+type EventClient interface {
+	Emit(ctx context.Context, eventType event.Type, payload any) error
+}
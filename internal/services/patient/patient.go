@@ -2,17 +2,32 @@ package patient
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	event "github.com/sopial42/cleanic/internal/domains/event"
 	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/services/transaction"
 )
 
+// ErrPatientNotFound is also returned for the patients of another clinic
+var ErrPatientNotFound = errors.New("patient not found")
+
 type patientService struct {
 	persistence Persistence
+	events      EventClient
+	consents    ConsentClient
+	uow         transaction.UnitOfWork
 }
 
-func NewPatientService(persistence Persistence) Service {
+func NewPatientService(persistence Persistence, events EventClient, consents ConsentClient, uow transaction.UnitOfWork) Service {
 	return &patientService{
 		persistence: persistence,
+		events:      events,
+		consents:    consents,
+		uow:         uow,
 	}
 }
 
@@ -25,8 +40,43 @@ func (p *patientService) GetPatients(ctx context.Context) ([]patient.Patient, er
 	return patients, nil
 }
 
+func (p *patientService) GetPatientsAllowing(ctx context.Context, purpose consent.Purpose) ([]patient.Patient, error) {
+	patients, err := p.persistence.ListPatients(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]patient.ID, 0, len(patients))
+	for _, listed := range patients {
+		ids = append(ids, listed.ID)
+	}
+
+	allowedIDs, err := p.consents.AllowedPatients(ctx, purpose, ids)
+	if err != nil {
+		return nil, fmt.Errorf("unable to check consents: %w", err)
+	}
+
+	isAllowed := make(map[patient.ID]bool, len(allowedIDs))
+	for _, id := range allowedIDs {
+		isAllowed[id] = true
+	}
+
+	allowed := make([]patient.Patient, 0, len(allowedIDs))
+	for _, listed := range patients {
+		if isAllowed[listed.ID] {
+			allowed = append(allowed, listed)
+		}
+	}
+
+	return allowed, nil
+}
+
 func (p *patientService) GetPatientByID(ctx context.Context, id int64) (patient.Patient, error) {
 	currentPatient, err := p.persistence.GetPatientByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return patient.Patient{}, fmt.Errorf("%w: %d", ErrPatientNotFound, id)
+	}
+
 	if err != nil {
 		return patient.Patient{}, err
 	}
@@ -35,7 +85,16 @@ func (p *patientService) GetPatientByID(ctx context.Context, id int64) (patient.
 }
 
 func (p *patientService) CreatePatient(ctx context.Context, inputPatient patient.Patient) (patient.Patient, error) {
-	patientCreated, err := p.persistence.InsertPatient(ctx, inputPatient)
+	var patientCreated patient.Patient
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		patientCreated, err = p.persistence.InsertPatient(ctx, inputPatient)
+		if err != nil {
+			return err
+		}
+
+		return p.events.Emit(ctx, event.TypePatientCreated, patientCreated)
+	})
 	if err != nil {
 		return patient.Patient{}, err
 	}
@@ -44,7 +103,20 @@ func (p *patientService) CreatePatient(ctx context.Context, inputPatient patient
 }
 
 func (p *patientService) UpdatePatient(ctx context.Context, inputPatient patient.Patient) (patient.Patient, error) {
-	patientUpdated, err := p.persistence.UpdatePatient(ctx, inputPatient)
+	var patientUpdated patient.Patient
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		patientUpdated, err = p.persistence.UpdatePatient(ctx, inputPatient)
+		if err != nil {
+			return err
+		}
+
+		return p.events.Emit(ctx, event.TypePatientUpdated, patientUpdated)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return patient.Patient{}, fmt.Errorf("%w: %d", ErrPatientNotFound, inputPatient.ID)
+	}
+
 	if err != nil {
 		return patient.Patient{}, err
 	}
@@ -53,7 +125,22 @@ func (p *patientService) UpdatePatient(ctx context.Context, inputPatient patient
 }
 
 func (p *patientService) DeletePatient(ctx context.Context, id int64) error {
-	err := p.persistence.DeletePatient(ctx, id)
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		// the persistence deletes the missing patients silently
+		if _, err := p.persistence.GetPatientByID(ctx, id); err != nil {
+			return err
+		}
+
+		if err := p.persistence.DeletePatient(ctx, id); err != nil {
+			return err
+		}
+
+		return p.events.Emit(ctx, event.TypePatientDeleted, event.PatientDeleted{ID: id})
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrPatientNotFound, id)
+	}
+
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,70 @@
+package patient
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+var tracer = otel.Tracer("github.com/sopial42/cleanic/internal/services/patient")
+
+type tracedService struct {
+	next Service
+}
+
+// NewTracedService wraps each method of the service in a span
+func NewTracedService(next Service) Service {
+	return &tracedService{next: next}
+}
+
+func (t *tracedService) CreatePatient(ctx context.Context, inputPatient patient.Patient) (patient.Patient, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "patientSVC.CreatePatient")
+	patientCreated, err := t.next.CreatePatient(ctx, inputPatient)
+	end(err)
+
+	return patientCreated, err
+}
+
+func (t *tracedService) GetPatients(ctx context.Context) ([]patient.Patient, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "patientSVC.GetPatients")
+	patients, err := t.next.GetPatients(ctx)
+	end(err)
+
+	return patients, err
+}
+
+func (t *tracedService) GetPatientsAllowing(ctx context.Context, purpose consent.Purpose) ([]patient.Patient, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "patientSVC.GetPatientsAllowing")
+	patients, err := t.next.GetPatientsAllowing(ctx, purpose)
+	end(err)
+
+	return patients, err
+}
+
+func (t *tracedService) GetPatientByID(ctx context.Context, id int64) (patient.Patient, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "patientSVC.GetPatientByID")
+	patientFound, err := t.next.GetPatientByID(ctx, id)
+	end(err)
+
+	return patientFound, err
+}
+
+func (t *tracedService) UpdatePatient(ctx context.Context, inputPatient patient.Patient) (patient.Patient, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "patientSVC.UpdatePatient")
+	patientUpdated, err := t.next.UpdatePatient(ctx, inputPatient)
+	end(err)
+
+	return patientUpdated, err
+}
+
+func (t *tracedService) DeletePatient(ctx context.Context, id int64) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "patientSVC.DeletePatient")
+	err := t.next.DeletePatient(ctx, id)
+	end(err)
+
+	return err
+}
@@ -0,0 +1,56 @@
+package privacy
+
+import (
+	"context"
+
+	consent "github.com/sopial42/cleanic/internal/domains/consent"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	privacy "github.com/sopial42/cleanic/internal/domains/privacy"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+)
+
+// Service answers the data subject requests of the patients of the context clinic
+type Service interface {
+	// ExportPatient gathers every record referencing the patient
+	ExportPatient(ctx context.Context, patientID patient.ID) (privacy.Export, error)
+	// RequestErasure waits for another user to approve the erasure of the patient
+	RequestErasure(ctx context.Context, patientID patient.ID, requestedBy user.ID, reason string) (privacy.Erasure, error)
+	GetErasure(ctx context.Context, erasureID privacy.ErasureID) (privacy.Erasure, error)
+	// ListErasures returns the erasures of the clinic in the status, all of them when empty, newest first
+	ListErasures(ctx context.Context, status privacy.ErasureStatus) ([]privacy.Erasure, error)
+	// ApproveErasure pseudonymizes the patient, the reviewer can not be the requester
+	ApproveErasure(ctx context.Context, erasureID privacy.ErasureID, reviewedBy user.ID) (privacy.Erasure, error)
+	RejectErasure(ctx context.Context, erasureID privacy.ErasureID, reviewedBy user.ID) (privacy.Erasure, error)
+}
+
+// Persistence reaches every table referencing the patients, the queries are scoped to the context clinic
+type Persistence interface {
+	// GetPatient returns sql.ErrNoRows when the patient is not one of the clinic
+	GetPatient(ctx context.Context, patientID patient.ID) (patient.Patient, error)
+	ListConsents(ctx context.Context, patientID patient.ID) ([]consent.Consent, error)
+	// ListEvents returns the events of the outbox whose payload references the patient, see privacy.PatientReferences
+	ListEvents(ctx context.Context, patientID patient.ID) ([]event.Event, error)
+	// ListWebhookDeliveries returns the deliveries of the events referencing the patient, along with their attempts
+	ListWebhookDeliveries(ctx context.Context, patientID patient.ID) ([]webhook.Delivery, error)
+	// ErasePatient replaces the patient row by the pseudonym, along with the payloads of the privacy.IdentityEventTypes
+	// referencing it in the outbox and in the webhook deliveries. The responses to these deliveries are emptied and
+	// the idempotency records whose response holds the email of the patient are deleted.
+	// It returns sql.ErrNoRows when the patient is not one of the clinic
+	ErasePatient(ctx context.Context, pseudonym patient.Patient) error
+
+	InsertErasure(ctx context.Context, newErasure privacy.Erasure) (privacy.Erasure, error)
+	// GetErasure returns sql.ErrNoRows when the erasure is not one of the clinic
+	GetErasure(ctx context.Context, erasureID privacy.ErasureID) (privacy.Erasure, error)
+	// ListErasures returns the erasures in the status, all of them when empty, newest first
+	ListErasures(ctx context.Context, status privacy.ErasureStatus) ([]privacy.Erasure, error)
+	ListPatientErasures(ctx context.Context, patientID patient.ID) ([]privacy.Erasure, error)
+	// ReviewErasure sets the status and the review of a pending erasure, sql.ErrNoRows when it is no longer pending
+	ReviewErasure(ctx context.Context, reviewed privacy.Erasure) (privacy.Erasure, error)
+}
+
+// EventClient records the domain events in the outbox, with the ctx of the unit of work of the change
+type EventClient interface {
+	Emit(ctx context.Context, eventType event.Type, payload any) error
+}
@@ -0,0 +1,247 @@
+package privacy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	patient "github.com/sopial42/cleanic/internal/domains/patient"
+	privacy "github.com/sopial42/cleanic/internal/domains/privacy"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/services/transaction"
+)
+
+// maxReasonLength fits a reference to the request of the patient and a short explanation
+const maxReasonLength = 512
+
+var (
+	ErrPatientNotFound   = errors.New("patient not found")
+	ErrErasureNotFound   = errors.New("erasure not found")
+	ErrInvalidErasure    = errors.New("invalid erasure")
+	ErrErasureConflict   = errors.New("erasure conflict")
+	ErrSameReviewer      = errors.New("an erasure must be reviewed by another user than its requester")
+	ErrErasureNotPending = errors.New("erasure already reviewed")
+)
+
+type privacyService struct {
+	persistence Persistence
+	events      EventClient
+	uow         transaction.UnitOfWork
+}
+
+func NewPrivacyService(persistence Persistence, events EventClient, uow transaction.UnitOfWork) Service {
+	return &privacyService{
+		persistence: persistence,
+		events:      events,
+		uow:         uow,
+	}
+}
+
+func (p *privacyService) ExportPatient(ctx context.Context, patientID patient.ID) (privacy.Export, error) {
+	export := privacy.Export{PatientID: patientID, ExportedAt: time.Now().UTC()}
+	// a single transaction so that the files agree with each other
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		export.Patient, err = p.getPatient(ctx, patientID)
+		if err != nil {
+			return err
+		}
+
+		export.Consents, err = p.persistence.ListConsents(ctx, patientID)
+		if err != nil {
+			return fmt.Errorf("unable to list consents: %w", err)
+		}
+
+		export.Erasures, err = p.persistence.ListPatientErasures(ctx, patientID)
+		if err != nil {
+			return fmt.Errorf("unable to list erasures: %w", err)
+		}
+
+		events, err := p.persistence.ListEvents(ctx, patientID)
+		if err != nil {
+			return fmt.Errorf("unable to list events: %w", err)
+		}
+
+		export.Events = make([]event.Envelope, 0, len(events))
+		for _, pending := range events {
+			export.Events = append(export.Events, pending.Envelope())
+		}
+
+		export.WebhookDeliveries, err = p.persistence.ListWebhookDeliveries(ctx, patientID)
+		if err != nil {
+			return fmt.Errorf("unable to list webhook deliveries: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return privacy.Export{}, err
+	}
+
+	return export, nil
+}
+
+func (p *privacyService) RequestErasure(ctx context.Context, patientID patient.ID, requestedBy user.ID, reason string) (privacy.Erasure, error) {
+	if len(reason) > maxReasonLength {
+		return privacy.Erasure{}, fmt.Errorf("%w: reason exceeds %d characters", ErrInvalidErasure, maxReasonLength)
+	}
+
+	var erasureRequested privacy.Erasure
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		if _, err := p.getPatient(ctx, patientID); err != nil {
+			return err
+		}
+
+		erasures, err := p.persistence.ListPatientErasures(ctx, patientID)
+		if err != nil {
+			return fmt.Errorf("unable to list erasures: %w", err)
+		}
+
+		for _, erasure := range erasures {
+			if erasure.Status != privacy.ErasureStatusRejected {
+				return fmt.Errorf("%w: erasure %d of patient %d is %s", ErrErasureConflict, erasure.ID, patientID, erasure.Status)
+			}
+		}
+
+		erasureRequested, err = p.persistence.InsertErasure(ctx, privacy.Erasure{
+			PatientID:   patientID,
+			Status:      privacy.ErasureStatusPending,
+			Reason:      reason,
+			RequestedBy: requestedBy,
+			RequestedAt: time.Now().UTC(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to request erasure: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return privacy.Erasure{}, err
+	}
+
+	return erasureRequested, nil
+}
+
+func (p *privacyService) GetErasure(ctx context.Context, erasureID privacy.ErasureID) (privacy.Erasure, error) {
+	erasure, err := p.persistence.GetErasure(ctx, erasureID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return privacy.Erasure{}, fmt.Errorf("%w: %d", ErrErasureNotFound, erasureID)
+	}
+
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to get erasure %d: %w", erasureID, err)
+	}
+
+	return erasure, nil
+}
+
+func (p *privacyService) ListErasures(ctx context.Context, status privacy.ErasureStatus) ([]privacy.Erasure, error) {
+	if status != "" && !status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidErasure, status)
+	}
+
+	erasures, err := p.persistence.ListErasures(ctx, status)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list erasures: %w", err)
+	}
+
+	return erasures, nil
+}
+
+// ApproveErasure keeps the consents and the erasures of the patient, they are the proof of what it agreed to
+// and of its erasure. The partners are told with a patient.erased event
+func (p *privacyService) ApproveErasure(ctx context.Context, erasureID privacy.ErasureID, reviewedBy user.ID) (privacy.Erasure, error) {
+	var erasureApproved privacy.Erasure
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		erasure, err := p.reviewable(ctx, erasureID, reviewedBy)
+		if err != nil {
+			return err
+		}
+
+		if err := p.persistence.ErasePatient(ctx, privacy.Pseudonym(erasure.PatientID)); err != nil {
+			return fmt.Errorf("unable to erase patient %d: %w", erasure.PatientID, err)
+		}
+
+		erasureApproved, err = p.review(ctx, erasure, privacy.ErasureStatusCompleted, reviewedBy)
+		if err != nil {
+			return err
+		}
+
+		return p.events.Emit(ctx, event.TypePatientErased, event.PatientErased{ID: int64(erasure.PatientID)})
+	})
+	if err != nil {
+		return privacy.Erasure{}, err
+	}
+
+	return erasureApproved, nil
+}
+
+func (p *privacyService) RejectErasure(ctx context.Context, erasureID privacy.ErasureID, reviewedBy user.ID) (privacy.Erasure, error) {
+	var erasureRejected privacy.Erasure
+	err := p.uow.Do(ctx, func(ctx context.Context) error {
+		erasure, err := p.reviewable(ctx, erasureID, reviewedBy)
+		if err != nil {
+			return err
+		}
+
+		erasureRejected, err = p.review(ctx, erasure, privacy.ErasureStatusRejected, reviewedBy)
+		return err
+	})
+	if err != nil {
+		return privacy.Erasure{}, err
+	}
+
+	return erasureRejected, nil
+}
+
+// reviewable returns the erasure when it is pending and reviewedBy did not request it
+func (p *privacyService) reviewable(ctx context.Context, erasureID privacy.ErasureID, reviewedBy user.ID) (privacy.Erasure, error) {
+	erasure, err := p.GetErasure(ctx, erasureID)
+	if err != nil {
+		return privacy.Erasure{}, err
+	}
+
+	if erasure.Status != privacy.ErasureStatusPending {
+		return privacy.Erasure{}, fmt.Errorf("%w: erasure %d is %s", ErrErasureNotPending, erasureID, erasure.Status)
+	}
+
+	if erasure.RequestedBy == reviewedBy {
+		return privacy.Erasure{}, ErrSameReviewer
+	}
+
+	return erasure, nil
+}
+
+func (p *privacyService) review(ctx context.Context, erasure privacy.Erasure, status privacy.ErasureStatus, reviewedBy user.ID) (privacy.Erasure, error) {
+	reviewedAt := time.Now().UTC()
+	erasure.Status = status
+	erasure.ReviewedBy = reviewedBy
+	erasure.ReviewedAt = &reviewedAt
+	erasureReviewed, err := p.persistence.ReviewErasure(ctx, erasure)
+	if errors.Is(err, sql.ErrNoRows) {
+		return privacy.Erasure{}, fmt.Errorf("%w: erasure %d", ErrErasureNotPending, erasure.ID)
+	}
+
+	if err != nil {
+		return privacy.Erasure{}, fmt.Errorf("unable to review erasure %d: %w", erasure.ID, err)
+	}
+
+	return erasureReviewed, nil
+}
+
+func (p *privacyService) getPatient(ctx context.Context, patientID patient.ID) (patient.Patient, error) {
+	patientFound, err := p.persistence.GetPatient(ctx, patientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return patient.Patient{}, fmt.Errorf("%w: %d", ErrPatientNotFound, patientID)
+	}
+
+	if err != nil {
+		return patient.Patient{}, fmt.Errorf("unable to get patient %d: %w", patientID, err)
+	}
+
+	return patientFound, nil
+}
@@ -0,0 +1,19 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/ratelimit"
+)
+
+type Service interface {
+	// Allow takes a token from the bucket of key
+	Allow(ctx context.Context, key string, quota ratelimit.Quota) (ratelimit.Decision, error)
+}
+
+// Store keeps the buckets, in memory for a single replica or in a database shared by all the replicas.
+// Take must be atomic for a given key
+type Store interface {
+	Take(ctx context.Context, key string, quota ratelimit.Quota, now time.Time) (ratelimit.Decision, error)
+}
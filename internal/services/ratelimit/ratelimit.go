@@ -0,0 +1,26 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/domains/ratelimit"
+)
+
+type rateLimitSVC struct {
+	store Store
+}
+
+func NewRateLimitService(store Store) Service {
+	return &rateLimitSVC{store: store}
+}
+
+func (r *rateLimitSVC) Allow(ctx context.Context, key string, quota ratelimit.Quota) (ratelimit.Decision, error) {
+	decision, err := r.store.Take(ctx, key, quota, time.Now())
+	if err != nil {
+		return ratelimit.Decision{}, fmt.Errorf("unable to take a rate limit token: %w", err)
+	}
+
+	return decision, nil
+}
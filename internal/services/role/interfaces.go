@@ -0,0 +1,29 @@
+package role
+
+import (
+	"context"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// Service manages the roles of the context clinic
+type Service interface {
+	ListRoles(ctx context.Context) ([]user.RoleDefinition, error)
+	GetRole(ctx context.Context, name user.Role) (user.RoleDefinition, error)
+	CreateRole(ctx context.Context, newRole user.RoleDefinition) (user.RoleDefinition, error)
+	UpdateRole(ctx context.Context, updatedRole user.RoleDefinition) (user.RoleDefinition, error)
+	DeleteRole(ctx context.Context, name user.Role) error
+	// PermissionsForRoles returns the union of the roles permissions, unknown roles grant nothing
+	PermissionsForRoles(ctx context.Context, roles user.Roles) (user.Permissions, error)
+	// EnsureRolesExist fails if any of the roles is not defined
+	EnsureRolesExist(ctx context.Context, roles user.Roles) error
+}
+
+type Persistence interface {
+	ListRoles(ctx context.Context) ([]user.RoleDefinition, error)
+	GetRole(ctx context.Context, name user.Role) (user.RoleDefinition, error)
+	InsertRole(ctx context.Context, newRole user.RoleDefinition) (user.RoleDefinition, error)
+	UpdateRole(ctx context.Context, updatedRole user.RoleDefinition) (user.RoleDefinition, error)
+	DeleteRole(ctx context.Context, name user.Role) error
+	CountUsersWithRole(ctx context.Context, name user.Role) (int, error)
+}
@@ -0,0 +1,199 @@
+package role
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	user "github.com/sopial42/cleanic/internal/domains/user"
+)
+
+// cacheTTL bounds how long a permission change made on another replica takes to apply
+const cacheTTL = 30 * time.Second
+
+type roleSVC struct {
+	persistence Persistence
+
+	// cache holds the permissions of the roles of each clinic
+	mu    sync.RWMutex
+	cache map[clinic.ID]cachedRoles
+}
+
+type cachedRoles struct {
+	permissions map[user.Role]user.Permissions
+	loadedAt    time.Time
+}
+
+func NewRoleService(persistence Persistence) Service {
+	return &roleSVC{
+		persistence: persistence,
+		cache:       map[clinic.ID]cachedRoles{},
+	}
+}
+
+func (r *roleSVC) ListRoles(ctx context.Context) ([]user.RoleDefinition, error) {
+	roles, err := r.persistence.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+func (r *roleSVC) GetRole(ctx context.Context, name user.Role) (user.RoleDefinition, error) {
+	role, err := r.persistence.GetRole(ctx, name)
+	if err != nil {
+		return user.RoleDefinition{}, err
+	}
+
+	return role, nil
+}
+
+func (r *roleSVC) CreateRole(ctx context.Context, newRole user.RoleDefinition) (user.RoleDefinition, error) {
+	if err := validateRoleDefinition(newRole); err != nil {
+		return user.RoleDefinition{}, err
+	}
+
+	newRole.Builtin = false
+	roleCreated, err := r.persistence.InsertRole(ctx, newRole)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to create role: %w", err)
+	}
+
+	r.invalidate(ctx)
+	return roleCreated, nil
+}
+
+func (r *roleSVC) UpdateRole(ctx context.Context, updatedRole user.RoleDefinition) (user.RoleDefinition, error) {
+	if err := validateRoleDefinition(updatedRole); err != nil {
+		return user.RoleDefinition{}, err
+	}
+
+	existingRole, err := r.persistence.GetRole(ctx, updatedRole.Name)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to get role: %w", err)
+	}
+
+	// builtin roles are frozen, they are the same in every clinic and nobody can lock every admin out
+	if existingRole.Builtin {
+		return user.RoleDefinition{}, fmt.Errorf("unable to update role: %s", existingRole.Name)
+	}
+
+	roleUpdated, err := r.persistence.UpdateRole(ctx, updatedRole)
+	if err != nil {
+		return user.RoleDefinition{}, fmt.Errorf("unable to update role: %w", err)
+	}
+
+	r.invalidate(ctx)
+	return roleUpdated, nil
+}
+
+func (r *roleSVC) DeleteRole(ctx context.Context, name user.Role) error {
+	existingRole, err := r.persistence.GetRole(ctx, name)
+	if err != nil {
+		return fmt.Errorf("unable to get role: %w", err)
+	}
+
+	if existingRole.Builtin {
+		return fmt.Errorf("unable to delete builtin role: %s", name)
+	}
+
+	usersCount, err := r.persistence.CountUsersWithRole(ctx, name)
+	if err != nil {
+		return fmt.Errorf("unable to count users with role: %w", err)
+	}
+
+	if usersCount > 0 {
+		return fmt.Errorf("unable to delete role %s still assigned to %d users", name, usersCount)
+	}
+
+	if err := r.persistence.DeleteRole(ctx, name); err != nil {
+		return fmt.Errorf("unable to delete role: %w", err)
+	}
+
+	r.invalidate(ctx)
+	return nil
+}
+
+func (r *roleSVC) PermissionsForRoles(ctx context.Context, roles user.Roles) (user.Permissions, error) {
+	cache, err := r.loadCache(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var permissions user.Permissions
+	for _, role := range roles {
+		permissions = permissions.Merge(cache[role])
+	}
+
+	return permissions, nil
+}
+
+func (r *roleSVC) EnsureRolesExist(ctx context.Context, roles user.Roles) error {
+	cache, err := r.loadCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, role := range roles {
+		if _, exists := cache[role]; !exists {
+			return fmt.Errorf("unknown role: %s", role)
+		}
+	}
+
+	return nil
+}
+
+// loadCache reloads the roles of the context clinic once its cache is older than cacheTTL
+func (r *roleSVC) loadCache(ctx context.Context) (map[user.Role]user.Permissions, error) {
+	clinicID, err := clinic.IDFromContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load roles: %w", err)
+	}
+
+	r.mu.RLock()
+	cached, found := r.cache[clinicID]
+	r.mu.RUnlock()
+
+	if found && time.Since(cached.loadedAt) < cacheTTL {
+		return cached.permissions, nil
+	}
+
+	roles, err := r.persistence.ListRoles(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load roles: %w", err)
+	}
+
+	cached = cachedRoles{permissions: make(map[user.Role]user.Permissions, len(roles)), loadedAt: time.Now()}
+	for _, role := range roles {
+		cached.permissions[role.Name] = role.Permissions
+	}
+
+	r.mu.Lock()
+	r.cache[clinicID] = cached
+	r.mu.Unlock()
+
+	return cached.permissions, nil
+}
+
+func (r *roleSVC) invalidate(ctx context.Context) {
+	clinicID, _ := clinic.IDFromContext(ctx)
+
+	r.mu.Lock()
+	delete(r.cache, clinicID)
+	r.mu.Unlock()
+}
+
+func validateRoleDefinition(role user.RoleDefinition) error {
+	if !role.Name.IsValid() {
+		return fmt.Errorf("invalid role name: %s", role.Name)
+	}
+
+	if !role.Permissions.AreValid() {
+		return fmt.Errorf("invalid permissions: %s", role.Permissions.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,20 @@
+package tools
+
+import "time"
+
+// ClaimLease outlasts the handling of a claimed batch, the rows claimed by a worker stopped meanwhile are claimed again once it ends
+const ClaimLease = 5 * time.Minute
+
+// BackoffDelay doubles the base delay for each failed attempt, up to maxDelay
+func BackoffDelay(base, maxDelay time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		return maxDelay
+	}
+
+	return delay
+}
@@ -0,0 +1,21 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for attempts, expected := range map[int]time.Duration{
+		0: time.Second,
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		if delay := BackoffDelay(time.Second, 10*time.Second, attempts); delay != expected {
+			t.Errorf("attempt %d: expected %s, got %s", attempts, expected, delay)
+		}
+	}
+}
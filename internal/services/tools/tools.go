@@ -6,8 +6,9 @@ import (
 	"github.com/sopial42/cleanic/internal/domains/user"
 )
 
-func EnsureUserOwnershipOrRoleAdmin(reqUser user.User, userIDToUpdate user.ID) error {
-	if reqUser.ID != userIDToUpdate && !reqUser.Roles.Has(user.RoleAdmin) {
+// EnsureUserOwnershipOrUserManage allows a user to act on itself, or on anyone with the user:manage permission
+func EnsureUserOwnershipOrUserManage(reqUserID user.ID, reqPermissions user.Permissions, userIDToUpdate user.ID) error {
+	if reqUserID != userIDToUpdate && !reqPermissions.Has(user.PermissionUserManage) {
 		return fmt.Errorf("unauthorized to update another user")
 	}
 
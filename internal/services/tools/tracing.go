@@ -0,0 +1,20 @@
+package tools
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StartSpan starts the span of a service method, the returned end records the error of the method
+func StartSpan(ctx context.Context, tracer trace.Tracer, name string) (context.Context, func(error)) {
+	ctx, span := tracer.Start(ctx, name)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
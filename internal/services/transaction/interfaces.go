@@ -0,0 +1,11 @@
+package transaction
+
+import "context"
+
+// UnitOfWork runs the persistence calls of a use case as a whole, whatever the ports they go through
+type UnitOfWork interface {
+	// Do commits the calls made with the ctx given to fn when it returns nil, and rolls them back otherwise.
+	// A Do nested in another one joins it. fn may run again when the transaction is retried
+	// after a serialization failure, it must have no side effect outside the persistence
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
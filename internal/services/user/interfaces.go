@@ -3,17 +3,26 @@ package user
 import (
 	"context"
 
+	event "github.com/sopial42/cleanic/internal/domains/event"
 	user "github.com/sopial42/cleanic/internal/domains/user"
 )
 
 type Service interface {
 	Create(ctx context.Context, newUser user.User) (user.User, error)
+	// CreateServiceAccount creates a user without usable password, it authenticates with API keys only
+	CreateServiceAccount(ctx context.Context, reqUserID user.ID, newUser user.User) (user.User, error)
 	GetUsers(ctx context.Context) ([]user.User, error)
 	GetUserByID(ctx context.Context, id user.ID) (user.User, error)
 	GetUserByEmail(ctx context.Context, email user.Email) (user.User, error)
 	UpdateUser(ctx context.Context, reqUserID user.ID, updatedUser user.User) (user.User, error)
 	UpdateUserRoles(ctx context.Context, reqUserID user.ID, updatedUser user.User) (user.User, error)
 	DeleteUser(ctx context.Context, reqUserID user.ID, userIDToDelete user.ID) error
+	// UpdatePassword sets a new password following the policy, used for forced rotations
+	UpdatePassword(ctx context.Context, userID user.ID, newPassword user.Password) error
+	// RehashPassword stores the already verified password with the current hasher
+	RehashPassword(ctx context.Context, userID user.ID, password user.Password) error
+	// AssignRoles is used by trusted internal callers such as the SSO provisioning, it is never exposed over REST
+	AssignRoles(ctx context.Context, userID user.ID, roles user.Roles) (user.User, error)
 }
 
 type Persistence interface {
@@ -24,4 +33,23 @@ type Persistence interface {
 	UpdateUser(ctx context.Context, updatedUser user.User) (user.User, error)
 	UpdateUserRoles(ctx context.Context, updatedUserRoles user.User) (user.User, error)
 	DeleteUser(ctx context.Context, userIDToDelete user.ID) error
+	// ListPasswordHistory returns the last hashes of a user, most recent first
+	ListPasswordHistory(ctx context.Context, userID user.ID, limit int) ([]user.Password, error)
+	InsertPasswordHistory(ctx context.Context, userID user.ID, hash user.Password) error
+}
+
+// RoleClient resolves roles stored by the role service
+type RoleClient interface {
+	PermissionsForRoles(ctx context.Context, roles user.Roles) (user.Permissions, error)
+	EnsureRolesExist(ctx context.Context, roles user.Roles) error
+}
+
+// SessionClient revokes the refresh token of a user
+type SessionClient interface {
+	DeleteRefreshTokenClaims(ctx context.Context, userID user.ID) error
+}
+
+// EventClient records the domain events in the outbox, with the ctx of the unit of work of the change
+type EventClient interface {
+	Emit(ctx context.Context, eventType event.Type, payload any) error
 }
@@ -0,0 +1,109 @@
+package user
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+
+	user "github.com/sopial42/cleanic/internal/domains/user"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+var tracer = otel.Tracer("github.com/sopial42/cleanic/internal/services/user")
+
+type tracedService struct {
+	next Service
+}
+
+// NewTracedService wraps each method of the service in a span
+func NewTracedService(next Service) Service {
+	return &tracedService{next: next}
+}
+
+func (t *tracedService) Create(ctx context.Context, newUser user.User) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.Create")
+	userCreated, err := t.next.Create(ctx, newUser)
+	end(err)
+
+	return userCreated, err
+}
+
+func (t *tracedService) CreateServiceAccount(ctx context.Context, reqUserID user.ID, newUser user.User) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.CreateServiceAccount")
+	userCreated, err := t.next.CreateServiceAccount(ctx, reqUserID, newUser)
+	end(err)
+
+	return userCreated, err
+}
+
+func (t *tracedService) GetUsers(ctx context.Context) ([]user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.GetUsers")
+	users, err := t.next.GetUsers(ctx)
+	end(err)
+
+	return users, err
+}
+
+func (t *tracedService) GetUserByID(ctx context.Context, id user.ID) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.GetUserByID")
+	userFound, err := t.next.GetUserByID(ctx, id)
+	end(err)
+
+	return userFound, err
+}
+
+func (t *tracedService) GetUserByEmail(ctx context.Context, email user.Email) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.GetUserByEmail")
+	userFound, err := t.next.GetUserByEmail(ctx, email)
+	end(err)
+
+	return userFound, err
+}
+
+func (t *tracedService) UpdateUser(ctx context.Context, reqUserID user.ID, updatedUser user.User) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.UpdateUser")
+	userUpdated, err := t.next.UpdateUser(ctx, reqUserID, updatedUser)
+	end(err)
+
+	return userUpdated, err
+}
+
+func (t *tracedService) UpdateUserRoles(ctx context.Context, reqUserID user.ID, updatedUser user.User) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.UpdateUserRoles")
+	userUpdated, err := t.next.UpdateUserRoles(ctx, reqUserID, updatedUser)
+	end(err)
+
+	return userUpdated, err
+}
+
+func (t *tracedService) DeleteUser(ctx context.Context, reqUserID user.ID, userIDToDelete user.ID) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.DeleteUser")
+	err := t.next.DeleteUser(ctx, reqUserID, userIDToDelete)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) UpdatePassword(ctx context.Context, userID user.ID, newPassword user.Password) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.UpdatePassword")
+	err := t.next.UpdatePassword(ctx, userID, newPassword)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) RehashPassword(ctx context.Context, userID user.ID, password user.Password) error {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.RehashPassword")
+	err := t.next.RehashPassword(ctx, userID, password)
+	end(err)
+
+	return err
+}
+
+func (t *tracedService) AssignRoles(ctx context.Context, userID user.ID, roles user.Roles) (user.User, error) {
+	ctx, end := tools.StartSpan(ctx, tracer, "userSVC.AssignRoles")
+	userUpdated, err := t.next.AssignRoles(ctx, userID, roles)
+	end(err)
+
+	return userUpdated, err
+}
@@ -2,36 +2,122 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"time"
 
+	event "github.com/sopial42/cleanic/internal/domains/event"
 	user "github.com/sopial42/cleanic/internal/domains/user"
+	passwordSVC "github.com/sopial42/cleanic/internal/services/password"
 	"github.com/sopial42/cleanic/internal/services/tools"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/sopial42/cleanic/internal/services/transaction"
 )
 
+// ErrUserNotFound is also returned for the users who are not members of the context clinic
+var ErrUserNotFound = errors.New("user not found")
+
 type userSVC struct {
 	persistence Persistence
+	passwords   passwordSVC.Service
+	roles       RoleClient
+	sessions    SessionClient
+	events      EventClient
+	uow         transaction.UnitOfWork
 }
 
-func NewUserService(persistence Persistence) Service {
+func NewUserService(persistence Persistence, passwords passwordSVC.Service, roles RoleClient, sessions SessionClient, events EventClient, uow transaction.UnitOfWork) Service {
 	return &userSVC{
 		persistence: persistence,
+		passwords:   passwords,
+		roles:       roles,
+		sessions:    sessions,
+		events:      events,
+		uow:         uow,
 	}
 }
 
 func (u *userSVC) Create(ctx context.Context, newUser user.User) (user.User, error) {
 	// Assign default role
-	newUser.Roles = []user.Role{user.MinimalMandatoryRole}
-
-	// Hash password
-	hash, _ := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
-	newUser.Password = user.Password(hash)
+	newUser.Roles = []user.Role{user.DefaultRole}
 
 	// Check email is correct
 	if !newUser.Email.IsValid() {
 		return user.User{}, fmt.Errorf("invalid email input: %v", newUser.Email)
 	}
 
+	// Hash password, a new user has no history yet
+	hash, err := u.newPasswordHash(ctx, 0, newUser.Password)
+	if err != nil {
+		return user.User{}, err
+	}
+
+	newUser.Password = hash
+	newUser.PasswordChangedAt = time.Now()
+
+	var userCreated user.User
+	err = u.uow.Do(ctx, func(ctx context.Context) error {
+		userCreated, err = u.persistence.Insert(ctx, newUser)
+		if err != nil {
+			return err
+		}
+
+		if err := u.persistence.InsertPasswordHistory(ctx, userCreated.ID, hash); err != nil {
+			return fmt.Errorf("unable to store password history: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+
+	return userCreated, nil
+}
+
+func (u *userSVC) CreateServiceAccount(ctx context.Context, reqUserID user.ID, newUser user.User) (user.User, error) {
+	reqUser, err := u.persistence.GetUserByID(ctx, reqUserID)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to get request's user details: %w", err)
+	}
+
+	reqPermissions, err := u.roles.PermissionsForRoles(ctx, reqUser.Roles)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to get request's user permissions: %w", err)
+	}
+
+	if !reqPermissions.Has(user.PermissionUserManage) {
+		return user.User{}, fmt.Errorf("unauthorized to create service accounts")
+	}
+
+	if !newUser.Email.IsValid() {
+		return user.User{}, fmt.Errorf("invalid email input: %v", newUser.Email)
+	}
+
+	if !newUser.Roles.AreValid() {
+		return user.User{}, fmt.Errorf("unable to create service account, invalid roles: %v", newUser.Roles)
+	}
+
+	if err := u.roles.EnsureRolesExist(ctx, newUser.Roles); err != nil {
+		return user.User{}, fmt.Errorf("unable to create service account: %w", err)
+	}
+
+	// Nobody knows this password, login is refused for service accounts anyway
+	randomPassword := make([]byte, 32)
+	if _, err := rand.Read(randomPassword); err != nil {
+		return user.User{}, fmt.Errorf("unable to generate password: %w", err)
+	}
+
+	hash, err := u.passwords.Hash(user.Password(base64.RawURLEncoding.EncodeToString(randomPassword)))
+	if err != nil {
+		return user.User{}, err
+	}
+
+	newUser.Password = hash
+	newUser.PasswordChangedAt = time.Now()
+	newUser.ServiceAccount = true
 	userCreated, err := u.persistence.Insert(ctx, newUser)
 	if err != nil {
 		return user.User{}, err
@@ -83,8 +169,12 @@ func (u *userSVC) UpdateUser(ctx context.Context, reqUserID user.ID, newUser use
 		return user.User{}, fmt.Errorf("unable to update nothing on the user")
 	}
 
-	// Ensure user try to update its own user OR is admin
-	if err = tools.EnsureUserOwnershipOrRoleAdmin(reqUser, newUser.ID); err != nil {
+	// Ensure user try to update its own user OR can manage users
+	if err = u.ensureOwnershipOrUserManage(ctx, reqUser, newUser.ID); err != nil {
+		return user.User{}, err
+	}
+
+	if err := u.ensureMember(ctx, newUser.ID); err != nil {
 		return user.User{}, err
 	}
 
@@ -95,13 +185,32 @@ func (u *userSVC) UpdateUser(ctx context.Context, reqUserID user.ID, newUser use
 	}
 
 	if newUser.Password != "" {
-		hash, _ := bcrypt.GenerateFromPassword([]byte(newUser.Password), bcrypt.DefaultCost)
-		newUser.Password = user.Password(hash)
+		hash, err := u.newPasswordHash(ctx, newUser.ID, newUser.Password)
+		if err != nil {
+			return user.User{}, err
+		}
+
+		newUser.Password = hash
+		newUser.PasswordChangedAt = time.Now()
 	}
 
-	userUpdated, err := u.persistence.UpdateUser(ctx, newUser)
+	var userUpdated user.User
+	err = u.uow.Do(ctx, func(ctx context.Context) error {
+		userUpdated, err = u.persistence.UpdateUser(ctx, newUser)
+		if err != nil {
+			return fmt.Errorf("unable to update user: %w", err)
+		}
+
+		if newUser.Password != "" {
+			if err := u.persistence.InsertPasswordHistory(ctx, newUser.ID, newUser.Password); err != nil {
+				return fmt.Errorf("unable to store password history: %w", err)
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
-		return user.User{}, fmt.Errorf("unable to update user: %w", err)
+		return user.User{}, err
 	}
 
 	return userUpdated, nil
@@ -113,7 +222,12 @@ func (u *userSVC) UpdateUserRoles(ctx context.Context, reqUserID user.ID, update
 		return user.User{}, fmt.Errorf("unable to get request's user details: %w", err)
 	}
 
-	if !reqUser.Roles.Has(user.RoleAdmin) {
+	reqPermissions, err := u.roles.PermissionsForRoles(ctx, reqUser.Roles)
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to get request's user permissions: %w", err)
+	}
+
+	if !reqPermissions.Has(user.PermissionUserManage) {
 		return user.User{}, fmt.Errorf("unauthorized to updated roles")
 	}
 
@@ -121,7 +235,11 @@ func (u *userSVC) UpdateUserRoles(ctx context.Context, reqUserID user.ID, update
 		return user.User{}, fmt.Errorf("unable to update roles, invalid input: %v", updatedUser.Roles)
 	}
 
-	userUpdated, err := u.persistence.UpdateUserRoles(ctx, updatedUser)
+	if err := u.roles.EnsureRolesExist(ctx, updatedUser.Roles); err != nil {
+		return user.User{}, fmt.Errorf("unable to update roles: %w", err)
+	}
+
+	userUpdated, err := u.updateRoles(ctx, updatedUser)
 	if err != nil {
 		return user.User{}, fmt.Errorf("unable to update user: %w", err)
 	}
@@ -135,14 +253,146 @@ func (u *userSVC) DeleteUser(ctx context.Context, reqUserID user.ID, userIDToDel
 		return fmt.Errorf("unable to get request's user details: %w", err)
 	}
 
-	if err = tools.EnsureUserOwnershipOrRoleAdmin(reqUser, userIDToDelete); err != nil {
+	if err = u.ensureOwnershipOrUserManage(ctx, reqUser, userIDToDelete); err != nil {
+		return err
+	}
+
+	// The refresh tokens are not scoped to a clinic, the membership is checked before revoking them
+	if err := u.ensureMember(ctx, userIDToDelete); err != nil {
 		return err
 	}
 
-	err = u.persistence.DeleteUser(ctx, userIDToDelete)
+	// The session may be scoped to the clinic the user is removed from, it is revoked along
+	return u.uow.Do(ctx, func(ctx context.Context) error {
+		if err := u.persistence.DeleteUser(ctx, userIDToDelete); err != nil {
+			return fmt.Errorf("unable to delete user: %w", err)
+		}
+
+		if err := u.sessions.DeleteRefreshTokenClaims(ctx, userIDToDelete); err != nil {
+			return fmt.Errorf("unable to revoke user session: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (u *userSVC) UpdatePassword(ctx context.Context, userID user.ID, newPassword user.Password) error {
+	hash, err := u.newPasswordHash(ctx, userID, newPassword)
+	if err != nil {
+		return err
+	}
+
+	return u.uow.Do(ctx, func(ctx context.Context) error {
+		_, err := u.persistence.UpdateUser(ctx, user.User{
+			ID:                userID,
+			Password:          hash,
+			PasswordChangedAt: time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to update password: %w", err)
+		}
+
+		if err := u.persistence.InsertPasswordHistory(ctx, userID, hash); err != nil {
+			return fmt.Errorf("unable to store password history: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (u *userSVC) AssignRoles(ctx context.Context, userID user.ID, roles user.Roles) (user.User, error) {
+	if !roles.AreValid() {
+		return user.User{}, fmt.Errorf("unable to assign roles, invalid input: %v", roles)
+	}
+
+	if err := u.roles.EnsureRolesExist(ctx, roles); err != nil {
+		return user.User{}, fmt.Errorf("unable to assign roles: %w", err)
+	}
+
+	userUpdated, err := u.updateRoles(ctx, user.User{ID: userID, Roles: roles})
+	if err != nil {
+		return user.User{}, fmt.Errorf("unable to assign roles: %w", err)
+	}
+
+	return userUpdated, nil
+}
+
+// updateRoles stores the roles in the context clinic along with the event announcing them
+func (u *userSVC) updateRoles(ctx context.Context, updatedUser user.User) (user.User, error) {
+	var userUpdated user.User
+	err := u.uow.Do(ctx, func(ctx context.Context) error {
+		var err error
+		userUpdated, err = u.persistence.UpdateUserRoles(ctx, updatedUser)
+		if err != nil {
+			return err
+		}
+
+		return u.events.Emit(ctx, event.TypeUserRolesChanged, event.UserRolesChanged{UserID: userUpdated.ID, Roles: userUpdated.Roles})
+	})
+	if err != nil {
+		return user.User{}, err
+	}
+
+	return userUpdated, nil
+}
+
+// RehashPassword neither checks the policy nor touches the password age and history
+// as the password itself does not change
+func (u *userSVC) RehashPassword(ctx context.Context, userID user.ID, password user.Password) error {
+	hash, err := u.passwords.Hash(password)
+	if err != nil {
+		return err
+	}
+
+	if _, err := u.persistence.UpdateUser(ctx, user.User{ID: userID, Password: hash}); err != nil {
+		return fmt.Errorf("unable to rehash password: %w", err)
+	}
+
+	return nil
+}
+
+func (u *userSVC) ensureOwnershipOrUserManage(ctx context.Context, reqUser user.User, targetUserID user.ID) error {
+	reqPermissions, err := u.roles.PermissionsForRoles(ctx, reqUser.Roles)
+	if err != nil {
+		return fmt.Errorf("unable to get request's user permissions: %w", err)
+	}
+
+	return tools.EnsureUserOwnershipOrUserManage(reqUser.ID, reqPermissions, targetUserID)
+}
+
+// ensureMember fails with ErrUserNotFound when the user is not a member of the context clinic
+func (u *userSVC) ensureMember(ctx context.Context, userID user.ID) error {
+	_, err := u.persistence.GetUserByID(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: %d", ErrUserNotFound, userID)
+	}
+
 	if err != nil {
-		return fmt.Errorf("unable to update user: %w", err)
+		return fmt.Errorf("unable to get user %d: %w", userID, err)
 	}
 
 	return nil
 }
+
+// newPasswordHash validates a new password against the policy and the user's
+// previous passwords, then hashes it with the current hasher
+func (u *userSVC) newPasswordHash(ctx context.Context, userID user.ID, newPassword user.Password) (user.Password, error) {
+	if err := u.passwords.Validate(newPassword); err != nil {
+		return "", fmt.Errorf("invalid password: %w", err)
+	}
+
+	if userID != 0 && u.passwords.HistorySize() > 0 {
+		previousHashes, err := u.persistence.ListPasswordHistory(ctx, userID, u.passwords.HistorySize())
+		if err != nil {
+			return "", fmt.Errorf("unable to get password history: %w", err)
+		}
+
+		for _, previousHash := range previousHashes {
+			if match, _ := u.passwords.Verify(previousHash, newPassword); match {
+				return "", fmt.Errorf("invalid password: must differ from the last %d passwords", u.passwords.HistorySize())
+			}
+		}
+	}
+
+	return u.passwords.Hash(newPassword)
+}
@@ -0,0 +1,57 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+)
+
+// Service manages the webhook subscriptions of the context clinic and delivers the events to them
+type Service interface {
+	// CreateSubscription generates the secret when none is given, it is only returned here
+	CreateSubscription(ctx context.Context, newSubscription webhook.Subscription) (webhook.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error)
+	GetSubscription(ctx context.Context, id webhook.ID) (webhook.Subscription, error)
+	// UpdateSubscription keeps the secret unless a new one is given
+	UpdateSubscription(ctx context.Context, subscription webhook.Subscription) (webhook.Subscription, error)
+	DeleteSubscription(ctx context.Context, id webhook.ID) error
+	// ListDeliveries returns the latest deliveries of a subscription with their attempts, status filters them when set
+	ListDeliveries(ctx context.Context, id webhook.ID, status webhook.Status) ([]webhook.Delivery, error)
+	// RetryDelivery gives a dead delivery another round of attempts
+	RetryDelivery(ctx context.Context, id webhook.ID, deliveryID webhook.DeliveryID) error
+
+	// Enqueue records a delivery of the event for each subscription of its clinic accepting it.
+	// It is called once per event by the events dispatcher, a redelivered event is not enqueued twice
+	Enqueue(ctx context.Context, envelope event.Envelope) error
+	// Deliver attempts a batch of due deliveries and returns how many it handled.
+	// A failed attempt is retried with an exponential backoff until the delivery is dead
+	Deliver(ctx context.Context) (int, error)
+}
+
+type Persistence interface {
+	// The subscriptions are scoped to the context clinic
+	InsertSubscription(ctx context.Context, newSubscription webhook.Subscription) (webhook.Subscription, error)
+	ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error)
+	GetSubscription(ctx context.Context, id webhook.ID) (webhook.Subscription, error)
+	UpdateSubscription(ctx context.Context, subscription webhook.Subscription) (webhook.Subscription, error)
+	// DeleteSubscription also deletes its deliveries
+	DeleteSubscription(ctx context.Context, id webhook.ID) error
+
+	// InsertDeliveries skips the deliveries of an event already recorded for the subscription
+	InsertDeliveries(ctx context.Context, deliveries []webhook.Delivery) error
+	// ClaimDueDeliveries leases up to limit pending deliveries due at now, of every clinic, oldest first
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]webhook.Delivery, error)
+	// RecordAttempt stores the attempt, moves the delivery to status and releases the lease
+	RecordAttempt(ctx context.Context, deliveryID webhook.DeliveryID, attempt webhook.Attempt, status webhook.Status, nextAttemptAt time.Time) error
+	// ListDeliveries returns up to limit deliveries of the subscription, newest first, with their attempts
+	ListDeliveries(ctx context.Context, id webhook.ID, status webhook.Status, limit int) ([]webhook.Delivery, error)
+	// ResetDelivery moves a dead delivery of the subscription back to pending, due at now
+	ResetDelivery(ctx context.Context, id webhook.ID, deliveryID webhook.DeliveryID, now time.Time) error
+}
+
+// Sender POSTs a signed payload, a response of any status is an attempt without error
+type Sender interface {
+	Send(ctx context.Context, url string, headers map[string]string, body []byte) webhook.Attempt
+}
@@ -0,0 +1,263 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/sopial42/cleanic/internal/config"
+	clinic "github.com/sopial42/cleanic/internal/domains/clinic"
+	event "github.com/sopial42/cleanic/internal/domains/event"
+	webhook "github.com/sopial42/cleanic/internal/domains/webhook"
+	"github.com/sopial42/cleanic/internal/services/tools"
+)
+
+// deliveriesListed bounds the dashboard to the latest deliveries
+const deliveriesListed = 100
+
+var (
+	ErrInvalidSubscription  = errors.New("invalid webhook subscription")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotDead      = errors.New("webhook delivery not found or not dead")
+)
+
+type webhookService struct {
+	persistence    Persistence
+	sender         Sender
+	webhooksConfig config.WebhooksConfig
+}
+
+func NewWebhookService(persistence Persistence, sender Sender, webhooksConfig config.WebhooksConfig) Service {
+	return &webhookService{
+		persistence:    persistence,
+		sender:         sender,
+		webhooksConfig: webhooksConfig,
+	}
+}
+
+func (w *webhookService) CreateSubscription(ctx context.Context, newSubscription webhook.Subscription) (webhook.Subscription, error) {
+	if err := w.validateSubscription(newSubscription); err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to create webhook subscription: %w", err)
+	}
+
+	if newSubscription.Secret == "" {
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			return webhook.Subscription{}, err
+		}
+
+		newSubscription.Secret = secret
+	}
+
+	subscriptionCreated, err := w.persistence.InsertSubscription(ctx, newSubscription)
+	if err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to create webhook subscription: %w", err)
+	}
+
+	return subscriptionCreated, nil
+}
+
+func (w *webhookService) ListSubscriptions(ctx context.Context) ([]webhook.Subscription, error) {
+	subscriptions, err := w.persistence.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list webhook subscriptions: %w", err)
+	}
+
+	return subscriptions, nil
+}
+
+func (w *webhookService) GetSubscription(ctx context.Context, id webhook.ID) (webhook.Subscription, error) {
+	subscription, err := w.persistence.GetSubscription(ctx, id)
+	if err != nil {
+		return webhook.Subscription{}, subscriptionError("get", id, err)
+	}
+
+	return subscription, nil
+}
+
+func (w *webhookService) UpdateSubscription(ctx context.Context, subscription webhook.Subscription) (webhook.Subscription, error) {
+	if err := w.validateSubscription(subscription); err != nil {
+		return webhook.Subscription{}, fmt.Errorf("unable to update webhook subscription %d: %w", subscription.ID, err)
+	}
+
+	if subscription.Secret == "" {
+		current, err := w.persistence.GetSubscription(ctx, subscription.ID)
+		if err != nil {
+			return webhook.Subscription{}, subscriptionError("update", subscription.ID, err)
+		}
+
+		subscription.Secret = current.Secret
+	}
+
+	subscriptionUpdated, err := w.persistence.UpdateSubscription(ctx, subscription)
+	if err != nil {
+		return webhook.Subscription{}, subscriptionError("update", subscription.ID, err)
+	}
+
+	return subscriptionUpdated, nil
+}
+
+func (w *webhookService) DeleteSubscription(ctx context.Context, id webhook.ID) error {
+	if err := w.persistence.DeleteSubscription(ctx, id); err != nil {
+		return subscriptionError("delete", id, err)
+	}
+
+	return nil
+}
+
+func (w *webhookService) ListDeliveries(ctx context.Context, id webhook.ID, status webhook.Status) ([]webhook.Delivery, error) {
+	// the deliveries of the subscriptions of another clinic are not disclosed
+	if _, err := w.GetSubscription(ctx, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := w.persistence.ListDeliveries(ctx, id, status, deliveriesListed)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list deliveries of webhook subscription %d: %w", id, err)
+	}
+
+	return deliveries, nil
+}
+
+func (w *webhookService) RetryDelivery(ctx context.Context, id webhook.ID, deliveryID webhook.DeliveryID) error {
+	if _, err := w.GetSubscription(ctx, id); err != nil {
+		return err
+	}
+
+	err := w.persistence.ResetDelivery(ctx, id, deliveryID, time.Now().UTC())
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("unable to retry delivery %d: %w", deliveryID, ErrDeliveryNotDead)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to retry delivery %d: %w", deliveryID, err)
+	}
+
+	return nil
+}
+
+func (w *webhookService) Enqueue(ctx context.Context, envelope event.Envelope) error {
+	subscriptions, err := w.persistence.ListSubscriptions(clinic.WithID(ctx, envelope.ClinicID))
+	if err != nil {
+		return fmt.Errorf("unable to list webhook subscriptions of clinic %d: %w", envelope.ClinicID, err)
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("unable to marshal event %d: %w", envelope.ID, err)
+	}
+
+	now := time.Now().UTC()
+	var deliveries []webhook.Delivery
+	for _, subscription := range subscriptions {
+		if !subscription.Accepts(envelope.Type) {
+			continue
+		}
+
+		deliveries = append(deliveries, webhook.Delivery{
+			SubscriptionID: subscription.ID,
+			ClinicID:       envelope.ClinicID,
+			EventID:        envelope.ID,
+			EventType:      envelope.Type,
+			Payload:        payload,
+			Status:         webhook.StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+	}
+
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if err := w.persistence.InsertDeliveries(ctx, deliveries); err != nil {
+		return fmt.Errorf("unable to enqueue deliveries of event %d: %w", envelope.ID, err)
+	}
+
+	return nil
+}
+
+func (w *webhookService) Deliver(ctx context.Context) (int, error) {
+	dueDeliveries, err := w.persistence.ClaimDueDeliveries(ctx, time.Now().UTC(), tools.ClaimLease, w.webhooksConfig.BatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("unable to claim due deliveries: %w", err)
+	}
+
+	var errs []error
+	for _, due := range dueDeliveries {
+		if err := w.attempt(ctx, due); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return len(dueDeliveries), errors.Join(errs...)
+}
+
+// attempt POSTs the delivery, only the failures to record the attempt are returned,
+// the failed attempts are visible in the deliveries of the subscription
+func (w *webhookService) attempt(ctx context.Context, due webhook.Delivery) error {
+	subscription, err := w.persistence.GetSubscription(clinic.WithID(ctx, due.ClinicID), due.SubscriptionID)
+	if err != nil {
+		return fmt.Errorf("unable to get webhook subscription %d of delivery %d: %w", due.SubscriptionID, due.ID, err)
+	}
+
+	attempt := w.sender.Send(ctx, subscription.URL, map[string]string{
+		webhook.HeaderSignature: webhook.Sign(subscription.Secret, time.Now(), due.Payload),
+		webhook.HeaderEvent:     string(due.EventType),
+		webhook.HeaderDelivery:  strconv.FormatInt(int64(due.ID), 10),
+		// a receiver deduplicates the redeliveries of an event with it
+		"Idempotency-Key": strconv.FormatInt(int64(due.EventID), 10),
+	}, due.Payload)
+
+	status, nextAttemptAt := webhook.StatusSucceeded, attempt.AttemptedAt
+	if !attempt.Succeeded() {
+		status, nextAttemptAt = webhook.StatusPending, attempt.AttemptedAt.Add(tools.BackoffDelay(w.webhooksConfig.RetryBase, w.webhooksConfig.RetryMax, due.AttemptCount+1))
+		if due.AttemptCount+1 >= w.webhooksConfig.MaxAttempts {
+			status = webhook.StatusDead
+		}
+	}
+
+	if err := w.persistence.RecordAttempt(ctx, due.ID, attempt, status, nextAttemptAt); err != nil {
+		return fmt.Errorf("unable to record attempt of delivery %d: %w", due.ID, err)
+	}
+
+	return nil
+}
+
+func (w *webhookService) validateSubscription(subscription webhook.Subscription) error {
+	u, err := url.Parse(subscription.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %q is not an http or https URL", ErrInvalidSubscription, subscription.URL)
+	}
+
+	// the host names are checked by the sender once resolved, the addresses are refused early
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !webhook.ReceiverAllowed(ip, w.webhooksConfig.AllowedNetworks) {
+		return fmt.Errorf("%w: %s is not a public address", ErrInvalidSubscription, u.Hostname())
+	}
+
+	if len(subscription.EventTypes) == 0 {
+		return fmt.Errorf("%w: missing event types", ErrInvalidSubscription)
+	}
+
+	for _, eventType := range subscription.EventTypes {
+		if !eventType.IsValid() {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidSubscription, eventType)
+		}
+	}
+
+	return nil
+}
+
+// subscriptionError tells a missing subscription apart, the REST adapter answers 404 for it
+func subscriptionError(action string, id webhook.ID, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		err = ErrSubscriptionNotFound
+	}
+
+	return fmt.Errorf("unable to %s webhook subscription %d: %w", action, id, err)
+}